@@ -0,0 +1,131 @@
+package awos
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// access log levels of AccessLogLevel
+const (
+	AccessLogLevelDebug = "debug"
+	AccessLogLevelInfo  = "info"
+	AccessLogLevelWarn  = "warn"
+	AccessLogLevelError = "error"
+)
+
+// accessLogger logs a line for each operation span of tracedComponent when it ends, so the access log
+// covers the same operations as EnableOperationTrace. The spans aren't put in the contexts of the requests,
+// the requests are children of the spans of the context, if any.
+type accessLogger struct {
+	log    func(msg string, fields ...elog.Field)
+	actor  func(ctx context.Context) string
+	redact func(key string) string
+}
+
+func newAccessLogger(cfg *config, logger *elog.Component) *accessLogger {
+	l := &accessLogger{actor: cfg.accessLogActor, redact: cfg.accessLogRedact}
+	switch strings.ToLower(cfg.AccessLogLevel) {
+	case AccessLogLevelDebug:
+		l.log = logger.Debug
+	case AccessLogLevelWarn:
+		l.log = logger.Warn
+	case AccessLogLevelError:
+		l.log = logger.Error
+	default:
+		l.log = logger.Info
+	}
+	return l
+}
+
+func (l *accessLogger) Tracer(instrumentationName string, opts ...trace.TracerOption) trace.Tracer {
+	return l
+}
+
+func (l *accessLogger) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &accessLogSpan{
+		// the noop span implements the methods which aren't logged
+		Span:   trace.SpanFromContext(context.Background()),
+		logger: l,
+		start:  time.Now(),
+		attrs:  make(map[attribute.Key]attribute.Value),
+	}
+	if l.actor != nil {
+		span.actor = l.actor(ctx)
+	}
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+	return ctx, span
+}
+
+type accessLogSpan struct {
+	trace.Span
+	logger *accessLogger
+	start  time.Time
+	actor  string
+	attrs  map[attribute.Key]attribute.Value
+	err    error
+	status string
+}
+
+func (s *accessLogSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *accessLogSpan) RecordError(err error, options ...trace.EventOption) {
+	s.err = err
+}
+
+func (s *accessLogSpan) SetStatus(code codes.Code, description string) {
+	if code == codes.Error {
+		s.status = description
+	}
+}
+
+func (s *accessLogSpan) key(attr attribute.Key) string {
+	key := s.attrs[attr].AsString()
+	if s.logger.redact != nil && key != "" {
+		return s.logger.redact(key)
+	}
+	return key
+}
+
+func (s *accessLogSpan) End(options ...trace.SpanEndOption) {
+	fields := []elog.Field{
+		elog.FieldMethod(s.attrs[traceAttrOperation].AsString()),
+		elog.FieldAddr(s.attrs[traceAttrBucket].AsString()),
+		elog.FieldCost(time.Since(s.start)),
+	}
+	if key := s.key(traceAttrKey); key != "" {
+		fields = append(fields, elog.FieldKey(key))
+	}
+	if destKey := s.key(traceAttrDestKey); destKey != "" {
+		fields = append(fields, elog.FieldCustomKeyValue("destKey", destKey))
+	}
+	if size, ok := s.attrs[traceAttrSize]; ok {
+		fields = append(fields, zap.Int64("size", size.AsInt64()))
+	}
+	if count, ok := s.attrs[traceAttrKeyCount]; ok {
+		fields = append(fields, zap.Int64("keyCount", count.AsInt64()))
+	}
+	if s.actor != "" {
+		fields = append(fields, elog.FieldCustomKeyValue("actor", s.actor))
+	}
+	switch {
+	case s.err != nil:
+		fields = append(fields, elog.FieldCustomKeyValue("status", "error"), elog.FieldErr(s.err))
+	case s.status != "":
+		fields = append(fields, elog.FieldCustomKeyValue("status", "error"), elog.FieldDescription(s.status))
+	default:
+		fields = append(fields, elog.FieldCustomKeyValue("status", "ok"))
+	}
+	s.logger.log("access", fields...)
+}
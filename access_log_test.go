@@ -0,0 +1,63 @@
+package awos
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type actorKey struct{}
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	container := DefaultContainer()
+	container.logger = elog.DefaultContainer().Build(elog.WithZapCore(core))
+	client := container.Build(
+		WithStorageType(StorageTypeMemory),
+		WithBucket("test-bucket"),
+		WithEnableAccessLog(AccessLogLevelDebug),
+		WithAccessLogActor(func(ctx context.Context) string {
+			actor, _ := ctx.Value(actorKey{}).(string)
+			return actor
+		}),
+		WithAccessLogRedact(func(key string) string {
+			return strings.Repeat("*", len(key))
+		}),
+	)
+	assert.NoError(t, client.Put("secret", strings.NewReader(content), nil))
+	logs.TakeAll()
+
+	ctx := context.WithValue(context.Background(), actorKey{}, "tenant-1")
+	res, err := client.WithContext(ctx).Get("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	entries := logs.TakeAll()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "access", entries[0].Message)
+		assert.Equal(t, zap.DebugLevel, entries[0].Level)
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "Get", fields["method"])
+		assert.Equal(t, "test-bucket", fields["addr"])
+		assert.Equal(t, "******", fields["key"])
+		assert.Equal(t, int64(len(content)), fields["size"])
+		assert.Equal(t, "tenant-1", fields["actor"])
+		assert.Equal(t, "ok", fields["status"])
+		assert.Contains(t, fields, "cost")
+	}
+
+	_, err = client.Get("missing")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	entries = logs.TakeAll()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "error", fields["status"])
+		assert.Contains(t, fields, "error")
+		assert.NotContains(t, fields, "actor")
+	}
+}
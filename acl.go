@@ -0,0 +1,73 @@
+package awos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// backend neutral object acls of PutWithACL and PutObjectACL, which are the canned acls of s3 and the object acls of oss
+const (
+	ACLPrivate         = "private"
+	ACLPublicRead      = "public-read"
+	ACLPublicReadWrite = "public-read-write"
+	// ACLDefault means the object inherits the acl of the bucket, only oss supports it
+	ACLDefault = "default"
+)
+
+func validateACL(acl string) error {
+	switch acl {
+	case "", ACLPrivate, ACLPublicRead, ACLPublicReadWrite, ACLDefault:
+		return nil
+	}
+	return fmt.Errorf("unsupported acl %q", acl)
+}
+
+// validateObjectACL validates the acl of PutObjectACL, which can't be empty
+func validateObjectACL(acl string) error {
+	if acl == "" {
+		return errors.New("acl can't be empty")
+	}
+	return validateACL(acl)
+}
+
+// s3CannedACL returns the canned acl of acl, nil if it's empty
+func s3CannedACL(acl string) (*string, error) {
+	switch acl {
+	case "":
+		return nil, nil
+	case ACLDefault:
+		return nil, fmt.Errorf("acl %q: %w", acl, ErrUnsupported)
+	}
+	return aws.String(acl), nil
+}
+
+// s3GrantsACL returns the canned acl granting the permissions of grants to all users,
+// grants to other users and groups are ignored
+func s3GrantsACL(grants []*s3.Grant) string {
+	var read, write bool
+	for _, grant := range grants {
+		// cos has its own uri of all users
+		if grant.Grantee == nil || !strings.HasSuffix(aws.StringValue(grant.Grantee.URI), "/groups/global/AllUsers") {
+			continue
+		}
+		switch aws.StringValue(grant.Permission) {
+		case s3.PermissionRead:
+			read = true
+		case s3.PermissionWrite:
+			write = true
+		case s3.PermissionFullControl:
+			read, write = true, true
+		}
+	}
+	switch {
+	case read && write:
+		return ACLPublicReadWrite
+	case read:
+		return ACLPublicRead
+	}
+	return ACLPrivate
+}
@@ -0,0 +1,111 @@
+package awos
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// aclServer keeps the acl of the canned acl header of puts and returns it like s3 or oss
+type aclServer struct {
+	mu          sync.Mutex
+	storageType string
+	header      string
+	acl         string
+}
+
+func (s *aclServer) sent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acl
+}
+
+func (s *aclServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodPut {
+		s.acl = r.Header.Get(s.header)
+		return
+	}
+	if s.storageType == StorageTypeOSS {
+		_, _ = w.Write([]byte(`<AccessControlPolicy><Owner><ID>owner</ID></Owner><AccessControlList><Grant>` + s.acl + `</Grant></AccessControlList></AccessControlPolicy>`))
+		return
+	}
+	var grants strings.Builder
+	for _, permission := range map[string][]string{ACLPublicRead: {"READ"}, ACLPublicReadWrite: {"READ", "WRITE"}}[s.acl] {
+		grants.WriteString(`<Grant><Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee><Permission>` + permission + `</Permission></Grant>`)
+	}
+	_, _ = w.Write([]byte(`<AccessControlPolicy><Owner><ID>owner</ID></Owner><AccessControlList>` +
+		`<Grant><Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser"><ID>owner</ID></Grantee><Permission>FULL_CONTROL</Permission></Grant>` +
+		grants.String() + `</AccessControlList></AccessControlPolicy>`))
+}
+
+func TestObjectACL(t *testing.T) {
+	for storageType, header := range map[string]string{StorageTypeS3: "X-Amz-Acl", StorageTypeOSS: "X-Oss-Object-Acl"} {
+		t.Run(storageType, func(t *testing.T) {
+			server := &aclServer{storageType: storageType, header: header}
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithACL(ACLPublicRead)))
+			assert.Equal(t, ACLPublicRead, server.sent())
+			acl, err := client.GetObjectACL(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, ACLPublicRead, acl)
+
+			for _, acl := range []string{ACLPublicReadWrite, ACLPrivate} {
+				assert.NoError(t, client.PutObjectACL(guid, acl))
+				assert.Equal(t, acl, server.sent())
+				res, err := client.GetObjectACL(guid)
+				assert.NoError(t, err)
+				assert.Equal(t, acl, res)
+			}
+
+			assert.Error(t, client.PutObjectACL(guid, "authenticated-read"))
+			assert.Error(t, client.PutObjectACL(guid, ""))
+			assert.Error(t, client.Put(guid, strings.NewReader(content), nil, PutWithACL("authenticated-read")))
+		})
+	}
+}
+
+func TestObjectACL_Local(t *testing.T) {
+	fs, _ := newTestFS(t)
+	for name, client := range map[string]Component{"memory": newTestMemory(), "fs": fs} {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+			acl, err := client.GetObjectACL(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, ACLPrivate, acl)
+
+			assert.NoError(t, client.PutObjectACL(guid, ACLPublicRead))
+			acl, err = client.GetObjectACL(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, ACLPublicRead, acl)
+
+			// a new put replaces the acl like s3 and oss
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithACL(ACLPublicReadWrite)))
+			acl, err = client.GetObjectACL(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, ACLPublicReadWrite, acl)
+
+			_, err = client.GetObjectACL("not-exist")
+			assert.True(t, errors.Is(err, ErrObjectNotFound))
+			assert.True(t, errors.Is(client.PutObjectACL("not-exist", ACLPrivate), ErrObjectNotFound))
+		})
+	}
+}
+
+func TestObjectACL_Unsupported(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {}, WithStorageType(StorageTypeAzure), WithAccessKeySecret(testAzureKey))
+	_, err := client.GetObjectACL(guid)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+	assert.True(t, errors.Is(client.PutObjectACL(guid, ACLPrivate), ErrUnsupported))
+	assert.True(t, errors.Is(client.Put(guid, strings.NewReader(content), nil, PutWithACL(ACLPublicRead)), ErrUnsupported))
+
+	// the default acl of oss can't be set on s3
+	client = newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {})
+	assert.True(t, errors.Is(client.PutObjectACL(guid, ACLDefault), ErrUnsupported))
+}
@@ -0,0 +1,80 @@
+package awos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSS_Append(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		data []byte
+	)
+	client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		position, _ := strconv.Atoi(r.URL.Query().Get("position"))
+		if _, ok := r.URL.Query()["append"]; !ok || position != len(data) {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>PositionNotEqualToLength</Code></Error>`))
+			return
+		}
+		data = append(data, body...)
+		w.Header().Set("X-Oss-Next-Append-Position", strconv.Itoa(len(data)))
+	})
+
+	next, err := client.Append(guid, strings.NewReader("hello "), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), next)
+
+	next, err = client.Append(guid, strings.NewReader("world"), next)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), next)
+
+	_, err = client.Append(guid, strings.NewReader("!"), 0)
+	assert.Error(t, err)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", res)
+}
+
+func TestS3_AppendUnsupported(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {})
+	_, err := client.Append(guid, strings.NewReader("hello"), 0)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
+
+func TestMemory_Append(t *testing.T) {
+	client := newTestMemory()
+
+	next, err := client.Append(guid, strings.NewReader("hello "), 0)
+	assert.NoError(t, err)
+	next, err = client.Append(guid, bytes.NewReader([]byte("world")), next)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), next)
+
+	_, err = client.Append(guid, strings.NewReader("!"), 3)
+	assert.Error(t, err)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", res)
+
+	assert.NoError(t, client.Put(compressGUID, strings.NewReader(content), nil))
+	_, err = client.Append(compressGUID, strings.NewReader("!"), int64(len(content)))
+	assert.Error(t, err)
+}
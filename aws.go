@@ -3,16 +3,25 @@ package awos
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/golang/snappy"
 )
@@ -24,22 +33,45 @@ type S3 struct {
 	BucketName   string
 	Client       *s3.S3
 	ctx          context.Context
+	// detectContentType is EnableContentTypeDetection of config
+	detectContentType bool
+	// storageClasses maps the storage classes of PutWithStorageClass
+	storageClasses map[string]string
+	// noChecksumSHA256 is set for the s3-like backends not supporting x-amz-checksum-sha256
+	noChecksumSHA256 bool
+	// noObjectACL is set for the s3-like backends whose object acls aren't compatible with s3
+	noObjectACL bool
+	// noObjectLock is set for the s3-like backends without object lock
+	noObjectLock bool
+	// clock is the signing time, see WithTimeSource
+	clock *signingClock
 }
 
 func (a *S3) WithContext(ctx context.Context) Component {
 	b := &S3{
-		ShardsBucket: a.ShardsBucket,
-		BucketName:   a.BucketName,
-		Client:       a.Client,
-		ctx:          ctx,
+		ShardsBucket:      a.ShardsBucket,
+		BucketName:        a.BucketName,
+		Client:            a.Client,
+		ctx:               markNoDeadline(ctx),
+		detectContentType: a.detectContentType,
+		storageClasses:    a.storageClasses,
+		noChecksumSHA256:  a.noChecksumSHA256,
+		noObjectACL:       a.noObjectACL,
+		noObjectLock:      a.noObjectLock,
+		clock:             a.clock,
 	}
 	return b
 }
 
-func (a *S3) getBucket(key string) (string, error) {
-	if a.ctx == nil {
-		a.ctx = context.Background()
+// now is the time of the signatures
+func (a *S3) now() time.Time {
+	if a.clock == nil {
+		return time.Now()
 	}
+	return a.clock.now()
+}
+
+func (a *S3) getBucket(key string) (string, error) {
 	if a.ShardsBucket != nil && len(a.ShardsBucket) > 0 {
 		keyLength := len(key)
 		bucketName := a.ShardsBucket[strings.ToLower(key[keyLength-1:keyLength])]
@@ -55,50 +87,18 @@ func (a *S3) getBucket(key string) (string, error) {
 
 // don't forget to call the close() method of the io.ReadCloser
 func (a *S3) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
-	bucketName, err := a.getBucket(key)
-	if err != nil {
-		return nil, err
-	}
-
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-	}
-	setS3Options(options, input)
-
-	result, err := a.Client.GetObjectWithContext(a.ctx, input)
+	result, err := a.get(key, options...)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == s3.ErrCodeNoSuchKey {
-				return nil, nil
-			}
-		}
 		return nil, err
 	}
 
-	return result.Body, err
+	return result.Body, nil
 }
 
 // don't forget to call the close() method of the io.ReadCloser
 func (a *S3) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
-	bucketName, err := a.getBucket(key)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-	}
-	setS3Options(options, input)
-
-	result, err := a.Client.GetObjectWithContext(a.ctx, input)
+	result, err := a.get(key, options...)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == s3.ErrCodeNoSuchKey {
-				return nil, nil, nil
-			}
-		}
 		return nil, nil, err
 	}
 	return result.Body, getS3Meta(attributes, mergeHttpStandardHeaders(&HeadGetObjectOutputWrapper{
@@ -107,44 +107,49 @@ func (a *S3) GetWithMeta(key string, attributes []string, options ...GetOptions)
 }
 
 func (a *S3) Get(key string, options ...GetOptions) (string, error) {
-	data, err := a.GetBytes(key, options...)
-	if err != nil {
-		return "", err
-	}
-	return string(data), nil
+	var res string
+	err := a.read(key, options, func(data []byte) error {
+		res = string(data)
+		return nil
+	})
+	return res, err
 }
 
 func (a *S3) GetBytes(key string, options ...GetOptions) ([]byte, error) {
-	result, err := a.get(key, options...)
-	if err != nil {
-		return nil, err
-	}
-	if result == nil {
-		return nil, nil
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
 	}
+	var res []byte
+	err := a.read(key, options, func(data []byte) error {
+		res = getOpts.copyBytes(data)
+		return nil
+	})
+	return res, err
+}
 
-	body := result.Body
-	defer func() {
-		if body != nil {
-			body.Close()
-		}
-	}()
-
-	return ioutil.ReadAll(body)
+// GetMulti stops fetching when the context of WithContext is done
+func (a *S3) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(a.ctx, keys, options, a.GetBytes)
 }
 
-func (a *S3) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
-	readRange := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(a.BucketName),
-		Key:    aws.String(key),
-		Range:  &readRange,
+// read reads the whole object and calls consume with the content, see readBody
+func (a *S3) read(key string, options []GetOptions, consume func(data []byte) error) error {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
 	}
-	r, err := a.Client.GetObjectWithContext(a.ctx, input)
+	result, err := a.get(key, options...)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return r.Body, nil
+	defer result.Body.Close()
+
+	return readBody(result.Body, getOpts.buffer, consume)
+}
+
+func (a *S3) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	return a.GetAsReader(key, GetWithRange(offset, offset+length-1))
 }
 
 func (a *S3) GetAndDecompress(key string) (string, error) {
@@ -152,9 +157,6 @@ func (a *S3) GetAndDecompress(key string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if result == nil {
-		return "", nil
-	}
 
 	body := result.Body
 	defer func() {
@@ -191,7 +193,7 @@ func (a *S3) GetAndDecompress(key string) (string, error) {
 		return string(decodedBytes), nil
 	}
 
-	data, err := ioutil.ReadAll(body)
+	data, err := decompressBody(aws.StringValue(result.ContentEncoding), body)
 	if err != nil {
 		return "", err
 	}
@@ -217,14 +219,59 @@ func (a *S3) Put(key string, reader io.ReadSeeker, meta map[string]string, optio
 	for _, opt := range options {
 		opt(putOptions)
 	}
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	if putOptions.checksumAlgorithm == ChecksumSHA256 && a.noChecksumSHA256 {
+		return fmt.Errorf("PutWithChecksum(ChecksumSHA256): %w", ErrUnsupported)
+	}
+	if a.detectContentType {
+		if err := putOptions.detectContentType(key, reader); err != nil {
+			return err
+		}
+	}
+	storageClass, err := a.storageClass(putOptions)
+	if err != nil {
+		return err
+	}
+	acl, err := a.cannedACL(putOptions.acl)
+	if err != nil {
+		return err
+	}
+	if putOptions.objectLock() && a.noObjectLock {
+		return errObjectLock
+	}
+	meta, err = putOptions.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	reader, err = putOptions.compressBody(reader)
+	if err != nil {
+		return err
+	}
+	var checksum *contentChecksum
+	if reader != nil {
+		if checksum, err = putOptions.checksum(reader); err != nil {
+			return err
+		}
+		putOptions.progress.setReaderTotal(reader)
+	}
 
 	input := &s3.PutObjectInput{
-		Body:        reader,
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(key),
-		Metadata:    aws.StringMap(meta),
-		ContentType: aws.String(putOptions.contentType),
+		Body:                 reader,
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		Metadata:             aws.StringMap(meta),
+		ContentType:          aws.String(putOptions.contentType),
+		ContentMD5:           checksum.contentMD5(),
+		ServerSideEncryption: s3ServerSideEncryption(putOptions),
+		SSEKMSKeyId:          s3SSEKMSKeyID(putOptions),
+		SSECustomerAlgorithm: s3SSECustomerAlgorithm(putOptions.sseCustomerKey),
+		SSECustomerKey:       s3SSECustomerKey(putOptions.sseCustomerKey),
+		StorageClass:         storageClass,
+		ACL:                  acl,
 	}
+	input.ObjectLockMode, input.ObjectLockRetainUntilDate, input.ObjectLockLegalHoldStatus = s3ObjectLock(putOptions)
 	if putOptions.contentEncoding != nil {
 		input.ContentEncoding = putOptions.contentEncoding
 	}
@@ -238,233 +285,1374 @@ func (a *S3) Put(key string, reader io.ReadSeeker, meta map[string]string, optio
 		input.Expires = putOptions.expires
 	}
 
+	var header http.Header
+	reqOptions := []request.Option{
+		request.WithSetRequestHeaders(checksum.addS3Headers(putOptions.conditionalHeaders())),
+		withResponseHeader(&header),
+	}
 	err = retry.Do(func() error {
-		_, err := a.Client.PutObjectWithContext(a.ctx, input)
+		res, err := a.Client.PutObjectWithContext(withProgress(a.ctx, putOptions.progress), input, reqOptions...)
+		if err == nil {
+			// a mismatch is retried like a corrupted upload rejected by the backend
+			err = checksum.verify(aws.StringValue(res.ETag), header)
+		}
+		if err == nil {
+			putOptions.setPutResult(header, "X-Amz-")
+		}
 		if err != nil && reader != nil {
 			// Reset the body reader after the request since at this point it's already read
 			// Note that it's safe to ignore the error here since the 0,0 position is always valid
 			_, _ = reader.Seek(0, 0)
 		}
-		return err
-	}, retry.Attempts(3), retry.Delay(1*time.Second))
+		return wrapS3Error(err)
+	}, putRetryOptions()...)
 
 	return err
 }
 
-func (a *S3) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
-	data, err := ioutil.ReadAll(reader)
+// MultipartUpload uploads reader in parts, which is suitable for large objects.
+// The upload is aborted if any part fails.
+func (a *S3) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	bucketName, err := a.getBucket(key)
 	if err != nil {
 		return err
 	}
-	if meta == nil {
-		meta = make(map[string]string)
-	}
-
-	encodedBytes := snappy.Encode(nil, data)
-
-	meta["Compressor"] = "snappy"
-
-	return a.Put(key, bytes.NewReader(encodedBytes), meta, options...)
-}
 
-func (a *S3) Del(key string) error {
-	bucketName, err := a.getBucket(key)
-	if err != nil {
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+	if err := putOptions.validate(); err != nil {
 		return err
 	}
-
-	input := &s3.DeleteObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
+	if putOptions.compression == "" {
+		putOptions.progress.setReaderTotal(reader)
 	}
-
-	_, err = a.Client.DeleteObjectWithContext(a.ctx, input)
-	return err
-}
-
-func (a *S3) DelMulti(keys []string) error {
-	bucketsNameKeys := make(map[string][]string)
-	for _, key := range keys {
-		bucketName, err := a.getBucket(key)
-		if err != nil {
+	if a.detectContentType {
+		if reader, err = putOptions.detectStreamContentType(key, reader); err != nil {
 			return err
 		}
-		bucketsNameKeys[bucketName] = append(bucketsNameKeys[bucketName], key)
 	}
+	meta, err = putOptions.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	body := putOptions.compressStream(reader)
+	defer body.Close()
 
-	for bucketName, BKeys := range bucketsNameKeys {
-		delObjects := make([]*s3.ObjectIdentifier, len(BKeys))
-
-		for idx, key := range BKeys {
-			delObjects[idx] = &s3.ObjectIdentifier{
-				Key: aws.String(key),
-			}
-		}
-
-		input := &s3.DeleteObjectsInput{
-			Bucket: aws.String(bucketName),
-			Delete: &s3.Delete{
-				Objects: delObjects,
-				Quiet:   aws.Bool(false),
-			},
-		}
+	uploadID, err := a.createMultipartUpload(bucketName, key, meta, putOptions)
+	if err != nil {
+		return err
+	}
 
-		_, err := a.Client.DeleteObjectsWithContext(a.ctx, input)
+	var (
+		mu    sync.Mutex
+		parts []*s3.CompletedPart
+	)
+	err = uploadParts(body, putOptions.partSize, putOptions.concurrency, func(partNumber int, data []byte) error {
+		etag, err := a.uploadPart(bucketName, key, uploadID, partNumber, data, putOptions)
 		if err != nil {
 			return err
 		}
-	}
-
-	return nil
-}
-
-func (a *S3) Head(key string, attributes []string) (map[string]string, error) {
-	bucketName, err := a.getBucket(key)
+		mu.Lock()
+		parts = append(parts, &s3.CompletedPart{ETag: etag, PartNumber: aws.Int64(int64(partNumber))})
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
-		return nil, err
-	}
-
-	input := &s3.HeadObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
+		_, _ = a.Client.AbortMultipartUploadWithContext(a.ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(key),
+			UploadId: uploadID,
+		})
+		return err
 	}
 
-	result, err := a.Client.HeadObjectWithContext(a.ctx, input)
+	return a.completeMultipartUpload(bucketName, key, uploadID, parts, putOptions)
+}
 
+// createMultipartUpload starts a multipart upload with the headers of putOptions, meta must be validated
+func (a *S3) createMultipartUpload(bucketName string, key string, meta map[string]string, putOptions *putOptions) (*string, error) {
+	storageClass, err := a.storageClass(putOptions)
 	if err != nil {
-		if aerr, ok := err.(awserr.RequestFailure); ok {
-			if aerr.StatusCode() == 404 {
-				return nil, nil
-			}
-		}
 		return nil, err
 	}
-	return getS3Meta(attributes, mergeHttpStandardHeaders(&HeadGetObjectOutputWrapper{
-		headObjectOutput: result,
-	})), nil
-}
-
-func (a *S3) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
-	bucketName, err := a.getBucket(key)
+	acl, err := a.cannedACL(putOptions.acl)
 	if err != nil {
 		return nil, err
 	}
-
-	input := &s3.ListObjectsInput{
-		Bucket: aws.String(bucketName),
-	}
-	if prefix != "" {
-		input.Prefix = aws.String(prefix)
-	}
-	if marker != "" {
-		input.Marker = aws.String(marker)
+	if putOptions.objectLock() && a.noObjectLock {
+		return nil, errObjectLock
 	}
-	if maxKeys > 0 {
-		input.MaxKeys = aws.Int64(int64(maxKeys))
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		Metadata:             aws.StringMap(meta),
+		ContentType:          aws.String(putOptions.contentType),
+		ContentEncoding:      putOptions.contentEncoding,
+		ContentDisposition:   putOptions.contentDisposition,
+		CacheControl:         putOptions.cacheControl,
+		Expires:              putOptions.expires,
+		ServerSideEncryption: s3ServerSideEncryption(putOptions),
+		SSEKMSKeyId:          s3SSEKMSKeyID(putOptions),
+		SSECustomerAlgorithm: s3SSECustomerAlgorithm(putOptions.sseCustomerKey),
+		SSECustomerKey:       s3SSECustomerKey(putOptions.sseCustomerKey),
+		StorageClass:         storageClass,
+		ACL:                  acl,
 	}
-	if delimiter != "" {
-		input.Delimiter = aws.String(delimiter)
+	input.ObjectLockMode, input.ObjectLockRetainUntilDate, input.ObjectLockLegalHoldStatus = s3ObjectLock(putOptions)
+	upload, err := a.Client.CreateMultipartUploadWithContext(a.ctx, input)
+	if err != nil {
+		return nil, wrapS3Error(err)
 	}
+	return upload.UploadId, nil
+}
 
-	result, err := a.Client.ListObjectsWithContext(a.ctx, input)
+// uploadPart uploads a part and returns its etag, the part is verified by its md5 with PutWithChecksum
+func (a *S3) uploadPart(bucketName string, key string, uploadID *string, partNumber int, data []byte, putOptions *putOptions) (*string, error) {
+	checksum := putOptions.partChecksum(data)
+	var header http.Header
+	res, err := a.Client.UploadPartWithContext(withProgress(a.ctx, putOptions.progress), &s3.UploadPartInput{
+		Bucket:        aws.String(bucketName),
+		Key:           aws.String(key),
+		UploadId:      uploadID,
+		PartNumber:    aws.Int64(int64(partNumber)),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentMD5:    checksum.contentMD5(),
+		// each part must be encrypted with the same key
+		SSECustomerAlgorithm: s3SSECustomerAlgorithm(putOptions.sseCustomerKey),
+		SSECustomerKey:       s3SSECustomerKey(putOptions.sseCustomerKey),
+	}, withResponseHeader(&header))
 	if err != nil {
-		return nil, err
+		return nil, wrapS3Error(err)
 	}
-
-	keys := make([]string, 0)
-	for _, v := range result.Contents {
-		keys = append(keys, *v.Key)
+	if err := checksum.verify(aws.StringValue(res.ETag), header); err != nil {
+		return nil, err
 	}
+	return res.ETag, nil
+}
 
-	return keys, nil
+// completeMultipartUpload completes the upload with the parts in any order
+func (a *S3) completeMultipartUpload(bucketName string, key string, uploadID *string, parts []*s3.CompletedPart, putOptions *putOptions) error {
+	sort.Slice(parts, func(i, j int) bool {
+		return *parts[i].PartNumber < *parts[j].PartNumber
+	})
+	_, err := a.Client.CompleteMultipartUploadWithContext(a.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}, request.WithSetRequestHeaders(putOptions.conditionalHeaders()))
+	return wrapS3Error(err)
 }
 
-func (a *S3) SignURL(key string, expired int64) (string, error) {
+func (a *S3) createResumableUpload(key string, meta map[string]string, putOptions *putOptions) (string, error) {
 	bucketName, err := a.getBucket(key)
 	if err != nil {
 		return "", err
 	}
-
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
+	uploadID, err := a.createMultipartUpload(bucketName, key, meta, putOptions)
+	if err != nil {
+		return "", err
 	}
-
-	req, _ := a.Client.GetObjectRequest(input)
-	return req.Presign(time.Duration(expired) * time.Second)
+	return aws.StringValue(uploadID), nil
 }
 
-func (a *S3) Exists(key string) (bool, error) {
+func (a *S3) uploadResumablePart(key string, uploadID string, partNumber int, data []byte, putOptions *putOptions) (string, error) {
 	bucketName, err := a.getBucket(key)
 	if err != nil {
-		return false, err
-	}
-
-	input := &s3.HeadObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
+		return "", err
 	}
-	_, err = a.Client.HeadObjectWithContext(a.ctx, input)
-	if err == nil {
-		return true, nil
+	etag, err := a.uploadPart(bucketName, key, aws.String(uploadID), partNumber, data, putOptions)
+	if err != nil {
+		return "", err
 	}
+	return aws.StringValue(etag), nil
+}
 
-	if aerr, ok := err.(awserr.RequestFailure); ok {
-		if aerr.StatusCode() == 404 {
-			return false, nil
+func (a *S3) listResumableParts(key string, uploadID string) ([]UploadedPart, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]UploadedPart, 0)
+	err = a.Client.ListPartsPagesWithContext(a.ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range page.Parts {
+			parts = append(parts, UploadedPart{
+				PartNumber: int(aws.Int64Value(part.PartNumber)),
+				ETag:       aws.StringValue(part.ETag),
+				Size:       aws.Int64Value(part.Size),
+			})
 		}
+		return true
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchUpload {
+		return nil, errUploadNotFound
 	}
-	return false, err
+	return parts, err
 }
 
-func (a *S3) get(key string, options ...GetOptions) (*s3.GetObjectOutput, error) {
+func (a *S3) completeResumableUpload(key string, uploadID string, parts []UploadedPart, putOptions *putOptions) error {
 	bucketName, err := a.getBucket(key)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
+	completed := make([]*s3.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, &s3.CompletedPart{ETag: aws.String(part.ETag), PartNumber: aws.Int64(int64(part.PartNumber))})
 	}
-	setS3Options(options, input)
+	return a.completeMultipartUpload(bucketName, key, aws.String(uploadID), completed, putOptions)
+}
 
-	result, err := a.Client.GetObjectWithContext(a.ctx, input)
+// Append is not supported by s3, it always returns ErrUnsupported.
+func (a *S3) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	return position, ErrUnsupported
+}
 
+func (a *S3) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	data, err := ioutil.ReadAll(reader)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == s3.ErrCodeNoSuchKey {
-				return nil, nil
-			}
-		}
-		return nil, err
+		return err
+	}
+	if meta == nil {
+		meta = make(map[string]string)
 	}
 
-	return result, nil
+	encodedBytes := snappy.Encode(nil, data)
+
+	meta["Compressor"] = "snappy"
+
+	return a.Put(key, bytes.NewReader(encodedBytes), meta, options...)
 }
 
-func getS3Meta(attributes []string, metaData map[string]*string) map[string]string {
-	// https://github.com/aws/aws-sdk-go/issues/445
-	// aws 会将 meta 的首字母大写，在这里需要转换下
-	res := make(map[string]string)
-	for _, v := range attributes {
-		key := strings.Title(v)
-		if metaData[key] != nil {
-			res[v] = *metaData[key]
-		}
+// Copy copies srcKey to dstKey on the server side, objects larger than MaxCopySize are copied in parts.
+// The metadata of the source object is copied unless CopyWithMeta or CopyWithContentType is given.
+func (a *S3) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	srcBucket, err := a.getBucket(srcKey)
+	if err != nil {
+		return err
 	}
-	return res
-}
 
-func setS3Options(options []GetOptions, getObjectInput *s3.GetObjectInput) {
-	getOpts := DefaultGetOptions()
+	copyOpts := DefaultCopyOptions()
 	for _, opt := range options {
-		opt(getOpts)
+		opt(copyOpts)
 	}
-	if getOpts.contentEncoding != nil {
-		getObjectInput.ResponseContentEncoding = getOpts.contentEncoding
+	if err := copyOpts.validate(); err != nil {
+		return err
 	}
-	if getOpts.contentType != nil {
+	dstBucket := copyOpts.destBucket
+	if dstBucket == "" {
+		if dstBucket, err = a.getBucket(dstKey); err != nil {
+			return err
+		}
+	}
+
+	head, err := a.Client.HeadObjectWithContext(a.ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(srcBucket),
+		Key:                  aws.String(srcKey),
+		SSECustomerAlgorithm: s3SSECustomerAlgorithm(copyOpts.sourceSSECustomerKey),
+		SSECustomerKey:       s3SSECustomerKey(copyOpts.sourceSSECustomerKey),
+	})
+	if err != nil {
+		return wrapS3Error(err)
+	}
+	meta, contentType := head.Metadata, head.ContentType
+	if copyOpts.meta != nil || copyOpts.metadataDirective == DirectiveReplace {
+		meta = aws.StringMap(copyOpts.userMeta(nil))
+	}
+	if copyOpts.contentType != nil {
+		contentType = copyOpts.contentType
+	}
+	var tagging *string
+	if tags := copyOpts.objectTags(nil); len(tags) > 0 {
+		tagging = aws.String(encodeTagging(tags))
+	}
+	source := s3CopySource(srcBucket, srcKey)
+
+	if aws.Int64Value(head.ContentLength) > MaxCopySize {
+		// the parts don't carry the tags, the ones of the source are set at the upload like CopyObject copies them
+		if !copyOpts.replaceTags() {
+			tags, err := a.GetObjectTagging(srcKey)
+			if err != nil {
+				return err
+			}
+			if len(tags) > 0 {
+				tagging = aws.String(encodeTagging(tags))
+			}
+		}
+		return a.multipartCopy(source, dstBucket, dstKey, aws.Int64Value(head.ContentLength), &s3.CreateMultipartUploadInput{
+			Bucket:               aws.String(dstBucket),
+			Key:                  aws.String(dstKey),
+			Metadata:             meta,
+			ContentType:          contentType,
+			ContentEncoding:      head.ContentEncoding,
+			ContentDisposition:   head.ContentDisposition,
+			CacheControl:         head.CacheControl,
+			SSECustomerAlgorithm: s3SSECustomerAlgorithm(copyOpts.sseCustomerKey),
+			SSECustomerKey:       s3SSECustomerKey(copyOpts.sseCustomerKey),
+			StorageClass:         head.StorageClass,
+			Tagging:              tagging,
+		}, copyOpts)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:                         aws.String(dstBucket),
+		Key:                            aws.String(dstKey),
+		CopySource:                     aws.String(source),
+		CopySourceSSECustomerAlgorithm: s3SSECustomerAlgorithm(copyOpts.sourceSSECustomerKey),
+		CopySourceSSECustomerKey:       s3SSECustomerKey(copyOpts.sourceSSECustomerKey),
+		SSECustomerAlgorithm:           s3SSECustomerAlgorithm(copyOpts.sseCustomerKey),
+		SSECustomerKey:                 s3SSECustomerKey(copyOpts.sseCustomerKey),
+		// the copy is standard otherwise, like the objects copied in parts the storage class of the source is kept
+		StorageClass: head.StorageClass,
+	}
+	if copyOpts.replaceMeta() {
+		// REPLACE drops all the metadata of the source, keep the standard headers which aren't replaced
+		input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+		input.Metadata = meta
+		input.ContentType = contentType
+		input.ContentEncoding = head.ContentEncoding
+		input.ContentDisposition = head.ContentDisposition
+		input.CacheControl = head.CacheControl
+	}
+	if copyOpts.replaceTags() {
+		input.TaggingDirective = aws.String(s3.TaggingDirectiveReplace)
+		input.Tagging = tagging
+	}
+	_, err = a.Client.CopyObjectWithContext(a.ctx, input)
+	return wrapS3Error(err)
+}
+
+// multipartCopy copies source of size in parts with UploadPartCopy, the upload is aborted if any part fails
+func (a *S3) multipartCopy(source, dstBucket, dstKey string, size int64, input *s3.CreateMultipartUploadInput, copyOpts *copyOptions) error {
+	upload, err := a.Client.CreateMultipartUploadWithContext(a.ctx, input)
+	if err != nil {
+		return wrapS3Error(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		parts []*s3.CompletedPart
+	)
+	err = copyParts(size, copyOpts.partSize, copyOpts.concurrency, func(partNumber int, start, end int64) error {
+		res, err := a.Client.UploadPartCopyWithContext(a.ctx, &s3.UploadPartCopyInput{
+			Bucket:                         aws.String(dstBucket),
+			Key:                            aws.String(dstKey),
+			UploadId:                       upload.UploadId,
+			PartNumber:                     aws.Int64(int64(partNumber)),
+			CopySource:                     aws.String(source),
+			CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			CopySourceSSECustomerAlgorithm: s3SSECustomerAlgorithm(copyOpts.sourceSSECustomerKey),
+			CopySourceSSECustomerKey:       s3SSECustomerKey(copyOpts.sourceSSECustomerKey),
+			SSECustomerAlgorithm:           s3SSECustomerAlgorithm(copyOpts.sseCustomerKey),
+			SSECustomerKey:                 s3SSECustomerKey(copyOpts.sseCustomerKey),
+		})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		parts = append(parts, &s3.CompletedPart{ETag: res.CopyPartResult.ETag, PartNumber: aws.Int64(int64(partNumber))})
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		_, _ = a.Client.AbortMultipartUploadWithContext(a.ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(dstBucket),
+			Key:      aws.String(dstKey),
+			UploadId: upload.UploadId,
+		})
+		return wrapS3Error(err)
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return *parts[i].PartNumber < *parts[j].PartNumber
+	})
+	_, err = a.Client.CompleteMultipartUploadWithContext(a.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(dstBucket),
+		Key:             aws.String(dstKey),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	return wrapS3Error(err)
+}
+
+// Move copies srcKey to dstKey and deletes srcKey, the source is kept if the copy fails.
+func (a *S3) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return move(a, srcKey, dstKey, options)
+}
+
+func (a *S3) Del(key string) error {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+
+	_, err = a.Client.DeleteObjectWithContext(a.ctx, input)
+	return wrapS3Error(err)
+}
+
+// RestoreObject restores an archived object for reading, check ObjectMeta.Restore of HeadObject for the status
+func (a *S3) RestoreObject(key string, options ...RestoreOptions) error {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	restoreOpts := DefaultRestoreOptions()
+	for _, opt := range options {
+		opt(restoreOpts)
+	}
+	if err := restoreOpts.validate(); err != nil {
+		return err
+	}
+	restoreRequest := &s3.RestoreRequest{
+		Days: aws.Int64(int64(restoreOpts.days)),
+	}
+	if restoreOpts.tier != "" {
+		restoreRequest.GlacierJobParameters = &s3.GlacierJobParameters{Tier: aws.String(restoreOpts.tier)}
+	}
+	_, err = a.Client.RestoreObjectWithContext(a.ctx, &s3.RestoreObjectInput{
+		Bucket:         aws.String(bucketName),
+		Key:            aws.String(key),
+		RestoreRequest: restoreRequest,
+	})
+	return wrapS3Error(err)
+}
+
+// GetVersion gets a specific version of the object in a versioned bucket
+func (a *S3) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return a.Get(key, append(options, GetWithVersionID(versionID))...)
+}
+
+// DelVersion permanently deletes a specific version of the object in a versioned bucket
+func (a *S3) DelVersion(key string, versionID string, options ...DelOptions) error {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+	delOpts := DefaultDelOptions()
+	for _, opt := range options {
+		opt(delOpts)
+	}
+
+	input := &s3.DeleteObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	}
+	if delOpts.bypassGovernance {
+		input.BypassGovernanceRetention = aws.Bool(true)
+	}
+	_, err = a.Client.DeleteObjectWithContext(a.ctx, input)
+	return wrapS3Error(err)
+}
+
+func (a *S3) DelMulti(keys []string) (map[string]error, error) {
+	failed := make(map[string]error)
+	bucketsNameKeys := make(map[string][]string)
+	for _, key := range keys {
+		bucketName, err := a.getBucket(key)
+		if err != nil {
+			failed[key] = err
+			continue
+		}
+		bucketsNameKeys[bucketName] = append(bucketsNameKeys[bucketName], key)
+	}
+
+	for bucketName, BKeys := range bucketsNameKeys {
+		for _, chunk := range chunkKeys(BKeys, MaxDeleteKeys) {
+			delObjects := make([]*s3.ObjectIdentifier, len(chunk))
+			for idx, key := range chunk {
+				delObjects[idx] = &s3.ObjectIdentifier{
+					Key: aws.String(key),
+				}
+			}
+
+			input := &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucketName),
+				Delete: &s3.Delete{
+					Objects: delObjects,
+					Quiet:   aws.Bool(true),
+				},
+			}
+
+			output, err := a.Client.DeleteObjectsWithContext(a.ctx, input)
+			if err != nil {
+				err = wrapS3Error(err)
+				for _, key := range chunk {
+					failed[key] = err
+				}
+				continue
+			}
+			// quiet mode only reports the failed keys
+			for _, e := range output.Errors {
+				failed[aws.StringValue(e.Key)] = awserr.New(aws.StringValue(e.Code), aws.StringValue(e.Message), nil)
+			}
+		}
+	}
+
+	return delMultiResult(failed, len(keys))
+}
+
+func (a *S3) Head(key string, attributes []string, options ...GetOptions) (map[string]string, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if err := validateSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, err
+	}
+	input := &s3.HeadObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: s3SSECustomerAlgorithm(getOpts.sseCustomerKey),
+		SSECustomerKey:       s3SSECustomerKey(getOpts.sseCustomerKey),
+		VersionId:            getOpts.versionID,
+	}
+
+	result, err := a.Client.HeadObjectWithContext(a.ctx, input)
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+	return getS3Meta(attributes, mergeHttpStandardHeaders(&HeadGetObjectOutputWrapper{
+		headObjectOutput: result,
+	})), nil
+}
+
+func (a *S3) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if err := validateSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, err
+	}
+	result, err := a.Client.HeadObjectWithContext(a.ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: s3SSECustomerAlgorithm(getOpts.sseCustomerKey),
+		SSECustomerKey:       s3SSECustomerKey(getOpts.sseCustomerKey),
+		VersionId:            getOpts.versionID,
+	})
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+
+	meta := &ObjectMeta{
+		Key:             key,
+		ETag:            trimETag(aws.StringValue(result.ETag)),
+		Size:            aws.Int64Value(result.ContentLength),
+		LastModified:    aws.TimeValue(result.LastModified),
+		ContentType:     aws.StringValue(result.ContentType),
+		ContentEncoding: aws.StringValue(result.ContentEncoding),
+		// s3 omits the storage class of standard objects
+		StorageClass: s3.StorageClassStandard,
+		VersionID:    aws.StringValue(result.VersionId),
+		UserMeta:     make(map[string]string, len(result.Metadata)),
+	}
+	if result.StorageClass != nil {
+		meta.StorageClass = *result.StorageClass
+	}
+	if meta.Restore, err = parseRestoreStatus(aws.StringValue(result.Restore)); err != nil {
+		return nil, err
+	}
+	if meta.Expiration, err = parseObjectExpiration(aws.StringValue(result.Expiration)); err != nil {
+		return nil, err
+	}
+	for k, v := range result.Metadata {
+		meta.UserMeta[strings.ToLower(k)] = aws.StringValue(v)
+	}
+	return meta, nil
+}
+
+func (a *S3) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if marker != "" {
+		input.Marker = aws.String(marker)
+	}
+	if maxKeys > 0 {
+		input.MaxKeys = aws.Int64(int64(maxKeys))
+	}
+	if delimiter != "" {
+		input.Delimiter = aws.String(delimiter)
+	}
+
+	result, err := a.Client.ListObjectsWithContext(a.ctx, input)
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+
+	keys := make([]string, 0)
+	for _, v := range result.Contents {
+		keys = append(keys, *v.Key)
+	}
+
+	return keys, nil
+}
+
+func (a *S3) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if marker != "" {
+		input.Marker = aws.String(marker)
+	}
+	if maxKeys > 0 {
+		input.MaxKeys = aws.Int64(int64(maxKeys))
+	}
+	if delimiter != "" {
+		input.Delimiter = aws.String(delimiter)
+	}
+	result, err := a.Client.ListObjectsWithContext(a.ctx, input)
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+
+	res := &ListResult{
+		Objects:        make([]ObjectMeta, 0, len(result.Contents)),
+		CommonPrefixes: make([]string, 0, len(result.CommonPrefixes)),
+	}
+	for _, v := range result.Contents {
+		res.Objects = append(res.Objects, ObjectMeta{
+			Key:          aws.StringValue(v.Key),
+			ETag:         trimETag(aws.StringValue(v.ETag)),
+			Size:         aws.Int64Value(v.Size),
+			LastModified: aws.TimeValue(v.LastModified),
+			StorageClass: aws.StringValue(v.StorageClass),
+		})
+	}
+	for _, v := range result.CommonPrefixes {
+		res.CommonPrefixes = append(res.CommonPrefixes, aws.StringValue(v.Prefix))
+	}
+	if aws.BoolValue(result.IsTruncated) {
+		res.NextMarker = aws.StringValue(result.NextMarker)
+		// NextMarker is only returned with a delimiter, the last key is the marker otherwise
+		if res.NextMarker == "" && len(res.Objects) > 0 {
+			res.NextMarker = res.Objects[len(res.Objects)-1].Key
+		}
+	}
+	return res, nil
+}
+
+func (a *S3) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+	return walkObjects(a.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		result, err := a.Client.ListObjectsWithContext(a.ctx, &s3.ListObjectsInput{
+			Bucket:  aws.String(bucketName),
+			Prefix:  aws.String(prefix),
+			Marker:  aws.String(marker),
+			MaxKeys: aws.Int64(int64(maxKeys)),
+		})
+		if err != nil {
+			return nil, wrapS3Error(err)
+		}
+		objects := make([]ObjectMeta, 0, len(result.Contents))
+		for _, v := range result.Contents {
+			objects = append(objects, ObjectMeta{
+				Key:          aws.StringValue(v.Key),
+				ETag:         trimETag(aws.StringValue(v.ETag)),
+				Size:         aws.Int64Value(v.Size),
+				LastModified: aws.TimeValue(v.LastModified),
+				StorageClass: aws.StringValue(v.StorageClass),
+			})
+		}
+		return objects, nil
+	}, fn, listWithMaxPageSize(options, s3MaxListKeys))
+}
+
+// ListObjectVersions lists at most maxKeys versions and delete markers of keys with prefix,
+// pass the Key and VersionID of the last version as markers to get the next page.
+func (a *S3) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if keyMarker != "" {
+		input.KeyMarker = aws.String(keyMarker)
+	}
+	if versionIDMarker != "" {
+		input.VersionIdMarker = aws.String(versionIDMarker)
+	}
+	if maxKeys > 0 {
+		input.MaxKeys = aws.Int64(int64(maxKeys))
+	}
+
+	result, err := a.Client.ListObjectVersionsWithContext(a.ctx, input)
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+
+	versions := make([]ObjectVersion, 0, len(result.Versions)+len(result.DeleteMarkers))
+	for _, v := range result.Versions {
+		versions = append(versions, ObjectVersion{
+			Key:          aws.StringValue(v.Key),
+			VersionID:    aws.StringValue(v.VersionId),
+			IsLatest:     aws.BoolValue(v.IsLatest),
+			LastModified: aws.TimeValue(v.LastModified),
+			Size:         aws.Int64Value(v.Size),
+			ETag:         aws.StringValue(v.ETag),
+		})
+	}
+	for _, v := range result.DeleteMarkers {
+		versions = append(versions, ObjectVersion{
+			Key:            aws.StringValue(v.Key),
+			VersionID:      aws.StringValue(v.VersionId),
+			IsLatest:       aws.BoolValue(v.IsLatest),
+			IsDeleteMarker: true,
+			LastModified:   aws.TimeValue(v.LastModified),
+		})
+	}
+	sortObjectVersions(versions)
+	return versions, nil
+}
+
+func (a *S3) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return newObjectIterator(a.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		res, err := a.ListObjectsWithDelimiter(key, prefix, marker, maxKeys, "")
+		if err != nil {
+			return nil, err
+		}
+		return res.Objects, nil
+	}, listWithMaxPageSize(options, s3MaxListKeys)...)
+}
+
+func (a *S3) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return "", err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+	if err := signOptions.validate(); err != nil {
+		return "", err
+	}
+
+	// the response-* query parameters are signed
+	input := &s3.GetObjectInput{
+		Bucket:                     aws.String(bucketName),
+		Key:                        aws.String(key),
+		ResponseContentType:        signOptions.responseContentType,
+		ResponseContentDisposition: signOptions.responseContentDisposition,
+		ResponseCacheControl:       signOptions.responseCacheControl,
+	}
+
+	req, _ := a.Client.GetObjectRequest(input)
+	return req.Presign(time.Duration(expired) * time.Second)
+}
+
+// SignURLForPut returns a presigned url for uploading the object with http PUT,
+// the uploader must send the Content-Type/Content-Length given in options.
+func (a *S3) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return "", err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(key),
+		ContentType: signOptions.contentType,
+	}
+
+	req, _ := a.Client.PutObjectRequest(input)
+	if signOptions.contentLength != nil {
+		// http.Request.ContentLength isn't signed, so set the header explicitly
+		req.HTTPRequest.Header.Set("Content-Length", strconv.FormatInt(*signOptions.contentLength, 10))
+	}
+	return req.Presign(time.Duration(expired) * time.Second)
+}
+
+// SignPostPolicy returns a SigV4 signed post policy for browser form uploads.
+func (a *S3) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+
+	creds, err := a.Client.Config.Credentials.Get()
+	if err != nil {
+		return nil, err
+	}
+	now := a.now().UTC()
+	date := now.Format("20060102")
+	region := aws.StringValue(a.Client.Config.Region)
+	credential := creds.AccessKeyID + "/" + date + "/" + region + "/s3/aws4_request"
+
+	fields := map[string]string{
+		"key":              key,
+		"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+		"x-amz-credential": credential,
+		"x-amz-date":       now.Format("20060102T150405Z"),
+	}
+	if creds.SessionToken != "" {
+		fields["x-amz-security-token"] = creds.SessionToken
+	}
+	conditions := postPolicyConditions(bucketName, key, signOptions)
+	for _, k := range []string{"x-amz-algorithm", "x-amz-credential", "x-amz-date", "x-amz-security-token"} {
+		if v, ok := fields[k]; ok {
+			conditions = append(conditions, map[string]string{k: v})
+		}
+	}
+	if signOptions.contentType != nil {
+		fields["Content-Type"] = *signOptions.contentType
+	}
+
+	policy, err := encodePostPolicy(now.Add(time.Duration(expired)*time.Second), conditions)
+	if err != nil {
+		return nil, err
+	}
+	fields["policy"] = policy
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signingKey, policy))
+
+	// HeadBucket is used to get the bucket url, which respects path style and custom endpoints
+	req, _ := a.Client.HeadBucketRequest(&s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+	if err := req.Build(); err != nil {
+		return nil, err
+	}
+	u := *req.HTTPRequest.URL
+	u.RawQuery = ""
+	return &PostPolicy{URL: u.String(), Fields: fields}, nil
+}
+
+func (a *S3) Exists(key string) (bool, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return false, err
+	}
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	_, err = a.Client.HeadObjectWithContext(a.ctx, input)
+	if err == nil {
+		return true, nil
+	}
+	// only a missing object means false, other failures such as 403 and 5xx are errors
+	err = wrapS3Error(err)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (a *S3) GetObjectTagging(key string) (map[string]string, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.Client.GetObjectTaggingWithContext(a.ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range result.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// PutObjectTagging replaces all tags of the object
+func (a *S3) PutObjectTagging(key string, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	tagSet := make([]*s3.Tag, 0, len(tags))
+	for _, k := range sortedTagKeys(tags) {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	_, err = a.Client.PutObjectTaggingWithContext(a.ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(key),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	return wrapS3Error(err)
+}
+
+// GetObjectACL returns the canned acl matching the grants to all users
+func (a *S3) GetObjectACL(key string) (string, error) {
+	if a.noObjectACL {
+		return "", ErrUnsupported
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := a.Client.GetObjectAclWithContext(a.ctx, &s3.GetObjectAclInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", wrapS3Error(err)
+	}
+	return s3GrantsACL(result.Grants), nil
+}
+
+func (a *S3) PutObjectACL(key string, acl string) error {
+	if err := validateObjectACL(acl); err != nil {
+		return err
+	}
+	cannedACL, err := a.cannedACL(acl)
+	if err != nil {
+		return err
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.Client.PutObjectAclWithContext(a.ctx, &s3.PutObjectAclInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		ACL:    cannedACL,
+	})
+	return wrapS3Error(err)
+}
+
+// cannedACL returns the canned acl of PutWithACL, nil if it isn't set
+func (a *S3) cannedACL(acl string) (*string, error) {
+	if acl != "" && a.noObjectACL {
+		return nil, fmt.Errorf("acl %q: %w", acl, ErrUnsupported)
+	}
+	return s3CannedACL(acl)
+}
+
+func (a *S3) get(key string, options ...GetOptions) (*s3.GetObjectOutput, error) {
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	if err := setS3Options(options, input); err != nil {
+		return nil, err
+	}
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	var (
+		header     http.Header
+		reqOptions []request.Option
+	)
+	headers := make(map[string]string)
+	if getOpts.checksumValidation {
+		reqOptions = append(reqOptions, withResponseHeader(&header))
+		if !a.noChecksumSHA256 {
+			headers[s3ChecksumModeHeader] = "ENABLED"
+		}
+	}
+	if getOpts.acceptIdentity() {
+		headers["Accept-Encoding"] = "identity"
+	}
+	if len(headers) > 0 {
+		reqOptions = append(reqOptions, request.WithSetRequestHeaders(headers))
+	}
+
+	result, err := a.Client.GetObjectWithContext(withProgress(a.ctx, getOpts.progress), input, reqOptions...)
+	if err != nil {
+		return nil, wrapS3Error(err)
+	}
+	result.Body = getOpts.verifyBody(result.Body, aws.StringValue(result.ETag), header)
+	var inflated bool
+	if result.Body, inflated, err = getOpts.inflateIdentityGzip(result.Body, aws.StringValue(result.ContentEncoding)); err != nil {
+		return nil, err
+	}
+	if inflated {
+		result.ContentEncoding, result.ContentLength = nil, nil
+	}
+	if result.Body, err = getOpts.decodeBody(result.Body, aws.StringValue(result.ContentEncoding)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func getS3Meta(attributes []string, metaData map[string]*string) map[string]string {
+	// https://github.com/aws/aws-sdk-go/issues/445
+	// aws 会将 meta 的首字母大写，在这里需要转换下
+	// the keys of user metadata may not be canonical, e.g. for headers added by a proxy,
+	// so they are looked up case-insensitively if the canonical key is missing
+	res := make(map[string]string)
+	for _, v := range attributes {
+		if value := metaData[http.CanonicalHeaderKey(v)]; value != nil {
+			res[v] = *value
+			continue
+		}
+		for k, value := range metaData {
+			if value != nil && strings.EqualFold(k, v) {
+				res[v] = *value
+				break
+			}
+		}
+	}
+	return res
+}
+
+func setS3Options(options []GetOptions, getObjectInput *s3.GetObjectInput) error {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if getOpts.contentEncoding != nil {
+		getObjectInput.ResponseContentEncoding = getOpts.contentEncoding
+	}
+	if getOpts.contentType != nil {
 		getObjectInput.ResponseContentType = getOpts.contentType
 	}
+	if getOpts.ifNoneMatch != nil {
+		getObjectInput.IfNoneMatch = getOpts.ifNoneMatch
+	}
+	getObjectInput.VersionId = getOpts.versionID
+	byteRange, err := getOpts.byteRange()
+	if err != nil {
+		return err
+	}
+	if byteRange != "" {
+		getObjectInput.Range = aws.String(byteRange)
+	}
+	if err := validateSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return err
+	}
+	getObjectInput.SSECustomerAlgorithm = s3SSECustomerAlgorithm(getOpts.sseCustomerKey)
+	getObjectInput.SSECustomerKey = s3SSECustomerKey(getOpts.sseCustomerKey)
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func s3ServerSideEncryption(putOptions *putOptions) *string {
+	if putOptions.sseS3 {
+		return aws.String(s3.ServerSideEncryptionAes256)
+	}
+	if putOptions.sseKMSKeyID != nil {
+		return aws.String(s3.ServerSideEncryptionAwsKms)
+	}
+	return nil
+}
+
+// s3SSEKMSKeyID returns nil for the default kms key
+func s3SSEKMSKeyID(putOptions *putOptions) *string {
+	if putOptions.sseKMSKeyID != nil && *putOptions.sseKMSKeyID != "" {
+		return putOptions.sseKMSKeyID
+	}
+	return nil
+}
+
+// storageClass returns the storage class of putOptions for s3 or gcs, nil for the default
+func (a *S3) storageClass(putOptions *putOptions) (*string, error) {
+	classes := a.storageClasses
+	if classes == nil {
+		classes = s3StorageClasses
+	}
+	class, err := backendStorageClass(classes, putOptions)
+	if err != nil || class == "" {
+		return nil, err
+	}
+	return aws.String(class), nil
+}
+
+// s3CopySource is the url encoded bucket/key
+func s3CopySource(bucket, key string) string {
+	return (&url.URL{Path: bucket + "/" + key}).EscapedPath()
+}
+
+func s3SSECustomerAlgorithm(key []byte) *string {
+	if key == nil {
+		return nil
+	}
+	return aws.String(s3.ServerSideEncryptionAes256)
+}
+
+// s3SSECustomerKey returns the raw key, the aws sdk encodes it and computes the md5 header
+func s3SSECustomerKey(key []byte) *string {
+	if key == nil {
+		return nil
+	}
+	return aws.String(string(key))
+}
+
+// withResponseHeader copies the response headers into header, unlike request.WithGetResponseHeaders
+// it doesn't panic if the request fails without a response
+func withResponseHeader(header *http.Header) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(req *request.Request) {
+			if req.HTTPResponse != nil {
+				*header = req.HTTPResponse.Header
+			}
+		})
+	}
+}
+
+// buckets returns the bucket names of the shards, or the bucket if there are no shards
+func (a *S3) buckets() []string {
+	if len(a.ShardsBucket) == 0 {
+		return []string{a.BucketName}
+	}
+	names := make([]string, 0, len(a.ShardsBucket))
+	seen := make(map[string]bool, len(a.ShardsBucket))
+	for _, name := range a.ShardsBucket {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *S3) bucketExists() (bool, error) {
+	for _, name := range a.buckets() {
+		_, err := a.Client.HeadBucketWithContext(a.ctx, &s3.HeadBucketInput{Bucket: aws.String(name)})
+		if err != nil {
+			if rerr, ok := err.(awserr.RequestFailure); ok && rerr.StatusCode() == http.StatusNotFound {
+				return false, nil
+			}
+			return false, wrapS3Error(err)
+		}
+	}
+	return true, nil
+}
+
+func (a *S3) ping() error {
+	for _, name := range a.buckets() {
+		_, err := a.Client.HeadBucketWithContext(a.ctx, &s3.HeadBucketInput{Bucket: aws.String(name)})
+		if rerr, ok := err.(awserr.RequestFailure); ok {
+			switch rerr.StatusCode() {
+			case http.StatusForbidden:
+				continue
+			case http.StatusNotFound:
+				return fmt.Errorf("ping bucket %s: %w", name, &wrappedError{kind: ErrBucketNotFound, err: err})
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("ping bucket %s: %w", name, wrapS3Error(err))
+		}
+	}
+	return nil
+}
+
+func (a *S3) createBucket(options *createBucketOptions) error {
+	cannedACL, err := s3CannedACL(options.acl)
+	if err != nil {
+		return err
+	}
+	region := options.region
+	if region == "" {
+		region = aws.StringValue(a.Client.Config.Region)
+	}
+	input := &s3.CreateBucketInput{ACL: cannedACL}
+	// us-east-1 is the default location, which s3 rejects as a location constraint
+	if region != "" && region != "us-east-1" {
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{LocationConstraint: aws.String(region)}
+	}
+	for _, name := range a.buckets() {
+		input.Bucket = aws.String(name)
+		_, err := a.Client.CreateBucketWithContext(a.ctx, input)
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeBucketAlreadyOwnedByYou:
+				continue
+			case s3.ErrCodeBucketAlreadyExists:
+				return &wrappedError{kind: ErrBucketAlreadyExists, err: err}
+			}
+		}
+		if err != nil {
+			return wrapS3Error(err)
+		}
+	}
+	return nil
+}
+
+func (a *S3) GetObjectLegalHold(key string) (bool, error) {
+	if a.noObjectLock {
+		return false, ErrUnsupported
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := a.Client.GetObjectLegalHoldWithContext(a.ctx, &s3.GetObjectLegalHoldInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if isS3NoObjectLockConfiguration(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, wrapS3Error(err)
+	}
+	return res.LegalHold != nil && aws.StringValue(res.LegalHold.Status) == s3.ObjectLockLegalHoldStatusOn, nil
+}
+
+func (a *S3) PutObjectLegalHold(key string, on bool) error {
+	if a.noObjectLock {
+		return ErrUnsupported
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	status := s3.ObjectLockLegalHoldStatusOff
+	if on {
+		status = s3.ObjectLockLegalHoldStatusOn
+	}
+	_, err = a.Client.PutObjectLegalHoldWithContext(a.ctx, &s3.PutObjectLegalHoldInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(key),
+		LegalHold: &s3.ObjectLockLegalHold{Status: aws.String(status)},
+	})
+	return wrapS3Error(err)
+}
+
+func (a *S3) GetObjectRetention(key string) (string, time.Time, error) {
+	if a.noObjectLock {
+		return "", time.Time{}, ErrUnsupported
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	res, err := a.Client.GetObjectRetentionWithContext(a.ctx, &s3.GetObjectRetentionInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if isS3NoObjectLockConfiguration(err) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, wrapS3Error(err)
+	}
+	if res.Retention == nil {
+		return "", time.Time{}, nil
+	}
+	return aws.StringValue(res.Retention.Mode), aws.TimeValue(res.Retention.RetainUntilDate), nil
+}
+
+// PutObjectRetention sets the retention of the object, which can only be extended. Shortening or removing a
+// governance retention needs the s3:BypassGovernanceRetention permission, a compliance retention can't be shortened.
+func (a *S3) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	if err := validateRetention(mode, retainUntil); err != nil {
+		return err
+	}
+	if a.noObjectLock {
+		return ErrUnsupported
+	}
+	bucketName, err := a.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.Client.PutObjectRetentionWithContext(a.ctx, &s3.PutObjectRetentionInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Retention: &s3.ObjectLockRetention{
+			Mode:            aws.String(mode),
+			RetainUntilDate: aws.Time(retainUntil),
+		},
+	})
+	return wrapS3Error(err)
+}
+
+// s3ObjectLock returns the object lock mode, retain until date and legal hold of PutWithRetention and PutWithLegalHold
+func s3ObjectLock(putOptions *putOptions) (*string, *time.Time, *string) {
+	var mode, legalHold *string
+	if putOptions.retentionMode != "" {
+		mode = aws.String(putOptions.retentionMode)
+	}
+	if putOptions.legalHold {
+		legalHold = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+	return mode, putOptions.retainUntil, legalHold
+}
+
+func (a *S3) Close() error {
+	a.Client.Config.HTTPClient.CloseIdleConnections()
+	return nil
 }
@@ -2,6 +2,7 @@ package awos
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"strconv"
 	"strings"
@@ -218,7 +219,7 @@ func TestS3_Del(t *testing.T) {
 
 func TestS3_GetNotExist(t *testing.T) {
 	res1, err := awsClient.Get(S3Guid + "123")
-	if res1 != "" || err != nil {
+	if res1 != "" || !errors.Is(err, ErrObjectNotFound) {
 		t.Log("aws get not exist key fail, res:", res1, "err:", err)
 		t.Fail()
 	}
@@ -226,7 +227,7 @@ func TestS3_GetNotExist(t *testing.T) {
 	attributes := make([]string, 0)
 	attributes = append(attributes, "head")
 	res2, err := awsClient.Head(S3Guid+"123", attributes)
-	if res2 != nil || err != nil {
+	if res2 != nil || !errors.Is(err, ErrObjectNotFound) {
 		t.Log("aws head not exist key fail, res:", res2, "err:", err, err.Error())
 		t.Fail()
 	}
@@ -238,7 +239,7 @@ func TestS3_DelMulti(t *testing.T) {
 		awsClient.Put(key, strings.NewReader("2333333"), nil)
 	}
 
-	err := awsClient.DelMulti(keys)
+	_, err := awsClient.DelMulti(keys)
 	if err != nil {
 		t.Log("aws del multi keys fail, err:", err)
 		t.Fail()
@@ -246,7 +247,7 @@ func TestS3_DelMulti(t *testing.T) {
 
 	for _, key := range keys {
 		res, err := awsClient.Get(key)
-		if res != "" || err != nil {
+		if res != "" || !errors.Is(err, ErrObjectNotFound) {
 			t.Logf("key:%s should not be exist", key)
 			t.Fail()
 		}
@@ -0,0 +1,1431 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/golang/snappy"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+const (
+	// azureAPIVersion is the x-ms-version of requests and the signed version of SAS
+	azureAPIVersion = "2020-10-02"
+	// azureDefaultEndpoint is the blob endpoint of an account, e.g. https://myaccount.blob.core.windows.net
+	azureDefaultEndpoint = "https://%s.blob.core.windows.net"
+	// azureMaxAppendBlockSize is the largest block appended by one Append Block request
+	azureMaxAppendBlockSize = 4 << 20
+	// azureCopyPollInterval is the interval of checking a pending copy
+	azureCopyPollInterval = 500 * time.Millisecond
+)
+
+var _ Component = (*Azure)(nil)
+
+// Azure talks to Azure Blob Storage through its REST API, requests are authorized by the shared key of the account.
+// AccessKeyID is the account name, AccessKeySecret is the base64 account key, and buckets are containers.
+// The endpoint defaults to https://<account>.blob.core.windows.net, emulators such as azurite
+// take the account in the path, e.g. http://127.0.0.1:10000/devstoreaccount1.
+type Azure struct {
+	ContainerName   string
+	ShardsContainer map[string]string
+	client          *http.Client
+	endpoint        *url.URL
+	account         string
+	accountKey      []byte
+	ctx             context.Context
+	// detectContentType is EnableContentTypeDetection of config
+	detectContentType bool
+}
+
+func newAzure(name string, cfg *config, logger *elog.Component) (*Azure, error) {
+	if cfg.AccessKeyID == "" {
+		return nil, errors.New("azure requires the account name as accessKeyID")
+	}
+	accountKey, err := base64.StdEncoding.DecodeString(cfg.AccessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("azure requires the base64 account key as accessKeySecret: %w", err)
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(azureDefaultEndpoint, cfg.AccessKeyID)
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	az := &Azure{
+		client: &http.Client{
+			Timeout:   time.Second * time.Duration(cfg.S3HttpTimeoutSecs),
+			Transport: newHTTPTransport(name, cfg, logger),
+		},
+		endpoint:          u,
+		account:           cfg.AccessKeyID,
+		accountKey:        accountKey,
+		ctx:               markNoDeadline(context.Background()),
+		detectContentType: cfg.EnableContentTypeDetection,
+	}
+	if cfg.Shards != nil && len(cfg.Shards) > 0 {
+		az.ShardsContainer = make(map[string]string)
+		for _, v := range cfg.Shards {
+			for i := 0; i < len(v); i++ {
+				az.ShardsContainer[strings.ToLower(v[i:i+1])] = cfg.Bucket + "-" + v
+			}
+		}
+	} else {
+		az.ContainerName = cfg.Bucket
+	}
+	return az, nil
+}
+
+func (az *Azure) WithContext(ctx context.Context) Component {
+	return &Azure{
+		ContainerName:     az.ContainerName,
+		ShardsContainer:   az.ShardsContainer,
+		client:            az.client,
+		endpoint:          az.endpoint,
+		account:           az.account,
+		accountKey:        az.accountKey,
+		ctx:               markNoDeadline(ctx),
+		detectContentType: az.detectContentType,
+	}
+}
+
+// withProgress returns az whose requests report their bytes to p, az is returned as is if p is nil
+func (az *Azure) withProgress(p *progressTracker) *Azure {
+	if p == nil {
+		return az
+	}
+	return az.WithContext(withProgress(az.ctx, p)).(*Azure)
+}
+
+func (az *Azure) getContainer(key string) (string, error) {
+	if az.ShardsContainer != nil && len(az.ShardsContainer) > 0 {
+		keyLength := len(key)
+		container := az.ShardsContainer[strings.ToLower(key[keyLength-1:keyLength])]
+		if container == "" {
+			return "", errors.New("shards can't find container")
+		}
+
+		return container, nil
+	}
+
+	return az.ContainerName, nil
+}
+
+// blobURL returns the url of the blob, or of the container if key is empty
+func (az *Azure) blobURL(container string, key string, query url.Values) *url.URL {
+	u := *az.endpoint
+	u.Path += "/" + container
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = ""
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// do sends a request signed by the shared key, header is not modified. body is sent from its current offset
+// and rewound for retries. Error responses are returned as AzureError translated by wrapAzureError.
+func (az *Azure) do(method string, u *url.URL, header http.Header, body io.ReadSeeker) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(az.ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil {
+		req.Header = header.Clone()
+	}
+	if body != nil {
+		start, err := body.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		size, ok := seekerSize(body)
+		if !ok {
+			return nil, errors.New("azure: can't get the size of the body")
+		}
+		if size > 0 {
+			req.ContentLength = size
+			req.Body = ioutil.NopCloser(body)
+			req.GetBody = func() (io.ReadCloser, error) {
+				if _, err := body.Seek(start, io.SeekStart); err != nil {
+					return nil, err
+				}
+				return ioutil.NopCloser(body), nil
+			}
+		}
+	}
+	req.Header.Set("X-Ms-Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+	req.Header.Set("Authorization", "SharedKey "+az.account+":"+
+		base64.StdEncoding.EncodeToString(hmacSHA256(az.accountKey, azureStringToSign(req, az.account))))
+
+	res, err := az.client.Do(req)
+	if err != nil {
+		return nil, withRequestID(az.ctx, err, "")
+	}
+	if res.StatusCode >= http.StatusMultipleChoices {
+		defer res.Body.Close()
+		azureErr := parseAzureError(res)
+		return nil, withRequestID(az.ctx, wrapAzureError(azureErr), azureErr.RequestID)
+	}
+	return res, nil
+}
+
+// doAndClose sends the request and discards the response body
+func (az *Azure) doAndClose(method string, u *url.URL, header http.Header, body io.ReadSeeker) (http.Header, error) {
+	res, err := az.do(method, u, header, body)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return res.Header, res.Body.Close()
+}
+
+// parseAzureError reads the error code and message from the body, or from the x-ms-error-code header for HEAD
+func parseAzureError(res *http.Response) *AzureError {
+	azureErr := &AzureError{
+		StatusCode: res.StatusCode,
+		Code:       res.Header.Get("X-Ms-Error-Code"),
+		RequestID:  res.Header.Get("X-Ms-Request-Id"),
+	}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if xml.Unmarshal(data, &body) == nil {
+		if body.Code != "" {
+			azureErr.Code = body.Code
+		}
+		// the message is followed by the request id and time
+		azureErr.Message = strings.SplitN(body.Message, "\n", 2)[0]
+	}
+	if azureErr.Message == "" {
+		azureErr.Message = http.StatusText(res.StatusCode)
+	}
+	return azureErr
+}
+
+// azureStringToSign is the string to sign of the shared key authorization
+func azureStringToSign(r *http.Request, account string) string {
+	contentLength := ""
+	if r.ContentLength > 0 {
+		contentLength = strconv.FormatInt(r.ContentLength, 10)
+	}
+	h := r.Header
+	return strings.Join([]string{
+		r.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		contentLength,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		h.Get("Date"),
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+		azureCanonicalizedHeaders(h) + azureCanonicalizedResource(r.URL, account),
+	}, "\n")
+}
+
+// azureCanonicalizedHeaders are the lower cased x-ms- headers in order, each followed by a new line
+func azureCanonicalizedHeaders(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		if name := strings.ToLower(k); strings.HasPrefix(name, "x-ms-") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(h.Get(name)) + "\n")
+	}
+	return b.String()
+}
+
+// azureCanonicalizedResource is the account, the escaped path and the sorted query parameters
+func azureCanonicalizedResource(u *url.URL, account string) string {
+	var b strings.Builder
+	b.WriteString("/" + account + u.EscapedPath())
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for k := range query {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		b.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(values, ","))
+	}
+	return b.String()
+}
+
+// blobSAS returns the query of a service SAS of the blob with permissions, such as "r", valid until expiry
+func (az *Azure) blobSAS(container string, key string, permissions string, expiry time.Time, signOptions *signOptions) url.Values {
+	signedExpiry := expiry.UTC().Format("2006-01-02T15:04:05Z")
+	sas := url.Values{
+		"sv": {azureAPIVersion},
+		"se": {signedExpiry},
+		"sr": {"b"},
+		"sp": {permissions},
+	}
+	// the overridden Cache-Control, Content-Disposition and Content-Type of the response
+	overrides := make([]string, 3)
+	for i, header := range []struct {
+		param string
+		value *string
+	}{
+		{"rscc", signOptions.responseCacheControl},
+		{"rscd", signOptions.responseContentDisposition},
+		{"rsct", signOptions.responseContentType},
+	} {
+		if header.value != nil {
+			overrides[i] = *header.value
+			sas.Set(header.param, *header.value)
+		}
+	}
+	// permissions, start, expiry, resource, identifier, ip, protocol, version, resource type,
+	// snapshot time and the overridden response headers rscc, rscd, rsce, rscl and rsct
+	stringToSign := strings.Join([]string{
+		permissions, "", signedExpiry, "/blob/" + az.account + "/" + container + "/" + key,
+		"", "", "", azureAPIVersion, "b", "", overrides[0], overrides[1], "", "", overrides[2],
+	}, "\n")
+	sas.Set("sig", base64.StdEncoding.EncodeToString(hmacSHA256(az.accountKey, stringToSign)))
+	return sas
+}
+
+// azureETag quotes etags like ObjectMeta.ETag for the conditional headers
+func azureETag(etag string) string {
+	if etag == "*" || strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// setAzureSSECustomerKey sets the headers of customer provided keys, which must be sent over https
+func setAzureSSECustomerKey(header http.Header, key []byte) {
+	if key == nil {
+		return
+	}
+	sum := sha256.Sum256(key)
+	header.Set("X-Ms-Encryption-Key", base64.StdEncoding.EncodeToString(key))
+	header.Set("X-Ms-Encryption-Key-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+	header.Set("X-Ms-Encryption-Algorithm", "AES256")
+}
+
+// getAzureOptions returns the headers and query of getting a blob.
+// GetWithContentType and GetWithContentEncoding are ignored, azure only overrides them for SAS.
+func getAzureOptions(getOpts *getOptions) (http.Header, url.Values, error) {
+	header := http.Header{}
+	query := url.Values{}
+	byteRange, err := getOpts.byteRange()
+	if err != nil {
+		return nil, nil, err
+	}
+	if byteRange != "" {
+		header.Set("X-Ms-Range", byteRange)
+	}
+	if getOpts.ifNoneMatch != nil {
+		header.Set("If-None-Match", azureETag(*getOpts.ifNoneMatch))
+	}
+	if getOpts.versionID != nil {
+		query.Set("versionid", *getOpts.versionID)
+	}
+	if getOpts.acceptIdentity() {
+		header.Set("Accept-Encoding", "identity")
+	}
+	if err := validateSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, nil, err
+	}
+	setAzureSSECustomerKey(header, getOpts.sseCustomerKey)
+	return header, query, nil
+}
+
+var errAzureSSEKMS = fmt.Errorf("azure doesn't support SSE-KMS: %w", ErrUnsupported)
+
+// getAzurePutHeaders returns the headers of the properties and metadata of a new blob.
+// PutWithSSES3 needs no header, azure always encrypts blobs with keys it manages.
+func getAzurePutHeaders(meta map[string]string, putOptions *putOptions) (http.Header, error) {
+	if putOptions.sseKMSKeyID != nil {
+		return nil, errAzureSSEKMS
+	}
+	if putOptions.expires != nil {
+		return nil, fmt.Errorf("azure doesn't support PutWithExpireTime: %w", ErrUnsupported)
+	}
+	if putOptions.acl != "" {
+		return nil, errAzureObjectACL
+	}
+	if putOptions.objectLock() {
+		return nil, errObjectLock
+	}
+	storageClass, err := backendStorageClass(azureStorageClasses, putOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("X-Ms-Blob-Content-Type", putOptions.contentType)
+	if putOptions.contentEncoding != nil {
+		header.Set("X-Ms-Blob-Content-Encoding", *putOptions.contentEncoding)
+	}
+	if putOptions.contentDisposition != nil {
+		header.Set("X-Ms-Blob-Content-Disposition", *putOptions.contentDisposition)
+	}
+	if putOptions.cacheControl != nil {
+		header.Set("X-Ms-Blob-Cache-Control", *putOptions.cacheControl)
+	}
+	for k, v := range meta {
+		header.Set("X-Ms-Meta-"+k, v)
+	}
+	if storageClass != "" {
+		header.Set("X-Ms-Access-Tier", storageClass)
+	}
+	for k, v := range putOptions.conditionalHeaders() {
+		header.Set(k, azureETag(v))
+	}
+	setAzureSSECustomerKey(header, putOptions.sseCustomerKey)
+	return header, nil
+}
+
+// getAzureMeta returns the attributes from the http standard headers, the user metadata
+// or the x-ms- headers, such as Version-Id
+func getAzureMeta(attributes []string, header http.Header) map[string]string {
+	meta := make(map[string]string)
+	for _, v := range attributes {
+		for _, name := range []string{v, "X-Ms-Meta-" + v, "X-Ms-" + v} {
+			if value := header.Get(name); value != "" {
+				meta[v] = value
+				break
+			}
+		}
+	}
+	return meta
+}
+
+func (az *Azure) get(key string, options ...GetOptions) (*http.Response, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	header, query, err := getAzureOptions(getOpts)
+	if err != nil {
+		return nil, err
+	}
+	res, err := az.withProgress(getOpts.progress).do(http.MethodGet, az.blobURL(container, key, query), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the etag isn't an md5, the blob has Content-MD5 if it's uploaded by Put
+	res.Body = getOpts.verifyBody(res.Body, "", res.Header)
+	var inflated bool
+	if res.Body, inflated, err = getOpts.inflateIdentityGzip(res.Body, res.Header.Get("Content-Encoding")); err != nil {
+		return nil, err
+	}
+	if inflated {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+	}
+	if res.Body, err = getOpts.decodeBody(res.Body, res.Header.Get("Content-Encoding")); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (az *Azure) head(key string, options ...GetOptions) (http.Header, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	header, query, err := getAzureOptions(getOpts)
+	if err != nil {
+		return nil, err
+	}
+	return az.doAndClose(http.MethodHead, az.blobURL(container, key, query), header, nil)
+}
+
+// don't forget to call the close() method of the io.ReadCloser
+func (az *Azure) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	res, err := az.get(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// don't forget to call the close() method of the io.ReadCloser
+func (az *Azure) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
+	res, err := az.get(key, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.Body, getAzureMeta(attributes, res.Header), nil
+}
+
+func (az *Azure) Get(key string, options ...GetOptions) (string, error) {
+	var res string
+	err := az.read(key, options, func(data []byte) error {
+		res = string(data)
+		return nil
+	})
+	return res, err
+}
+
+func (az *Azure) GetBytes(key string, options ...GetOptions) ([]byte, error) {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	var res []byte
+	err := az.read(key, options, func(data []byte) error {
+		res = getOpts.copyBytes(data)
+		return nil
+	})
+	return res, err
+}
+
+// read reads the whole blob and calls consume with the content, see readBody
+func (az *Azure) read(key string, options []GetOptions, consume func(data []byte) error) error {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	res, err := az.get(key, options...)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return readBody(res.Body, getOpts.buffer, consume)
+}
+
+// GetMulti stops fetching when the context of WithContext is done
+func (az *Azure) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(az.ctx, keys, options, az.GetBytes)
+}
+
+func (az *Azure) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	return az.GetAsReader(key, GetWithRange(offset, offset+length-1))
+}
+
+func (az *Azure) GetAndDecompress(key string) (string, error) {
+	res, err := az.get(key)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	compressor := res.Header.Get("X-Ms-Meta-Compressor")
+	if compressor != "" {
+		if compressor != "snappy" {
+			return "", errors.New("GetAndDecompress only supports snappy for now, got " + compressor)
+		}
+
+		rawBytes, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return "", err
+		}
+
+		decodedBytes, err := snappy.Decode(nil, rawBytes)
+		if err != nil {
+			if errors.Is(err, snappy.ErrCorrupt) {
+				reader := snappy.NewReader(bytes.NewReader(rawBytes))
+				data, err := ioutil.ReadAll(reader)
+				if err != nil {
+					return "", err
+				}
+
+				return string(data), nil
+			}
+			return "", err
+		}
+
+		return string(decodedBytes), nil
+	}
+
+	data, err := decompressBody(res.Header.Get("Content-Encoding"), res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (az *Azure) GetAndDecompressAsReader(key string) (io.ReadCloser, error) {
+	result, err := az.GetAndDecompress(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(strings.NewReader(result)), nil
+}
+
+// Put uploads a block blob with a single request
+func (az *Azure) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	if putOptions.checksumAlgorithm == ChecksumSHA256 {
+		return fmt.Errorf("PutWithChecksum(ChecksumSHA256): %w", ErrUnsupported)
+	}
+	if az.detectContentType {
+		if err := putOptions.detectContentType(key, reader); err != nil {
+			return err
+		}
+	}
+	meta, err = putOptions.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	reader, err = putOptions.compressBody(reader)
+	if err != nil {
+		return err
+	}
+	header, err := getAzurePutHeaders(meta, putOptions)
+	if err != nil {
+		return err
+	}
+	header.Set("X-Ms-Blob-Type", "BlockBlob")
+	var checksum *contentChecksum
+	if reader != nil {
+		if checksum, err = putOptions.checksum(reader); err != nil {
+			return err
+		}
+		putOptions.progress.setReaderTotal(reader)
+	}
+	if checksum != nil {
+		header.Set("Content-MD5", *checksum.contentMD5())
+	}
+
+	u := az.blobURL(container, key, nil)
+	return retry.Do(func() error {
+		resHeader, err := az.withProgress(putOptions.progress).doAndClose(http.MethodPut, u, header, reader)
+		if err == nil {
+			err = checksum.verify("", resHeader)
+		}
+		if err != nil && reader != nil {
+			// Note that it's safe to ignore the error here since the 0,0 position is always valid
+			_, _ = reader.Seek(0, 0)
+		}
+		return err
+	}, putRetryOptions()...)
+}
+
+// azureBlockList is the body of Put Block List
+type azureBlockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+// azureBlockID is the id of the block of partNumber, all the ids of a blob must have the same length
+func azureBlockID(partNumber int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", partNumber)))
+}
+
+// MultipartUpload uploads reader in blocks, which are committed by a block list after all of them are uploaded.
+// Azure discards the uncommitted blocks if the upload fails.
+func (az *Azure) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	if putOptions.compression == "" {
+		putOptions.progress.setReaderTotal(reader)
+	}
+	if az.detectContentType {
+		if reader, err = putOptions.detectStreamContentType(key, reader); err != nil {
+			return err
+		}
+	}
+	meta, err = putOptions.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	body := putOptions.compressStream(reader)
+	defer body.Close()
+	header, err := getAzurePutHeaders(meta, putOptions)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu    sync.Mutex
+		parts []int
+	)
+	err = uploadParts(body, putOptions.partSize, putOptions.concurrency, func(partNumber int, data []byte) error {
+		// each block must be encrypted with the same key
+		blockHeader := http.Header{}
+		setAzureSSECustomerKey(blockHeader, putOptions.sseCustomerKey)
+		checksum := putOptions.partChecksum(data)
+		if checksum != nil {
+			blockHeader.Set("Content-MD5", *checksum.contentMD5())
+		}
+		query := url.Values{"comp": {"block"}, "blockid": {azureBlockID(partNumber)}}
+		resHeader, err := az.withProgress(putOptions.progress).doAndClose(http.MethodPut, az.blobURL(container, key, query), blockHeader, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if err := checksum.verify("", resHeader); err != nil {
+			return err
+		}
+		mu.Lock()
+		parts = append(parts, partNumber)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Ints(parts)
+	blockList := azureBlockList{Latest: make([]string, 0, len(parts))}
+	for _, partNumber := range parts {
+		blockList.Latest = append(blockList.Latest, azureBlockID(partNumber))
+	}
+	data, err := xml.Marshal(blockList)
+	if err != nil {
+		return err
+	}
+	header.Set("Content-Type", "application/xml")
+	_, err = az.doAndClose(http.MethodPut, az.blobURL(container, key, url.Values{"comp": {"blocklist"}}), header, bytes.NewReader(data))
+	return err
+}
+
+// Append appends to an append blob which is created if position is 0, like oss appendable objects.
+// Block blobs created by Put can't be appended, and position must be equal to the size of the blob.
+func (az *Azure) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return position, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return position, err
+	}
+
+	u := az.blobURL(container, key, nil)
+	if position == 0 {
+		putOptions := DefaultPutOptions()
+		for _, opt := range options {
+			opt(putOptions)
+		}
+		header, err := getAzurePutHeaders(nil, putOptions)
+		if err != nil {
+			return position, err
+		}
+		header.Set("X-Ms-Blob-Type", "AppendBlob")
+		// an empty append blob may exist already, the position condition of the blocks checks it's empty
+		header.Set("If-None-Match", "*")
+		if _, err := az.doAndClose(http.MethodPut, u, header, nil); err != nil && !errors.Is(err, ErrPreconditionFailed) {
+			return position, err
+		}
+	}
+
+	appendURL := az.blobURL(container, key, url.Values{"comp": {"appendblock"}})
+	for len(data) > 0 {
+		block := data
+		if len(block) > azureMaxAppendBlockSize {
+			block = block[:azureMaxAppendBlockSize]
+		}
+		header := http.Header{}
+		header.Set("X-Ms-Blob-Condition-Appendpos", strconv.FormatInt(position, 10))
+		if _, err := az.doAndClose(http.MethodPut, appendURL, header, bytes.NewReader(block)); err != nil {
+			return position, err
+		}
+		position += int64(len(block))
+		data = data[len(block):]
+	}
+	return position, nil
+}
+
+func (az *Azure) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+
+	encodedBytes := snappy.Encode(nil, data)
+
+	meta["Compressor"] = "snappy"
+
+	return az.Put(key, bytes.NewReader(encodedBytes), meta, options...)
+}
+
+var errAzureCopySSECustomerKey = fmt.Errorf("azure doesn't support copying with SSE-C: %w", ErrUnsupported)
+
+// Copy copies srcKey to dstKey on the server side and waits until the copy completes.
+// The metadata of the source blob is copied unless CopyWithMeta is given,
+// CopyWithContentType sets the properties of the destination blob after copying.
+func (az *Azure) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	srcContainer, err := az.getContainer(srcKey)
+	if err != nil {
+		return err
+	}
+
+	copyOpts := DefaultCopyOptions()
+	for _, opt := range options {
+		opt(copyOpts)
+	}
+	if err := copyOpts.validate(); err != nil {
+		return err
+	}
+	if copyOpts.sourceSSECustomerKey != nil || copyOpts.sseCustomerKey != nil {
+		return errAzureCopySSECustomerKey
+	}
+	// the tags of the source are never copied
+	if copyOpts.taggingDirective == DirectiveCopy {
+		return fmt.Errorf("CopyWithTaggingDirective(DirectiveCopy): %w", ErrUnsupported)
+	}
+	dstContainer := copyOpts.destBucket
+	if dstContainer == "" {
+		if dstContainer, err = az.getContainer(dstKey); err != nil {
+			return err
+		}
+	}
+
+	header := http.Header{}
+	header.Set("X-Ms-Copy-Source", az.blobURL(srcContainer, srcKey, nil).String())
+	for k, v := range copyOpts.meta {
+		header.Set("X-Ms-Meta-"+strings.ToLower(k), v)
+	}
+	if len(copyOpts.tags) > 0 {
+		header.Set("X-Ms-Tags", encodeTagging(copyOpts.tags))
+	}
+	dst := az.blobURL(dstContainer, dstKey, nil)
+	res, err := az.doAndClose(http.MethodPut, dst, header, nil)
+	if err != nil {
+		return err
+	}
+	for status := res.Get("X-Ms-Copy-Status"); status != "success"; status = res.Get("X-Ms-Copy-Status") {
+		if status != "pending" {
+			return fmt.Errorf("azure copy %s: %s", status, res.Get("X-Ms-Copy-Status-Description"))
+		}
+		timer := time.NewTimer(azureCopyPollInterval)
+		select {
+		case <-az.ctx.Done():
+			timer.Stop()
+			return az.ctx.Err()
+		case <-timer.C:
+		}
+		if res, err = az.doAndClose(http.MethodHead, dst, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	// the metadata of the source is copied if none is given
+	if copyOpts.metadataDirective == DirectiveReplace && len(copyOpts.meta) == 0 {
+		if _, err := az.doAndClose(http.MethodPut, az.blobURL(dstContainer, dstKey, url.Values{"comp": {"metadata"}}), nil, nil); err != nil {
+			return err
+		}
+	}
+	if copyOpts.contentType != nil {
+		return az.setContentType(dstContainer, dstKey, *copyOpts.contentType)
+	}
+	return nil
+}
+
+// setContentType sets the content type of the blob, the other properties are sent again
+// since Set Blob Properties clears the ones which aren't given
+func (az *Azure) setContentType(container string, key string, contentType string) error {
+	u := az.blobURL(container, key, nil)
+	res, err := az.doAndClose(http.MethodHead, u, nil, nil)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("X-Ms-Blob-Content-Type", contentType)
+	for _, name := range []string{"Content-Encoding", "Content-Language", "Content-Disposition", "Cache-Control", "Content-MD5"} {
+		if v := res.Get(name); v != "" {
+			header.Set("X-Ms-Blob-"+name, v)
+		}
+	}
+	_, err = az.doAndClose(http.MethodPut, az.blobURL(container, key, url.Values{"comp": {"properties"}}), header, nil)
+	return err
+}
+
+// Move copies srcKey to dstKey and deletes srcKey, the source is kept if the copy fails.
+func (az *Azure) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return move(az, srcKey, dstKey, options)
+}
+
+// Del deletes the blob with its snapshots, blobs which don't exist are not an error, same as s3
+func (az *Azure) Del(key string) error {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("X-Ms-Delete-Snapshots", "include")
+	_, err = az.doAndClose(http.MethodDelete, az.blobURL(container, key, nil), header, nil)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	return err
+}
+
+// RestoreObject rehydrates an archived blob to the hot tier, RestoreTierExpedited rehydrates with high priority.
+// Rehydrated blobs stay in the hot tier, so RestoreWithDays doesn't apply.
+func (az *Azure) RestoreObject(key string, options ...RestoreOptions) error {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+
+	restoreOpts := DefaultRestoreOptions()
+	for _, opt := range options {
+		opt(restoreOpts)
+	}
+	if err := restoreOpts.validate(); err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("X-Ms-Access-Tier", azureStorageClasses[StorageClassStandard])
+	header.Set("X-Ms-Rehydrate-Priority", "Standard")
+	if restoreOpts.tier == RestoreTierExpedited {
+		header.Set("X-Ms-Rehydrate-Priority", "High")
+	}
+	_, err = az.doAndClose(http.MethodPut, az.blobURL(container, key, url.Values{"comp": {"tier"}}), header, nil)
+	return err
+}
+
+// GetVersion gets a specific version of the blob in an account with versioning enabled
+func (az *Azure) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return az.Get(key, append(options, GetWithVersionID(versionID))...)
+}
+
+// DelVersion permanently deletes a specific version of the blob
+func (az *Azure) DelVersion(key string, versionID string, options ...DelOptions) error {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = az.doAndClose(http.MethodDelete, az.blobURL(container, key, url.Values{"versionid": {versionID}}), nil, nil)
+	return err
+}
+
+// DelMulti deletes keys one by one, keys which don't exist are not reported as failed, same as s3.
+func (az *Azure) DelMulti(keys []string) (map[string]error, error) {
+	failed := make(map[string]error)
+	for _, key := range keys {
+		if err := az.Del(key); err != nil {
+			failed[key] = err
+		}
+	}
+	return delMultiResult(failed, len(keys))
+}
+
+func (az *Azure) Head(key string, attributes []string, options ...GetOptions) (map[string]string, error) {
+	header, err := az.head(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	return getAzureMeta(attributes, header), nil
+}
+
+// HeadObject returns the access tier, such as Hot, as the storage class,
+// an archived blob being rehydrated has an ongoing Restore.
+func (az *Azure) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	header, err := az.head(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := parseObjectMeta(header, "X-Ms-")
+	if err != nil {
+		return nil, err
+	}
+	meta.Key = key
+	meta.StorageClass = header.Get("X-Ms-Access-Tier")
+	if strings.HasPrefix(header.Get("X-Ms-Archive-Status"), "rehydrate-pending") {
+		meta.Restore = &RestoreStatus{Ongoing: true}
+	}
+	return meta, nil
+}
+
+// azureListResult is the body of List Blobs
+type azureListResult struct {
+	Blobs        []azureBlob `xml:"Blobs>Blob"`
+	BlobPrefixes []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>BlobPrefix"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+type azureBlob struct {
+	Name       string `xml:"Name"`
+	Properties struct {
+		LastModified    string `xml:"Last-Modified"`
+		ETag            string `xml:"Etag"`
+		ContentLength   int64  `xml:"Content-Length"`
+		ContentType     string `xml:"Content-Type"`
+		ContentEncoding string `xml:"Content-Encoding"`
+		AccessTier      string `xml:"AccessTier"`
+	} `xml:"Properties"`
+	// only listed with include=metadata
+	Metadata struct {
+		Items []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"Metadata"`
+}
+
+func (b *azureBlob) objectMeta() (ObjectMeta, error) {
+	meta := ObjectMeta{
+		Key:             b.Name,
+		ETag:            trimETag(b.Properties.ETag),
+		Size:            b.Properties.ContentLength,
+		ContentType:     b.Properties.ContentType,
+		ContentEncoding: b.Properties.ContentEncoding,
+		StorageClass:    b.Properties.AccessTier,
+	}
+	if v := b.Properties.LastModified; v != "" {
+		lastModified, err := http.ParseTime(v)
+		if err != nil {
+			return meta, err
+		}
+		meta.LastModified = lastModified
+	}
+	if len(b.Metadata.Items) > 0 {
+		meta.UserMeta = make(map[string]string, len(b.Metadata.Items))
+	}
+	for _, item := range b.Metadata.Items {
+		meta.UserMeta[strings.ToLower(item.XMLName.Local)] = item.Value
+	}
+	return meta, nil
+}
+
+// listBlobs lists at most maxKeys blobs with prefix from marker, which is the opaque continuation marker of azure.
+// Azure may return short pages, the following pages are listed until there are maxKeys blobs.
+// It returns the marker of the next page, which is empty if all the blobs are listed.
+func (az *Azure) listBlobs(container string, prefix string, delimiter string, marker string, maxKeys int, include string) ([]azureBlob, string, error) {
+	blobs := make([]azureBlob, 0, maxKeys)
+	for {
+		query := url.Values{
+			"restype":    {"container"},
+			"comp":       {"list"},
+			"maxresults": {strconv.Itoa(maxKeys - len(blobs))},
+		}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		if include != "" {
+			query.Set("include", include)
+		}
+		res, err := az.do(http.MethodGet, az.blobURL(container, "", query), nil, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		var result azureListResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+		blobs = append(blobs, result.Blobs...)
+		marker = result.NextMarker
+		if marker == "" || len(blobs) >= maxKeys {
+			return blobs, marker, nil
+		}
+	}
+}
+
+// azurePager lists blobs page by page with the markers of azure,
+// the key markers of ObjectIterator and walkObjects are ignored.
+type azurePager struct {
+	az        *Azure
+	container string
+	prefix    string
+	include   string
+	marker    string
+	done      bool
+}
+
+func (p *azurePager) next(maxKeys int) ([]azureBlob, error) {
+	if p.done {
+		return nil, nil
+	}
+	blobs, marker, err := p.az.listBlobs(p.container, p.prefix, "", p.marker, maxKeys, p.include)
+	if err != nil {
+		return nil, err
+	}
+	p.marker, p.done = marker, marker == ""
+	return blobs, nil
+}
+
+// ListObject takes the last key of the previous page as marker like s3. Since the markers of azure are opaque,
+// the keys up to marker are listed again and skipped, ListObjectsIter and WalkObjects page efficiently instead.
+func (az *Azure) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	keys := make([]string, 0)
+	next := ""
+	for {
+		blobs, nextMarker, err := az.listBlobs(container, prefix, delimiter, next, maxKeys, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range blobs {
+			if blob.Name <= marker {
+				continue
+			}
+			keys = append(keys, blob.Name)
+			if len(keys) == maxKeys {
+				return keys, nil
+			}
+		}
+		if nextMarker == "" {
+			return keys, nil
+		}
+		next = nextMarker
+	}
+}
+
+// ListObjectsWithDelimiter takes the opaque NextMarker of the previous page as marker, unlike ListObject.
+// Azure may return short pages, the following pages are listed until there are maxKeys blobs and prefixes.
+func (az *Azure) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	res := &ListResult{Objects: make([]ObjectMeta, 0), CommonPrefixes: make([]string, 0)}
+	for {
+		query := url.Values{
+			"restype":    {"container"},
+			"comp":       {"list"},
+			"maxresults": {strconv.Itoa(maxKeys - len(res.Objects) - len(res.CommonPrefixes))},
+		}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, err := az.do(http.MethodGet, az.blobURL(container, "", query), nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result azureListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.Blobs {
+			meta, err := result.Blobs[i].objectMeta()
+			if err != nil {
+				return nil, err
+			}
+			res.Objects = append(res.Objects, meta)
+		}
+		for _, p := range result.BlobPrefixes {
+			res.CommonPrefixes = append(res.CommonPrefixes, p.Name)
+		}
+		marker = result.NextMarker
+		if marker == "" || len(res.Objects)+len(res.CommonPrefixes) >= maxKeys {
+			res.NextMarker = marker
+			return res, nil
+		}
+	}
+}
+
+func (az *Azure) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	container, err := az.getContainer(key)
+	pager := &azurePager{az: az, container: container, prefix: prefix}
+	return newObjectIterator(az.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		if err != nil {
+			return nil, err
+		}
+		blobs, err := pager.next(maxKeys)
+		if err != nil {
+			return nil, err
+		}
+		objects := make([]ObjectMeta, 0, len(blobs))
+		for i := range blobs {
+			meta, err := blobs[i].objectMeta()
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, meta)
+		}
+		return objects, nil
+	}, listWithMaxPageSize(options, azureMaxListKeys)...)
+}
+
+// WalkObjects also sets the content type and user metadata, which azure lists too
+func (az *Azure) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+	pager := &azurePager{az: az, container: container, prefix: prefix, include: "metadata"}
+	return walkObjects(az.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		blobs, err := pager.next(maxKeys)
+		if err != nil {
+			return nil, err
+		}
+		objects := make([]ObjectMeta, 0, len(blobs))
+		for i := range blobs {
+			meta, err := blobs[i].objectMeta()
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, meta)
+		}
+		return objects, nil
+	}, fn, listWithMaxPageSize(options, azureMaxListKeys))
+}
+
+// ListObjectVersions is not supported, azure pages versions with opaque markers. It always returns ErrUnsupported.
+func (az *Azure) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	return nil, ErrUnsupported
+}
+
+// SignURL returns the url of the blob with a SAS for reading, which expires in expired seconds
+func (az *Azure) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return "", err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+	if err := signOptions.validate(); err != nil {
+		return "", err
+	}
+	sas := az.blobSAS(container, key, "r", time.Now().Add(time.Duration(expired)*time.Second), signOptions)
+	return az.blobURL(container, key, sas).String(), nil
+}
+
+// SignURLForPut returns the url of the blob with a SAS for creating or writing it,
+// the uploader must send the x-ms-blob-type: BlockBlob header. SignOptions are not enforced by azure.
+func (az *Azure) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return "", err
+	}
+
+	sas := az.blobSAS(container, key, "cw", time.Now().Add(time.Duration(expired)*time.Second), DefaultSignOptions())
+	return az.blobURL(container, key, sas).String(), nil
+}
+
+// SignPostPolicy is not supported, azure has no form uploads. It always returns ErrUnsupported.
+func (az *Azure) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	return nil, ErrUnsupported
+}
+
+func (az *Azure) Exists(key string) (bool, error) {
+	_, err := az.head(key)
+	if err == nil {
+		return true, nil
+	}
+	// only a missing blob means false, other failures such as 403 and 5xx are errors
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// azureTags is the body of Get Blob Tags and Set Blob Tags
+type azureTags struct {
+	XMLName xml.Name `xml:"Tags"`
+	TagSet  []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"TagSet>Tag"`
+}
+
+var errAzureObjectACL = fmt.Errorf("azure doesn't support object acls, the public access is set by container: %w", ErrUnsupported)
+
+// GetObjectACL is not supported, it always returns ErrUnsupported.
+func (az *Azure) GetObjectACL(key string) (string, error) {
+	return "", errAzureObjectACL
+}
+
+// PutObjectACL is not supported, it always returns ErrUnsupported.
+func (az *Azure) PutObjectACL(key string, acl string) error {
+	return errAzureObjectACL
+}
+
+// GetObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (az *Azure) GetObjectLegalHold(key string) (bool, error) {
+	return false, errObjectLock
+}
+
+// PutObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (az *Azure) PutObjectLegalHold(key string, on bool) error {
+	return errObjectLock
+}
+
+// GetObjectRetention is not supported, it always returns ErrUnsupported.
+func (az *Azure) GetObjectRetention(key string) (string, time.Time, error) {
+	return "", time.Time{}, errObjectLock
+}
+
+// PutObjectRetention is not supported, it always returns ErrUnsupported.
+func (az *Azure) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return errObjectLock
+}
+
+func (az *Azure) GetObjectTagging(key string) (map[string]string, error) {
+	container, err := az.getContainer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := az.do(http.MethodGet, az.blobURL(container, key, url.Values{"comp": {"tags"}}), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var result azureTags
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range result.TagSet {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// PutObjectTagging replaces all tags of the blob
+func (az *Azure) PutObjectTagging(key string, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	container, err := az.getContainer(key)
+	if err != nil {
+		return err
+	}
+
+	var body azureTags
+	for _, k := range sortedTagKeys(tags) {
+		body.TagSet = append(body.TagSet, struct {
+			Key   string `xml:"Key"`
+			Value string `xml:"Value"`
+		}{Key: k, Value: tags[k]})
+	}
+	data, err := xml.Marshal(body)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/xml")
+	_, err = az.doAndClose(http.MethodPut, az.blobURL(container, key, url.Values{"comp": {"tags"}}), header, bytes.NewReader(data))
+	return err
+}
+
+// containers returns the container, or the distinct shard containers sorted by name
+func (az *Azure) containers() []string {
+	if len(az.ShardsContainer) == 0 {
+		return []string{az.ContainerName}
+	}
+	names := make([]string, 0, len(az.ShardsContainer))
+	seen := make(map[string]bool, len(az.ShardsContainer))
+	for _, name := range az.ShardsContainer {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (az *Azure) ping() error {
+	for _, container := range az.containers() {
+		_, err := az.doAndClose(http.MethodHead, az.blobURL(container, "", url.Values{"restype": {"container"}}), nil, nil)
+		var azureErr *AzureError
+		if errors.As(err, &azureErr) {
+			switch azureErr.StatusCode {
+			case http.StatusForbidden:
+				continue
+			case http.StatusNotFound:
+				err = withRequestID(az.ctx, &wrappedError{kind: ErrBucketNotFound, err: azureErr}, azureErr.RequestID)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("ping container %s: %w", container, err)
+		}
+	}
+	return nil
+}
+
+func (az *Azure) Close() error {
+	az.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,302 @@
+package awos
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAzureAccount = "devstoreaccount1"
+	testAzureKey     = "a2V5LWZvci10ZXN0aW5nLWF6dXJl"
+)
+
+func newTestAzure(t *testing.T, handler http.HandlerFunc) *Azure {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := DefaultConfig()
+	cfg.StorageType = StorageTypeAzure
+	cfg.Endpoint = server.URL + "/" + testAzureAccount
+	cfg.Bucket = "test-container"
+	cfg.AccessKeyID = testAzureAccount
+	cfg.AccessKeySecret = testAzureKey
+	client, err := newAzure("test", cfg, elog.DefaultLogger)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestAzure_Config(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StorageType = StorageTypeAzure
+	cfg.Bucket = "test-container"
+	_, err := newStorage("test", cfg, elog.DefaultLogger)
+	assert.Error(t, err)
+
+	cfg.AccessKeyID = "myaccount"
+	cfg.AccessKeySecret = "not base64!"
+	_, err = newStorage("test", cfg, elog.DefaultLogger)
+	assert.Error(t, err)
+
+	cfg.AccessKeySecret = testAzureKey
+	comp, err := newStorage("test", cfg, elog.DefaultLogger)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://myaccount.blob.core.windows.net/test-container/a%20b",
+		comp.(*Azure).blobURL("test-container", "a b", nil).String())
+}
+
+func TestAzure_SharedKey(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:10000/devstoreaccount1/test-container/dir/a?comp=block&blockid=MDAwMDAwMDE%3D", strings.NewReader(content))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Ms-Date", "Wed, 14 Oct 2026 08:30:00 GMT")
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+	req.Header.Set("X-Ms-Meta-Head", " 1 ")
+
+	assert.Equal(t, "PUT\n\n\n"+strconv.Itoa(len(content))+"\n\ntext/plain\n\n\n\n\n\n\n"+
+		"x-ms-date:Wed, 14 Oct 2026 08:30:00 GMT\nx-ms-meta-head:1\nx-ms-version:2020-10-02\n"+
+		"/devstoreaccount1/devstoreaccount1/test-container/dir/a\nblockid:MDAwMDAwMDE=\ncomp:block",
+		azureStringToSign(req, testAzureAccount))
+}
+
+func TestAzure_Put(t *testing.T) {
+	var header http.Header
+	var path, body string
+	client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		path = r.URL.Path
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.Put(guid, strings.NewReader(content), map[string]string{"head": "1"},
+		PutWithContentType("text/plain"), PutWithStorageClass(StorageClassIA))
+	assert.NoError(t, err)
+	assert.Equal(t, "/devstoreaccount1/test-container/"+guid, path)
+	assert.Equal(t, content, body)
+	assert.Equal(t, "BlockBlob", header.Get("X-Ms-Blob-Type"))
+	assert.Equal(t, "text/plain", header.Get("X-Ms-Blob-Content-Type"))
+	assert.Equal(t, "1", header.Get("X-Ms-Meta-Head"))
+	assert.Equal(t, "Cool", header.Get("X-Ms-Access-Tier"))
+	assert.Equal(t, azureAPIVersion, header.Get("X-Ms-Version"))
+	assert.True(t, strings.HasPrefix(header.Get("Authorization"), "SharedKey devstoreaccount1:"))
+
+	err = client.Put(guid, strings.NewReader(content), nil, PutWithStorageClass(StorageClassColdArchive))
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
+
+func TestAzure_NotFound(t *testing.T) {
+	client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ms-Request-Id", "req-1")
+		w.Header().Set("X-Ms-Error-Code", "BlobNotFound")
+		w.WriteHeader(http.StatusNotFound)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><Error><Code>BlobNotFound</Code><Message>The specified blob does not exist.
+RequestId:req-1
+Time:2026-10-14T08:30:00.0000000Z</Message></Error>`))
+		}
+	})
+
+	_, err := client.Get(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	var azureErr *AzureError
+	assert.True(t, errors.As(err, &azureErr))
+	assert.Equal(t, &AzureError{StatusCode: 404, Code: "BlobNotFound", Message: "The specified blob does not exist.", RequestID: "req-1"}, azureErr)
+	assert.Equal(t, "req-1", ErrorRequestID(err))
+
+	_, err = client.HeadObject(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	exists, err := client.Exists(guid)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, client.Del(guid))
+}
+
+func TestAzure_Head(t *testing.T) {
+	client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.Header().Set("Content-Length", "7")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Etag", `"0x8D9"`)
+		w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 08:30:00 GMT")
+		w.Header().Set("X-Ms-Meta-Head", "1")
+		w.Header().Set("X-Ms-Access-Tier", "Archive")
+		w.Header().Set("X-Ms-Archive-Status", "rehydrate-pending-to-hot")
+	})
+
+	meta, err := client.HeadObject(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, guid, meta.Key)
+	assert.Equal(t, int64(7), meta.Size)
+	assert.Equal(t, "0x8D9", meta.ETag)
+	assert.Equal(t, map[string]string{"head": "1"}, meta.UserMeta)
+	assert.Equal(t, "Archive", meta.StorageClass)
+	assert.Equal(t, &RestoreStatus{Ongoing: true}, meta.Restore)
+
+	attrs, err := client.Head(guid, []string{"head", "Content-Type", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"head": "1", "Content-Type": "text/plain"}, attrs)
+}
+
+func TestAzure_SignURL(t *testing.T) {
+	client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	before := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	signed, err := client.SignURL("dir/a", 3600)
+	assert.NoError(t, err)
+	u, err := url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "/devstoreaccount1/test-container/dir/a", u.Path)
+
+	query := u.Query()
+	assert.Equal(t, "r", query.Get("sp"))
+	assert.Equal(t, "b", query.Get("sr"))
+	assert.Equal(t, azureAPIVersion, query.Get("sv"))
+	expiry, err := time.Parse("2006-01-02T15:04:05Z", query.Get("se"))
+	assert.NoError(t, err)
+	assert.False(t, expiry.Before(before))
+	assert.True(t, expiry.Before(before.Add(time.Minute)))
+
+	key, _ := base64.StdEncoding.DecodeString(testAzureKey)
+	stringToSign := "r\n\n" + query.Get("se") + "\n/blob/devstoreaccount1/test-container/dir/a\n\n\n\n2020-10-02\nb\n\n\n\n\n\n"
+	assert.Equal(t, base64.StdEncoding.EncodeToString(hmacSHA256(key, stringToSign)), query.Get("sig"))
+
+	signed, err = client.SignURLForPut("dir/a", 60)
+	assert.NoError(t, err)
+	u, err = url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "cw", u.Query().Get("sp"))
+}
+
+func TestAzure_ListPagination(t *testing.T) {
+	names := []string{"dir/a", "dir/b", "dir/c", "dir/d", "dir/e"}
+	markers := make([]string, 0)
+	client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "/devstoreaccount1/test-container", r.URL.Path)
+		assert.Equal(t, "container", query.Get("restype"))
+		assert.Equal(t, "list", query.Get("comp"))
+		assert.Equal(t, "dir/", query.Get("prefix"))
+		marker := query.Get("marker")
+		markers = append(markers, marker)
+		start := 0
+		if marker != "" {
+			start, _ = strconv.Atoi(strings.TrimPrefix(marker, "m"))
+		}
+		// azure may return fewer blobs than maxresults, at most 2 here
+		end := start + 2
+		if maxResults, _ := strconv.Atoi(query.Get("maxresults")); start+maxResults < end {
+			end = start + maxResults
+		}
+		if end > len(names) {
+			end = len(names)
+		}
+		var blobs, nextMarker string
+		for _, name := range names[start:end] {
+			metadata := ""
+			if query.Get("include") == "metadata" {
+				metadata = "<Metadata><Head>1</Head></Metadata>"
+			}
+			blobs += fmt.Sprintf(`<Blob><Name>%s</Name><Properties><Last-Modified>Wed, 14 Oct 2026 08:30:00 GMT</Last-Modified><Etag>0x8D9</Etag><Content-Length>1</Content-Length><Content-Type>text/plain</Content-Type><AccessTier>Hot</AccessTier></Properties>%s</Blob>`, name, metadata)
+		}
+		if end < len(names) {
+			nextMarker = "m" + strconv.Itoa(end)
+		}
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>%s</Blobs><NextMarker>%s</NextMarker></EnumerationResults>`, blobs, nextMarker)
+	})
+
+	keys, err := client.ListObject(guid, "dir/", "", 3, "")
+	assert.NoError(t, err)
+	assert.Equal(t, names[:3], keys)
+	assert.Equal(t, []string{"", "m2"}, markers)
+
+	markers = markers[:0]
+	keys, err = client.ListObject(guid, "dir/", "dir/c", 3, "")
+	assert.NoError(t, err)
+	assert.Equal(t, names[3:], keys)
+
+	markers = markers[:0]
+	it := client.ListObjectsIter(guid, "dir/", ListWithPageSize(3))
+	keys = keys[:0]
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, names, keys)
+	assert.Equal(t, []string{"", "m2", "m3"}, markers)
+
+	objects := make([]ObjectMeta, 0)
+	err = client.WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+		objects = append(objects, obj)
+		return nil
+	}, ListWithPageSize(2))
+	assert.NoError(t, err)
+	assert.Len(t, objects, len(names))
+	assert.Equal(t, ObjectMeta{
+		Key:          "dir/b",
+		ETag:         "0x8D9",
+		Size:         1,
+		LastModified: time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC),
+		ContentType:  "text/plain",
+		StorageClass: "Hot",
+		UserMeta:     map[string]string{"head": "1"},
+	}, objects[1])
+}
+
+// Set AWOS_AZURE_ACCOUNT, AWOS_AZURE_KEY and AWOS_AZURE_CONTAINER to run against a real container,
+// or AWOS_AZURE_ENDPOINT too for azurite.
+func TestAzure_Integration(t *testing.T) {
+	container := os.Getenv("AWOS_AZURE_CONTAINER")
+	if container == "" {
+		t.Skip("AWOS_AZURE_CONTAINER not set")
+	}
+	client := DefaultContainer().Build(
+		WithStorageType(StorageTypeAzure),
+		WithBucket(container),
+		WithEndpoint(os.Getenv("AWOS_AZURE_ENDPOINT")),
+		WithAccessKeyID(os.Getenv("AWOS_AZURE_ACCOUNT")),
+		WithAccessKeySecret(os.Getenv("AWOS_AZURE_KEY")),
+	)
+
+	err := client.Put(guid, strings.NewReader(content), map[string]string{"head": "1"})
+	assert.NoError(t, err)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	meta, err := client.Head(guid, []string{"head"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", meta["head"])
+
+	keys, err := client.ListObject(guid, guid[0:4], "", 10, "")
+	assert.NoError(t, err)
+	assert.Contains(t, keys, guid)
+
+	signed, err := client.SignURL(guid, 60)
+	assert.NoError(t, err)
+	resp, err := http.Get(signed)
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, content, string(data))
+
+	assert.NoError(t, client.Del(guid))
+	_, err = client.GetAsReader(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
@@ -0,0 +1,91 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+)
+
+// bucketManager is implemented by the backends supporting BucketExists and CreateBucket,
+// both apply to the bucket and all the shard buckets of the component
+type bucketManager interface {
+	bucketExists() (bool, error)
+	createBucket(options *createBucketOptions) error
+}
+
+// pinger is implemented by the backends supporting Ping
+type pinger interface {
+	ping() error
+}
+
+type CreateBucketOptions func(options *createBucketOptions)
+
+type createBucketOptions struct {
+	region string
+	acl    string
+}
+
+func DefaultCreateBucketOptions() *createBucketOptions {
+	return &createBucketOptions{}
+}
+
+// CreateBucketWithRegion creates the bucket in region instead of the region of the component, only s3-like backends
+// support it since the region of oss is the one of the endpoint
+func CreateBucketWithRegion(region string) CreateBucketOptions {
+	return func(options *createBucketOptions) {
+		options.region = region
+	}
+}
+
+// CreateBucketWithACL sets the acl of the bucket, ACLPrivate, ACLPublicRead or ACLPublicReadWrite
+func CreateBucketWithACL(acl string) CreateBucketOptions {
+	return func(options *createBucketOptions) {
+		options.acl = acl
+	}
+}
+
+func (o *createBucketOptions) validate() error {
+	if o.acl == ACLDefault {
+		return fmt.Errorf("unsupported bucket acl %q", o.acl)
+	}
+	return validateACL(o.acl)
+}
+
+// BucketExists reports whether the bucket of c exists, and all its shard buckets if it has any.
+// It's supported by s3, gcs, cos and oss.
+func BucketExists(ctx context.Context, c Component) (bool, error) {
+	manager, ok := c.WithContext(ctx).(bucketManager)
+	if !ok {
+		return false, fmt.Errorf("BucketExists: %w", ErrUnsupported)
+	}
+	return manager.bucketExists()
+}
+
+// CreateBucket creates the bucket of c, and all its shard buckets if it has any. It's idempotent, a bucket
+// already owned by the caller isn't an error and is left unchanged, while a bucket owned by another account
+// returns ErrBucketAlreadyExists. It's supported by s3, gcs, cos and oss.
+func CreateBucket(ctx context.Context, c Component, options ...CreateBucketOptions) error {
+	createOpts := DefaultCreateBucketOptions()
+	for _, opt := range options {
+		opt(createOpts)
+	}
+	if err := createOpts.validate(); err != nil {
+		return err
+	}
+	manager, ok := c.WithContext(ctx).(bucketManager)
+	if !ok {
+		return fmt.Errorf("CreateBucket: %w", ErrUnsupported)
+	}
+	return manager.createBucket(createOpts)
+}
+
+// Ping checks that the backend is reachable and the bucket of c exists, and all its shard buckets if it has any,
+// with a HEAD request on each of them, e.g. for readiness probes. A bucket which exists but can't be accessed
+// by the credentials (403) is reachable, a missing bucket returns ErrBucketNotFound. The requests are sent
+// with ctx, so they fail when its deadline is exceeded. The local backends only check their bucket.
+func Ping(ctx context.Context, c Component) error {
+	p, ok := c.WithContext(ctx).(pinger)
+	if !ok {
+		return fmt.Errorf("Ping: %w", ErrUnsupported)
+	}
+	return p.ping()
+}
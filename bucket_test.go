@@ -0,0 +1,227 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bucketAdminServer keeps the buckets created by puts like s3 or oss, taken buckets are owned by another account
+type bucketAdminServer struct {
+	mu          sync.Mutex
+	storageType string
+	owned       map[string]bool
+	taken       map[string]bool
+	// creates are the acl header and the body of each create request
+	creates [][2]string
+}
+
+func newBucketAdminServer(storageType string) *bucketAdminServer {
+	return &bucketAdminServer{storageType: storageType, owned: make(map[string]bool), taken: make(map[string]bool)}
+}
+
+func (s *bucketAdminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket := strings.Trim(r.URL.Path, "/")
+	writeError := func(status int, code string) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`<Error><Code>` + code + `</Code><Message>` + code + `</Message></Error>`))
+	}
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		switch {
+		case s.owned[bucket]:
+			_, _ = w.Write([]byte(`<BucketInfo><Bucket><Name>` + bucket + `</Name></Bucket></BucketInfo>`))
+		case s.taken[bucket]:
+			writeError(http.StatusForbidden, "AccessDenied")
+		default:
+			writeError(http.StatusNotFound, "NoSuchBucket")
+		}
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		acl := r.Header.Get("X-Amz-Acl")
+		if s.storageType == StorageTypeOSS {
+			acl = r.Header.Get("X-Oss-Acl")
+		}
+		s.creates = append(s.creates, [2]string{acl, string(body)})
+		switch {
+		case s.owned[bucket] && s.storageType == StorageTypeS3:
+			writeError(http.StatusConflict, "BucketAlreadyOwnedByYou")
+		case s.owned[bucket], s.taken[bucket]:
+			writeError(http.StatusConflict, "BucketAlreadyExists")
+		default:
+			s.owned[bucket] = true
+		}
+	}
+}
+
+func TestCreateBucket(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := newBucketAdminServer(storageType)
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+			ctx := context.Background()
+
+			exists, err := BucketExists(ctx, client)
+			assert.NoError(t, err)
+			assert.False(t, exists)
+
+			assert.NoError(t, CreateBucket(ctx, client, CreateBucketWithACL(ACLPublicRead)))
+			assert.True(t, server.owned["test-bucket"])
+			assert.Equal(t, ACLPublicRead, server.creates[0][0])
+			exists, err = BucketExists(ctx, client)
+			assert.NoError(t, err)
+			assert.True(t, exists)
+
+			// creating an owned bucket again succeeds
+			assert.NoError(t, CreateBucket(ctx, client))
+			assert.Len(t, server.creates, 2)
+			assert.Empty(t, server.creates[1][0])
+
+			server.taken["other-bucket"] = true
+			other := newTestComponent(t, storageType, server.ServeHTTP, WithBucket("other-bucket"))
+			err = CreateBucket(ctx, other)
+			assert.True(t, errors.Is(err, ErrBucketAlreadyExists), "%v", err)
+
+			assert.Error(t, CreateBucket(ctx, client, CreateBucketWithACL(ACLDefault)))
+			assert.Error(t, CreateBucket(ctx, client, CreateBucketWithACL("authenticated-read")))
+		})
+	}
+}
+
+func TestCreateBucket_Region(t *testing.T) {
+	server := newBucketAdminServer(StorageTypeS3)
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	ctx := context.Background()
+
+	// us-east-1 can't be sent as the location constraint
+	assert.NoError(t, CreateBucket(ctx, client))
+	assert.NotContains(t, server.creates[0][1], "LocationConstraint")
+
+	client = newTestComponent(t, StorageTypeS3, server.ServeHTTP, WithBucket("eu-bucket"), WithRegion("eu-west-1"))
+	assert.NoError(t, CreateBucket(ctx, client))
+	assert.Contains(t, server.creates[1][1], "<LocationConstraint>eu-west-1</LocationConstraint>")
+
+	client = newTestComponent(t, StorageTypeS3, server.ServeHTTP, WithBucket("ap-bucket"))
+	assert.NoError(t, CreateBucket(ctx, client, CreateBucketWithRegion("ap-southeast-1")))
+	assert.Contains(t, server.creates[2][1], "<LocationConstraint>ap-southeast-1</LocationConstraint>")
+
+	client = newTestComponent(t, StorageTypeOSS, newBucketAdminServer(StorageTypeOSS).ServeHTTP)
+	assert.True(t, errors.Is(CreateBucket(ctx, client, CreateBucketWithRegion("cn-hangzhou")), ErrUnsupported))
+}
+
+func TestCreateBucket_Shards(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := newBucketAdminServer(storageType)
+			client := newTestComponent(t, storageType, server.ServeHTTP, WithShards([]string{"01", "ab"}))
+			ctx := context.Background()
+
+			server.owned["test-bucket-01"] = true
+			exists, err := BucketExists(ctx, client)
+			assert.NoError(t, err)
+			assert.False(t, exists)
+
+			assert.NoError(t, CreateBucket(ctx, client))
+			assert.True(t, server.owned["test-bucket-ab"])
+			exists, err = BucketExists(ctx, client)
+			assert.NoError(t, err)
+			assert.True(t, exists)
+		})
+	}
+}
+
+func TestCreateBucket_Unsupported(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {}, WithStorageType(StorageTypeAzure), WithAccessKeySecret(testAzureKey))
+	_, err := BucketExists(context.Background(), client)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+	assert.True(t, errors.Is(CreateBucket(context.Background(), client), ErrUnsupported))
+}
+
+func TestPing(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				status = http.StatusOK
+			)
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if storageType != StorageTypeOSS {
+					assert.Equal(t, http.MethodHead, r.Method)
+				}
+				w.WriteHeader(status)
+				if storageType == StorageTypeOSS && status == http.StatusOK {
+					_, _ = w.Write([]byte(`<BucketInfo><Bucket><Name>test-bucket</Name></Bucket></BucketInfo>`))
+				}
+			}, WithAccessKeySecret(testAzureKey))
+			setStatus := func(code int) {
+				mu.Lock()
+				status = code
+				mu.Unlock()
+			}
+			ctx := context.Background()
+
+			assert.NoError(t, Ping(ctx, client))
+			// the bucket exists but the credentials can't access it
+			setStatus(http.StatusForbidden)
+			assert.NoError(t, Ping(ctx, client))
+			setStatus(http.StatusNotFound)
+			err := Ping(ctx, client)
+			assert.True(t, errors.Is(err, ErrBucketNotFound), "%v", err)
+			assert.False(t, errors.Is(err, ErrObjectNotFound), "%v", err)
+			setStatus(http.StatusUnauthorized)
+			assert.Error(t, Ping(ctx, client))
+
+			unreachable := newTestComponent(t, storageType, nil, WithAccessKeySecret(testAzureKey), WithEndpoint("http://127.0.0.1:1"))
+			ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+			defer cancel()
+			assert.Error(t, Ping(ctx, unreachable))
+		})
+	}
+}
+
+func TestPing_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	err := Ping(ctx, client)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.True(t, time.Since(begin) < 5*time.Second)
+}
+
+func TestPing_Local(t *testing.T) {
+	ctx := context.Background()
+	fs, root := newTestFS(t)
+	assert.NoError(t, Ping(ctx, fs))
+	assert.NoError(t, os.RemoveAll(root))
+	err := Ping(ctx, fs)
+	assert.True(t, errors.Is(err, ErrBucketNotFound), "%v", err)
+
+	assert.NoError(t, Ping(ctx, newTestMemory()))
+
+	fake := NewFakeClient("fake-bucket")
+	assert.NoError(t, Ping(ctx, fake))
+	fake.FailNext("Ping", "", 1, errors.New("down"))
+	assert.Error(t, Ping(ctx, fake))
+	assert.NoError(t, Ping(ctx, fake))
+}
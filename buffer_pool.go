@@ -0,0 +1,49 @@
+package awos
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize bounds the buffers kept by bufferPool, larger ones are left to the gc
+const maxPooledBufferSize = 4 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// readBody reads body and calls consume with the content. The content is read into a pooled buffer
+// and is only valid until consume returns, unless dst is given, then it's read into dst[:0] which grows if needed.
+func readBody(body io.Reader, dst []byte, consume func(data []byte) error) error {
+	if dst != nil {
+		buf := bytes.NewBuffer(dst[:0])
+		if _, err := buf.ReadFrom(body); err != nil {
+			return err
+		}
+		return consume(buf.Bytes())
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+	return consume(buf.Bytes())
+}
+
+// copyBytes returns data itself if it's read into the buffer of GetWithBuffer, otherwise a copy of it
+func (o *getOptions) copyBytes(data []byte) []byte {
+	if o.buffer != nil {
+		return data
+	}
+	return append(make([]byte, 0, len(data)), data...)
+}
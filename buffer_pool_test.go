@@ -0,0 +1,124 @@
+package awos
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// onlyReader hides io.WriterTo of the underlying reader, like http response bodies
+type onlyReader struct {
+	io.Reader
+}
+
+func TestReadBody(t *testing.T) {
+	var res string
+	assert.NoError(t, readBody(onlyReader{strings.NewReader(largeContent)}, nil, func(data []byte) error {
+		res = string(data)
+		return nil
+	}))
+	assert.Equal(t, largeContent, res)
+
+	// dst is reused if it's large enough
+	buf := make([]byte, 10, len(largeContent))
+	assert.NoError(t, readBody(onlyReader{strings.NewReader(largeContent)}, buf, func(data []byte) error {
+		assert.Equal(t, largeContent, string(data))
+		assert.Equal(t, &buf[:1][0], &data[0])
+		return nil
+	}))
+
+	// and grows otherwise
+	small := make([]byte, 0, 4)
+	assert.NoError(t, readBody(onlyReader{strings.NewReader(largeContent)}, small, func(data []byte) error {
+		assert.Equal(t, largeContent, string(data))
+		return nil
+	}))
+
+	errConsume := io.ErrShortWrite
+	assert.Equal(t, errConsume, readBody(strings.NewReader(content), nil, func(data []byte) error {
+		return errConsume
+	}))
+}
+
+// keyContentHandler serves objects whose content is derived from the key
+func keyContentHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	_, _ = w.Write([]byte(strings.Repeat(key, 1000)))
+}
+
+func TestGetConcurrently(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, keyContentHandler)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					key := "key-" + strconv.Itoa(i)
+					buf := make([]byte, 0, 1024)
+					for j := 0; j < 10; j++ {
+						res, err := client.Get(key)
+						assert.NoError(t, err)
+						assert.Equal(t, strings.Repeat(key, 1000), res)
+
+						data, err := client.GetBytes(key, GetWithBuffer(buf))
+						assert.NoError(t, err)
+						assert.Equal(t, strings.Repeat(key, 1000), string(data))
+						buf = data
+					}
+				}(i)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestMemory_GetWithBuffer(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put("buffer", strings.NewReader(content), nil))
+
+	buf := make([]byte, 0, 64)
+	data, err := client.GetBytes("buffer", GetWithBuffer(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, &buf[:1][0], &data[0])
+}
+
+func BenchmarkReadBody(b *testing.B) {
+	body := bytes.Repeat([]byte(content), 64<<10/len(content))
+	b.Run("ioutil.ReadAll", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, _ := ioutil.ReadAll(onlyReader{bytes.NewReader(body)})
+			_ = string(data)
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = readBody(onlyReader{bytes.NewReader(body)}, nil, func(data []byte) error {
+				_ = string(data)
+				return nil
+			})
+		}
+	})
+	b.Run("buffer", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, len(body))
+		for i := 0; i < b.N; i++ {
+			_ = readBody(onlyReader{bytes.NewReader(body)}, buf, func(data []byte) error {
+				buf = data
+				return nil
+			})
+		}
+	})
+}
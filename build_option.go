@@ -1,5 +1,12 @@
 package awos
 
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
 type BuildOption func(c *Container)
 
 func WithStorageType(storageType string) BuildOption {
@@ -44,12 +51,49 @@ func WithRegion(region string) BuildOption {
 	}
 }
 
+// WithForcePathStyle addresses the bucket in the path of the endpoint for s3-like backends, see ForcePathStyle of the config
+func WithForcePathStyle(forcePathStyle bool) BuildOption {
+	return func(c *Container) {
+		c.config.ForcePathStyle = forcePathStyle
+	}
+}
+
+// Deprecated: use WithForcePathStyle
 func WithS3ForcePathStyle(s3ForcePathStyle bool) BuildOption {
 	return func(c *Container) {
 		c.config.S3ForcePathStyle = s3ForcePathStyle
 	}
 }
 
+// WithUseAccelerate routes the requests of s3 and oss through the transfer acceleration endpoint, see UseAccelerate of config
+func WithUseAccelerate(useAccelerate bool) BuildOption {
+	return func(c *Container) {
+		c.config.UseAccelerate = useAccelerate
+	}
+}
+
+// WithSignatureVersion signs the s3 requests with SignatureV2 or SignatureV4, which is the default
+func WithSignatureVersion(version string) BuildOption {
+	return func(c *Container) {
+		c.config.SignatureVersion = version
+	}
+}
+
+// WithCorrectClockSkew corrects the signing time of s3 by the clock of the server, see CorrectClockSkew of config
+func WithCorrectClockSkew(correctClockSkew bool) BuildOption {
+	return func(c *Container) {
+		c.config.CorrectClockSkew = correctClockSkew
+	}
+}
+
+// WithTimeSource signs the s3 requests at the time of fn instead of time.Now, e.g. a clock synced by ntp,
+// which CorrectClockSkew corrects if enabled
+func WithTimeSource(fn func() time.Time) BuildOption {
+	return func(c *Container) {
+		c.config.timeSource = fn
+	}
+}
+
 func WithSSL(ssl bool) BuildOption {
 	return func(c *Container) {
 		c.config.SSL = ssl
@@ -62,8 +106,264 @@ func WithS3HttpTimeoutSecs(s3HttpTimeoutSecs int64) BuildOption {
 	}
 }
 
+func WithEnableRetryInterceptor(enableRetryInterceptor bool) BuildOption {
+	return func(c *Container) {
+		c.config.EnableRetryInterceptor = enableRetryInterceptor
+	}
+}
+
+func WithRetryMaxAttempts(retryMaxAttempts int) BuildOption {
+	return func(c *Container) {
+		c.config.RetryMaxAttempts = retryMaxAttempts
+	}
+}
+
+func WithRetryBaseDelay(retryBaseDelay time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.RetryBaseDelay = retryBaseDelay
+	}
+}
+
+func WithRetryMaxDelay(retryMaxDelay time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.RetryMaxDelay = retryMaxDelay
+	}
+}
+
+// WithRetryableFunc decides which failed attempts the retry interceptor retries instead of DefaultRetryable,
+// which fn can call to extend it. Only the GET/HEAD/PUT requests which can be rewound are retried whatever fn returns.
+func WithRetryableFunc(fn RetryableFunc) BuildOption {
+	return func(c *Container) {
+		c.config.retryableFunc = fn
+	}
+}
+
+// WithRetryBudgetRatio caps the retries to a fraction of the requests, see RetryBudgetRatio of config
+func WithRetryBudgetRatio(retryBudgetRatio float64) BuildOption {
+	return func(c *Container) {
+		c.config.RetryBudgetRatio = retryBudgetRatio
+	}
+}
+
+// WithHedgeDelay hedges the GET/HEAD requests without a response after delay, see HedgeDelay of config
+func WithHedgeDelay(delay time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.HedgeDelay = delay
+	}
+}
+
+// WithEnableAccessLog logs each operation at level, see EnableAccessLog of config
+func WithEnableAccessLog(level string) BuildOption {
+	return func(c *Container) {
+		c.config.EnableAccessLog = true
+		c.config.AccessLogLevel = level
+	}
+}
+
+// WithAccessLogActor logs the actor returned by fn with the context of each operation, such as the user or tenant
+func WithAccessLogActor(fn func(ctx context.Context) string) BuildOption {
+	return func(c *Container) {
+		c.config.accessLogActor = fn
+	}
+}
+
+// WithAccessLogRedact logs the keys returned by fn instead of the keys of the operations
+func WithAccessLogRedact(fn func(key string) string) BuildOption {
+	return func(c *Container) {
+		c.config.accessLogRedact = fn
+	}
+}
+
+// WithEnableMetricInterceptor enables or disables all the metrics, see EnableMetricInterceptor of config
+func WithEnableMetricInterceptor(enableMetricInterceptor bool) BuildOption {
+	return func(c *Container) {
+		c.config.EnableMetricInterceptor = enableMetricInterceptor
+	}
+}
+
+// WithMetricsSink records the metrics with sink instead of the ego metric registry,
+// e.g. to plug in prometheus collectors of the application. Not for memory and fs.
+func WithMetricsSink(sink MetricsSink) BuildOption {
+	return func(c *Container) {
+		c.config.metricsSink = sink
+	}
+}
+
+// WithDisableBodyMetrics skips the metrics of the response bodies, see DisableBodyMetrics of config
+func WithDisableBodyMetrics(disableBodyMetrics bool) BuildOption {
+	return func(c *Container) {
+		c.config.DisableBodyMetrics = disableBodyMetrics
+	}
+}
+
+func WithEnableContentTypeDetection(enableContentTypeDetection bool) BuildOption {
+	return func(c *Container) {
+		c.config.EnableContentTypeDetection = enableContentTypeDetection
+	}
+}
+
+func WithEnableOperationTrace(enableOperationTrace bool) BuildOption {
+	return func(c *Container) {
+		c.config.EnableOperationTrace = enableOperationTrace
+	}
+}
+
+func WithSlowThreshold(slowThreshold time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.SlowThreshold = slowThreshold
+	}
+}
+
+func WithCircuitBreaker(threshold int, cooldown time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.CircuitBreakerThreshold = threshold
+		c.config.CircuitBreakerCooldown = cooldown
+	}
+}
+
+func WithRateLimit(qps float64, burst int) BuildOption {
+	return func(c *Container) {
+		c.config.RateLimitQPS = qps
+		c.config.RateLimitBurst = burst
+	}
+}
+
+// WithConnectionPool sets the connection pool of the http transport, see the fields of config
+func WithConnectionPool(maxIdleConns int, maxIdleConnsPerHost int, maxConnsPerHost int, idleConnTimeout time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.MaxIdleConns = maxIdleConns
+		c.config.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		c.config.MaxConnsPerHost = maxConnsPerHost
+		c.config.IdleConnTimeout = idleConnTimeout
+	}
+}
+
+func WithTLSHandshakeTimeout(tlsHandshakeTimeout time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.TLSHandshakeTimeout = tlsHandshakeTimeout
+	}
+}
+
+// WithTLSConfig sets the tls config of the transports of s3-like, oss and azure, which TLSMinVersion, TLSCAFile
+// and TLSInsecureSkipVerify are applied to. It isn't applied to the transport of WithRoundTripper.
+// TLSCAFile replaces the RootCAs of tlsConfig with the system CAs and the ones of the file, tlsConfig isn't modified.
+func WithTLSConfig(tlsConfig *tls.Config) BuildOption {
+	return func(c *Container) {
+		c.config.tlsConfig = tlsConfig
+	}
+}
+
+// WithTLSMinVersion sets the minimum TLS version, see TLSMinVersion of config
+func WithTLSMinVersion(version string) BuildOption {
+	return func(c *Container) {
+		c.config.TLSMinVersion = version
+	}
+}
+
+// WithTLSCAFile trusts the CAs of a PEM file in addition to the system ones, see TLSCAFile of config
+func WithTLSCAFile(file string) BuildOption {
+	return func(c *Container) {
+		c.config.TLSCAFile = file
+	}
+}
+
+// WithTLSInsecureSkipVerify disables the verification of the server certificates, only for testing,
+// see TLSInsecureSkipVerify of config
+func WithTLSInsecureSkipVerify(insecureSkipVerify bool) BuildOption {
+	return func(c *Container) {
+		c.config.TLSInsecureSkipVerify = insecureSkipVerify
+	}
+}
+
+// WithReadTimeout sets the idle timeout of receiving responses, see ReadTimeout of config
+func WithReadTimeout(readTimeout time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.ReadTimeout = readTimeout
+	}
+}
+
+// WithWriteTimeout sets the idle timeout of sending request bodies, see WriteTimeout of config
+func WithWriteTimeout(writeTimeout time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.WriteTimeout = writeTimeout
+	}
+}
+
+// WithDefaultTimeout sets the timeout of requests without a deadline, see DefaultTimeout of config
+func WithDefaultTimeout(timeout time.Duration) BuildOption {
+	return func(c *Container) {
+		c.config.DefaultTimeout = timeout
+	}
+}
+
 func WithBucketKey(bucketKey string) BuildOption {
 	return func(c *Container) {
 		c.config.bucketKey = bucketKey
 	}
 }
+
+func WithGCSCredentialsFile(gcsCredentialsFile string) BuildOption {
+	return func(c *Container) {
+		c.config.GCSCredentialsFile = gcsCredentialsFile
+	}
+}
+
+func WithFSRootDir(fsRootDir string) BuildOption {
+	return func(c *Container) {
+		c.config.FSRootDir = fsRootDir
+	}
+}
+
+// WithHeaders adds headers to every request, see Headers of config
+func WithHeaders(headers map[string]string) BuildOption {
+	return func(c *Container) {
+		c.config.Headers = headers
+	}
+}
+
+// WithKeyPrefix keeps the objects in the namespace of prefix, see KeyPrefix of config
+func WithKeyPrefix(prefix string) BuildOption {
+	return func(c *Container) {
+		c.config.KeyPrefix = prefix
+	}
+}
+
+// WithFailoverEndpoints adds fallback endpoints of the reads, see FailoverEndpoints of config
+func WithFailoverEndpoints(endpoints ...FailoverEndpoint) BuildOption {
+	return func(c *Container) {
+		c.config.FailoverEndpoints = append(c.config.FailoverEndpoints, endpoints...)
+	}
+}
+
+// WithUserAgent appends userAgent to the User-Agent of every request, see UserAgent of config
+func WithUserAgent(userAgent string) BuildOption {
+	return func(c *Container) {
+		c.config.UserAgent = userAgent
+	}
+}
+
+// WithRoundTripper sends the requests of s3-like, oss and azure with rt, which the interceptors wrap instead of
+// the transport built by awos, e.g. a transport of a proxy or a tracing library. The connection pool settings
+// don't apply to it, and Close doesn't close its idle connections since it's owned by the caller.
+// Not for memory and fs.
+func WithRoundTripper(rt http.RoundTripper) BuildOption {
+	return func(c *Container) {
+		c.config.roundTripper = rt
+	}
+}
+
+// WithInterceptors adds interceptors of s3-like, oss and azure, see Interceptor for the order they run in.
+// It can be called more than once, the interceptors are appended.
+func WithInterceptors(interceptors ...Interceptor) BuildOption {
+	return func(c *Container) {
+		c.config.interceptors = append(c.config.interceptors, interceptors...)
+	}
+}
+
+// WithHeadersFunc adds the headers returned by fn with the context of each request, e.g. the context of WithContext,
+// which take precedence over WithHeaders. Reserved headers of WithHeaders and headers set by the sdks are skipped.
+func WithHeadersFunc(fn func(ctx context.Context) map[string]string) BuildOption {
+	return func(c *Container) {
+		c.config.headersFunc = fn
+	}
+}
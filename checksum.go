@@ -0,0 +1,231 @@
+package awos
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ChecksumMD5 sends Content-MD5 and checks it against the etag, see PutWithChecksum
+	ChecksumMD5 = "md5"
+	// ChecksumSHA256 also sends x-amz-checksum-sha256, s3 only
+	ChecksumSHA256 = "sha256"
+
+	s3ChecksumSHA256Header = "X-Amz-Checksum-Sha256"
+	s3ChecksumModeHeader   = "X-Amz-Checksum-Mode"
+)
+
+func validateChecksum(algorithm string) error {
+	switch algorithm {
+	case "", ChecksumMD5, ChecksumSHA256:
+		return nil
+	}
+	return fmt.Errorf("unsupported checksum %q", algorithm)
+}
+
+// contentChecksum is the checksum of the uploaded content
+type contentChecksum struct {
+	md5    []byte
+	sha256 []byte
+	// kms and customer keys make the etag not an md5
+	encrypted bool
+}
+
+// checksum returns the checksum of PutWithChecksum of the rest of reader, whose offset is kept, nil if it isn't set
+func (o *putOptions) checksum(reader io.ReadSeeker) (*contentChecksum, error) {
+	if o.checksumAlgorithm == "" {
+		return nil, nil
+	}
+	pos, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	writers := []io.Writer{md5Hash}
+	if o.checksumAlgorithm == ChecksumSHA256 {
+		writers = append(writers, sha256Hash)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), reader); err != nil {
+		return nil, err
+	}
+	if _, err := reader.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	res := &contentChecksum{md5: md5Hash.Sum(nil), encrypted: o.encryptedETag()}
+	if o.checksumAlgorithm == ChecksumSHA256 {
+		res.sha256 = sha256Hash.Sum(nil)
+	}
+	return res, nil
+}
+
+// partChecksum returns the checksum of a part of MultipartUpload, which is only md5. The parts of
+// a locked object always have it, s3 requires the Content-MD5 of them.
+func (o *putOptions) partChecksum(data []byte) *contentChecksum {
+	if o.checksumAlgorithm == "" && !o.objectLock() {
+		return nil
+	}
+	sum := md5.Sum(data)
+	return &contentChecksum{md5: sum[:], encrypted: o.encryptedETag()}
+}
+
+// encryptedETag reports whether the etag isn't the md5 of the content because of the server side encryption
+func (o *putOptions) encryptedETag() bool {
+	return o.sseKMSKeyID != nil || o.sseCustomerKey != nil
+}
+
+// contentMD5 is the value of the Content-MD5 header, nil if c is nil
+func (c *contentChecksum) contentMD5() *string {
+	if c == nil {
+		return nil
+	}
+	value := base64.StdEncoding.EncodeToString(c.md5)
+	return &value
+}
+
+// addS3Headers adds x-amz-checksum-sha256 to headers, Content-MD5 is a field of the sdk inputs
+func (c *contentChecksum) addS3Headers(headers map[string]string) map[string]string {
+	if c != nil && c.sha256 != nil {
+		headers[s3ChecksumSHA256Header] = base64.StdEncoding.EncodeToString(c.sha256)
+	}
+	return headers
+}
+
+// verify checks the etag, the Content-MD5 and the x-amz-checksum-sha256 of the response, the etag is skipped
+// if it isn't an md5, which is the case for multipart uploads and some server side encryptions. The encryption
+// is also read from the response header, as buckets may encrypt the objects with kms by default.
+func (c *contentChecksum) verify(etag string, header http.Header) error {
+	if c == nil {
+		return nil
+	}
+	encrypted := c.encrypted || encryptedETag(header)
+	if sum := md5FromETag(etag); sum != nil && !encrypted && !bytes.Equal(sum, c.md5) {
+		return fmt.Errorf("%w: etag %s, md5 %x", ErrChecksumMismatch, etag, c.md5)
+	}
+	if sum := decodeBase64Checksum(header.Get("Content-MD5")); sum != nil && !bytes.Equal(sum, c.md5) {
+		return fmt.Errorf("%w: Content-MD5 %x, md5 %x", ErrChecksumMismatch, sum, c.md5)
+	}
+	if sum := decodeBase64Checksum(header.Get(s3ChecksumSHA256Header)); sum != nil && c.sha256 != nil && !bytes.Equal(sum, c.sha256) {
+		return fmt.Errorf("%w: sha256 %x, expected %x", ErrChecksumMismatch, sum, c.sha256)
+	}
+	return nil
+}
+
+// md5FromETag returns the md5 of etag, nil if it isn't the hex md5 of the content
+func md5FromETag(etag string) []byte {
+	etag = trimETag(etag)
+	if len(etag) != 2*md5.Size {
+		return nil
+	}
+	sum, err := hex.DecodeString(etag)
+	if err != nil {
+		return nil
+	}
+	return sum
+}
+
+// decodeBase64Checksum returns nil for empty or invalid values
+func decodeBase64Checksum(value string) []byte {
+	if value == "" {
+		return nil
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	return sum
+}
+
+// checksumReader verifies body against the expected checksums when it's read to the end
+type checksumReader struct {
+	body      io.ReadCloser
+	hashes    []hash.Hash
+	expected  [][]byte
+	verified  bool
+	verifyErr error
+}
+
+// newChecksumReader returns body verifying the md5 and the sha256 which aren't nil,
+// body is returned as is if there are neither of them
+func newChecksumReader(body io.ReadCloser, md5Sum []byte, sha256Sum []byte) io.ReadCloser {
+	r := &checksumReader{body: body}
+	if md5Sum != nil {
+		r.hashes = append(r.hashes, md5.New())
+		r.expected = append(r.expected, md5Sum)
+	}
+	if sha256Sum != nil {
+		r.hashes = append(r.hashes, sha256.New())
+		r.expected = append(r.expected, sha256Sum)
+	}
+	if len(r.hashes) == 0 {
+		return body
+	}
+	return r
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	if r.verified {
+		if r.verifyErr != nil {
+			return 0, r.verifyErr
+		}
+		return 0, io.EOF
+	}
+	n, err := r.body.Read(p)
+	for _, h := range r.hashes {
+		h.Write(p[:n])
+	}
+	if err == io.EOF {
+		r.verified = true
+		for i, h := range r.hashes {
+			if sum := h.Sum(nil); !bytes.Equal(sum, r.expected[i]) {
+				r.verifyErr = fmt.Errorf("%w: got %x, expected %x", ErrChecksumMismatch, sum, r.expected[i])
+				return n, r.verifyErr
+			}
+		}
+	}
+	return n, err
+}
+
+func (r *checksumReader) Close() error {
+	return r.body.Close()
+}
+
+// verifyBody returns body verifying the checksums of the whole object in header, unless GetWithChecksumValidation
+// isn't set or it's a range, which has the checksums of the whole object
+func (o *getOptions) verifyBody(body io.ReadCloser, etag string, header http.Header) io.ReadCloser {
+	if !o.checksumValidation || o.rangeStart != nil {
+		return body
+	}
+	var md5Sum []byte
+	if !encryptedETag(header) {
+		md5Sum = md5FromETag(etag)
+	}
+	if md5Sum == nil {
+		md5Sum = decodeBase64Checksum(header.Get("Content-MD5"))
+	}
+	return newChecksumReader(body, md5Sum, decodeBase64Checksum(header.Get(s3ChecksumSHA256Header)))
+}
+
+// acceptIdentity reports whether the object is requested with Accept-Encoding identity, so the checksums of
+// GetWithChecksumValidation are verified against the stored bytes, and Transfer copies them. The http transport
+// requests and inflates gzip otherwise, whose bytes don't match the etag and the size of a gzip encoded object.
+// See inflateIdentityGzip.
+func (o *getOptions) acceptIdentity() bool {
+	return (o.checksumValidation || o.rawContent) && o.rangeStart == nil
+}
+
+// encryptedETag reports whether the etag in the response header isn't the md5 of the content,
+// which is the case for kms and customer keys
+func encryptedETag(header http.Header) bool {
+	return strings.EqualFold(header.Get("X-Amz-Server-Side-Encryption"), "aws:kms") ||
+		strings.EqualFold(header.Get("X-Oss-Server-Side-Encryption"), "KMS") ||
+		header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" ||
+		header.Get("X-Oss-Server-Side-Encryption-Customer-Algorithm") != ""
+}
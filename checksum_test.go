@@ -0,0 +1,197 @@
+package awos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checksumServer stores one object, badETag returns a wrong etag on put and corrupt flips the body on get
+type checksumServer struct {
+	mu         sync.Mutex
+	object     []byte
+	contentMD5 string
+	sha256     string
+	badETag    bool
+	corrupt    bool
+}
+
+func (s *checksumServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.object, _ = ioutil.ReadAll(r.Body)
+		s.contentMD5 = r.Header.Get("Content-MD5")
+		s.sha256 = r.Header.Get(s3ChecksumSHA256Header)
+		etag := partETag(s.object)
+		if s.badETag {
+			etag = partETag([]byte("other"))
+		}
+		w.Header().Set("ETag", etag)
+	case http.MethodGet:
+		body := append([]byte(nil), s.object...)
+		w.Header().Set("ETag", partETag(body))
+		if s.sha256 != "" {
+			w.Header().Set(s3ChecksumSHA256Header, s.sha256)
+		}
+		if s.corrupt {
+			body[0] ^= 0xff
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+	}
+}
+
+func TestPutWithChecksum(t *testing.T) {
+	sum := md5.Sum([]byte(content))
+	shaSum := sha256.Sum256([]byte(content))
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := &checksumServer{}
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+
+			assert.NoError(t, client.Put("key", strings.NewReader(content), nil, PutWithChecksum(ChecksumMD5)))
+			assert.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), server.contentMD5)
+			assert.Equal(t, content, string(server.object))
+
+			server.badETag = true
+			err := client.Put("key", strings.NewReader(content), nil, PutWithChecksum(ChecksumMD5))
+			assert.True(t, errors.Is(err, ErrChecksumMismatch), "%v", err)
+
+			// the etag isn't checked without the option
+			assert.NoError(t, client.Put("key", strings.NewReader(content), nil))
+
+			err = client.Put("key", strings.NewReader(content), nil, PutWithChecksum("crc32"))
+			assert.Error(t, err)
+		})
+	}
+
+	server := &checksumServer{}
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	assert.NoError(t, client.Put("key", strings.NewReader(content), nil, PutWithChecksum(ChecksumSHA256)))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(shaSum[:]), server.sha256)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), server.contentMD5)
+
+	client = newTestComponent(t, StorageTypeOSS, server.ServeHTTP)
+	err := client.Put("key", strings.NewReader(content), nil, PutWithChecksum(ChecksumSHA256))
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+}
+
+func TestPutWithChecksum_BadDigest(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`<Error><Code>BadDigest</Code><Message>digest mismatch</Message></Error>`))
+			})
+			err := client.Put("key", strings.NewReader(content), nil, PutWithChecksum(ChecksumMD5))
+			assert.True(t, errors.Is(err, ErrChecksumMismatch), "%v", err)
+		})
+	}
+}
+
+func TestPutWithChecksum_DefaultKMS(t *testing.T) {
+	for storageType, header := range map[string]string{StorageTypeS3: "X-Amz-", StorageTypeOSS: "X-Oss-"} {
+		t.Run(storageType, func(t *testing.T) {
+			var puts int32
+			value := map[string]string{StorageTypeS3: "aws:kms", StorageTypeOSS: "KMS"}[storageType]
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&puts, 1)
+				_, _ = ioutil.ReadAll(r.Body)
+				// the bucket encrypts the objects with kms by default, so the etag isn't the md5 of the content
+				w.Header().Set(header+"Server-Side-Encryption", value)
+				w.Header().Set("ETag", partETag([]byte("other")))
+			})
+			assert.NoError(t, client.Put("key", strings.NewReader(content), nil, PutWithChecksum(ChecksumMD5)))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&puts))
+		})
+	}
+}
+
+func TestGetWithChecksumValidation(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := &checksumServer{object: []byte(content)}
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+
+			res, err := client.Get("key", GetWithChecksumValidation())
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+
+			server.corrupt = true
+			_, err = client.Get("key", GetWithChecksumValidation())
+			assert.True(t, errors.Is(err, ErrChecksumMismatch), "%v", err)
+			_, err = client.GetBytes("key", GetWithChecksumValidation())
+			assert.True(t, errors.Is(err, ErrChecksumMismatch), "%v", err)
+			reader, err := client.GetAsReader("key", GetWithChecksumValidation())
+			assert.NoError(t, err)
+			_, err = ioutil.ReadAll(reader)
+			assert.True(t, errors.Is(err, ErrChecksumMismatch), "%v", err)
+			assert.NoError(t, reader.Close())
+
+			// the corrupted body is returned as is without the option
+			res, err = client.Get("key")
+			assert.NoError(t, err)
+			assert.NotEqual(t, content, res)
+		})
+	}
+
+	// the sha256 of s3 is verified as well
+	server := &checksumServer{object: []byte(content), sha256: base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))}
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	_, err := client.Get("key", GetWithChecksumValidation())
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "%v", err)
+}
+
+func TestGetWithChecksumValidation_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(largeContent))
+	assert.NoError(t, zw.Close())
+	stored := buf.Bytes()
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var acceptEncoding string
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding = r.Header.Get("Accept-Encoding")
+				// the etag is the md5 of the stored gzip bytes
+				w.Header().Set("ETag", partETag(stored))
+				w.Header().Set("Content-Encoding", CompressionGzip)
+				_, _ = w.Write(stored)
+			})
+
+			// the stored bytes are verified, then inflated like without the option
+			res, err := client.Get(guid, GetWithChecksumValidation())
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+			assert.Equal(t, "identity", acceptEncoding)
+			res, err = client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+			res, err = client.Get(guid, GetWithChecksumValidation(), GetWithDecompression())
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+
+			reader, meta, err := client.GetWithMeta(guid, []string{"Content-Encoding"}, GetWithChecksumValidation())
+			assert.NoError(t, err)
+			data, err := ioutil.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, string(data))
+			assert.Empty(t, meta["Content-Encoding"])
+			assert.NoError(t, reader.Close())
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package awos
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// maxClockSkew is the skew s3 accepts between the signing time and its clock
+const maxClockSkew = 5 * time.Minute
+
+// signingClock is the time of the signatures of s3, the time of WithTimeSource corrected by the skew
+// to the clock of the server once CorrectClockSkew detects one
+type signingClock struct {
+	source func() time.Time
+	// offset is the nanoseconds added to source
+	offset int64
+}
+
+func newSigningClock(source func() time.Time) *signingClock {
+	if source == nil {
+		source = time.Now
+	}
+	return &signingClock{source: source}
+}
+
+func (c *signingClock) now() time.Time {
+	return c.source().Add(time.Duration(atomic.LoadInt64(&c.offset)))
+}
+
+// correct sets the offset so that now is serverTime
+func (c *signingClock) correct(serverTime time.Time) {
+	atomic.StoreInt64(&c.offset, int64(serverTime.Sub(c.source())))
+}
+
+// s3V4SignHandler signs with sigv4 at the time of clock, it keeps the name of the sdk signer
+// so that it's swapped by the other signers
+func s3V4SignHandler(clock *signingClock) request.NamedHandler {
+	return request.NamedHandler{Name: v4.SignRequestHandler.Name, Fn: func(r *request.Request) {
+		v4.SignSDKRequestWithCurrentTime(r, clock.now)
+	}}
+}
+
+// s3ClockSkewRetryHandler corrects clock by the Date of the responses rejected for a skewed signing time
+// and retries them once more than the retryer would. The responses of HEAD have no error code,
+// a 403 whose Date is farther than maxClockSkew is deemed skewed then.
+func s3ClockSkewRetryHandler(clock *signingClock) request.NamedHandler {
+	return request.NamedHandler{Name: "awos.s3ClockSkewRetryHandler", Fn: func(r *request.Request) {
+		if r.HTTPResponse == nil || r.HTTPResponse.StatusCode != http.StatusForbidden {
+			return
+		}
+		serverTime, err := http.ParseTime(r.HTTPResponse.Header.Get("Date"))
+		if err != nil {
+			return
+		}
+		skew := serverTime.Sub(clock.now())
+		if skew < 0 {
+			skew = -skew
+		}
+		var code string
+		if aerr, ok := r.Error.(awserr.Error); ok {
+			code = aerr.Code()
+		}
+		if code != "RequestTimeTooSkewed" && skew <= maxClockSkew {
+			return
+		}
+		clock.correct(serverTime)
+		if _, ok := r.Retryer.(clockSkewRetryer); !ok {
+			r.Retryer = clockSkewRetryer{Retryer: r.Retryer}
+			r.Retryable = aws.Bool(true)
+		}
+	}}
+}
+
+// clockSkewRetryer allows the retry of the skewed request on top of the retries of Retryer
+type clockSkewRetryer struct {
+	request.Retryer
+}
+
+func (r clockSkewRetryer) MaxRetries() int {
+	return r.Retryer.MaxRetries() + 1
+}
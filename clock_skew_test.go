@@ -0,0 +1,91 @@
+package awos
+
+import (
+	"bytes"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// skewedHandler rejects the requests signed more than maxClockSkew away from the clock of the server
+func skewedHandler(attempts *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(attempts, 1)
+		signed, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if skew := time.Since(signed); skew > maxClockSkew || skew < -maxClockSkew {
+			w.WriteHeader(http.StatusForbidden)
+			if r.Method != http.MethodHead {
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>RequestTimeTooSkewed</Code>` +
+					`<Message>The difference between the request time and the current time is too large.</Message></Error>`))
+			}
+			return
+		}
+		w.Header().Set("Content-Length", "6")
+		w.Write([]byte(content))
+	}
+}
+
+func TestCorrectClockSkew(t *testing.T) {
+	skewed := func() time.Time { return time.Now().Add(-time.Hour) }
+
+	var attempts int32
+	c := newTestComponent(t, StorageTypeS3, skewedHandler(&attempts), WithCorrectClockSkew(true), WithTimeSource(skewed))
+	data, err := c.GetBytes(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	// the clock stays corrected
+	_, err = c.GetBytes(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// the skew of HEAD is detected by the Date of the response
+	atomic.StoreInt32(&attempts, 0)
+	c = newTestComponent(t, StorageTypeS3, skewedHandler(&attempts), WithCorrectClockSkew(true), WithTimeSource(skewed))
+	ok, err := c.Exists(guid)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	// the puts rewind their bodies for the retry
+	atomic.StoreInt32(&attempts, 0)
+	c = newTestComponent(t, StorageTypeS3, skewedHandler(&attempts), WithCorrectClockSkew(true), WithTimeSource(skewed))
+	assert.NoError(t, c.Put(guid, bytes.NewReader([]byte(content)), nil))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestCorrectClockSkew_Disabled(t *testing.T) {
+	var attempts int32
+	c := newTestComponent(t, StorageTypeS3, skewedHandler(&attempts), WithTimeSource(func() time.Time {
+		return time.Now().Add(-time.Hour)
+	}))
+	_, err := c.GetBytes(guid)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// the time source is the signing time
+	atomic.StoreInt32(&attempts, 0)
+	c = newTestComponent(t, StorageTypeS3, skewedHandler(&attempts), WithTimeSource(time.Now))
+	_, err = c.GetBytes(guid)
+	assert.NoError(t, err)
+}
+
+func TestCorrectClockSkew_Forbidden(t *testing.T) {
+	// a 403 with a synced clock isn't retried
+	var attempts int32
+	c := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}, WithCorrectClockSkew(true))
+	_, err := c.Exists(guid)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"strings"
@@ -13,10 +14,12 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gotomicro/ego/core/elog"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
 )
 
 const PackageName = "component.awos"
@@ -27,25 +30,115 @@ type Component interface {
 	Get(key string, options ...GetOptions) (string, error)
 	GetBytes(key string, options ...GetOptions) ([]byte, error)
 	GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error)
+	// GetMulti fetches keys concurrently, a failed key doesn't abort the others.
+	// It returns the fetched objects and the errors of the failed keys, missing keys fail with ErrObjectNotFound.
+	GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error)
+	// RestoreObject restores an archived object, getting it returns ErrObjectArchived until restored
+	RestoreObject(key string, options ...RestoreOptions) error
+	// GetVersion gets a specific version of the object in a versioned bucket, like Get with GetWithVersionID
+	GetVersion(key string, versionID string, options ...GetOptions) (string, error)
 	GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error)
 	Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error
+	MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error
+	Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error)
+	// Copy copies srcKey to dstKey on the server side, see CopyWithDestBucket to copy across buckets
+	Copy(srcKey, dstKey string, options ...CopyOptions) error
+	// Move copies srcKey to dstKey and deletes srcKey after the copy succeeded,
+	// an error is returned if the delete fails, leaving both objects in place.
+	Move(srcKey, dstKey string, options ...CopyOptions) error
 	Del(key string) error
-	DelMulti(keys []string) error
-	Head(key string, meta []string) (map[string]string, error)
+	// DelVersion permanently deletes a specific version of the object in a versioned bucket,
+	// see DelWithBypassGovernance for the versions locked in governance mode
+	DelVersion(key string, versionID string, options ...DelOptions) error
+	// DelMulti deletes keys in batches, a failed key doesn't abort the others.
+	// It returns the failed keys with their errors, and a non-nil error if any key failed.
+	DelMulti(keys []string) (map[string]error, error)
+	// Head returns the values of the given user metadata and headers, keyed by the names in meta.
+	// The names are looked up case-insensitively, such as "owner" for the header X-Amz-Meta-Owner.
+	Head(key string, meta []string, options ...GetOptions) (map[string]string, error)
+	// HeadObject returns the metadata of the object, parsed the same way for all the backends
+	HeadObject(key string, options ...GetOptions) (*ObjectMeta, error)
 	ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error)
-	SignURL(key string, expired int64) (string, error)
+	// ListObjectsWithDelimiter lists a page of the objects and the common prefixes with prefix, which are the "folders"
+	// of the keys containing delimiter after prefix. Pass NextMarker of the result as marker to get the next page.
+	ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error)
+	// ListObjectsIter walks all the keys with prefix lazily, stops when the context is done
+	ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator
+	// WalkObjects calls fn for each object with prefix page by page, stops when fn returns an error or the context is done.
+	// fn returns ErrStopWalk to stop early without an error. The objects have the key, etag, size, last modified time and storage class.
+	WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error
+	// ListObjectVersions lists versions and delete markers of keys with prefix in a versioned bucket,
+	// pass the Key and VersionID of the last version as markers to get the next page.
+	ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error)
+	// SignURL returns a presigned url for downloading the object, the response headers can be overridden
+	// by SignWithResponseContentType, SignWithResponseContentDisposition and SignWithResponseCacheControl.
+	SignURL(key string, expired int64, options ...SignOptions) (string, error)
+	SignURLForPut(key string, expired int64, options ...SignOptions) (string, error)
+	SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error)
 	GetAndDecompress(key string) (string, error)
 	GetAndDecompressAsReader(key string) (io.ReadCloser, error)
 	CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error
 	Range(key string, offset int64, length int64) (io.ReadCloser, error)
 	Exists(key string) (bool, error)
+	GetObjectTagging(key string) (map[string]string, error)
+	PutObjectTagging(key string, tags map[string]string) error
+	// GetObjectACL returns the acl of the object, such as ACLPublicRead
+	GetObjectACL(key string) (string, error)
+	// PutObjectACL replaces the acl of the object
+	PutObjectACL(key string, acl string) error
+	// GetObjectLegalHold reports whether the legal hold of the object is on, only s3 supports object lock
+	GetObjectLegalHold(key string) (bool, error)
+	// PutObjectLegalHold turns the legal hold of the object on or off, a version under legal hold can't be deleted
+	PutObjectLegalHold(key string, on bool) error
+	// GetObjectRetention returns the retention mode and the retain until date of the object, empty if it has no retention
+	GetObjectRetention(key string) (string, time.Time, error)
+	// PutObjectRetention locks the object until retainUntil in RetentionGovernance or RetentionCompliance mode
+	PutObjectRetention(key string, mode string, retainUntil time.Time) error
+	// Close closes the idle connections of the client, which is shared by the clients of WithContext.
+	// The client and the clients of WithContext must not be used after Close.
+	Close() error
 }
 
 func newComponent(name string, cfg *config, logger *elog.Component) (Component, error) {
+	comp, err := newStorage(name, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.FailoverEndpoints) > 0 {
+		if comp, err = newFailoverComponent(name, cfg, logger, comp); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.EnableAccessLog {
+		comp = newTracedComponent(cfg.Bucket, comp, newAccessLogger(cfg, logger))
+	}
+	if cfg.EnableOperationTrace {
+		comp = newTracedComponent(cfg.Bucket, comp, otel.GetTracerProvider())
+	}
+	// the spans have the prefixed keys
+	if cfg.KeyPrefix != "" {
+		comp = newPrefixedComponent(cfg.KeyPrefix, comp)
+	}
+	return comp, nil
+}
+
+func newStorage(name string, cfg *config, logger *elog.Component) (Component, error) {
 	storageType := strings.ToLower(cfg.StorageType)
+	if err := validateHeaders(cfg.Headers); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateAccelerate(storageType); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := cfg.buildTLSConfig(logger)
+	if err != nil {
+		return nil, err
+	}
+	cfg.tls = tlsConfig
 
 	if storageType == StorageTypeOSS {
-		client, err := oss.New(cfg.Endpoint, cfg.AccessKeyID, cfg.AccessKeySecret)
+		transport := newOSSHTTPTransport(name, cfg, logger)
+		client, err := oss.New(cfg.ossEndpoint(), cfg.AccessKeyID, cfg.AccessKeySecret, oss.HTTPClient(&http.Client{Transport: transport}))
 		if err != nil {
 			return nil, err
 		}
@@ -64,7 +157,10 @@ func newComponent(name string, cfg *config, logger *elog.Component) (Component,
 			}
 
 			ossClient = &OSS{
-				Shards: buckets,
+				Shards:            buckets,
+				ctx:               context.Background(),
+				transport:         transport,
+				detectContentType: cfg.EnableContentTypeDetection,
 			}
 		} else {
 			bucket, err := client.Bucket(cfg.Bucket)
@@ -73,80 +169,298 @@ func newComponent(name string, cfg *config, logger *elog.Component) (Component,
 			}
 
 			ossClient = &OSS{
-				Bucket: bucket,
+				Bucket:            bucket,
+				ctx:               context.Background(),
+				transport:         transport,
+				detectContentType: cfg.EnableContentTypeDetection,
 			}
 		}
 
 		return ossClient, nil
 	} else if storageType == StorageTypeS3 {
-		var config *aws.Config
-
-		// use minio
-		if cfg.S3ForcePathStyle {
-			config = &aws.Config{
-				Region:           aws.String(cfg.Region),
-				DisableSSL:       aws.Bool(!cfg.SSL),
-				Credentials:      credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.AccessKeySecret, ""),
-				Endpoint:         aws.String(cfg.Endpoint),
-				S3ForcePathStyle: aws.Bool(true),
-			}
-		} else {
-			config = &aws.Config{
-				Region:      aws.String(cfg.Region),
-				DisableSSL:  aws.Bool(!cfg.SSL),
-				Credentials: credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.AccessKeySecret, ""),
-			}
-			if cfg.Endpoint != "" {
-				config.Endpoint = aws.String(cfg.Endpoint)
-			}
+		// path style is used by minio and the s3 gateways whose bucket hosts don't resolve,
+		// the bucket is appended to the path of a custom endpoint then
+		config := &aws.Config{
+			Region:           aws.String(cfg.Region),
+			DisableSSL:       aws.Bool(!cfg.SSL),
+			Credentials:      credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.AccessKeySecret, ""),
+			S3ForcePathStyle: aws.Bool(cfg.forcePathStyle()),
+			S3UseAccelerate:  aws.Bool(cfg.UseAccelerate),
 		}
-		if cfg.Debug {
-			config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody | aws.LogDebugWithSigning)
+		if cfg.Endpoint != "" {
+			config.Endpoint = aws.String(cfg.Endpoint)
 		}
-
-		config.HTTPClient = &http.Client{
-			Timeout: time.Second * time.Duration(cfg.S3HttpTimeoutSecs),
+		s3Client := newS3(name, cfg, logger, config)
+		if err := setS3SignatureVersion(s3Client.Client, cfg.SignatureVersion, s3Client.now); err != nil {
+			return nil, err
 		}
-		var tp = http.DefaultTransport
-		if cfg.EnableMetricInterceptor {
-			tp = metricInterceptor(name, cfg, logger, tp)
+		return s3Client, nil
+	} else if storageType == StorageTypeGCS {
+		return newGCS(name, cfg, logger)
+	} else if storageType == StorageTypeCOS {
+		return newCOS(name, cfg, logger)
+	} else if storageType == StorageTypeAzure {
+		return newAzure(name, cfg, logger)
+	} else if storageType == StorageTypeMemory {
+		m := newMemory(cfg.Bucket)
+		m.detectContentType = cfg.EnableContentTypeDetection
+		return m, nil
+	} else if storageType == StorageTypeFS {
+		return newFS(cfg)
+	} else {
+		return nil, fmt.Errorf("unknown StorageType:\"%s\", only supports oss,s3,gcs,cos,azure,memory,fs", cfg.StorageType)
+	}
+}
+
+// newOSSHTTPTransport returns the transport of oss with the enabled interceptors,
+// which is shared by the clients bound to a context by WithContext
+func newOSSHTTPTransport(name string, cfg *config, logger *elog.Component) http.RoundTripper {
+	var base http.RoundTripper = newOSSTransport(cfg)
+	if cfg.roundTripper != nil {
+		base = cfg.roundTripper
+	}
+	var tp http.RoundTripper = progressInterceptor(name, cfg, logger, base)
+	if cfg.ReadTimeout > 0 || cfg.WriteTimeout > 0 {
+		tp = idleTimeoutInterceptor(name, cfg, logger, tp)
+	}
+	if len(cfg.Headers) > 0 || cfg.headersFunc != nil {
+		tp = headerInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.UserAgent != "" {
+		tp = userAgentInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.RateLimitQPS > 0 {
+		tp = rateLimitInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.HedgeDelay > 0 {
+		tp = hedgeInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.EnableRetryInterceptor {
+		tp = retryInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.CircuitBreakerThreshold > 0 {
+		tp = circuitBreakerInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.SlowThreshold > 0 {
+		tp = slowLogInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.DefaultTimeout > 0 {
+		tp = defaultTimeoutInterceptor(name, cfg, logger, tp)
+	}
+	return newCloseIdleTransport(cfg, userInterceptors(cfg, fixedInterceptor(name, cfg, logger, tp)), base)
+}
+
+// the timeouts of the default transport of oss sdk, see oss.getDefaultOssConfig
+const (
+	ossReadWriteTimeout = 60 * time.Second
+	ossLongTimeout      = 300 * time.Second
+)
+
+// newOSSTransport has the same timeouts as the default transport of oss sdk, the connections fail a read or
+// a write making no progress for 60s, and the connection pool of cfg
+func newOSSTransport(cfg *config) *http.Transport {
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           ossDialContext(ossReadWriteTimeout, ossLongTimeout),
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		TLSClientConfig:       cfg.tls,
+		ResponseHeaderTimeout: 60 * time.Second,
+	}
+}
+
+// ossDialContext dials the connections with the deadlines of the timeoutConn of oss sdk
+func ossDialContext(timeout time.Duration, longTimeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
 		}
-		if cfg.EnableTraceInterceptor {
-			tp = traceLogReqIdInterceptor(name, cfg, logger, tp)
-			if cfg.EnableClientTrace {
-				tp = otelhttp.NewTransport(tp,
-					otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
-						return otelhttptrace.NewClientTrace(ctx)
-					}))
-			} else {
-				tp = otelhttp.NewTransport(tp)
+		_ = conn.SetReadDeadline(time.Now().Add(longTimeout))
+		return &ossTimeoutConn{Conn: conn, timeout: timeout, longTimeout: longTimeout}, nil
+	}
+}
+
+// ossTimeoutConn fails a read or a write which makes no progress for timeout, like the timeoutConn of oss sdk,
+// an idle connection fails its reads after longTimeout
+type ossTimeoutConn struct {
+	net.Conn
+	timeout     time.Duration
+	longTimeout time.Duration
+}
+
+func (c *ossTimeoutConn) Read(b []byte) (int, error) {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	n, err := c.Conn.Read(b)
+	_ = c.Conn.SetReadDeadline(time.Now().Add(c.longTimeout))
+	return n, err
+}
+
+func (c *ossTimeoutConn) Write(b []byte) (int, error) {
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	n, err := c.Conn.Write(b)
+	_ = c.Conn.SetReadDeadline(time.Now().Add(c.longTimeout))
+	return n, err
+}
+
+// newBaseTransport returns the transport wrapped by the interceptors of s3-like and azure,
+// which is http.DefaultTransport with the connection pool of cfg, or the one of WithRoundTripper
+func newBaseTransport(cfg *config) http.RoundTripper {
+	if cfg.roundTripper != nil {
+		return cfg.roundTripper
+	}
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		// replaced by a custom RoundTripper, which has its own pool
+		return http.DefaultTransport
+	}
+	tp := base.Clone()
+	tp.MaxIdleConns = cfg.MaxIdleConns
+	tp.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	tp.MaxConnsPerHost = cfg.MaxConnsPerHost
+	tp.IdleConnTimeout = cfg.IdleConnTimeout
+	tp.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	if cfg.tls != nil {
+		tp.TLSClientConfig = cfg.tls
+	}
+	return tp
+}
+
+// s3DefaultRegion signs the requests to a custom endpoint without a region
+const s3DefaultRegion = "us-east-1"
+
+func newS3(name string, cfg *config, logger *elog.Component, config *aws.Config) *S3 {
+	if cfg.Debug {
+		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody | aws.LogDebugWithSigning)
+	}
+
+	config.HTTPClient = &http.Client{
+		Timeout:   time.Second * time.Duration(cfg.S3HttpTimeoutSecs),
+		Transport: newHTTPTransport(name, cfg, logger),
+	}
+	if cfg.EnableRetryInterceptor {
+		// don't retry twice
+		config.MaxRetries = aws.Int(0)
+	}
+	sess := session.Must(session.NewSession(config))
+	// sigv4 needs a region, s3-compatible stores such as r2 and spaces accept us-east-1 if none is configured,
+	// which is checked after the session since it may be loaded from the environment
+	if aws.StringValue(sess.Config.Region) == "" && aws.StringValue(config.Endpoint) != "" {
+		sess.Config.Region = aws.String(s3DefaultRegion)
+	}
+	service := s3.New(sess)
+	service.Handlers.AfterRetry.PushBack(s3RequestIDHandler(cfg.StorageType))
+	clock := newSigningClock(cfg.timeSource)
+	if cfg.CorrectClockSkew || cfg.timeSource != nil {
+		service.Handlers.Sign.Swap(v4.SignRequestHandler.Name, s3V4SignHandler(clock))
+	}
+	if cfg.CorrectClockSkew {
+		service.Handlers.Retry.PushBackNamed(s3ClockSkewRetryHandler(clock))
+	}
+
+	var s3Client *S3
+	if cfg.Shards != nil && len(cfg.Shards) > 0 {
+		buckets := make(map[string]string)
+		for _, v := range cfg.Shards {
+			for i := 0; i < len(v); i++ {
+				buckets[strings.ToLower(v[i:i+1])] = cfg.Bucket + "-" + v
 			}
 		}
-		tp = fixedInterceptor(name, cfg, logger, tp)
-		config.HTTPClient.Transport = tp
-		service := s3.New(session.Must(session.NewSession(config)))
+		s3Client = &S3{
+			ShardsBucket:      buckets,
+			Client:            service,
+			ctx:               markNoDeadline(context.Background()),
+			detectContentType: cfg.EnableContentTypeDetection,
+			storageClasses:    s3StorageClasses,
+			clock:             clock,
+		}
+	} else {
+		s3Client = &S3{
+			BucketName:        cfg.Bucket,
+			Client:            service,
+			ctx:               markNoDeadline(context.Background()),
+			detectContentType: cfg.EnableContentTypeDetection,
+			storageClasses:    s3StorageClasses,
+			clock:             clock,
+		}
+	}
 
-		var s3Client *S3
-		if cfg.Shards != nil && len(cfg.Shards) > 0 {
-			buckets := make(map[string]string)
-			for _, v := range cfg.Shards {
-				for i := 0; i < len(v); i++ {
-					buckets[strings.ToLower(v[i:i+1])] = cfg.Bucket + "-" + v
-				}
-			}
-			s3Client = &S3{
-				ShardsBucket: buckets,
-				Client:       service,
-			}
+	return s3Client
+}
+
+// newHTTPTransport returns the transport of s3-like and azure with the enabled interceptors
+func newHTTPTransport(name string, cfg *config, logger *elog.Component) http.RoundTripper {
+	base := newBaseTransport(cfg)
+	var tp http.RoundTripper = progressInterceptor(name, cfg, logger, base)
+	if cfg.ReadTimeout > 0 || cfg.WriteTimeout > 0 {
+		tp = idleTimeoutInterceptor(name, cfg, logger, tp)
+	}
+	if len(cfg.Headers) > 0 || cfg.headersFunc != nil {
+		tp = headerInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.UserAgent != "" {
+		tp = userAgentInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.RateLimitQPS > 0 {
+		tp = rateLimitInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.HedgeDelay > 0 {
+		tp = hedgeInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.EnableRetryInterceptor {
+		tp = retryInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.CircuitBreakerThreshold > 0 {
+		tp = circuitBreakerInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.EnableMetricInterceptor {
+		tp = metricInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.SlowThreshold > 0 {
+		tp = slowLogInterceptor(name, cfg, logger, tp)
+	}
+	if cfg.EnableTraceInterceptor {
+		tp = traceLogReqIdInterceptor(name, cfg, logger, tp)
+		if cfg.EnableClientTrace {
+			tp = otelhttp.NewTransport(tp,
+				otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
+					return otelhttptrace.NewClientTrace(ctx)
+				}))
 		} else {
-			s3Client = &S3{
-				BucketName: cfg.Bucket,
-				Client:     service,
-			}
+			tp = otelhttp.NewTransport(tp)
 		}
+	}
+	if cfg.DefaultTimeout > 0 {
+		tp = defaultTimeoutInterceptor(name, cfg, logger, tp)
+	}
+	return newCloseIdleTransport(cfg, userInterceptors(cfg, fixedInterceptor(name, cfg, logger, tp)), base)
+}
 
-		return s3Client, nil
-	} else {
-		return nil, fmt.Errorf("unknown StorageType:\"%s\", only supports oss,s3", cfg.StorageType)
+// closeIdleTransport closes the idle connections of the base transport, which aren't reachable through
+// the interceptors. http.Client.CloseIdleConnections calls it.
+type closeIdleTransport struct {
+	http.RoundTripper
+	base http.RoundTripper
+}
+
+func newCloseIdleTransport(cfg *config, rt http.RoundTripper, base http.RoundTripper) *closeIdleTransport {
+	// the transport of WithRoundTripper is owned by the caller
+	if cfg.roundTripper != nil {
+		base = nil
+	}
+	return &closeIdleTransport{RoundTripper: rt, base: base}
+}
+
+func (t *closeIdleTransport) CloseIdleConnections() {
+	// a replaced http.DefaultTransport is shared with the rest of the process
+	if t.base == http.DefaultTransport {
+		return
+	}
+	if tp, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		tp.CloseIdleConnections()
 	}
 }
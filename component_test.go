@@ -0,0 +1,361 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// newTestComponent builds a component of storageType talking to a mock server.
+func newTestComponent(t *testing.T, storageType string, handler http.HandlerFunc, options ...BuildOption) Component {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newTestEndpointComponent(storageType, server.URL, options...)
+}
+
+// newTestEndpointComponent builds a component of storageType talking to endpoint, such as a mock tls server.
+func newTestEndpointComponent(storageType string, endpoint string, options ...BuildOption) Component {
+	options = append([]BuildOption{
+		WithStorageType(storageType),
+		WithEndpoint(endpoint),
+		WithBucket("test-bucket"),
+		WithAccessKeyID("ak"),
+		WithAccessKeySecret("sk"),
+		WithRegion("us-east-1"),
+		WithS3ForcePathStyle(true),
+	}, options...)
+	return DefaultContainer().Build(options...)
+}
+
+func TestNewOSSTransport_StalledBody(t *testing.T) {
+	stall := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+		w.(http.Flusher).Flush()
+		<-stall
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(stall) })
+
+	// the timeouts of the sdk are too long for a test
+	tp := newOSSTransport(DefaultConfig())
+	tp.DialContext = ossDialContext(100*time.Millisecond, time.Second)
+	t.Cleanup(tp.CloseIdleConnections)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	res, err := tp.RoundTrip(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+	_, err = ioutil.ReadAll(res.Body)
+	var netErr net.Error
+	if assert.True(t, errors.As(err, &netErr), "%v", err) {
+		assert.True(t, netErr.Timeout())
+	}
+}
+
+func TestNewBaseTransport(t *testing.T) {
+	c := DefaultContainer()
+	WithConnectionPool(10, 5, 3, time.Minute)(c)
+	WithTLSHandshakeTimeout(time.Second)(c)
+
+	for _, tp := range []*http.Transport{newBaseTransport(c.config).(*http.Transport), newOSSTransport(c.config)} {
+		assert.Equal(t, 10, tp.MaxIdleConns)
+		assert.Equal(t, 5, tp.MaxIdleConnsPerHost)
+		assert.Equal(t, 3, tp.MaxConnsPerHost)
+		assert.Equal(t, time.Minute, tp.IdleConnTimeout)
+		assert.Equal(t, time.Second, tp.TLSHandshakeTimeout)
+	}
+	// the proxy and dialer of the default transport are kept
+	assert.NotNil(t, newBaseTransport(c.config).(*http.Transport).Proxy)
+	assert.NotSame(t, http.DefaultTransport, newBaseTransport(c.config))
+
+	tp := newBaseTransport(DefaultConfig()).(*http.Transport)
+	assert.Equal(t, 1024, tp.MaxIdleConns)
+	assert.Equal(t, 256, tp.MaxIdleConnsPerHost)
+	assert.Equal(t, 0, tp.MaxConnsPerHost)
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			var (
+				mu      sync.Mutex
+				headers []http.Header
+			)
+			rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				mu.Lock()
+				headers = append(headers, r.Header.Clone())
+				mu.Unlock()
+				return http.DefaultTransport.RoundTrip(r)
+			})
+			options := []BuildOption{WithRoundTripper(rt), WithHeaders(map[string]string{"X-Tenant-Id": "1"})}
+			if storageType == StorageTypeAzure {
+				options = append(options, WithAccessKeySecret(testAzureKey))
+			}
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(content))
+			}, options...)
+
+			res, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+			assert.NoError(t, client.Close())
+
+			// the interceptors wrap the transport
+			mu.Lock()
+			if assert.Len(t, headers, 1) {
+				assert.Equal(t, "1", headers[0].Get("X-Tenant-Id"))
+				assert.NotEmpty(t, headers[0].Get("Authorization"))
+			}
+			mu.Unlock()
+		})
+	}
+}
+
+func TestConnectionPool_MaxConnsPerHost(t *testing.T) {
+	var mu sync.Mutex
+	remoteAddrs := make(map[string]bool)
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remoteAddrs[r.RemoteAddr] = true
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte(content))
+	}, WithConnectionPool(10, 10, 1, time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(guid)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	// the requests wait for the only connection
+	assert.Len(t, remoteAddrs, 1)
+}
+
+func TestForcePathStyle(t *testing.T) {
+	var mu sync.Mutex
+	var host, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		host, path = r.Host, r.URL.Path
+	}))
+	defer server.Close()
+
+	build := func(endpoint string, options ...BuildOption) Component {
+		return DefaultContainer().Build(append([]BuildOption{
+			WithStorageType(StorageTypeS3),
+			WithEndpoint(endpoint),
+			WithBucket("test-bucket"),
+			WithAccessKeyID("ak"),
+			WithAccessKeySecret("sk"),
+			WithRegion("us-east-1"),
+		}, options...)...)
+	}
+	serverHost := strings.TrimPrefix(server.URL, "http://")
+	for name, endpoint := range map[string]string{"scheme": server.URL, "no scheme": serverHost} {
+		t.Run(name, func(t *testing.T) {
+			client := build(endpoint, WithForcePathStyle(true))
+			assert.NoError(t, client.Put("dir/key", strings.NewReader(content), nil))
+			mu.Lock()
+			assert.Equal(t, serverHost, host)
+			assert.Equal(t, "/test-bucket/dir/key", path)
+			mu.Unlock()
+		})
+	}
+
+	// the bucket follows the path of the endpoint
+	client := build(server.URL+"/gateway", WithForcePathStyle(true))
+	assert.NoError(t, client.Put("key", strings.NewReader(content), nil))
+	mu.Lock()
+	assert.Equal(t, "/gateway/test-bucket/key", path)
+	mu.Unlock()
+
+	// the bucket is in the host without it, the deprecated option still works
+	requestURL := func(options ...BuildOption) string {
+		req, _ := build("https://s3.example.com", options...).(*S3).Client.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String("key"),
+		})
+		assert.NoError(t, req.Build())
+		return req.HTTPRequest.URL.String()
+	}
+	assert.Equal(t, "https://test-bucket.s3.example.com/key", requestURL())
+	assert.Equal(t, "https://s3.example.com/test-bucket/key", requestURL(WithS3ForcePathStyle(true)))
+}
+
+func TestEndpoint_S3Compatible(t *testing.T) {
+	var mu sync.Mutex
+	var host, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		host, authorization = r.Host, r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	serverHost := strings.TrimPrefix(server.URL, "http://")
+
+	// the region of the environment would be used instead of the default one
+	if region, ok := os.LookupEnv("AWS_REGION"); ok {
+		assert.NoError(t, os.Unsetenv("AWS_REGION"))
+		defer os.Setenv("AWS_REGION", region)
+	}
+	for region, signingRegion := range map[string]string{"": "us-east-1", "auto": "auto", "nyc3": "nyc3"} {
+		t.Run(signingRegion, func(t *testing.T) {
+			// without SSL the endpoint without a scheme is sent over http, like a local minio
+			client := DefaultContainer().Build(
+				WithStorageType(StorageTypeS3),
+				WithEndpoint(serverHost),
+				WithBucket("test-bucket"),
+				WithAccessKeyID("ak"),
+				WithAccessKeySecret("sk"),
+				WithRegion(region),
+				WithForcePathStyle(true),
+			)
+			assert.NoError(t, client.Put("key", strings.NewReader(content), nil))
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, serverHost, host)
+			assert.Contains(t, authorization, "/"+signingRegion+"/s3/aws4_request")
+		})
+	}
+}
+
+func TestUseAccelerate(t *testing.T) {
+	var mu sync.Mutex
+	var host, authorization string
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		host, authorization = r.URL.Host, r.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+	for storageType, tc := range map[string]struct {
+		endpoint string
+		host     string
+	}{
+		StorageTypeS3:  {host: "test-bucket.s3-accelerate.amazonaws.com"},
+		StorageTypeOSS: {endpoint: "https://oss-cn-hangzhou.aliyuncs.com", host: "test-bucket.oss-accelerate.aliyuncs.com"},
+	} {
+		t.Run(storageType, func(t *testing.T) {
+			client := DefaultContainer().Build(
+				WithStorageType(storageType),
+				WithEndpoint(tc.endpoint),
+				WithBucket("test-bucket"),
+				WithAccessKeyID("ak"),
+				WithAccessKeySecret("sk"),
+				WithRegion("us-west-2"),
+				WithUseAccelerate(true),
+				WithRoundTripper(rt),
+			)
+			assert.NoError(t, client.Put("key", strings.NewReader(content), nil))
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tc.host, host)
+			if storageType == StorageTypeS3 {
+				// signed for the region of the bucket
+				assert.Contains(t, authorization, "/us-west-2/s3/aws4_request")
+			}
+		})
+	}
+
+	build := func(storageType string, options ...BuildOption) error {
+		c := DefaultContainer()
+		for _, opt := range append([]BuildOption{
+			WithStorageType(storageType),
+			WithBucket("test-bucket"),
+			WithAccessKeyID("ak"),
+			WithAccessKeySecret(testAzureKey),
+			WithUseAccelerate(true),
+		}, options...) {
+			opt(c)
+		}
+		_, err := newComponent(c.name, c.config, c.logger)
+		return err
+	}
+	assert.EqualError(t, build(StorageTypeS3, WithForcePathStyle(true)), "awos: UseAccelerate is incompatible with ForcePathStyle")
+	assert.Error(t, build(StorageTypeS3, WithS3ForcePathStyle(true)))
+	err := build(StorageTypeAzure)
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+}
+
+func TestClose(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			ignore := goleak.IgnoreCurrent()
+			var (
+				mu     sync.Mutex
+				opened int
+				closed int
+			)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(content))
+			}))
+			server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch state {
+				case http.StateNew:
+					opened++
+				case http.StateClosed:
+					closed++
+				}
+			}
+			server.Start()
+
+			options := []BuildOption{
+				WithStorageType(storageType),
+				WithEndpoint(server.URL),
+				WithBucket("test-bucket"),
+				WithAccessKeyID("ak"),
+				WithAccessKeySecret("sk"),
+				WithRegion("us-east-1"),
+				WithForcePathStyle(true),
+			}
+			if storageType == StorageTypeAzure {
+				options = append(options, WithAccessKeySecret(testAzureKey))
+			}
+			client := DefaultContainer().Build(options...)
+			_, err := client.WithContext(context.Background()).Get(guid)
+			assert.NoError(t, err)
+			_, err = client.Get(guid)
+			assert.NoError(t, err)
+
+			// the connection is kept alive until Close
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			assert.Equal(t, 1, opened)
+			assert.Equal(t, 0, closed)
+			mu.Unlock()
+
+			assert.NoError(t, client.Close())
+			assert.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return closed == opened
+			}, time.Second, 10*time.Millisecond)
+
+			server.Close()
+			goleak.VerifyNone(t, ignore)
+		})
+	}
+}
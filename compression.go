@@ -0,0 +1,290 @@
+package awos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionGzip compresses objects with gzip, see PutWithCompression
+	CompressionGzip = "gzip"
+	// CompressionZstd compresses objects with zstd, which is much faster than gzip
+	CompressionZstd = "zstd"
+)
+
+func validateCompression(codec string) error {
+	switch codec {
+	case "", CompressionGzip, CompressionZstd:
+		return nil
+	}
+	return fmt.Errorf("unsupported compression %q", codec)
+}
+
+// zstd encoders and decoders are expensive to create, they are reused by level
+var (
+	zstdEncoderPools [zstd.SpeedBestCompression + 1]sync.Pool
+	zstdDecoderPool  sync.Pool
+)
+
+func getZstdEncoder(level zstd.EncoderLevel) (*zstd.Encoder, error) {
+	if enc, ok := zstdEncoderPools[level].Get().(*zstd.Encoder); ok {
+		return enc, nil
+	}
+	return zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+}
+
+func getZstdDecoder() (*zstd.Decoder, error) {
+	if dec, ok := zstdDecoderPool.Get().(*zstd.Decoder); ok {
+		return dec, nil
+	}
+	return zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+}
+
+// compressTo writes the compressed data of reader to w, level is the native level of the codec,
+// i.e. 1 to 22 for zstd.
+func compressTo(w io.Writer, reader io.Reader, codec string, level *int) error {
+	if codec == CompressionZstd {
+		zstdLevel := zstd.SpeedDefault
+		if level != nil {
+			zstdLevel = zstd.EncoderLevelFromZstd(*level)
+		}
+		enc, err := getZstdEncoder(zstdLevel)
+		if err != nil {
+			return err
+		}
+		enc.Reset(w)
+		if _, err := io.Copy(enc, reader); err != nil {
+			_ = enc.Close()
+			return err
+		}
+		err = enc.Close()
+		enc.Reset(nil)
+		zstdEncoderPools[zstdLevel].Put(enc)
+		return err
+	}
+
+	gzipLevel := gzip.DefaultCompression
+	if level != nil {
+		gzipLevel = *level
+	}
+	zw, err := gzip.NewWriterLevel(w, gzipLevel)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(zw, reader); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// compressBody compresses the body of Put in memory, so that it can still be seeked for retries
+func (o *putOptions) compressBody(reader io.ReadSeeker) (io.ReadSeeker, error) {
+	if o.compression == "" || reader == nil {
+		return reader, nil
+	}
+	var buf bytes.Buffer
+	if err := compressTo(&buf, reader, o.compression, o.compressionLevel); err != nil {
+		return nil, err
+	}
+	o.contentEncoding = &o.compression
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// compressStream compresses the body of MultipartUpload on the fly, the returned reader must be closed
+// to stop compressing if the upload fails.
+func (o *putOptions) compressStream(reader io.Reader) io.ReadCloser {
+	if o.compression == "" {
+		return ioutil.NopCloser(reader)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(compressTo(pw, reader, o.compression, o.compressionLevel))
+	}()
+	o.contentEncoding = &o.compression
+	return pr
+}
+
+// zstdReadCloser returns its decoder to the pool on Close
+type zstdReadCloser struct {
+	dec  *zstd.Decoder
+	body io.ReadCloser
+}
+
+func (r *zstdReadCloser) Read(p []byte) (int, error) {
+	if r.dec == nil {
+		return 0, errors.New("read on closed body")
+	}
+	return r.dec.Read(p)
+}
+
+func (r *zstdReadCloser) Close() error {
+	if r.dec != nil {
+		_ = r.dec.Reset(nil)
+		zstdDecoderPool.Put(r.dec)
+		r.dec = nil
+	}
+	return r.body.Close()
+}
+
+// decodeBody returns body inflating it according to its Content-Encoding if GetWithDecompression is set.
+// The http transport may have inflated gzip bodies already, see decompressBody, they have no Content-Encoding then.
+func (o *getOptions) decodeBody(body io.ReadCloser, contentEncoding string) (io.ReadCloser, error) {
+	if !o.decompress() {
+		return body, nil
+	}
+	switch strings.ToLower(contentEncoding) {
+	case CompressionGzip:
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		return CombinedReadCloser{ReadCloser: body, Reader: zr}, nil
+	case CompressionZstd:
+		dec, err := getZstdDecoder()
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		if err := dec.Reset(body); err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		return &zstdReadCloser{dec: dec, body: body}, nil
+	}
+	return body, nil
+}
+
+// decompress reports whether the body of the whole object is inflated by GetWithDecompression
+func (o *getOptions) decompress() bool {
+	return o.decompression && !o.rawContent && o.rangeStart == nil
+}
+
+// inflateIdentityGzip inflates the gzip body requested with Accept-Encoding identity, see acceptIdentity, as the
+// http transport inflates them for the other gets. It reports whether body is inflated, the Content-Encoding and
+// the Content-Length of the response are removed then like the transport does. The raw content isn't inflated.
+func (o *getOptions) inflateIdentityGzip(body io.ReadCloser, contentEncoding string) (io.ReadCloser, bool, error) {
+	if !o.acceptIdentity() || o.rawContent || !strings.EqualFold(contentEncoding, CompressionGzip) {
+		return body, false, nil
+	}
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		_ = body.Close()
+		return nil, false, err
+	}
+	return CombinedReadCloser{ReadCloser: body, Reader: zr}, true, nil
+}
+
+// decompressBody reads body and inflates it according to its Content-Encoding, other bodies are returned untouched.
+// Note that the http transport already inflates gzip bodies if it adds the Accept-Encoding header by itself,
+// the Content-Encoding header is removed then.
+func decompressBody(contentEncoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(contentEncoding) {
+	case CompressionGzip:
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(zr)
+	case CompressionZstd:
+		dec, err := getZstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.Reset(body); err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(dec)
+		_ = dec.Reset(nil)
+		zstdDecoderPool.Put(dec)
+		return data, err
+	}
+	return ioutil.ReadAll(body)
+}
+
+// compressionSnappy is the compressor of CompressAndPut, which isn't a Content-Encoding
+const compressionSnappy = "snappy"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	// snappyMagic is the stream identifier of the framed format, the block format has no magic bytes
+	snappyMagic = []byte("\xff\x06\x00\x00sNaPpY")
+)
+
+// compressionExtensions are the extensions of the compressed objects without Content-Encoding and magic bytes
+var compressionExtensions = map[string]string{
+	".gz":     CompressionGzip,
+	".zst":    CompressionZstd,
+	".zstd":   CompressionZstd,
+	".snappy": compressionSnappy,
+	".sz":     compressionSnappy,
+}
+
+// detectCompression detects the codec of data by its magic bytes, then by the extension of key,
+// it returns "" for uncompressed data
+func detectCompression(key string, data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(data, zstdMagic):
+		return CompressionZstd
+	case bytes.HasPrefix(data, snappyMagic):
+		return compressionSnappy
+	}
+	return compressionExtensions[strings.ToLower(path.Ext(key))]
+}
+
+// GetDecompressed gets the object of key and inflates it, whatever it's compressed with. The codec is picked by
+// the Content-Encoding, or the compressor of CompressAndPut, then by the magic bytes and the extension of key for
+// the objects uploaded compressed without the header. gzip, zstd and snappy are supported, the objects with
+// other encodings are returned as they are with a warning. Ranges of GetWithRange are never inflated.
+func GetDecompressed(ctx context.Context, c Component, key string, options ...GetOptions) ([]byte, error) {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	body, meta, err := c.WithContext(ctx).GetWithMeta(key, []string{"Content-Encoding", MetaCompressor}, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	if err != nil || getOpts.rangeStart != nil {
+		return data, err
+	}
+
+	codec := strings.ToLower(meta["Content-Encoding"])
+	if compressor := strings.ToLower(meta[MetaCompressor]); compressor != "" {
+		codec = compressor
+	}
+	if codec == "" || codec == "identity" {
+		codec = detectCompression(key, data)
+	}
+	switch codec {
+	case "":
+		return data, nil
+	case CompressionGzip, CompressionZstd:
+		return decompressBody(codec, bytes.NewReader(data))
+	case compressionSnappy:
+		if bytes.HasPrefix(data, snappyMagic) {
+			return ioutil.ReadAll(snappy.NewReader(bytes.NewReader(data)))
+		}
+		return snappy.Decode(nil, data)
+	}
+	elog.EgoLogger.With(elog.FieldComponent(PackageName)).Warn("unknown compression, the object is returned as it is",
+		elog.FieldKey(key), elog.FieldValue(codec))
+	return data, nil
+}
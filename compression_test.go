@@ -0,0 +1,258 @@
+package awos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+var largeContent = strings.Repeat(`{"key": "value", "list": [1, 2, 3]}`, 1000)
+
+// objectHandler stores one object with its Content-Encoding
+func objectHandler(stored *[]byte) http.HandlerFunc {
+	var mu sync.Mutex
+	var encoding string
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			*stored, _ = ioutil.ReadAll(r.Body)
+			encoding = r.Header.Get("Content-Encoding")
+		case http.MethodGet:
+			if encoding != "" {
+				w.Header().Set("Content-Encoding", encoding)
+			}
+			_, _ = w.Write(*stored)
+		}
+	}
+}
+
+func TestPutWithCompression(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var stored []byte
+			client := newTestComponent(t, storageType, objectHandler(&stored))
+
+			assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil, PutWithCompression(CompressionGzip)))
+			assert.True(t, len(stored) < len(largeContent))
+			zr, err := gzip.NewReader(bytes.NewReader(stored))
+			assert.NoError(t, err)
+			data, err := ioutil.ReadAll(zr)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, string(data))
+
+			res, err := client.GetAndDecompress(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+
+			assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil, PutWithCompression(CompressionZstd)))
+			dec, err := zstd.NewReader(bytes.NewReader(stored))
+			assert.NoError(t, err)
+			data, err = ioutil.ReadAll(dec)
+			dec.Close()
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, string(data))
+
+			res, err = client.GetAndDecompress(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+
+			// objects without Content-Encoding are untouched
+			assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil))
+			assert.Equal(t, largeContent, string(stored))
+			res, err = client.GetAndDecompress(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+		})
+	}
+}
+
+func TestGetWithDecompression(t *testing.T) {
+	check := func(t *testing.T, client Component) {
+		for _, codec := range []string{CompressionGzip, CompressionZstd} {
+			assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil, PutWithCompression(codec)))
+			res, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.NotEqual(t, largeContent, res, codec)
+
+			res, err = client.Get(guid, GetWithDecompression())
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res, codec)
+			reader, err := client.GetAsReader(guid, GetWithDecompression())
+			if assert.NoError(t, err) {
+				data, err := ioutil.ReadAll(reader)
+				assert.NoError(t, err)
+				assert.Equal(t, largeContent, string(data), codec)
+				assert.NoError(t, reader.Close())
+			}
+			// ranges are untouched
+			res, err = client.Get(guid, GetWithDecompression(), GetWithRange(0, 1))
+			assert.NoError(t, err)
+			assert.NotEqual(t, largeContent[:2], res, codec)
+		}
+
+		// objects without Content-Encoding are untouched
+		assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil))
+		res, err := client.Get(guid, GetWithDecompression())
+		assert.NoError(t, err)
+		assert.Equal(t, largeContent, res)
+	}
+
+	// the http transport doesn't inflate the gzip bodies when Accept-Encoding is set by the client
+	acceptEncoding := WithHeaders(map[string]string{"Accept-Encoding": "gzip"})
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var stored []byte
+			handler := objectHandler(&stored)
+			check(t, newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != "" {
+					w.Header().Set("Content-Encoding", CompressionGzip)
+					_, _ = w.Write(stored[:2])
+					return
+				}
+				handler(w, r)
+			}, acceptEncoding))
+		})
+	}
+	t.Run("memory", func(t *testing.T) {
+		check(t, newTestMemory())
+	})
+	t.Run("fs", func(t *testing.T) {
+		fs, _ := newTestFS(t)
+		check(t, fs)
+	})
+}
+
+func TestMemory_PutWithCompression(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil, PutWithCompression(CompressionGzip), PutWithCompressionLevel(gzip.BestSpeed)))
+	meta, err := client.Head(guid, []string{"Content-Encoding", "Content-Length"})
+	assert.NoError(t, err)
+	assert.Equal(t, CompressionGzip, meta["Content-Encoding"])
+	assert.NotEqual(t, strconv.Itoa(len(largeContent)), meta["Content-Length"])
+
+	res, err := client.GetAndDecompress(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, res)
+
+	reader, err := client.GetAndDecompressAsReader(guid)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, string(data))
+
+	assert.NoError(t, client.MultipartUpload(guid, strings.NewReader(largeContent), nil, PutWithCompression(CompressionGzip)))
+	res, err = client.GetAndDecompress(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, res)
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	res, err = client.GetAndDecompress(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	assert.Error(t, client.Put(guid, strings.NewReader(content), nil, PutWithCompression("unknown")))
+	assert.Error(t, client.Put(guid, strings.NewReader(content), nil, PutWithCompression(CompressionGzip), PutWithCompressionLevel(100)))
+}
+
+func TestCompressTo(t *testing.T) {
+	for _, codec := range []string{CompressionGzip, CompressionZstd} {
+		for _, level := range []int{1, 3, 9} {
+			var buf bytes.Buffer
+			assert.NoError(t, compressTo(&buf, strings.NewReader(largeContent), codec, &level))
+			assert.True(t, buf.Len() < len(largeContent))
+			data, err := decompressBody(codec, &buf)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, string(data))
+		}
+	}
+
+	data, err := decompressBody("", strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	_, err = decompressBody(CompressionZstd, strings.NewReader(content))
+	assert.Error(t, err)
+}
+
+func BenchmarkCompression(b *testing.B) {
+	for _, codec := range []string{CompressionGzip, CompressionZstd} {
+		b.Run(codec, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(largeContent)))
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := compressTo(&buf, strings.NewReader(largeContent), codec, nil); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := decompressBody(codec, &buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDecompressed(t *testing.T) {
+	ctx := context.Background()
+	check := func(t *testing.T, client Component) {
+		for _, codec := range []string{CompressionGzip, CompressionZstd} {
+			assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil, PutWithCompression(codec)))
+			res, err := GetDecompressed(ctx, client, guid)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, string(res), codec)
+
+			// uploaded compressed without Content-Encoding, detected by the magic bytes
+			var buf bytes.Buffer
+			assert.NoError(t, compressTo(&buf, strings.NewReader(largeContent), codec, nil))
+			assert.NoError(t, client.Put(guid, bytes.NewReader(buf.Bytes()), nil))
+			res, err = GetDecompressed(ctx, client, guid)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, string(res), codec)
+		}
+
+		assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil))
+		res, err := GetDecompressed(ctx, client, guid)
+		assert.NoError(t, err)
+		assert.Equal(t, largeContent, string(res))
+	}
+
+	acceptEncoding := WithHeaders(map[string]string{"Accept-Encoding": "gzip"})
+	t.Run(StorageTypeS3, func(t *testing.T) {
+		var stored []byte
+		check(t, newTestComponent(t, StorageTypeS3, objectHandler(&stored), acceptEncoding))
+	})
+	t.Run("memory", func(t *testing.T) {
+		client := newTestMemory()
+		check(t, client)
+
+		assert.NoError(t, client.CompressAndPut(guid, strings.NewReader(largeContent), nil))
+		res, err := GetDecompressed(ctx, client, guid)
+		assert.NoError(t, err)
+		assert.Equal(t, largeContent, string(res))
+
+		// the snappy block format has no magic bytes, detected by the extension
+		key := guid + ".sz"
+		assert.NoError(t, client.Put(key, bytes.NewReader(snappy.Encode(nil, []byte(largeContent))), nil))
+		res, err = GetDecompressed(ctx, client, key)
+		assert.NoError(t, err)
+		assert.Equal(t, largeContent, string(res))
+
+		// unknown encodings are returned as they are
+		assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithContentEncoding("br")))
+		res, err = GetDecompressed(ctx, client, guid)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(res))
+	})
+}
@@ -0,0 +1,142 @@
+package awos
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testETag = `"5d41402abc4b2a76b9719d911017c592"`
+
+func etagHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("ETag", testETag)
+	if r.Header.Get("If-None-Match") == testETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(content))
+	}
+}
+
+func TestGetWithIfNoneMatch(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, etagHandler)
+
+			meta, err := client.Head(guid, []string{"ETag"})
+			assert.NoError(t, err)
+			assert.Equal(t, testETag, meta["ETag"])
+
+			res, err := client.Get(guid, GetWithIfNoneMatch(`"other"`))
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+
+			_, err = client.Get(guid, GetWithIfNoneMatch(meta["ETag"]))
+			assert.True(t, errors.Is(err, ErrNotModified), err)
+
+			_, err = client.GetAsReader(guid, GetWithIfNoneMatch(meta["ETag"]))
+			assert.True(t, errors.Is(err, ErrNotModified), err)
+		})
+	}
+}
+
+func TestMemory_GetWithIfNoneMatch(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+
+	meta, err := client.Head(guid, []string{"ETag"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, meta["ETag"])
+
+	_, err = client.Get(guid, GetWithIfNoneMatch(meta["ETag"]))
+	assert.True(t, errors.Is(err, ErrNotModified))
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content+"changed"), nil))
+	res, err := client.Get(guid, GetWithIfNoneMatch(meta["ETag"]))
+	assert.NoError(t, err)
+	assert.Equal(t, content+"changed", res)
+}
+
+// createOnceHandler accepts only the first put which forbids overwriting
+func createOnceHandler() http.HandlerFunc {
+	var mu sync.Mutex
+	exists := false
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if exists && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = w.Write([]byte(`<Error><Code>PreconditionFailed</Code></Error>`))
+			return
+		}
+		if exists && r.Header.Get("X-Oss-Forbid-Overwrite") == "true" {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`<Error><Code>FileAlreadyExists</Code></Error>`))
+			return
+		}
+		exists = true
+		w.Header().Set("ETag", testETag)
+	}
+}
+
+func assertExactlyOnePut(t *testing.T, client Component) {
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Put(guid, strings.NewReader(content), nil, PutWithIfNoneMatch("*"))
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrPreconditionFailed), err)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestPutWithIfNoneMatch(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			assertExactlyOnePut(t, newTestComponent(t, storageType, createOnceHandler()))
+		})
+	}
+}
+
+func TestOSS_PutWithIfMatch(t *testing.T) {
+	client := newTestComponent(t, StorageTypeOSS, createOnceHandler())
+	err := client.Put(guid, strings.NewReader(content), nil, PutWithIfMatch(testETag))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+}
+
+func TestMemory_PutWithIfNoneMatch(t *testing.T) {
+	assertExactlyOnePut(t, newTestMemory())
+}
+
+func TestMemory_PutWithIfMatch(t *testing.T) {
+	client := newTestMemory()
+	err := client.Put(guid, strings.NewReader(content), nil, PutWithIfMatch("*"))
+	assert.True(t, errors.Is(err, ErrPreconditionFailed))
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	meta, err := client.Head(guid, []string{"ETag"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content+"v2"), nil, PutWithIfMatch(meta["ETag"])))
+	err = client.Put(guid, strings.NewReader(content+"v3"), nil, PutWithIfMatch(meta["ETag"]))
+	assert.True(t, errors.Is(err, ErrPreconditionFailed))
+}
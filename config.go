@@ -1,20 +1,52 @@
 package awos
 
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
 type config struct {
 	Debug bool
 	bucketConfig
 	Buckets   map[string]bucketConfig
 	bucketKey string
+	// headersFunc returns the headers added to each request by its context, see WithHeadersFunc
+	headersFunc func(ctx context.Context) map[string]string
+	// roundTripper is the base transport wrapped by the interceptors, see WithRoundTripper
+	roundTripper http.RoundTripper
+	// interceptors wrap the built-in interceptors, see WithInterceptors
+	interceptors []Interceptor
+	// accessLogActor returns the actor of the access log by the context of the operation, see WithAccessLogActor
+	accessLogActor func(ctx context.Context) string
+	// accessLogRedact redacts the keys of the access log, see WithAccessLogRedact
+	accessLogRedact func(key string) string
+	// metricsSink receives the metrics instead of the ego metric registry, see WithMetricsSink
+	metricsSink MetricsSink
+	// timeSource is the clock of the s3 signatures, see WithTimeSource
+	timeSource func() time.Time
+	// retryableFunc decides the retries of the retry interceptor, see WithRetryableFunc
+	retryableFunc RetryableFunc
+	// tlsConfig is the tls config of WithTLSConfig
+	tlsConfig *tls.Config
+	// tls is the tlsConfig with the TLS fields applied, built by newStorage
+	tls *tls.Config
 }
 
 type bucketConfig struct {
-	// Required, value is one of oss/s3, case insensetive
+	// Required, value is one of oss/s3/gcs/cos/azure/memory/fs, case insensetive
 	StorageType string
-	// Required
+	// Required, the account name for azure
 	AccessKeyID string
-	// Required
+	// Required, the base64 account key for azure
 	AccessKeySecret string
-	// Required
+	// Required for oss, the host of the service. For s3 it's optional and routes the requests to an s3-compatible
+	// store such as minio, cloudflare r2 or digitalocean spaces, signed with Region, or us-east-1 if no region is configured.
+	// For s3-like, an endpoint without a scheme uses https with SSL and http without it.
 	Endpoint string
 	// Required
 	Bucket string
@@ -22,21 +54,128 @@ type bucketConfig struct {
 	// if bucket is 'content', shards is ['abc', 'edf'],
 	// then the last character of the key with a/b/c will automatically use the content-abc bucket, and vice versa
 	Shards []string
+	// Optional, prepended to the keys of all the operations and stripped from the listed keys,
+	// e.g. "tenant-1/" to keep the objects of a tenant in a namespace of a shared bucket
+	KeyPrefix string
 	// Only for s3-like
 	Region string
-	// Only for s3-like, whether to force path style URLs for S3 objects.
+	// Optional, the ordered fallback endpoints of the reads, e.g. the replica of the bucket in a secondary region.
+	// The reads which fail on the primary bucket with network errors, 5xx or throttling after the retries are
+	// sent to the next endpoint, the writes always go to the primary bucket. See FailoverEndpoint.
+	FailoverEndpoints []FailoverEndpoint
+	// Only for s3-like, whether to address the bucket in the path of the endpoint, such as http://minio:9000/bucket/key,
+	// instead of the virtual-hosted style http://bucket.minio:9000/key, required by minio and most on-prem s3 gateways.
+	ForcePathStyle bool
+	// Deprecated: use ForcePathStyle, either of them enables path style URLs.
 	S3ForcePathStyle bool
-	// Only for s3-like
+	// Only for s3 and oss, whether to route the requests through the transfer acceleration endpoint,
+	// bucket.s3-accelerate.amazonaws.com for s3 and oss-accelerate.aliyuncs.com for oss, which must be enabled
+	// on the bucket. s3 requires virtual-hosted style, so it's incompatible with ForcePathStyle.
+	UseAccelerate bool
+	// Only for s3, the signature version of the requests and the presigned urls, SignatureV4 by default,
+	// or SignatureV2 for the legacy gateways which don't support sigv4.
+	SignatureVersion string
+	// Only for s3-like, whether to correct the signing time by the Date of the responses rejected with
+	// RequestTimeTooSkewed and retry them once, for the hosts whose clock drifts more than 5 minutes.
+	CorrectClockSkew bool
+	// Only for s3-like, whether to use https for an Endpoint without a scheme, disable it for a local minio
 	SSL bool
-	// Only for s3-like, set http client timeout.
-	// oss has default timeout, but s3 default timeout is 0 means no timeout.
+	// Only for s3-like and azure, set http client timeout, 0 means no timeout.
+	// oss has no client timeout, its connections fail a read or a write making no progress for 60s like the oss sdk.
 	S3HttpTimeoutSecs int64
-	// EnableTraceInterceptor enable otel trace (only for s3)
+	// Only for gcs, path of the service account json key file.
+	// Used when AccessKeyID/AccessKeySecret (HMAC keys) are empty,
+	// falls back to Application Default Credentials if not set either.
+	GCSCredentialsFile string
+	// Only for fs, the root directory, objects of the bucket are files under <FSRootDir>/<Bucket>
+	FSRootDir string
+	// EnableTraceInterceptor enable otel trace (only for s3-like and azure)
 	EnableTraceInterceptor bool
-	// EnableMetricInterceptor enable prom metrics
+	// EnableOperationTrace starts a span named awos.<Operation> for each operation, e.g. awos.Get,
+	// with the bucket, key, operation and object size as attributes, for all storage types
+	EnableOperationTrace bool
+	// EnableAccessLog logs a line for each operation, e.g. Get, with the operation, bucket, key, size, status and cost,
+	// and the actor of WithAccessLogActor, for all storage types
+	EnableAccessLog bool
+	// AccessLogLevel is the level of the access log, one of debug/info/warn/error, info by default
+	AccessLogLevel string
+	// EnableMetricInterceptor enable prom metrics, or the metrics of WithMetricsSink. If disabled, no metrics
+	// are recorded, including the retries, the hedged requests and the failover reads
 	EnableMetricInterceptor bool
+	// DisableBodyMetrics stops the metric interceptor from wrapping the response bodies, for streaming hot paths.
+	// The read bytes aren't counted then, and the latency is observed when the response headers are received.
+	DisableBodyMetrics bool
 	// EnableClientTrace
 	EnableClientTrace bool
+	// EnableRetryInterceptor retries GET/HEAD/PUT requests on 5xx and network errors, or the ones of WithRetryableFunc,
+	// PUT is only retried if the body can be rewound. The retries of aws sdk are disabled if enabled.
+	EnableRetryInterceptor bool
+	// RetryMaxAttempts is the max attempts of a request, including the first one
+	RetryMaxAttempts int
+	// RetryBaseDelay is the delay before the first retry, doubled for each retry
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between retries
+	RetryMaxDelay time.Duration
+	// RetryBudgetRatio caps the retries of the retry interceptor to a fraction of the requests of the client,
+	// e.g. 0.1 allows a retry per 10 requests, plus a reserve of 10 retries. It stops retry storms when the backend
+	// is down, the requests fail after the first attempt once the budget is spent. 0 means no budget.
+	RetryBudgetRatio float64
+	// HedgeDelay sends a second GET/HEAD request if the first one has no response after the delay, e.g. about the p95
+	// latency, the first successful response is returned and the other request is canceled. It trades more requests
+	// for a lower tail latency of the reads, each attempt of the retry interceptor is hedged. 0 means disabled.
+	// Not for memory and fs.
+	HedgeDelay time.Duration
+	// EnableContentTypeDetection sets the content type of Put and MultipartUpload by the extension of the key,
+	// or by sniffing the first 512 bytes, unless it's given by PutWithContentType. Defaults to text/plain if disabled.
+	EnableContentTypeDetection bool
+	// CircuitBreakerThreshold opens the circuit breaker after the number of consecutive failures (5xx and network errors),
+	// requests fail with ErrCircuitOpen until a probe request succeeds after CircuitBreakerCooldown. 0 means disabled.
+	CircuitBreakerThreshold int
+	// CircuitBreakerCooldown is how long the circuit breaker stays open before probing
+	CircuitBreakerCooldown time.Duration
+	// SlowThreshold logs a warning for requests taking longer, including reading the body, 0 means disabled
+	SlowThreshold time.Duration
+	// RateLimitQPS limits the requests per second of each bucket, including retries, 0 means no limit
+	RateLimitQPS float64
+	// RateLimitBurst is the max requests sent at once before being limited by RateLimitQPS, at least 1
+	RateLimitBurst int
+	// MaxIdleConns is the max idle connections of all hosts, 0 means no limit
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the max idle connections kept for each host, which is 2 by default in net/http
+	// and makes concurrent requests open new connections
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the connections of each host, including the ones in use, 0 means no limit
+	MaxConnsPerHost int
+	// IdleConnTimeout closes idle connections after the duration, 0 means no timeout
+	IdleConnTimeout time.Duration
+	// TLSHandshakeTimeout is the timeout of TLS handshakes, 0 means no timeout
+	TLSHandshakeTimeout time.Duration
+	// TLSMinVersion is the minimum TLS version of the connections, one of 1.0, 1.1, 1.2 and 1.3,
+	// the default of net/http if empty
+	TLSMinVersion string
+	// TLSCAFile is a PEM file of the CAs trusted in addition to the system ones, e.g. the private CA
+	// of an on-prem s3 gateway. It replaces the RootCAs of WithTLSConfig.
+	TLSCAFile string
+	// TLSInsecureSkipVerify disables the verification of the server certificates, which allows any server
+	// to intercept the requests. Only for testing, a warning is logged when enabled.
+	TLSInsecureSkipVerify bool
+	// ReadTimeout fails a request with ErrIdleTimeout if no bytes of the response are received for the duration,
+	// including waiting for the response headers, the timer is reset on every read. 0 means no timeout
+	ReadTimeout time.Duration
+	// WriteTimeout fails a request with ErrIdleTimeout if no bytes of the request body are sent for the duration,
+	// the timer is reset on every write. 0 means no timeout
+	WriteTimeout time.Duration
+	// DefaultTimeout bounds each request whose context has no deadline, e.g. context.Background(),
+	// including its retries and reading the response body. 0 means no timeout. Not for memory and fs.
+	DefaultTimeout time.Duration
+	// Headers are added to every request, e.g. X-Tenant-Id for a gateway in front of the storage,
+	// headers signed by the sdks like Authorization, Content-* and x-amz-*, x-oss-*, x-ms-*, x-goog-*, x-cos-*
+	// are rejected.
+	// Not for memory and fs.
+	Headers map[string]string
+	// UserAgent is appended to the User-Agent of the sdks on every request, e.g. "myapp/1.2.0",
+	// so the provider can identify the client. Not for memory and fs.
+	UserAgent string
 }
 
 // DefaultConfig 返回默认配置
@@ -46,6 +185,52 @@ func DefaultConfig() *config {
 		S3HttpTimeoutSecs:       60,
 		EnableTraceInterceptor:  true,
 		EnableMetricInterceptor: true,
+		RetryMaxAttempts:        3,
+		RetryBaseDelay:          100 * time.Millisecond,
+		RetryMaxDelay:           2 * time.Second,
+		CircuitBreakerCooldown:  30 * time.Second,
+		MaxIdleConns:            1024,
+		MaxIdleConnsPerHost:     256,
+		IdleConnTimeout:         90 * time.Second,
+		TLSHandshakeTimeout:     10 * time.Second,
 	},
 	}
 }
+
+// validateAccelerate checks that UseAccelerate is only enabled for s3 with virtual-hosted style and oss
+func (c *bucketConfig) validateAccelerate(storageType string) error {
+	if !c.UseAccelerate {
+		return nil
+	}
+	switch storageType {
+	case StorageTypeS3:
+		// the aws sdk ignores path style with accelerate, the URLs wouldn't be the configured ones
+		if c.forcePathStyle() {
+			return errors.New("awos: UseAccelerate is incompatible with ForcePathStyle")
+		}
+		return nil
+	case StorageTypeOSS:
+		return nil
+	}
+	return fmt.Errorf("UseAccelerate of %s: %w", storageType, ErrUnsupported)
+}
+
+// ossAccelerateEndpoint is the global transfer acceleration endpoint of oss
+const ossAccelerateEndpoint = "oss-accelerate.aliyuncs.com"
+
+// ossEndpoint returns the endpoint of oss, the accelerate endpoint with the scheme of Endpoint if UseAccelerate is set
+func (c *bucketConfig) ossEndpoint() string {
+	if !c.UseAccelerate {
+		return c.Endpoint
+	}
+	scheme := "https://"
+	if strings.HasPrefix(c.Endpoint, "http://") {
+		scheme = "http://"
+	}
+	return scheme + ossAccelerateEndpoint
+}
+
+// forcePathStyle reports whether the s3-like URLs are path style, by ForcePathStyle or the deprecated S3ForcePathStyle
+func (c *bucketConfig) forcePathStyle() bool {
+	return c.ForcePathStyle || c.S3ForcePathStyle
+}
@@ -3,6 +3,22 @@ package awos
 const (
 	StorageTypeOSS = "oss"
 	StorageTypeS3  = "s3"
+	StorageTypeGCS = "gcs"
+	StorageTypeCOS = "cos"
+	// StorageTypeAzure is azure blob storage, buckets are containers
+	StorageTypeAzure = "azure"
+	// StorageTypeMemory keeps objects in memory, only for unit testing
+	StorageTypeMemory = "memory"
+	// StorageTypeFS keeps objects as files under FSRootDir, for local development
+	StorageTypeFS = "fs"
 
 	MetaCompressor = "compressor"
+
+	// HeadServerSideEncryption is the Head attribute of the server side encryption algorithm,
+	// AES256 or aws:kms for s3, AES256 or KMS for oss
+	HeadServerSideEncryption = "Server-Side-Encryption"
+	// HeadServerSideEncryptionKeyID is the Head attribute of the kms key id
+	HeadServerSideEncryptionKeyID = "Server-Side-Encryption-Key-Id"
+	// HeadVersionID is the Head attribute of the version id, empty if the bucket isn't versioned
+	HeadVersionID = "Version-Id"
 )
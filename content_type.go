@@ -0,0 +1,60 @@
+package awos
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers
+const sniffLen = 512
+
+// detectContentType sets the content type by the extension of key, or sniffs the first 512 bytes of reader,
+// unless it's given by PutWithContentType. reader is seeked back after sniffing.
+func (o *putOptions) detectContentType(key string, reader io.ReadSeeker) error {
+	if o.contentTypeSet {
+		return nil
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		o.contentType = contentType
+		return nil
+	}
+	if reader == nil {
+		return nil
+	}
+	pos, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(reader, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if _, err := reader.Seek(pos, io.SeekStart); err != nil {
+		return err
+	}
+	o.contentType = http.DetectContentType(buf[:n])
+	return nil
+}
+
+// detectStreamContentType is detectContentType for readers which can't be seeked,
+// the returned reader must be read instead of reader.
+func (o *putOptions) detectStreamContentType(key string, reader io.Reader) (io.Reader, error) {
+	if o.contentTypeSet {
+		return reader, nil
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		o.contentType = contentType
+		return reader, nil
+	}
+	br := bufio.NewReaderSize(reader, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	o.contentType = http.DetectContentType(head)
+	return br, nil
+}
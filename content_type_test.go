@@ -0,0 +1,82 @@
+package awos
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// contentTypeHandler records the Content-Type of the last put
+func contentTypeHandler(contentType *string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			*contentType = r.Header.Get("Content-Type")
+		}
+	}
+}
+
+func TestPutWithContentTypeDetection(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var contentType string
+			client := newTestComponent(t, storageType, contentTypeHandler(&contentType), WithEnableContentTypeDetection(true))
+
+			assert.NoError(t, client.Put("image.png", strings.NewReader(content), nil))
+			assert.Equal(t, "image/png", contentType)
+
+			assert.NoError(t, client.Put("data.json", strings.NewReader(content), nil))
+			assert.Equal(t, "application/json", contentType)
+
+			assert.NoError(t, client.Put("image.unknownext", bytes.NewReader(pngHeader), nil))
+			assert.Equal(t, "image/png", contentType)
+
+			assert.NoError(t, client.Put("image.png", strings.NewReader(content), nil, PutWithContentType("application/octet-stream")))
+			assert.Equal(t, "application/octet-stream", contentType)
+		})
+	}
+}
+
+func TestPutWithoutContentTypeDetection(t *testing.T) {
+	var contentType string
+	client := newTestComponent(t, StorageTypeS3, contentTypeHandler(&contentType))
+
+	assert.NoError(t, client.Put("image.png", bytes.NewReader(pngHeader), nil))
+	assert.Equal(t, "text/plain", contentType)
+}
+
+func TestMemory_PutWithContentTypeDetection(t *testing.T) {
+	client := DefaultContainer().Build(WithStorageType(StorageTypeMemory), WithBucket("memory-bucket"), WithEnableContentTypeDetection(true))
+
+	assert.NoError(t, client.Put("data.json", strings.NewReader(content), nil))
+	head, err := client.Head("data.json", []string{"Content-Type"})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", head["Content-Type"])
+
+	assert.NoError(t, client.MultipartUpload("image.unknownext", bytes.NewReader(pngHeader), nil))
+	head, err = client.Head("image.unknownext", []string{"Content-Type"})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", head["Content-Type"])
+
+	data, err := client.GetBytes("image.unknownext")
+	assert.NoError(t, err)
+	assert.Equal(t, pngHeader, data)
+}
+
+func TestDetectStreamContentType(t *testing.T) {
+	opts := DefaultPutOptions()
+	reader, err := opts.detectStreamContentType("image.unknownext", bytes.NewReader(pngHeader))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", opts.contentType)
+	data := new(bytes.Buffer)
+	_, _ = data.ReadFrom(reader)
+	assert.Equal(t, pngHeader, data.Bytes())
+}
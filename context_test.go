@@ -0,0 +1,69 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stallingHandler sends half of the body and stalls until the client goes away
+func stallingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Length", "2048")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(strings.Repeat("a", 1024)))
+	w.(http.Flusher).Flush()
+	select {
+	case <-r.Context().Done():
+	case <-time.After(10 * time.Second):
+	}
+}
+
+func TestContext_CancelDownload(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, stallingHandler)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			start := time.Now()
+			_, err := client.WithContext(ctx).Get(guid)
+			assert.True(t, errors.Is(err, context.Canceled), err)
+			assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+			ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start = time.Now()
+			_, err = client.WithContext(ctx).GetBytes(guid)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+			assert.Less(t, int64(time.Since(start)), int64(time.Second))
+		})
+	}
+}
+
+func TestContext_CancelBeforeRequest(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var calls int32
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			start := time.Now()
+			err := client.WithContext(ctx).Put(guid, strings.NewReader(content), nil)
+			assert.True(t, errors.Is(err, context.Canceled), err)
+			// Put isn't retried once the context is done
+			assert.Less(t, int64(time.Since(start)), int64(time.Second))
+			_, err = client.WithContext(ctx).HeadObject(guid)
+			assert.True(t, errors.Is(err, context.Canceled), err)
+			assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package awos
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// MaxCopySize is the largest object s3 copies in a single request, larger objects are copied in parts
+	MaxCopySize int64 = 5 << 30
+	// ossMaxCopySize is the largest object oss copies in a single request
+	ossMaxCopySize int64 = 1 << 30
+	// DefaultCopyPartSize is the default part size of copying large objects
+	DefaultCopyPartSize int64 = 512 << 20
+	// maxPartCount is the maximum number of parts of a multipart upload, for both s3 and oss
+	maxPartCount = 10000
+)
+
+const (
+	// DirectiveCopy copies the metadata or the tags of the source object, it's the default
+	DirectiveCopy = "COPY"
+	// DirectiveReplace replaces the metadata or the tags with the ones of the options, none if they aren't given
+	DirectiveReplace = "REPLACE"
+)
+
+type CopyOptions func(options *copyOptions)
+
+type copyOptions struct {
+	destBucket string
+	// nil copies the metadata of the source object
+	meta              map[string]string
+	contentType       *string
+	metadataDirective string
+	// nil copies the tags of the source object
+	tags                 map[string]string
+	taggingDirective     string
+	sourceSSECustomerKey []byte
+	sseCustomerKey       []byte
+	// only for objects copied in parts
+	partSize    int64
+	concurrency int
+}
+
+func DefaultCopyOptions() *copyOptions {
+	return &copyOptions{
+		partSize:    DefaultCopyPartSize,
+		concurrency: DefaultPartConcurrency,
+	}
+}
+
+// CopyWithDestBucket copies to another bucket of the same account and endpoint,
+// the bucket of the destination key is used by default.
+func CopyWithDestBucket(bucket string) CopyOptions {
+	return func(options *copyOptions) {
+		options.destBucket = bucket
+	}
+}
+
+// CopyWithMeta replaces the user metadata of the destination object, which is copied from the source by default
+func CopyWithMeta(meta map[string]string) CopyOptions {
+	return func(options *copyOptions) {
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+		options.meta = meta
+	}
+}
+
+// CopyWithContentType replaces the content type of the destination object, which is copied from the source by default
+func CopyWithContentType(contentType string) CopyOptions {
+	return func(options *copyOptions) {
+		options.contentType = &contentType
+	}
+}
+
+// CopyWithMetadataDirective sets whether the user metadata of the source object is copied or replaced,
+// DirectiveReplace without CopyWithMeta leaves the destination object without user metadata.
+// The content type and the other standard headers are copied either way, unless given by CopyWithContentType.
+func CopyWithMetadataDirective(directive string) CopyOptions {
+	return func(options *copyOptions) {
+		options.metadataDirective = directive
+	}
+}
+
+// CopyWithTagging replaces the tags of the destination object, which are copied from the source by default
+func CopyWithTagging(tags map[string]string) CopyOptions {
+	return func(options *copyOptions) {
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		options.tags = tags
+	}
+}
+
+// CopyWithTaggingDirective sets whether the tags of the source object are copied or replaced,
+// DirectiveReplace without CopyWithTagging leaves the destination object without tags.
+// Not for azure, which never copies the tags.
+func CopyWithTaggingDirective(directive string) CopyOptions {
+	return func(options *copyOptions) {
+		options.taggingDirective = directive
+	}
+}
+
+// CopyWithSourceSSECustomerKey decrypts the source object with a 256-bit customer provided key, s3 only
+func CopyWithSourceSSECustomerKey(key []byte) CopyOptions {
+	return func(options *copyOptions) {
+		options.sourceSSECustomerKey = key
+	}
+}
+
+// CopyWithSSECustomerKey encrypts the destination object with a 256-bit customer provided key, s3 only
+func CopyWithSSECustomerKey(key []byte) CopyOptions {
+	return func(options *copyOptions) {
+		options.sseCustomerKey = key
+	}
+}
+
+// CopyWithPartSize sets the part size of objects copied in parts, at least MinPartSize
+func CopyWithPartSize(partSize int64) CopyOptions {
+	return func(options *copyOptions) {
+		options.partSize = partSize
+	}
+}
+
+// CopyWithConcurrency sets the number of parts copied concurrently
+func CopyWithConcurrency(concurrency int) CopyOptions {
+	return func(options *copyOptions) {
+		options.concurrency = concurrency
+	}
+}
+
+// replaceMeta reports whether the metadata of the source object is replaced
+func (o *copyOptions) replaceMeta() bool {
+	return o.meta != nil || o.contentType != nil || o.metadataDirective == DirectiveReplace
+}
+
+// replaceTags reports whether the tags of the source object are replaced
+func (o *copyOptions) replaceTags() bool {
+	return o.tags != nil || o.taggingDirective == DirectiveReplace
+}
+
+// userMeta returns the user metadata of the destination object, src if it isn't replaced
+func (o *copyOptions) userMeta(src map[string]string) map[string]string {
+	if o.meta != nil || o.metadataDirective == DirectiveReplace {
+		meta := make(map[string]string, len(o.meta))
+		for k, v := range o.meta {
+			meta[strings.ToLower(k)] = v
+		}
+		return meta
+	}
+	return src
+}
+
+// objectTags returns the tags of the destination object, src if they aren't replaced
+func (o *copyOptions) objectTags(src map[string]string) map[string]string {
+	if o.replaceTags() {
+		tags := make(map[string]string, len(o.tags))
+		for k, v := range o.tags {
+			tags[k] = v
+		}
+		return tags
+	}
+	return src
+}
+
+func validateDirective(name string, directive string) error {
+	switch directive {
+	case "", DirectiveCopy, DirectiveReplace:
+		return nil
+	}
+	return fmt.Errorf("invalid %s directive %q, must be %s or %s", name, directive, DirectiveCopy, DirectiveReplace)
+}
+
+func (o *copyOptions) validate() error {
+	if err := validateDirective("metadata", o.metadataDirective); err != nil {
+		return err
+	}
+	if o.metadataDirective == DirectiveCopy && (o.meta != nil || o.contentType != nil) {
+		return fmt.Errorf("metadata directive %s can't be used with CopyWithMeta or CopyWithContentType", DirectiveCopy)
+	}
+	if err := validateDirective("tagging", o.taggingDirective); err != nil {
+		return err
+	}
+	if o.taggingDirective == DirectiveCopy && o.tags != nil {
+		return fmt.Errorf("tagging directive %s can't be used with CopyWithTagging", DirectiveCopy)
+	}
+	if err := validateTags(o.tags); err != nil {
+		return err
+	}
+	if err := validateSSECustomerKey(o.sourceSSECustomerKey); err != nil {
+		return err
+	}
+	if err := validateSSECustomerKey(o.sseCustomerKey); err != nil {
+		return err
+	}
+	if o.partSize < MinPartSize {
+		return fmt.Errorf("part size %d is smaller than the minimum %d", o.partSize, MinPartSize)
+	}
+	return nil
+}
+
+// copyParts calls copy for each byte range [start, end] of an object of size with at most concurrency copies in flight,
+// part numbers start at 1. partSize grows if the object would have more than maxPartCount parts.
+// The first error stops copying and is returned.
+func copyParts(size int64, partSize int64, concurrency int, copy func(partNumber int, start, end int64) error) error {
+	if minSize := (size + maxPartCount - 1) / maxPartCount; partSize < minSize {
+		partSize = minSize
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for number, start := 1, int64(0); start < size; number, start = number+1, start+partSize {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(number int, start, end int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := copy(number, start, end); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(number, start, end)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// move copies srcKey to dstKey and only deletes srcKey after the copy succeeded.
+// Nothing is rolled back if the delete fails, both objects exist then.
+func move(c Component, srcKey, dstKey string, options []CopyOptions) error {
+	copyOpts := DefaultCopyOptions()
+	for _, opt := range options {
+		opt(copyOpts)
+	}
+	if srcKey == dstKey && copyOpts.destBucket == "" {
+		return fmt.Errorf("can't move %q to itself", srcKey)
+	}
+
+	if err := c.Copy(srcKey, dstKey, options...); err != nil {
+		return err
+	}
+	if err := c.Del(srcKey); err != nil {
+		return fmt.Errorf("copied %q to %q but failed to delete the source: %w", srcKey, dstKey, err)
+	}
+	return nil
+}
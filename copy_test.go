@@ -0,0 +1,460 @@
+package awos
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockObject struct {
+	data   []byte
+	header http.Header
+	// size overrides the Content-Length of large objects stored without data
+	size int64
+}
+
+// bucketServer is a multi-bucket object store speaking the path style protocol of s3 and oss,
+// prefix is "X-Amz-" or "X-Oss-".
+type bucketServer struct {
+	mu      sync.Mutex
+	prefix  string
+	objects map[string]*mockObject
+	// header of the pending multipart upload
+	upload http.Header
+	// Copy-Source-Range of the copied parts
+	ranges []string
+	// headers of the last copy request
+	copyHeader http.Header
+}
+
+func newBucketServer(prefix string) *bucketServer {
+	return &bucketServer{prefix: prefix, objects: make(map[string]*mockObject)}
+}
+
+// objectHeader keeps the headers stored with an object, the tags and the storage class
+func (s *bucketServer) objectHeader(header http.Header) http.Header {
+	res := make(http.Header)
+	for k, v := range header {
+		if k == "Content-Type" || k == "Content-Encoding" || k == "Cache-Control" || k == "Content-Disposition" || k == s.prefix+"Tagging" ||
+			k == s.prefix+"Storage-Class" || strings.HasPrefix(k, s.prefix+"Meta-") {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func (s *bucketServer) object(path string) *mockObject {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[path]
+}
+
+// splitPath splits the path style /bucket/key
+func splitPath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	return parts[0], parts[1]
+}
+
+func (s *bucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := r.URL.Path
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		obj := s.objects[path]
+		if obj == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, ok := query["tagging"]; ok {
+			tags, _ := url.ParseQuery(obj.header.Get(s.prefix + "Tagging"))
+			_, _ = w.Write([]byte("<Tagging><TagSet>"))
+			for k := range tags {
+				_, _ = fmt.Fprintf(w, "<Tag><Key>%s</Key><Value>%s</Value></Tag>", k, tags.Get(k))
+			}
+			_, _ = w.Write([]byte("</TagSet></Tagging>"))
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		size := obj.size
+		if size == 0 {
+			size = int64(len(obj.data))
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodPost:
+		if _, ok := query["uploads"]; ok {
+			s.upload = s.objectHeader(r.Header)
+			bucket, key := splitPath(path)
+			_, _ = fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`, bucket, key)
+			return
+		}
+		s.objects[path] = &mockObject{header: s.upload}
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+	case http.MethodPut:
+		source := r.Header.Get(s.prefix + "Copy-Source")
+		if source == "" {
+			data, _ := ioutil.ReadAll(r.Body)
+			s.objects[path] = &mockObject{data: data, header: s.objectHeader(r.Header)}
+			return
+		}
+		s.copyHeader = r.Header.Clone()
+		if query.Get("partNumber") != "" {
+			s.ranges = append(s.ranges, r.Header.Get(s.prefix+"Copy-Source-Range"))
+			_, _ = fmt.Fprintf(w, `<CopyPartResult><ETag>"etag-%s"</ETag></CopyPartResult>`, query.Get("partNumber"))
+			return
+		}
+		source, _ = url.PathUnescape(source)
+		src := s.objects["/"+strings.TrimPrefix(source, "/")]
+		if src == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		header := src.header
+		if r.Header.Get(s.prefix+"Metadata-Directive") == "REPLACE" {
+			header = s.objectHeader(r.Header)
+		}
+		s.objects[path] = &mockObject{data: src.data, header: header, size: src.size}
+		_, _ = w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+	case http.MethodDelete:
+		delete(s.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		storageType string
+		prefix      string
+		maxCopySize int64
+	}{
+		{storageType: StorageTypeS3, prefix: "X-Amz-", maxCopySize: MaxCopySize},
+		{storageType: StorageTypeOSS, prefix: "X-Oss-", maxCopySize: ossMaxCopySize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.storageType, func(t *testing.T) {
+			server := newBucketServer(tt.prefix)
+			client := newTestComponent(t, tt.storageType, server.ServeHTTP)
+			assert.NoError(t, client.Put("src", strings.NewReader(content), map[string]string{"Owner": "a"}, PutWithContentType("application/json")))
+
+			// within the bucket, metadata is copied
+			assert.NoError(t, client.Copy("src", "dst"))
+			res, err := client.Get("dst")
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+			meta, err := client.Head("dst", []string{"Owner", "Content-Type"})
+			assert.NoError(t, err)
+			assert.Equal(t, "a", meta["Owner"])
+			assert.Equal(t, "application/json", meta["Content-Type"])
+
+			// across buckets, metadata is replaced
+			assert.NoError(t, client.Copy("src", "dst", CopyWithDestBucket("prod-bucket"), CopyWithMeta(map[string]string{"Owner": "b"})))
+			dst := server.object("/prod-bucket/dst")
+			if assert.NotNil(t, dst) {
+				assert.Equal(t, content, string(dst.data))
+				assert.Equal(t, "b", dst.header.Get(tt.prefix+"Meta-Owner"))
+				// the content type is kept
+				assert.Equal(t, "application/json", dst.header.Get("Content-Type"))
+			}
+
+			// only the content type is replaced
+			assert.NoError(t, client.Copy("src", "dst", CopyWithContentType("text/csv")))
+			meta, err = client.Head("dst", []string{"Owner", "Content-Type"})
+			assert.NoError(t, err)
+			assert.Equal(t, "a", meta["Owner"])
+			assert.Equal(t, "text/csv", meta["Content-Type"])
+
+			err = client.Copy("not-exist", "dst")
+			assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+
+			// large objects are copied in parts
+			size := tt.maxCopySize + DefaultCopyPartSize + 1
+			server.objects["/test-bucket/large"] = &mockObject{header: server.objects["/test-bucket/src"].header, size: size}
+			assert.NoError(t, client.Copy("large", "large-copy", CopyWithDestBucket("prod-bucket")))
+			parts := int(size / DefaultCopyPartSize)
+			if assert.Len(t, server.ranges, parts+1) {
+				assert.Contains(t, server.ranges, fmt.Sprintf("bytes=0-%d", DefaultCopyPartSize-1))
+				assert.Contains(t, server.ranges, fmt.Sprintf("bytes=%d-%d", size-1, size-1))
+			}
+			dst = server.object("/prod-bucket/large-copy")
+			if assert.NotNil(t, dst) {
+				assert.Equal(t, "a", dst.header.Get(tt.prefix+"Meta-Owner"))
+				assert.Equal(t, "application/json", dst.header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCopyWithDirectives(t *testing.T) {
+	for storageType, prefix := range map[string]string{StorageTypeS3: "X-Amz-", StorageTypeOSS: "X-Oss-"} {
+		t.Run(storageType, func(t *testing.T) {
+			server := newBucketServer(prefix)
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+			assert.NoError(t, client.Put("src", strings.NewReader(content), map[string]string{"Owner": "a"}, PutWithContentType("application/json")))
+
+			// the metadata is reset, the standard headers are kept
+			assert.NoError(t, client.Copy("src", "dst", CopyWithMetadataDirective(DirectiveReplace), CopyWithTaggingDirective(DirectiveReplace)))
+			assert.Equal(t, "REPLACE", server.copyHeader.Get(prefix+"Metadata-Directive"))
+			assert.Equal(t, "REPLACE", server.copyHeader.Get(prefix+"Tagging-Directive"))
+			assert.Empty(t, server.copyHeader.Get(prefix+"Tagging"))
+			meta, err := client.Head("dst", []string{"Owner", "Content-Type"})
+			assert.NoError(t, err)
+			assert.Empty(t, meta["Owner"])
+			assert.Equal(t, "application/json", meta["Content-Type"])
+
+			assert.NoError(t, client.Copy("src", "dst", CopyWithMeta(map[string]string{"Owner": "b"}), CopyWithTagging(map[string]string{"env": "prod", "team": "a b"})))
+			assert.Equal(t, "REPLACE", server.copyHeader.Get(prefix+"Tagging-Directive"))
+			assert.Equal(t, "env=prod&team=a+b", server.copyHeader.Get(prefix+"Tagging"))
+			meta, err = client.Head("dst", []string{"Owner"})
+			assert.NoError(t, err)
+			assert.Equal(t, "b", meta["Owner"])
+
+			// the source is copied by default
+			assert.NoError(t, client.Copy("src", "dst", CopyWithMetadataDirective(DirectiveCopy), CopyWithTaggingDirective(DirectiveCopy)))
+			assert.Empty(t, server.copyHeader.Get(prefix+"Metadata-Directive"))
+			assert.Empty(t, server.copyHeader.Get(prefix+"Tagging-Directive"))
+			meta, err = client.Head("dst", []string{"Owner"})
+			assert.NoError(t, err)
+			assert.Equal(t, "a", meta["Owner"])
+
+			// the tags of objects copied in parts are set at the upload
+			server.objects["/test-bucket/large"] = &mockObject{header: server.objects["/test-bucket/src"].header, size: MaxCopySize + 1}
+			assert.NoError(t, client.Copy("large", "large-copy", CopyWithTagging(map[string]string{"env": "prod"})))
+			assert.Equal(t, "env=prod", server.upload.Get(prefix+"Tagging"))
+
+			// the tags and the storage class of the source are copied by default, like CopyObject does
+			storageClass := map[string]string{StorageTypeS3: "STANDARD_IA", StorageTypeOSS: "IA"}[storageType]
+			server.objects["/test-bucket/large"] = &mockObject{header: http.Header{
+				prefix + "Tagging":       {"env=test"},
+				prefix + "Storage-Class": {storageClass},
+			}, size: MaxCopySize + 1}
+			assert.NoError(t, client.Copy("large", "large-copy"))
+			assert.Equal(t, "env=test", server.upload.Get(prefix+"Tagging"))
+			assert.Equal(t, storageClass, server.upload.Get(prefix+"Storage-Class"))
+			assert.NoError(t, client.Copy("large", "large-copy", CopyWithTaggingDirective(DirectiveReplace)))
+			assert.Empty(t, server.upload.Get(prefix+"Tagging"))
+			if storageType == StorageTypeS3 {
+				server.objects["/test-bucket/src"].header.Set(prefix+"Storage-Class", storageClass)
+				assert.NoError(t, client.Copy("src", "dst"))
+				assert.Equal(t, storageClass, server.copyHeader.Get(prefix+"Storage-Class"))
+			}
+		})
+	}
+}
+
+func TestCopy_MultipartErrors(t *testing.T) {
+	for _, failed := range []string{"uploads", "partNumber", "uploadId"} {
+		t.Run(failed, func(t *testing.T) {
+			server := newBucketServer("X-Amz-")
+			server.objects["/test-bucket/large"] = &mockObject{size: MaxCopySize + 1}
+			client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+				// the upload is created by ?uploads, the parts are copied by ?partNumber and completed by ?uploadId
+				query := r.URL.Query()
+				if _, ok := query[failed]; ok && (failed != "uploadId" || r.Method == http.MethodPost) {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+					return
+				}
+				server.ServeHTTP(w, r)
+			})
+			err := client.Copy("large", "large-copy")
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+		})
+	}
+}
+
+func TestCopyWithDirectives_Invalid(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put("src", strings.NewReader(content), nil))
+	for _, options := range [][]CopyOptions{
+		{CopyWithMetadataDirective("replace")},
+		{CopyWithTaggingDirective("KEEP")},
+		{CopyWithMetadataDirective(DirectiveCopy), CopyWithMeta(map[string]string{"owner": "b"})},
+		{CopyWithMetadataDirective(DirectiveCopy), CopyWithContentType("text/csv")},
+		{CopyWithTaggingDirective(DirectiveCopy), CopyWithTagging(map[string]string{"env": "prod"})},
+		{CopyWithTagging(map[string]string{"": "prod"})},
+	} {
+		assert.Error(t, client.Copy("src", "dst", options...))
+	}
+
+	err := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {}).Copy("src", "dst", CopyWithTaggingDirective(DirectiveCopy))
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+	err = newTestComponent(t, StorageTypeGCS, func(w http.ResponseWriter, r *http.Request) {}).Copy("src", "dst", CopyWithTagging(nil))
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+}
+
+func TestMove(t *testing.T) {
+	for storageType, prefix := range map[string]string{StorageTypeS3: "X-Amz-", StorageTypeOSS: "X-Oss-"} {
+		t.Run(storageType, func(t *testing.T) {
+			server := newBucketServer(prefix)
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+			attributes := []string{"Owner", "Content-Type"}
+			assert.NoError(t, client.Put("src", strings.NewReader(content), map[string]string{"Owner": "a"}, PutWithContentType("application/json")))
+			srcMeta, err := client.Head("src", attributes)
+			assert.NoError(t, err)
+
+			assert.NoError(t, client.Move("src", "dst"))
+			exists, err := client.Exists("src")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+			res, err := client.Get("dst")
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+			dstMeta, err := client.Head("dst", attributes)
+			assert.NoError(t, err)
+			assert.Equal(t, srcMeta, dstMeta)
+
+			// the source is kept if the copy fails
+			err = client.Move("not-exist", "dst")
+			assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+			assert.Error(t, client.Move("dst", "dst"))
+			assert.NotNil(t, server.object("/test-bucket/dst"))
+		})
+	}
+}
+
+func TestMove_DeleteFailed(t *testing.T) {
+	server := newBucketServer("X-Amz-")
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+	assert.NoError(t, client.Put("src", strings.NewReader(content), nil))
+
+	err := client.Move("src", "dst")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to delete the source")
+	// nothing is rolled back
+	assert.NotNil(t, server.object("/test-bucket/src"))
+	assert.NotNil(t, server.object("/test-bucket/dst"))
+}
+
+func sseCustomerKeyMD5Base64(key []byte) string {
+	sum := md5.Sum(key)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestS3_CopyWithSSECustomerKey(t *testing.T) {
+	server := newBucketServer("X-Amz-")
+	client := newTestTLSComponent(t, server.ServeHTTP)
+	srcKey := bytes.Repeat([]byte("s"), SSECustomerKeySize)
+	dstKey := bytes.Repeat([]byte("d"), SSECustomerKeySize)
+	assert.NoError(t, client.Put("src", strings.NewReader(content), nil))
+
+	assert.NoError(t, client.Copy("src", "dst", CopyWithSourceSSECustomerKey(srcKey), CopyWithSSECustomerKey(dstKey)))
+	assert.Equal(t, sseCustomerKeyMD5Base64(srcKey), server.copyHeader.Get("X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key-Md5"))
+	assert.Equal(t, sseCustomerKeyMD5Base64(dstKey), server.copyHeader.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5"))
+
+	assert.Error(t, client.Copy("src", "dst", CopyWithSSECustomerKey([]byte("short"))))
+}
+
+func TestMemory_Copy(t *testing.T) {
+	client := newTestMemory()
+	key := bytes.Repeat([]byte("k"), SSECustomerKeySize)
+	assert.NoError(t, client.Put("copy-src", strings.NewReader(content), map[string]string{"Owner": "a"}, PutWithSSECustomerKey(key)))
+
+	_, err := client.Head("copy-src", nil)
+	assert.Error(t, err)
+	assert.Error(t, client.Copy("copy-src", "copy-dst"))
+
+	assert.NoError(t, client.Copy("copy-src", "copy-dst", CopyWithSourceSSECustomerKey(key), CopyWithContentType("text/csv")))
+	res, err := client.Get("copy-dst")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	meta, err := client.Head("copy-dst", []string{"Owner", "Content-Type"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", meta["Owner"])
+	assert.Equal(t, "text/csv", meta["Content-Type"])
+
+	assert.NoError(t, client.Copy("copy-dst", "copy-dst2", CopyWithMeta(map[string]string{"Owner": "b"})))
+	meta, err = client.Head("copy-dst2", []string{"Owner"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", meta["Owner"])
+
+	// the metadata and the tags are reset or replaced
+	assert.NoError(t, client.PutObjectTagging("copy-dst2", map[string]string{"env": "dev"}))
+	assert.NoError(t, client.Copy("copy-dst2", "copy-dst3", CopyWithMetadataDirective(DirectiveReplace), CopyWithTagging(map[string]string{"env": "prod"})))
+	obj, err := client.HeadObject("copy-dst3")
+	assert.NoError(t, err)
+	assert.Empty(t, obj.UserMeta)
+	tags, err := client.GetObjectTagging("copy-dst3")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, tags)
+	assert.NoError(t, client.Copy("copy-dst2", "copy-dst3", CopyWithTaggingDirective(DirectiveReplace)))
+	tags, err = client.GetObjectTagging("copy-dst3")
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+	tags, err = client.GetObjectTagging("copy-dst2")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "dev"}, tags)
+
+	err = client.Copy("copy-dst", "copy-dst2", CopyWithDestBucket("prod-bucket"))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+	err = client.Copy("not-exist", "copy-dst2")
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+}
+
+func TestMemory_Move(t *testing.T) {
+	client := newTestMemory()
+	attributes := []string{"Owner", "Content-Type"}
+	assert.NoError(t, client.Put("move-src", strings.NewReader(content), map[string]string{"Owner": "a"}, PutWithContentType("application/json")))
+	srcMeta, err := client.Head("move-src", attributes)
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Move("move-src", "move-dst"))
+	exists, err := client.Exists("move-src")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	res, err := client.Get("move-dst")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	dstMeta, err := client.Head("move-dst", attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, srcMeta, dstMeta)
+}
+
+func TestCopyParts(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		copied int64
+	)
+	// the part size grows to keep at most maxPartCount parts
+	size := int64(maxPartCount) * MinPartSize * 2
+	err := copyParts(size, MinPartSize, 8, func(partNumber int, start, end int64) error {
+		assert.True(t, partNumber <= maxPartCount)
+		mu.Lock()
+		copied += end - start + 1
+		mu.Unlock()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, size, copied)
+
+	errPart := errors.New("part failed")
+	err = copyParts(10*MinPartSize, MinPartSize, 2, func(partNumber int, start, end int64) error {
+		if partNumber == 3 {
+			return errPart
+		}
+		return nil
+	})
+	assert.Equal(t, errPart, err)
+}
@@ -0,0 +1,56 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+// cosDefaultEndpoint is the endpoint of a region, e.g. https://cos.ap-guangzhou.myqcloud.com
+const cosDefaultEndpoint = "https://cos.%s.myqcloud.com"
+
+var _ Component = (*COS)(nil)
+
+// COS talks to Tencent Cloud Object Storage through its S3 compatible API, signed with SigV4
+// by the SecretId (AccessKeyID) and SecretKey (AccessKeySecret). The bucket name includes the APPID,
+// e.g. examplebucket-1250000000.
+//
+// It wraps S3 instead of using the COS Go SDK, so it shares the retries, interceptors, multipart uploads
+// and presigning of s3 without another sdk and its transport. The features only in the native COS API
+// aren't supported, such as its own signature and x-cos- headers, e.g. the data processing of CI,
+// and PutWithChecksum(ChecksumSHA256) returns ErrUnsupported as COS has no x-amz-checksum-sha256.
+type COS struct {
+	*S3
+}
+
+func newCOS(name string, cfg *config, logger *elog.Component) (*COS, error) {
+	// the region is part of the SigV4 scope even with a custom endpoint
+	if cfg.Region == "" {
+		return nil, errors.New("cos requires region, e.g. ap-guangzhou")
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(cosDefaultEndpoint, cfg.Region)
+	}
+	config := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		DisableSSL:       aws.Bool(!cfg.SSL),
+		Credentials:      credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.AccessKeySecret, ""),
+		Endpoint:         aws.String(endpoint),
+		S3ForcePathStyle: aws.Bool(cfg.forcePathStyle()),
+	}
+	s3Client := newS3(name, cfg, logger, config)
+	s3Client.storageClasses = cosStorageClasses
+	s3Client.noChecksumSHA256 = true
+	return &COS{S3: s3Client}, nil
+}
+
+func (c *COS) WithContext(ctx context.Context) Component {
+	return &COS{
+		S3: c.S3.WithContext(ctx).(*S3),
+	}
+}
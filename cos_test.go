@@ -0,0 +1,133 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestCOS(t *testing.T, handler http.HandlerFunc) *COS {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := DefaultConfig()
+	cfg.StorageType = StorageTypeCOS
+	cfg.Endpoint = server.URL
+	cfg.Region = "ap-guangzhou"
+	cfg.Bucket = "examplebucket-1250000000"
+	cfg.AccessKeyID = "AKIDTEST"
+	cfg.AccessKeySecret = "secret"
+	cfg.S3ForcePathStyle = true
+	client, err := newCOS("test", cfg, elog.DefaultLogger)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestCOS_Signing(t *testing.T) {
+	var auth, path, storageClass string
+	client := newTestCOS(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		path = r.URL.Path
+		storageClass = r.Header.Get("X-Amz-Storage-Class")
+		_, _ = w.Write([]byte(content))
+	})
+
+	res, err := client.WithContext(context.Background()).Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.Equal(t, "/examplebucket-1250000000/"+guid, path)
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/"), auth)
+	assert.Contains(t, auth, "/ap-guangzhou/s3/aws4_request")
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithStorageClass(StorageClassArchive)))
+	assert.Equal(t, "ARCHIVE", storageClass)
+
+	signed, err := client.SignURL(guid, 60)
+	assert.NoError(t, err)
+	assert.Contains(t, signed, "X-Amz-Signature=")
+}
+
+func TestCOS_RequestID(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := newTestCOS(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-cos-request-id", "NjE3YjE2ZjRfOTBmYTUwXzE5ZTRfMzQ3NTU3Ng==")
+		_, _ = w.Write([]byte(content))
+	})
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	_, err := client.WithContext(ctx).Get(guid)
+	assert.NoError(t, err)
+	span.End()
+
+	var reqId string
+	for _, s := range recorder.Ended() {
+		for _, attr := range s.Attributes() {
+			if attr.Key == "request-id" {
+				reqId = attr.Value.AsString()
+			}
+		}
+	}
+	assert.Equal(t, "NjE3YjE2ZjRfOTBmYTUwXzE5ZTRfMzQ3NTU3Ng==", reqId)
+}
+
+func TestCOS_Region(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StorageType = StorageTypeCOS
+	cfg.Bucket = "examplebucket-1250000000"
+	_, err := newStorage("test", cfg, elog.DefaultLogger)
+	assert.Error(t, err)
+
+	cfg.Region = "ap-shanghai"
+	comp, err := newStorage("test", cfg, elog.DefaultLogger)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://cos.ap-shanghai.myqcloud.com", comp.(*COS).Client.Endpoint)
+}
+
+// Set AWOS_COS_BUCKET, AWOS_COS_REGION, AWOS_COS_SECRET_ID and AWOS_COS_SECRET_KEY to run against a real bucket.
+func TestCOS_Integration(t *testing.T) {
+	bucket := os.Getenv("AWOS_COS_BUCKET")
+	if bucket == "" {
+		t.Skip("AWOS_COS_BUCKET not set")
+	}
+	client := DefaultContainer().Build(
+		WithStorageType(StorageTypeCOS),
+		WithBucket(bucket),
+		WithRegion(os.Getenv("AWOS_COS_REGION")),
+		WithAccessKeyID(os.Getenv("AWOS_COS_SECRET_ID")),
+		WithAccessKeySecret(os.Getenv("AWOS_COS_SECRET_KEY")),
+		WithSSL(true),
+	)
+
+	err := client.Put(guid, strings.NewReader(content), map[string]string{"head": "1"})
+	assert.NoError(t, err)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	meta, err := client.Head(guid, []string{"head"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", meta["head"])
+
+	keys, err := client.ListObject(guid, guid[0:4], "", 10, "")
+	assert.NoError(t, err)
+	assert.Contains(t, keys, guid)
+
+	signed, err := client.SignURL(guid, 60)
+	assert.NoError(t, err)
+	assert.Contains(t, signed, bucket)
+
+	assert.NoError(t, client.Del(guid))
+	_, err = client.GetAsReader(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
@@ -0,0 +1,128 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// DefaultDedupPrefix is the default prefix of the content-addressed keys of PutDedup
+const DefaultDedupPrefix = "dedup/sha256/"
+
+// MetaContentKey is the user metadata of the pointers of PutDedup, whose value is the content-addressed key
+const MetaContentKey = "content-key"
+
+type DedupOptions func(options *dedupOptions)
+
+type dedupOptions struct {
+	prefix     string
+	pointer    bool
+	putOptions []PutOptions
+}
+
+func DefaultDedupOptions() *dedupOptions {
+	return &dedupOptions{
+		prefix: DefaultDedupPrefix,
+	}
+}
+
+// DedupWithPrefix sets the prefix of the content-addressed keys, which are the prefix followed by the hex sha256
+func DedupWithPrefix(prefix string) DedupOptions {
+	return func(options *dedupOptions) {
+		options.prefix = prefix
+	}
+}
+
+// DedupWithPointer writes an empty pointer object at the key of PutDedup, whose MetaContentKey is the
+// content-addressed key, see ResolveContentKey
+func DedupWithPointer(pointer bool) DedupOptions {
+	return func(options *dedupOptions) {
+		options.pointer = pointer
+	}
+}
+
+// DedupWithPutOptions applies options to the upload of the content, such as PutWithContentType
+func DedupWithPutOptions(options ...PutOptions) DedupOptions {
+	return func(dedupOpts *dedupOptions) {
+		dedupOpts.putOptions = append(dedupOpts.putOptions, options...)
+	}
+}
+
+// DedupResult is the result of PutDedup
+type DedupResult struct {
+	// ContentKey is the content-addressed key of the content
+	ContentKey string
+	// Deduplicated is true if the content existed and wasn't uploaded
+	Deduplicated bool
+}
+
+// PutDedup uploads the content of reader to a content-addressed key, the prefix of DedupWithPrefix followed by
+// the hex sha256 of the content, unless an object of the same size and etag already exists at the key.
+// The key of the caller only gets a pointer with DedupWithPointer, otherwise the caller keeps ContentKey of the result.
+// The etag is only compared if it's the md5 of the content, which isn't for the multipart uploads and kms.
+func PutDedup(ctx context.Context, c Component, key string, reader io.ReadSeeker, meta map[string]string, options ...DedupOptions) (*DedupResult, error) {
+	dedupOpts := DefaultDedupOptions()
+	for _, opt := range options {
+		opt(dedupOpts)
+	}
+	c = c.WithContext(ctx)
+
+	pos, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), reader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reader.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	res := &DedupResult{ContentKey: dedupOpts.prefix + hex.EncodeToString(sha256Hash.Sum(nil))}
+
+	existing, err := c.HeadObject(res.ContentKey)
+	switch {
+	case err == nil:
+		res.Deduplicated = existing.Size == size && sameContentETag(existing.ETag, md5Hash.Sum(nil))
+	case !errors.Is(err, ErrObjectNotFound):
+		return nil, err
+	}
+	if !res.Deduplicated {
+		if err := c.Put(res.ContentKey, reader, meta, dedupOpts.putOptions...); err != nil {
+			return nil, err
+		}
+	}
+	if dedupOpts.pointer {
+		if err := c.Put(key, bytes.NewReader(nil), map[string]string{MetaContentKey: res.ContentKey}); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// sameContentETag compares etag with md5, etags which aren't an md5 are deemed the same
+func sameContentETag(etag string, md5 []byte) bool {
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	if len(etag) != hex.EncodedLen(len(md5)) || strings.Contains(etag, "-") {
+		return true
+	}
+	return etag == hex.EncodeToString(md5)
+}
+
+// ResolveContentKey returns the content-addressed key of a pointer of PutDedup, or key if it isn't a pointer
+func ResolveContentKey(ctx context.Context, c Component, key string) (string, error) {
+	meta, err := c.WithContext(ctx).Head(key, []string{MetaContentKey})
+	if err != nil {
+		return "", err
+	}
+	if contentKey := meta[MetaContentKey]; contentKey != "" {
+		return contentKey, nil
+	}
+	return key, nil
+}
@@ -0,0 +1,79 @@
+package awos
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutDedup(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemory()
+
+	res, err := PutDedup(ctx, client, "a.txt", strings.NewReader(content), nil, DedupWithPointer(true))
+	assert.NoError(t, err)
+	assert.False(t, res.Deduplicated)
+	assert.True(t, strings.HasPrefix(res.ContentKey, DefaultDedupPrefix))
+	data, err := client.Get(res.ContentKey)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// the same bytes are skipped
+	again, err := PutDedup(ctx, client, "b.txt", strings.NewReader(content), nil, DedupWithPointer(true))
+	assert.NoError(t, err)
+	assert.True(t, again.Deduplicated)
+	assert.Equal(t, res.ContentKey, again.ContentKey)
+	for _, key := range []string{"a.txt", "b.txt"} {
+		contentKey, err := ResolveContentKey(ctx, client, key)
+		assert.NoError(t, err)
+		assert.Equal(t, res.ContentKey, contentKey)
+	}
+	contentKey, err := ResolveContentKey(ctx, client, res.ContentKey)
+	assert.NoError(t, err)
+	assert.Equal(t, res.ContentKey, contentKey)
+
+	// a corrupted object of the content key is uploaded again
+	assert.NoError(t, client.Put(res.ContentKey, strings.NewReader("bbbbbb"), nil))
+	again, err = PutDedup(ctx, client, "c.txt", strings.NewReader(content), nil)
+	assert.NoError(t, err)
+	assert.False(t, again.Deduplicated)
+	data, err = client.Get(res.ContentKey)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+	exists, err := client.Exists("c.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	other, err := PutDedup(ctx, client, "d.txt", strings.NewReader(largeContent), nil, DedupWithPrefix("blobs/"))
+	assert.NoError(t, err)
+	assert.False(t, other.Deduplicated)
+	assert.True(t, strings.HasPrefix(other.ContentKey, "blobs/"))
+}
+
+func TestPutDedup_S3(t *testing.T) {
+	var puts int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			atomic.AddInt32(&puts, 1)
+			return
+		}
+		if atomic.LoadInt32(&puts) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("ETag", memoryETag([]byte(content)))
+	})
+
+	for i, deduplicated := range []bool{false, true} {
+		res, err := PutDedup(context.Background(), client, guid, strings.NewReader(content), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, deduplicated, res.Deduplicated, i)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&puts))
+}
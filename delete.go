@@ -0,0 +1,124 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxDeleteKeys is the max number of keys deleted by one DeleteObjects request, same for s3 and oss
+const MaxDeleteKeys = 1000
+
+// chunkKeys splits keys into batches of at most size keys
+func chunkKeys(keys []string, size int) [][]string {
+	chunks := make([][]string, 0, (len(keys)+size-1)/size)
+	for len(keys) > size {
+		chunks = append(chunks, keys[:size])
+		keys = keys[size:]
+	}
+	if len(keys) > 0 {
+		chunks = append(chunks, keys)
+	}
+	return chunks
+}
+
+// delMultiResult returns failed keys and a summary error, both nil if nothing failed
+func delMultiResult(failed map[string]error, total int) (map[string]error, error) {
+	if len(failed) == 0 {
+		return nil, nil
+	}
+	return failed, fmt.Errorf("awos: failed to delete %d of %d keys", len(failed), total)
+}
+
+type DeletePrefixOptions func(options *deletePrefixOptions)
+
+type deletePrefixOptions struct {
+	dryRun      bool
+	listOptions []ListOptions
+}
+
+func DefaultDeletePrefixOptions() *deletePrefixOptions {
+	return &deletePrefixOptions{}
+}
+
+// DeletePrefixWithDryRun lists the keys which would be deleted in DeletePrefixStats.Keys without deleting them
+func DeletePrefixWithDryRun(dryRun bool) DeletePrefixOptions {
+	return func(options *deletePrefixOptions) {
+		options.dryRun = dryRun
+	}
+}
+
+// DeletePrefixWithListOptions applies options to the listing, such as ListWithPageSize
+func DeletePrefixWithListOptions(options ...ListOptions) DeletePrefixOptions {
+	return func(prefixOptions *deletePrefixOptions) {
+		prefixOptions.listOptions = append(prefixOptions.listOptions, options...)
+	}
+}
+
+// DeletePrefixStats is the result of DeletePrefix
+type DeletePrefixStats struct {
+	// Deleted is the number of deleted keys, or the number of keys which would be deleted in a dry run
+	Deleted int
+	// Keys are the keys which would be deleted, only set in a dry run
+	Keys []string
+	// Errors are the failed keys
+	Errors map[string]error
+}
+
+// DeletePrefix deletes all the objects with prefix, listing them page by page and deleting the keys
+// in batches of MaxDeleteKeys by DelMulti. A failed key doesn't abort the others, the returned error
+// is non-nil if any key failed or the listing failed, which stops deleting. An empty prefix is rejected
+// so that a bucket isn't purged by mistake.
+func DeletePrefix(ctx context.Context, c Component, prefix string, options ...DeletePrefixOptions) (*DeletePrefixStats, error) {
+	prefixOpts := DefaultDeletePrefixOptions()
+	for _, opt := range options {
+		opt(prefixOpts)
+	}
+	stats := &DeletePrefixStats{Errors: make(map[string]error)}
+	if prefix == "" {
+		return stats, errors.New("awos: DeletePrefix with an empty prefix")
+	}
+	c = c.WithContext(ctx)
+
+	total := 0
+	batch := make([]string, 0, MaxDeleteKeys)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		total += len(batch)
+		if prefixOpts.dryRun {
+			stats.Deleted += len(batch)
+			stats.Keys = append(stats.Keys, batch...)
+		} else {
+			failed, err := c.DelMulti(batch)
+			// the error usually only summarizes the failed keys
+			if err != nil && len(failed) == 0 {
+				failed = make(map[string]error, len(batch))
+				for _, key := range batch {
+					failed[key] = err
+				}
+			}
+			stats.Deleted += len(batch) - len(failed)
+			for key, err := range failed {
+				stats.Errors[key] = err
+			}
+		}
+		batch = batch[:0]
+	}
+	walkErr := c.WalkObjects(prefix, prefix, func(obj ObjectMeta) error {
+		batch = append(batch, obj.Key)
+		if len(batch) == MaxDeleteKeys {
+			flush()
+		}
+		return nil
+	}, prefixOpts.listOptions...)
+	if walkErr != nil {
+		return stats, walkErr
+	}
+	flush()
+	if len(stats.Errors) > 0 {
+		return stats, fmt.Errorf("awos: failed to delete %d of %d keys", len(stats.Errors), total)
+	}
+	return stats, nil
+}
@@ -0,0 +1,124 @@
+package awos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deleteObjectsHandler deletes all the keys except the ones prefixed with "denied",
+// keys which don't exist are deleted successfully like s3 and oss do
+func deleteObjectsHandler(requests *int) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Quiet   bool     `xml:"Quiet"`
+			Objects []string `xml:"Object>Key"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		*requests++
+		mu.Unlock()
+
+		var res strings.Builder
+		res.WriteString("<DeleteResult>")
+		for _, key := range req.Objects {
+			if strings.HasPrefix(key, "denied") {
+				fmt.Fprintf(&res, "<Error><Key>%s</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>", key)
+			} else if !req.Quiet {
+				fmt.Fprintf(&res, "<Deleted><Key>%s</Key></Deleted>", key)
+			}
+		}
+		res.WriteString("</DeleteResult>")
+		_, _ = w.Write([]byte(res.String()))
+	}
+}
+
+func TestDelMulti(t *testing.T) {
+	keys := make([]string, 0, MaxDeleteKeys+502)
+	for i := 0; i < MaxDeleteKeys+500; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+	keys = append(keys, "denied-1", "denied-2")
+
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			requests := 0
+			client := newTestComponent(t, storageType, deleteObjectsHandler(&requests))
+
+			failed, err := client.DelMulti(keys)
+			assert.Error(t, err)
+			assert.Equal(t, 2, requests)
+			assert.Len(t, failed, 2)
+			assert.Error(t, failed["denied-1"])
+			assert.Error(t, failed["denied-2"])
+
+			failed, err = client.DelMulti(keys[:10])
+			assert.NoError(t, err)
+			assert.Empty(t, failed)
+		})
+	}
+}
+
+func TestMemory_DelMulti(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put("aaa", strings.NewReader(content), nil))
+
+	failed, err := client.DelMulti([]string{"aaa", "not-exist"})
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	ok, _ := client.Exists("aaa")
+	assert.False(t, ok)
+}
+
+func TestChunkKeys(t *testing.T) {
+	assert.Empty(t, chunkKeys(nil, 2))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, chunkKeys([]string{"a", "b", "c"}, 2))
+	assert.Equal(t, [][]string{{"a", "b"}}, chunkKeys([]string{"a", "b"}, 2))
+}
+
+func TestDeletePrefix(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	for i := 0; i < MaxDeleteKeys+5; i++ {
+		assert.NoError(t, client.Put(fmt.Sprintf("dir/%04d", i), strings.NewReader(content), nil))
+	}
+	assert.NoError(t, client.Put("other", strings.NewReader(content), nil))
+
+	stats, err := DeletePrefix(context.Background(), client, "dir/", DeletePrefixWithDryRun(true))
+	assert.NoError(t, err)
+	assert.Equal(t, MaxDeleteKeys+5, stats.Deleted)
+	assert.Len(t, stats.Keys, MaxDeleteKeys+5)
+	assert.Equal(t, "dir/0000", stats.Keys[0])
+	assert.Equal(t, 0, client.Calls("DelMulti"))
+	ok, _ := client.Exists("dir/0000")
+	assert.True(t, ok)
+
+	client.FailNext("DelMulti", "dir/0003", 1, errors.New("denied"))
+	stats, err = DeletePrefix(context.Background(), client, "dir/", DeletePrefixWithListOptions(ListWithPageSize(100)))
+	assert.Error(t, err)
+	assert.Equal(t, MaxDeleteKeys+4, stats.Deleted)
+	assert.Empty(t, stats.Keys)
+	assert.Len(t, stats.Errors, 1)
+	assert.Error(t, stats.Errors["dir/0003"])
+
+	// the failed key is deleted by the next call
+	stats, err = DeletePrefix(context.Background(), client, "dir/")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Deleted)
+	keys, err := client.ListObject("", "", "", 10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other"}, keys)
+
+	_, err = DeletePrefix(context.Background(), client, "")
+	assert.Error(t, err)
+}
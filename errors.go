@@ -0,0 +1,503 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrObjectNotFound is returned when the object doesn't exist,
+// use errors.Is(err, ErrObjectNotFound) to check it.
+var ErrObjectNotFound = errors.New("awos: object not found")
+
+// ErrAccessDenied is returned when the credentials aren't allowed to access the object or the bucket (403),
+// use errors.Is(err, ErrAccessDenied) to tell it from ErrObjectNotFound.
+var ErrAccessDenied = errors.New("awos: access denied")
+
+// ErrUnsupported is returned when the operation isn't supported by the storage backend.
+var ErrUnsupported = errors.New("awos: operation not supported by the storage backend")
+
+// ErrNotModified is returned by conditional gets when the object matches the given etag.
+var ErrNotModified = errors.New("awos: object not modified")
+
+// ErrPreconditionFailed is returned by conditional puts when the condition doesn't hold.
+var ErrPreconditionFailed = errors.New("awos: precondition failed")
+
+// ErrObjectArchived is returned when getting an archived object which isn't restored, see RestoreObject.
+var ErrObjectArchived = errors.New("awos: object is archived, restore it first")
+
+// ErrChecksumMismatch is returned when the content doesn't match its checksum, see PutWithChecksum and GetWithChecksumValidation.
+var ErrChecksumMismatch = errors.New("awos: checksum mismatch")
+
+// ErrBucketAlreadyExists is returned by CreateBucket when the bucket name is taken by another account.
+var ErrBucketAlreadyExists = errors.New("awos: bucket already exists and is owned by another account")
+
+// ErrBucketNotFound is returned by Ping when the bucket doesn't exist.
+var ErrBucketNotFound = errors.New("awos: bucket not found")
+
+// ErrStopWalk is returned by the callback of WalkObjects to stop walking, WalkObjects returns nil then.
+var ErrStopWalk = errors.New("awos: stop walk")
+
+// ErrWriterAborted is returned by the writes of an ObjectWriter after Abort.
+var ErrWriterAborted = errors.New("awos: the object writer is aborted")
+
+// ErrCircuitOpen is returned without sending the request when the circuit breaker is open.
+var ErrCircuitOpen error = circuitOpenError{}
+
+type circuitOpenError struct{}
+
+func (circuitOpenError) Error() string {
+	return "awos: circuit breaker is open"
+}
+
+// Temporary returns false to stop the aws sdk from retrying
+func (circuitOpenError) Temporary() bool {
+	return false
+}
+
+// ErrIdleTimeout is returned when no bytes are sent for WriteTimeout or received for ReadTimeout,
+// it's a net.Error whose Timeout returns true.
+var ErrIdleTimeout error = idleTimeoutError{}
+
+type idleTimeoutError struct{}
+
+func (idleTimeoutError) Error() string {
+	return "awos: idle timeout, no bytes transferred within the timeout"
+}
+
+func (idleTimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary returns true so that the stalled request is retried like other network errors
+func (idleTimeoutError) Temporary() bool {
+	return true
+}
+
+// RequestError is implemented by the errors of failed requests, which carry the request id of the backend
+// to file support tickets, use errors.As to get it, or ErrorRequestID and ErrorTraceID.
+type RequestError interface {
+	error
+	// RequestID returns the request id in the response headers, empty if there is no response
+	RequestID() string
+	// TraceID returns the trace id of the context of the request, empty if it isn't traced
+	TraceID() string
+}
+
+// ErrorRequestID returns the request id of the backend in err, empty if there is none.
+func ErrorRequestID(err error) string {
+	var reqErr interface{ RequestID() string }
+	if errors.As(err, &reqErr) {
+		return reqErr.RequestID()
+	}
+	return ""
+}
+
+// ErrorTraceID returns the trace id of the failed request in err, empty if there is none.
+func ErrorTraceID(err error) string {
+	var reqErr RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.TraceID()
+	}
+	return ""
+}
+
+// requestError adds the request id and the trace id to err
+type requestError struct {
+	err       error
+	requestID string
+	traceID   string
+}
+
+// withRequestID wraps err with requestID and the trace id of ctx, err is returned as is if there are neither of them
+func withRequestID(ctx context.Context, err error, requestID string) error {
+	if err == nil {
+		return nil
+	}
+	traceID := traceIDFromContext(ctx)
+	if requestID == "" && traceID == "" {
+		return err
+	}
+	return &requestError{err: err, requestID: requestID, traceID: traceID}
+}
+
+// traceIDFromContext returns the trace id of the span in ctx, empty if there is none
+func traceIDFromContext(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// requestErrorMessage appends the ids to msg, the request id is skipped if msg has it already
+func requestErrorMessage(msg string, requestID string, traceID string) string {
+	if requestID != "" && !strings.Contains(msg, requestID) {
+		msg += ", request id: " + requestID
+	}
+	if traceID != "" {
+		msg += ", trace id: " + traceID
+	}
+	return msg
+}
+
+func (e *requestError) Error() string {
+	return requestErrorMessage(e.err.Error(), e.requestID, e.traceID)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func (e *requestError) RequestID() string {
+	return e.requestID
+}
+
+func (e *requestError) TraceID() string {
+	return e.traceID
+}
+
+// s3RequestError adds the request id and the trace id to the errors of the aws sdk,
+// it's still an awserr.RequestFailure, since the sdk errors are checked by type assertions
+type s3RequestError struct {
+	err        awserr.Error
+	statusCode int
+	requestID  string
+	traceID    string
+}
+
+func (e *s3RequestError) Error() string {
+	return requestErrorMessage(e.err.Error(), e.requestID, e.traceID)
+}
+
+func (e *s3RequestError) Code() string {
+	return e.err.Code()
+}
+
+func (e *s3RequestError) Message() string {
+	return e.err.Message()
+}
+
+func (e *s3RequestError) OrigErr() error {
+	return e.err.OrigErr()
+}
+
+func (e *s3RequestError) Unwrap() error {
+	return e.err
+}
+
+// StatusCode is 0 if the request failed without a response
+func (e *s3RequestError) StatusCode() int {
+	return e.statusCode
+}
+
+func (e *s3RequestError) RequestID() string {
+	return e.requestID
+}
+
+func (e *s3RequestError) TraceID() string {
+	return e.traceID
+}
+
+// s3RequestIDHandler wraps the error of the request after the last retry into s3RequestError,
+// with the request id in the response headers of storageType, the x-amz-request-id read by the sdk isn't sent by gcs
+func s3RequestIDHandler(storageType string) func(r *request.Request) {
+	return func(r *request.Request) {
+		aerr, ok := r.Error.(awserr.Error)
+		if !ok {
+			return
+		}
+		reqErr := &s3RequestError{err: aerr, requestID: requestID(storageType, r.HTTPResponse), traceID: traceIDFromContext(r.Context())}
+		if rerr, ok := aerr.(awserr.RequestFailure); ok {
+			reqErr.statusCode = rerr.StatusCode()
+			if reqErr.requestID == "" {
+				reqErr.requestID = rerr.RequestID()
+			}
+		}
+		if reqErr.requestID == "" && reqErr.traceID == "" {
+			return
+		}
+		r.Error = reqErr
+	}
+}
+
+// wrappedError wraps the backend error into one of the awos errors,
+// so that it can still be inspected for debugging.
+type wrappedError struct {
+	kind error
+	err  error
+}
+
+func (e *wrappedError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+func (e *wrappedError) Is(target error) bool {
+	return target == e.kind
+}
+
+func isS3NotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	if aerr.Code() == s3.ErrCodeNoSuchKey {
+		return true
+	}
+	// HEAD responses have no body, so there is no error code but "NotFound"
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() == 404 && rerr.Code() == "NotFound"
+	}
+	return false
+}
+
+// isS3AccessDenied checks the status code, since HEAD responses have no body and the code is "Forbidden"
+func isS3AccessDenied(err error) bool {
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() == 403 || rerr.Code() == "AccessDenied"
+	}
+	return false
+}
+
+func isS3NotModified(err error) bool {
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() == 304
+	}
+	return false
+}
+
+func isS3PreconditionFailed(err error) bool {
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() == 412
+	}
+	return false
+}
+
+func isS3Archived(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == s3.ErrCodeInvalidObjectState
+	}
+	return false
+}
+
+// isChecksumErrorCode checks the error codes of s3 and oss for a Content-MD5 or checksum which doesn't match the content
+func isChecksumErrorCode(code string) bool {
+	return code == "BadDigest" || code == "InvalidDigest"
+}
+
+func isS3ChecksumMismatch(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return isChecksumErrorCode(aerr.Code())
+	}
+	return false
+}
+
+// isS3CircuitOpen checks the error returned by the transport, which the aws sdk wraps without Unwrap
+func isS3CircuitOpen(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return errors.Is(aerr.OrigErr(), ErrCircuitOpen)
+	}
+	return false
+}
+
+func isS3IdleTimeout(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return errors.Is(aerr.OrigErr(), ErrIdleTimeout)
+	}
+	return false
+}
+
+// s3ContextError returns the context error of canceled requests, which the aws sdk wraps without Unwrap.
+// The requests timed out by DefaultTimeout fail as request errors, since the context of the sdk isn't done.
+func s3ContextError(err error) error {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return nil
+	}
+	switch aerr.Code() {
+	case request.CanceledErrorCode:
+		if errors.Is(aerr.OrigErr(), context.DeadlineExceeded) {
+			return context.DeadlineExceeded
+		}
+		return context.Canceled
+	case request.ErrCodeRequestError:
+		if errors.Is(aerr.OrigErr(), context.DeadlineExceeded) {
+			return context.DeadlineExceeded
+		}
+	}
+	return nil
+}
+
+func isOSSNotFound(err error) bool {
+	if oerr, ok := err.(oss.ServiceError); ok {
+		return oerr.StatusCode == 404
+	}
+	return false
+}
+
+func isOSSAccessDenied(err error) bool {
+	if oerr, ok := err.(oss.ServiceError); ok {
+		return oerr.StatusCode == 403
+	}
+	return false
+}
+
+// isOSSPreconditionFailed also treats FileAlreadyExists as failed precondition,
+// which is returned when x-oss-forbid-overwrite is set
+func isOSSPreconditionFailed(err error) bool {
+	if oerr, ok := err.(oss.ServiceError); ok {
+		return oerr.StatusCode == 412 || oerr.Code == "FileAlreadyExists"
+	}
+	return false
+}
+
+func isOSSArchived(err error) bool {
+	if oerr, ok := err.(oss.ServiceError); ok {
+		return oerr.Code == "InvalidObjectState"
+	}
+	return false
+}
+
+func isOSSChecksumMismatch(err error) bool {
+	if oerr, ok := err.(oss.ServiceError); ok {
+		return isChecksumErrorCode(oerr.Code)
+	}
+	return false
+}
+
+// isOSSNotModified checks the error message, since the sdk returns a plain error for 3xx responses
+func isOSSNotModified(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "oss: service returned 304")
+}
+
+// errorStatusCode returns the status code of the error responses of the backends, 0 for other errors
+func errorStatusCode(err error) int {
+	var (
+		s3Err    awserr.RequestFailure
+		ossErr   oss.ServiceError
+		azureErr *AzureError
+		fakeErr  *FakeStatusError
+	)
+	switch {
+	case errors.As(err, &s3Err):
+		return s3Err.StatusCode()
+	case errors.As(err, &ossErr):
+		return ossErr.StatusCode
+	case errors.As(err, &azureErr):
+		return azureErr.StatusCode
+	case errors.As(err, &fakeErr):
+		return fakeErr.StatusCode
+	}
+	return 0
+}
+
+// isThrottled checks whether the backend rejected the request to slow down, with 429 or 503 responses
+func isThrottled(err error) bool {
+	statusCode := errorStatusCode(err)
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// AzureError is an error response of azure blob storage
+type AzureError struct {
+	StatusCode int
+	// Code is the error code, such as BlobNotFound
+	Code      string
+	Message   string
+	RequestID string
+}
+
+func (e *AzureError) Error() string {
+	return fmt.Sprintf("azure: %d %s: %s, request id: %s", e.StatusCode, e.Code, e.Message, e.RequestID)
+}
+
+// wrapS3Error translates s3 errors to awos errors
+func wrapS3Error(err error) error {
+	if isS3NotFound(err) {
+		return &wrappedError{kind: ErrObjectNotFound, err: err}
+	}
+	if isS3NotModified(err) {
+		return &wrappedError{kind: ErrNotModified, err: err}
+	}
+	if isS3PreconditionFailed(err) {
+		return &wrappedError{kind: ErrPreconditionFailed, err: err}
+	}
+	if isS3Archived(err) {
+		return &wrappedError{kind: ErrObjectArchived, err: err}
+	}
+	// archived objects are also rejected with 403
+	if isS3AccessDenied(err) {
+		return &wrappedError{kind: ErrAccessDenied, err: err}
+	}
+	if isS3ChecksumMismatch(err) {
+		return &wrappedError{kind: ErrChecksumMismatch, err: err}
+	}
+	if isS3CircuitOpen(err) {
+		return &wrappedError{kind: ErrCircuitOpen, err: err}
+	}
+	if isS3IdleTimeout(err) {
+		return &wrappedError{kind: ErrIdleTimeout, err: err}
+	}
+	if ctxErr := s3ContextError(err); ctxErr != nil {
+		return &wrappedError{kind: ctxErr, err: err}
+	}
+	return err
+}
+
+// wrapOSSError translates oss errors to awos errors
+func wrapOSSError(err error) error {
+	if isOSSNotFound(err) {
+		return &wrappedError{kind: ErrObjectNotFound, err: err}
+	}
+	if isOSSNotModified(err) {
+		return &wrappedError{kind: ErrNotModified, err: err}
+	}
+	if isOSSPreconditionFailed(err) {
+		return &wrappedError{kind: ErrPreconditionFailed, err: err}
+	}
+	if isOSSArchived(err) {
+		return &wrappedError{kind: ErrObjectArchived, err: err}
+	}
+	// archived objects are also rejected with 403
+	if isOSSAccessDenied(err) {
+		return &wrappedError{kind: ErrAccessDenied, err: err}
+	}
+	if isOSSChecksumMismatch(err) {
+		return &wrappedError{kind: ErrChecksumMismatch, err: err}
+	}
+	return err
+}
+
+// wrapAzureError translates azure errors to awos errors
+func wrapAzureError(err *AzureError) error {
+	switch {
+	// HEAD responses have no body, the code is only in the x-ms-error-code header,
+	// and copying a missing source fails with CannotVerifyCopySource
+	case err.StatusCode == http.StatusNotFound && (err.Code == "BlobNotFound" || err.Code == "CannotVerifyCopySource" || err.Code == ""):
+		return &wrappedError{kind: ErrObjectNotFound, err: err}
+	// such as AuthorizationFailure and AuthorizationPermissionMismatch
+	case err.StatusCode == http.StatusForbidden:
+		return &wrappedError{kind: ErrAccessDenied, err: err}
+	case err.StatusCode == http.StatusNotModified:
+		return &wrappedError{kind: ErrNotModified, err: err}
+	// BlobAlreadyExists is returned for If-None-Match: *
+	case err.StatusCode == http.StatusPreconditionFailed || err.Code == "BlobAlreadyExists":
+		return &wrappedError{kind: ErrPreconditionFailed, err: err}
+	case err.Code == "BlobArchived":
+		return &wrappedError{kind: ErrObjectArchived, err: err}
+	case err.Code == "Md5Mismatch":
+		return &wrappedError{kind: ErrChecksumMismatch, err: err}
+	}
+	return err
+}
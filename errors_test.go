@@ -0,0 +1,228 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestErrObjectNotFound_S3(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	})
+
+	_, err := client.Get(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+	var aerr awserr.Error
+	assert.True(t, errors.As(err, &aerr))
+	assert.Equal(t, "NoSuchKey", aerr.Code())
+
+	_, err = client.GetAsReader(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+
+	_, _, err = client.GetWithMeta(guid, nil)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+
+	_, err = client.Head(guid, nil)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+}
+
+func TestErrObjectNotFound_OSS(t *testing.T) {
+	client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	})
+
+	_, err := client.Get(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+	var oerr oss.ServiceError
+	assert.True(t, errors.As(err, &oerr))
+	assert.Equal(t, "NoSuchKey", oerr.Code)
+
+	_, err = client.GetAsReader(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+
+	_, _, err = client.GetWithMeta(guid, nil)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+
+	_, err = client.Head(guid, nil)
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+}
+
+func TestErrObjectNotFound_OtherErrors(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`))
+	})
+
+	_, err := client.Get(guid)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrObjectNotFound), err)
+}
+
+func TestErrAccessDenied(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if storageType == StorageTypeAzure {
+					w.Header().Set("x-ms-error-code", "AuthorizationPermissionMismatch")
+				}
+				w.WriteHeader(http.StatusForbidden)
+				if r.Method == http.MethodHead {
+					return
+				}
+				if storageType == StorageTypeAzure {
+					_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><Error><Code>AuthorizationPermissionMismatch</Code><Message>This request is not authorized.</Message></Error>`))
+					return
+				}
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+			}
+			var client Component
+			if storageType == StorageTypeAzure {
+				client = newTestAzure(t, handler)
+			} else {
+				client = newTestComponent(t, storageType, handler)
+			}
+
+			_, err := client.Get(guid)
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+			assert.False(t, errors.Is(err, ErrObjectNotFound))
+			switch storageType {
+			case StorageTypeS3:
+				var aerr awserr.RequestFailure
+				if assert.True(t, errors.As(err, &aerr)) {
+					assert.Equal(t, "AccessDenied", aerr.Code())
+				}
+			case StorageTypeOSS:
+				var oerr oss.ServiceError
+				if assert.True(t, errors.As(err, &oerr)) {
+					assert.Equal(t, "AccessDenied", oerr.Code)
+				}
+			case StorageTypeAzure:
+				var azureErr *AzureError
+				if assert.True(t, errors.As(err, &azureErr)) {
+					assert.Equal(t, "AuthorizationPermissionMismatch", azureErr.Code)
+				}
+			}
+
+			err = client.Put(guid, strings.NewReader(content), nil)
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+			err = client.Del(guid)
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+			_, err = client.Head(guid, nil)
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+			_, err = client.HeadObject(guid)
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+
+			// a forbidden object isn't a missing one
+			exists, err := client.Exists(guid)
+			assert.True(t, errors.Is(err, ErrAccessDenied), "%v", err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestExists(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodHead, r.Method)
+				switch {
+				case strings.HasSuffix(r.URL.Path, "missing"):
+					w.WriteHeader(http.StatusNotFound)
+				case strings.HasSuffix(r.URL.Path, "forbidden"):
+					w.WriteHeader(http.StatusForbidden)
+				case strings.HasSuffix(r.URL.Path, "broken"):
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			exists, err := client.Exists(guid)
+			assert.NoError(t, err)
+			assert.True(t, exists)
+
+			exists, err = client.Exists("missing")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+
+			for _, key := range []string{"forbidden", "broken"} {
+				exists, err = client.Exists(key)
+				assert.Error(t, err, key)
+				assert.False(t, errors.Is(err, ErrObjectNotFound))
+				assert.False(t, exists)
+			}
+		})
+	}
+}
+
+func TestErrorRequestID(t *testing.T) {
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+	traceID := span.SpanContext().TraceID().String()
+
+	tests := []struct {
+		storageType string
+		header      string
+	}{
+		{StorageTypeS3, "X-Amz-Request-Id"},
+		{StorageTypeOSS, "X-Oss-Request-Id"},
+		{StorageTypeGCS, "X-Guploader-Uploadid"},
+		{StorageTypeCOS, "X-Cos-Request-Id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.storageType, func(t *testing.T) {
+			client := newTestComponent(t, tt.storageType, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(tt.header, "req-1")
+				if r.URL.Path == "/test-bucket/missing" || r.URL.Path == "/missing" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>not found</Message><RequestId>req-1</RequestId></Error>`))
+					return
+				}
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>denied</Message><RequestId>req-1</RequestId></Error>`))
+			}).WithContext(ctx)
+
+			_, err := client.Get("key")
+			assert.Error(t, err)
+			assert.Equal(t, "req-1", ErrorRequestID(err))
+			assert.Equal(t, traceID, ErrorTraceID(err))
+			assert.Contains(t, err.Error(), "req-1")
+			assert.Contains(t, err.Error(), traceID)
+			var reqErr RequestError
+			assert.True(t, errors.As(err, &reqErr))
+
+			// the awos errors are still matched
+			_, err = client.Get("missing")
+			assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+			assert.Equal(t, "req-1", ErrorRequestID(err))
+			assert.Equal(t, traceID, ErrorTraceID(err))
+		})
+	}
+
+	// without a span, the request id is still returned
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Request-Id", "req-2")
+		w.WriteHeader(http.StatusForbidden)
+	})
+	_, err := client.Get("key")
+	assert.Equal(t, "req-2", ErrorRequestID(err))
+	assert.Equal(t, "", ErrorTraceID(err))
+	var aerr awserr.RequestFailure
+	assert.True(t, errors.As(err, &aerr))
+	assert.Equal(t, http.StatusForbidden, aerr.StatusCode())
+
+	assert.Equal(t, "", ErrorRequestID(ErrObjectNotFound))
+	assert.Equal(t, "", ErrorTraceID(nil))
+}
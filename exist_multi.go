@@ -0,0 +1,82 @@
+package awos
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultExistMultiConcurrency is the default number of keys ExistMulti checks concurrently
+const DefaultExistMultiConcurrency = 16
+
+type ExistMultiOptions func(options *existMultiOptions)
+
+type existMultiOptions struct {
+	concurrency int
+}
+
+func DefaultExistMultiOptions() *existMultiOptions {
+	return &existMultiOptions{
+		concurrency: DefaultExistMultiConcurrency,
+	}
+}
+
+// ExistMultiWithConcurrency sets the number of keys checked concurrently
+func ExistMultiWithConcurrency(concurrency int) ExistMultiOptions {
+	return func(options *existMultiOptions) {
+		if concurrency > 0 {
+			options.concurrency = concurrency
+		}
+	}
+}
+
+// ExistMulti checks whether keys exist by concurrent Exists, e.g. before a batch import. The missing keys are false,
+// the first other error cancels the checks left and is returned.
+func ExistMulti(ctx context.Context, c Component, keys []string, options ...ExistMultiOptions) (map[string]bool, error) {
+	existOpts := DefaultExistMultiOptions()
+	for _, opt := range options {
+		opt(existOpts)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	client := c.WithContext(ctx)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		res      = make(map[string]bool, len(keys))
+	)
+	pending := make(chan string)
+	for i := 0; i < existOpts.concurrency && i < len(keys); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range pending {
+				err := ctx.Err()
+				var exists bool
+				if err == nil {
+					exists, err = client.Exists(key)
+				}
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					res[key] = exists
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		pending <- key
+	}
+	close(pending)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return res, nil
+}
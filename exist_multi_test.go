@@ -0,0 +1,44 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExistMulti(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemory()
+	var keys []string
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		keys = append(keys, key)
+		if i%3 == 0 {
+			assert.NoError(t, client.Put(key, strings.NewReader(content), nil))
+		}
+	}
+
+	res, err := ExistMulti(ctx, client, keys, ExistMultiWithConcurrency(4))
+	assert.NoError(t, err)
+	assert.Len(t, res, len(keys))
+	for i, key := range keys {
+		assert.Equal(t, i%3 == 0, res[key], key)
+	}
+
+	res, err = ExistMulti(ctx, client, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestExistMulti_Error(t *testing.T) {
+	fake := NewFakeClient("test-bucket")
+	errHead := errors.New("head failed")
+	fake.FailNext("Exists", "b", 1, errHead)
+
+	_, err := ExistMulti(context.Background(), fake, []string{"a", "b", "c"})
+	assert.ErrorIs(t, err, errHead)
+}
@@ -0,0 +1,77 @@
+package awos
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// ObjectExpiration is when a lifecycle rule of the bucket expires the object,
+// computed by s3 and oss from the x-amz-expiration and x-oss-expiration headers
+type ObjectExpiration struct {
+	// ExpiryDate is when the object expires
+	ExpiryDate time.Time
+	// RuleID is the id of the lifecycle rule
+	RuleID string
+}
+
+var (
+	expirationExpiryDateRegexp = regexp.MustCompile(`expiry-date="([^"]+)"`)
+	expirationRuleIDRegexp     = regexp.MustCompile(`rule-id="([^"]*)"`)
+)
+
+// parseObjectExpiration parses the expiration header of s3 and oss, such as
+// expiry-date="Fri, 23 Dec 2012 00:00:00 GMT", rule-id="picture-deletion-rule".
+// It returns nil if the header is empty, which means no lifecycle rule expires the object.
+func parseObjectExpiration(header string) (*ObjectExpiration, error) {
+	if header == "" {
+		return nil, nil
+	}
+	expiryDate := expirationExpiryDateRegexp.FindStringSubmatch(header)
+	if expiryDate == nil {
+		return nil, fmt.Errorf("invalid expiration header %q", header)
+	}
+	t, err := http.ParseTime(expiryDate[1])
+	if err != nil {
+		return nil, err
+	}
+	expiration := &ObjectExpiration{ExpiryDate: t}
+	if ruleID := expirationRuleIDRegexp.FindStringSubmatch(header); ruleID != nil {
+		expiration.RuleID = ruleID[1]
+	}
+	return expiration, nil
+}
+
+// PutResult is the result of Put, see PutWithResult
+type PutResult struct {
+	// ETag without quotes
+	ETag string
+	// VersionID is empty if the bucket isn't versioned
+	VersionID string
+	// Expiration is nil unless a lifecycle rule expires the object
+	Expiration *ObjectExpiration
+}
+
+// PutWithResult sets res to the result of Put once it succeeds. Only for Put of s3-like and oss,
+// res is left unchanged by the other backends and methods.
+func PutWithResult(res *PutResult) PutOptions {
+	return func(options *putOptions) {
+		options.result = res
+	}
+}
+
+// setPutResult sets the result of PutWithResult by the headers of the put response, vendorPrefix is the
+// prefix of the backend specific headers, such as "X-Oss-". The object is put already, so an invalid
+// expiration header is left nil instead of failing the put.
+func (o *putOptions) setPutResult(header http.Header, vendorPrefix string) {
+	if o.result == nil {
+		return
+	}
+	expiration, _ := parseObjectExpiration(header.Get(vendorPrefix + "Expiration"))
+	*o.result = PutResult{
+		ETag:       trimETag(header.Get("ETag")),
+		VersionID:  header.Get(vendorPrefix + "Version-Id"),
+		Expiration: expiration,
+	}
+}
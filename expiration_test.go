@@ -0,0 +1,61 @@
+package awos
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseObjectExpiration(t *testing.T) {
+	expiration, err := parseObjectExpiration("")
+	assert.NoError(t, err)
+	assert.Nil(t, expiration)
+
+	expiration, err = parseObjectExpiration(`expiry-date="Fri, 23 Dec 2012 00:00:00 GMT", rule-id="picture-deletion-rule"`)
+	assert.NoError(t, err)
+	assert.Equal(t, &ObjectExpiration{
+		ExpiryDate: time.Date(2012, 12, 23, 0, 0, 0, 0, time.UTC),
+		RuleID:     "picture-deletion-rule",
+	}, expiration)
+
+	_, err = parseObjectExpiration(`rule-id="picture-deletion-rule"`)
+	assert.Error(t, err)
+	_, err = parseObjectExpiration(`expiry-date="tomorrow"`)
+	assert.Error(t, err)
+}
+
+func TestPutWithResult(t *testing.T) {
+	const expirationHeader = `expiry-date="Fri, 23 Dec 2012 00:00:00 GMT", rule-id="picture-deletion-rule"`
+	expires := time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC)
+	for storageType, vendorPrefix := range map[string]string{StorageTypeS3: "X-Amz-", StorageTypeOSS: "X-Oss-"} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(vendorPrefix+"Expiration", expirationHeader)
+				w.Header().Set(vendorPrefix+"Version-Id", "v1")
+				switch r.Method {
+				case http.MethodPut:
+					assert.Equal(t, expires.Format(http.TimeFormat), r.Header.Get("Expires"))
+					w.Header().Set("ETag", `"etag"`)
+				case http.MethodHead:
+					w.Header().Set("Content-Length", "6")
+					w.Header().Set("Last-Modified", expires.Format(http.TimeFormat))
+				}
+			})
+
+			var res PutResult
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithExpireTime(expires), PutWithResult(&res)))
+			expiration := &ObjectExpiration{
+				ExpiryDate: time.Date(2012, 12, 23, 0, 0, 0, 0, time.UTC),
+				RuleID:     "picture-deletion-rule",
+			}
+			assert.Equal(t, PutResult{ETag: "etag", VersionID: "v1", Expiration: expiration}, res)
+
+			meta, err := client.HeadObject(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, expiration, meta.Expiration)
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+)
+
+// FailoverEndpoint is a fallback of the reads, see FailoverEndpoints of config.
+// Empty fields are the ones of the primary bucket.
+type FailoverEndpoint struct {
+	Endpoint string
+	Bucket   string
+	Region   string
+}
+
+type failoverTarget struct {
+	c Component
+	// label is the endpoint of FailoverServed, the endpoint or the region followed by the bucket
+	label string
+}
+
+// failoverComponent sends the reads to the targets in order until one succeeds, the first target is the primary one
+// which all the writes go to. Only the endpoint failures are failed over, see isEndpointFailure.
+type failoverComponent struct {
+	targets []failoverTarget
+	name    string
+	bucket  string
+	ctx     context.Context
+	logger  *elog.Component
+	metrics MetricsSink
+}
+
+func newFailoverComponent(name string, cfg *config, logger *elog.Component, primary Component) (Component, error) {
+	f := &failoverComponent{
+		targets: []failoverTarget{{c: primary, label: failoverLabel(&cfg.bucketConfig)}},
+		name:    name,
+		bucket:  cfg.Bucket,
+		ctx:     context.Background(),
+		logger:  logger,
+		metrics: cfg.metrics(name),
+	}
+	for _, endpoint := range cfg.FailoverEndpoints {
+		fallback := *cfg
+		if endpoint.Endpoint != "" {
+			fallback.Endpoint = endpoint.Endpoint
+		}
+		if endpoint.Bucket != "" {
+			fallback.Bucket = endpoint.Bucket
+		}
+		if endpoint.Region != "" {
+			fallback.Region = endpoint.Region
+		}
+		c, err := newStorage(name, &fallback, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failover endpoint %s: %w", failoverLabel(&fallback.bucketConfig), err)
+		}
+		f.targets = append(f.targets, failoverTarget{c: c, label: failoverLabel(&fallback.bucketConfig)})
+	}
+	return f, nil
+}
+
+func failoverLabel(cfg *bucketConfig) string {
+	if cfg.Endpoint != "" {
+		return cfg.Endpoint + "/" + cfg.Bucket
+	}
+	return cfg.Region + "/" + cfg.Bucket
+}
+
+// isEndpointFailure reports whether err is a failure of the endpoint rather than of the request, such as the network
+// errors, 5xx and throttling left after the retries, or an open circuit breaker. Errors of unknown backends count too.
+func isEndpointFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	for _, kind := range []error{ErrObjectNotFound, ErrAccessDenied, ErrObjectArchived, ErrNotModified,
+		ErrPreconditionFailed, ErrChecksumMismatch, ErrBucketNotFound, ErrUnsupported} {
+		if errors.Is(err, kind) {
+			return false
+		}
+	}
+	if statusCode := errorStatusCode(err); statusCode != 0 {
+		return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// noFailoverError is returned by the fn of read to return err without failing over
+type noFailoverError struct {
+	err error
+}
+
+func (e noFailoverError) Error() string {
+	return e.err.Error()
+}
+
+// read calls fn with the targets in order until it doesn't fail with an endpoint failure,
+// the error of the last target is returned if all of them failed
+func (f *failoverComponent) read(operation string, key string, fn func(c Component) error) error {
+	var err error
+	for i, target := range f.targets {
+		if i > 0 {
+			// the reads of a done context fail on every endpoint
+			if f.ctx.Err() != nil {
+				return err
+			}
+			f.logger.Warn("failover read", elog.FieldName(f.name), elog.FieldMethod(operation), elog.FieldKey(key),
+				elog.FieldAddr(target.label), elog.FieldErr(err))
+		}
+		err = fn(target.c)
+		if nf, ok := err.(noFailoverError); ok {
+			err = nf.err
+		} else if isEndpointFailure(err) {
+			continue
+		}
+		f.metrics.FailoverServed(operation, f.bucket, target.label)
+		return err
+	}
+	return err
+}
+
+func (f *failoverComponent) primary() Component {
+	return f.targets[0].c
+}
+
+func (f *failoverComponent) WithContext(ctx context.Context) Component {
+	targets := make([]failoverTarget, 0, len(f.targets))
+	for _, target := range f.targets {
+		targets = append(targets, failoverTarget{c: target.c.WithContext(ctx), label: target.label})
+	}
+	return &failoverComponent{targets: targets, name: f.name, bucket: f.bucket, ctx: ctx, logger: f.logger, metrics: f.metrics}
+}
+
+func (f *failoverComponent) Get(key string, options ...GetOptions) (res string, err error) {
+	err = f.read("Get", key, func(c Component) error {
+		res, err = c.Get(key, options...)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) GetBytes(key string, options ...GetOptions) (res []byte, err error) {
+	err = f.read("GetBytes", key, func(c Component) error {
+		res, err = c.GetBytes(key, options...)
+		return err
+	})
+	return res, err
+}
+
+// GetAsReader only fails over opening the object, the reads of the body aren't
+func (f *failoverComponent) GetAsReader(key string, options ...GetOptions) (res io.ReadCloser, err error) {
+	err = f.read("GetAsReader", key, func(c Component) error {
+		res, err = c.GetAsReader(key, options...)
+		return err
+	})
+	return res, err
+}
+
+// GetMulti fails over each key separately
+func (f *failoverComponent) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(f.ctx, keys, options, f.GetBytes)
+}
+
+func (f *failoverComponent) RestoreObject(key string, options ...RestoreOptions) error {
+	return f.primary().RestoreObject(key, options...)
+}
+
+func (f *failoverComponent) GetVersion(key string, versionID string, options ...GetOptions) (res string, err error) {
+	err = f.read("GetVersion", key, func(c Component) error {
+		res, err = c.GetVersion(key, versionID, options...)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) GetWithMeta(key string, attributes []string, options ...GetOptions) (res io.ReadCloser, meta map[string]string, err error) {
+	err = f.read("GetWithMeta", key, func(c Component) error {
+		res, meta, err = c.GetWithMeta(key, attributes, options...)
+		return err
+	})
+	return res, meta, err
+}
+
+func (f *failoverComponent) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	return f.primary().Put(key, reader, meta, options...)
+}
+
+func (f *failoverComponent) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	return f.primary().MultipartUpload(key, reader, meta, options...)
+}
+
+func (f *failoverComponent) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	return f.primary().Append(key, reader, position, options...)
+}
+
+func (f *failoverComponent) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	return f.primary().Copy(srcKey, dstKey, options...)
+}
+
+func (f *failoverComponent) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return f.primary().Move(srcKey, dstKey, options...)
+}
+
+func (f *failoverComponent) Del(key string) error {
+	return f.primary().Del(key)
+}
+
+func (f *failoverComponent) DelVersion(key string, versionID string, options ...DelOptions) error {
+	return f.primary().DelVersion(key, versionID, options...)
+}
+
+func (f *failoverComponent) DelMulti(keys []string) (map[string]error, error) {
+	return f.primary().DelMulti(keys)
+}
+
+func (f *failoverComponent) Head(key string, meta []string, options ...GetOptions) (res map[string]string, err error) {
+	err = f.read("Head", key, func(c Component) error {
+		res, err = c.Head(key, meta, options...)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) HeadObject(key string, options ...GetOptions) (res *ObjectMeta, err error) {
+	err = f.read("HeadObject", key, func(c Component) error {
+		res, err = c.HeadObject(key, options...)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) (res []string, err error) {
+	err = f.read("ListObject", prefix, func(c Component) error {
+		res, err = c.ListObject(key, prefix, marker, maxKeys, delimiter)
+		return err
+	})
+	return res, err
+}
+
+// ListObjectsWithDelimiter fails over marker as well, which must be valid for the fallback endpoints,
+// the markers of azure are opaque to other accounts.
+func (f *failoverComponent) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (res *ListResult, err error) {
+	err = f.read("ListObjectsWithDelimiter", prefix, func(c Component) error {
+		res, err = c.ListObjectsWithDelimiter(key, prefix, marker, maxKeys, delimiter)
+		return err
+	})
+	return res, err
+}
+
+// ListObjectsIter isn't failed over, the pages of an iterator must come from the same endpoint
+func (f *failoverComponent) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return f.primary().ListObjectsIter(key, prefix, options...)
+}
+
+// WalkObjects fails over only before fn is called, a walk isn't restarted on another endpoint
+// after some of the objects were walked
+func (f *failoverComponent) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	walked := false
+	return f.read("WalkObjects", prefix, func(c Component) error {
+		err := c.WalkObjects(key, prefix, func(obj ObjectMeta) error {
+			walked = true
+			return fn(obj)
+		}, options...)
+		if walked && err != nil {
+			return noFailoverError{err: err}
+		}
+		return err
+	})
+}
+
+func (f *failoverComponent) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) (res []ObjectVersion, err error) {
+	err = f.read("ListObjectVersions", prefix, func(c Component) error {
+		res, err = c.ListObjectVersions(key, prefix, keyMarker, versionIDMarker, maxKeys)
+		return err
+	})
+	return res, err
+}
+
+// SignURL signs the urls of the primary endpoint, the signing doesn't send requests
+func (f *failoverComponent) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	return f.primary().SignURL(key, expired, options...)
+}
+
+func (f *failoverComponent) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	return f.primary().SignURLForPut(key, expired, options...)
+}
+
+func (f *failoverComponent) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	return f.primary().SignPostPolicy(key, expired, options...)
+}
+
+func (f *failoverComponent) GetAndDecompress(key string) (res string, err error) {
+	err = f.read("GetAndDecompress", key, func(c Component) error {
+		res, err = c.GetAndDecompress(key)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) GetAndDecompressAsReader(key string) (res io.ReadCloser, err error) {
+	err = f.read("GetAndDecompressAsReader", key, func(c Component) error {
+		res, err = c.GetAndDecompressAsReader(key)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	return f.primary().CompressAndPut(key, reader, meta, options...)
+}
+
+func (f *failoverComponent) Range(key string, offset int64, length int64) (res io.ReadCloser, err error) {
+	err = f.read("Range", key, func(c Component) error {
+		res, err = c.Range(key, offset, length)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) Exists(key string) (res bool, err error) {
+	err = f.read("Exists", key, func(c Component) error {
+		res, err = c.Exists(key)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) GetObjectTagging(key string) (res map[string]string, err error) {
+	err = f.read("GetObjectTagging", key, func(c Component) error {
+		res, err = c.GetObjectTagging(key)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) PutObjectTagging(key string, tags map[string]string) error {
+	return f.primary().PutObjectTagging(key, tags)
+}
+
+func (f *failoverComponent) GetObjectACL(key string) (res string, err error) {
+	err = f.read("GetObjectACL", key, func(c Component) error {
+		res, err = c.GetObjectACL(key)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) PutObjectACL(key string, acl string) error {
+	return f.primary().PutObjectACL(key, acl)
+}
+
+func (f *failoverComponent) GetObjectLegalHold(key string) (res bool, err error) {
+	err = f.read("GetObjectLegalHold", key, func(c Component) error {
+		res, err = c.GetObjectLegalHold(key)
+		return err
+	})
+	return res, err
+}
+
+func (f *failoverComponent) PutObjectLegalHold(key string, on bool) error {
+	return f.primary().PutObjectLegalHold(key, on)
+}
+
+func (f *failoverComponent) GetObjectRetention(key string) (mode string, retainUntil time.Time, err error) {
+	err = f.read("GetObjectRetention", key, func(c Component) error {
+		mode, retainUntil, err = c.GetObjectRetention(key)
+		return err
+	})
+	return mode, retainUntil, err
+}
+
+func (f *failoverComponent) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return f.primary().PutObjectRetention(key, mode, retainUntil)
+}
+
+// Close closes the clients of all the endpoints, the error of the first failed one is returned
+func (f *failoverComponent) Close() error {
+	var err error
+	for _, target := range f.targets {
+		if cerr := target.c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// the resumable uploads, the bucket operations and the symlinks are on the primary endpoint
+func (f *failoverComponent) createResumableUpload(key string, meta map[string]string, putOptions *putOptions) (string, error) {
+	u, ok := f.primary().(resumableUploader)
+	if !ok {
+		return "", fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.createResumableUpload(key, meta, putOptions)
+}
+
+func (f *failoverComponent) uploadResumablePart(key string, uploadID string, partNumber int, data []byte, putOptions *putOptions) (string, error) {
+	u, ok := f.primary().(resumableUploader)
+	if !ok {
+		return "", fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.uploadResumablePart(key, uploadID, partNumber, data, putOptions)
+}
+
+func (f *failoverComponent) listResumableParts(key string, uploadID string) ([]UploadedPart, error) {
+	u, ok := f.primary().(resumableUploader)
+	if !ok {
+		return nil, fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.listResumableParts(key, uploadID)
+}
+
+func (f *failoverComponent) completeResumableUpload(key string, uploadID string, parts []UploadedPart, putOptions *putOptions) error {
+	u, ok := f.primary().(resumableUploader)
+	if !ok {
+		return fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.completeResumableUpload(key, uploadID, parts, putOptions)
+}
+
+func (f *failoverComponent) bucketExists() (bool, error) {
+	m, ok := f.primary().(bucketManager)
+	if !ok {
+		return false, fmt.Errorf("BucketExists: %w", ErrUnsupported)
+	}
+	return m.bucketExists()
+}
+
+func (f *failoverComponent) createBucket(options *createBucketOptions) error {
+	m, ok := f.primary().(bucketManager)
+	if !ok {
+		return fmt.Errorf("CreateBucket: %w", ErrUnsupported)
+	}
+	return m.createBucket(options)
+}
+
+func (f *failoverComponent) ping() error {
+	p, ok := f.primary().(pinger)
+	if !ok {
+		return fmt.Errorf("Ping: %w", ErrUnsupported)
+	}
+	return p.ping()
+}
+
+func (f *failoverComponent) putSymlink(symlinkKey string, targetKey string) error {
+	s, ok := f.primary().(symlinker)
+	if !ok {
+		return fmt.Errorf("PutSymlink: %w", ErrUnsupported)
+	}
+	return s.putSymlink(symlinkKey, targetKey)
+}
+
+func (f *failoverComponent) getSymlink(symlinkKey string) (string, error) {
+	s, ok := f.primary().(symlinker)
+	if !ok {
+		return "", fmt.Errorf("GetSymlink: %w", ErrUnsupported)
+	}
+	return s.getSymlink(symlinkKey)
+}
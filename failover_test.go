@@ -0,0 +1,76 @@
+package awos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailoverEndpoints(t *testing.T) {
+	var primaryReqs, secondaryPuts int64
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut:
+			atomic.AddInt64(&secondaryPuts, 1)
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = w.Write([]byte(content))
+		}
+	}))
+	t.Cleanup(secondary.Close)
+
+	var notFound int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&primaryReqs, 1)
+		// the failed puts would be retried by Put
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if atomic.LoadInt32(&notFound) == 1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}, WithFailoverEndpoints(FailoverEndpoint{Endpoint: secondary.URL, Bucket: "test-bucket-replica"}),
+		WithEnableRetryInterceptor(true), WithRetryBaseDelay(time.Millisecond))
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.True(t, atomic.LoadInt64(&primaryReqs) > 0)
+	assert.Equal(t, float64(1), testutil.ToFloat64(failoverServedCounter.WithLabelValues("oss", "", "test-bucket", "Get", secondary.URL+"/test-bucket-replica")))
+
+	meta, err := client.HeadObject(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), meta.Size)
+
+	// the missing objects of the fallbacks aren't failed over further
+	_, err = client.Get("missing")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// writes stay on the primary endpoint
+	assert.ErrorIs(t, client.Put(guid, strings.NewReader(content), nil), ErrAccessDenied)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&secondaryPuts))
+
+	// the reads answered by the primary endpoint aren't failed over
+	atomic.StoreInt32(&notFound, 1)
+	_, err = client.Get(guid)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestIsEndpointFailure(t *testing.T) {
+	assert.False(t, isEndpointFailure(nil))
+	assert.False(t, isEndpointFailure(ErrObjectNotFound))
+	assert.False(t, isEndpointFailure(&FakeStatusError{StatusCode: http.StatusForbidden}))
+	assert.True(t, isEndpointFailure(&FakeStatusError{StatusCode: http.StatusInternalServerError}))
+	assert.True(t, isEndpointFailure(&FakeStatusError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, isEndpointFailure(ErrCircuitOpen))
+}
@@ -0,0 +1,433 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var _ Component = (*Fake)(nil)
+
+// Fake is an in-memory Component whose operations fail or stall on demand, to test retries and error handling
+// without a network. Operations are named after the methods of Component, e.g. "Get" or "Put",
+// batch operations like GetMulti and DelMulti are matched per key.
+type Fake struct {
+	m     *Memory
+	rules *fakeRules
+	ctx   context.Context
+}
+
+type fakeRules struct {
+	mu    sync.Mutex
+	rules []*fakeRule
+	calls map[string]int
+}
+
+type fakeRule struct {
+	operation string
+	// empty matches all the keys
+	key string
+	// negative means forever
+	times int
+	err   error
+	delay time.Duration
+}
+
+// FakeStatusError is injected to act like a backend answering with the http status code, e.g. 503
+type FakeStatusError struct {
+	StatusCode int
+}
+
+func (e *FakeStatusError) Error() string {
+	return fmt.Sprintf("awos: fake response %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// NewFakeClient returns a Fake with an empty bucket, which behaves like the memory storage until failures are injected
+func NewFakeClient(bucket string) *Fake {
+	return &Fake{
+		m:     newMemory(bucket),
+		rules: &fakeRules{calls: make(map[string]int)},
+		ctx:   context.Background(),
+	}
+}
+
+func (f *Fake) add(rule *fakeRule) {
+	f.rules.mu.Lock()
+	f.rules.rules = append(f.rules.rules, rule)
+	f.rules.mu.Unlock()
+}
+
+// FailNext makes the next n calls of operation on key fail with err, an empty key matches all the keys
+func (f *Fake) FailNext(operation string, key string, n int, err error) {
+	if n > 0 {
+		f.add(&fakeRule{operation: operation, key: key, times: n, err: err})
+	}
+}
+
+// Fail makes all the calls of operation on key fail with err until Reset, an empty key matches all the keys
+func (f *Fake) Fail(operation string, key string, err error) {
+	f.add(&fakeRule{operation: operation, key: key, times: -1, err: err})
+}
+
+// Delay stalls all the calls of operation on key for d until Reset, calls fail with the context error
+// if the context of WithContext is done before, which simulates timeouts.
+func (f *Fake) Delay(operation string, key string, d time.Duration) {
+	f.add(&fakeRule{operation: operation, key: key, times: -1, delay: d})
+}
+
+// Reset removes all the injected failures and resets the call counts, the objects are kept
+func (f *Fake) Reset() {
+	f.rules.mu.Lock()
+	f.rules.rules = nil
+	f.rules.calls = make(map[string]int)
+	f.rules.mu.Unlock()
+}
+
+// Calls returns the number of calls of operation, including the failed ones
+func (f *Fake) Calls(operation string) int {
+	f.rules.mu.Lock()
+	defer f.rules.mu.Unlock()
+	return f.rules.calls[operation]
+}
+
+// inject counts the call and applies the matching rules, the delays add up and the first error is returned
+func (f *Fake) inject(operation string, key string) error {
+	var (
+		delay time.Duration
+		err   error
+	)
+	f.rules.mu.Lock()
+	f.rules.calls[operation]++
+	rules := f.rules.rules[:0]
+	for _, r := range f.rules.rules {
+		if r.operation == operation && (r.key == "" || r.key == key) && (r.delay > 0 || err == nil) {
+			delay += r.delay
+			if err == nil {
+				err = r.err
+			}
+			if r.times > 0 {
+				r.times--
+			}
+		}
+		if r.times != 0 {
+			rules = append(rules, r)
+		}
+	}
+	f.rules.rules = rules
+	f.rules.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-f.ctx.Done():
+			return f.ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+func (f *Fake) WithContext(ctx context.Context) Component {
+	return &Fake{
+		m:     f.m.WithContext(ctx).(*Memory),
+		rules: f.rules,
+		ctx:   ctx,
+	}
+}
+
+func (f *Fake) Get(key string, options ...GetOptions) (string, error) {
+	if err := f.inject("Get", key); err != nil {
+		return "", err
+	}
+	return f.m.Get(key, options...)
+}
+
+func (f *Fake) GetBytes(key string, options ...GetOptions) ([]byte, error) {
+	if err := f.inject("GetBytes", key); err != nil {
+		return nil, err
+	}
+	return f.m.GetBytes(key, options...)
+}
+
+func (f *Fake) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	if err := f.inject("GetAsReader", key); err != nil {
+		return nil, err
+	}
+	return f.m.GetAsReader(key, options...)
+}
+
+func (f *Fake) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(f.ctx, keys, options, func(key string, options ...GetOptions) ([]byte, error) {
+		if err := f.inject("GetMulti", key); err != nil {
+			return nil, err
+		}
+		return f.m.GetBytes(key, options...)
+	})
+}
+
+func (f *Fake) RestoreObject(key string, options ...RestoreOptions) error {
+	if err := f.inject("RestoreObject", key); err != nil {
+		return err
+	}
+	return f.m.RestoreObject(key, options...)
+}
+
+func (f *Fake) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	if err := f.inject("GetVersion", key); err != nil {
+		return "", err
+	}
+	return f.m.GetVersion(key, versionID, options...)
+}
+
+func (f *Fake) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
+	if err := f.inject("GetWithMeta", key); err != nil {
+		return nil, nil, err
+	}
+	return f.m.GetWithMeta(key, attributes, options...)
+}
+
+func (f *Fake) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	if err := f.inject("Put", key); err != nil {
+		return err
+	}
+	return f.m.Put(key, reader, meta, options...)
+}
+
+func (f *Fake) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	if err := f.inject("MultipartUpload", key); err != nil {
+		return err
+	}
+	return f.m.MultipartUpload(key, reader, meta, options...)
+}
+
+func (f *Fake) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	if err := f.inject("Append", key); err != nil {
+		return 0, err
+	}
+	return f.m.Append(key, reader, position, options...)
+}
+
+func (f *Fake) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	if err := f.inject("Copy", srcKey); err != nil {
+		return err
+	}
+	return f.m.Copy(srcKey, dstKey, options...)
+}
+
+// Move goes through Copy and Del of the Fake, so their failures apply too
+func (f *Fake) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	if err := f.inject("Move", srcKey); err != nil {
+		return err
+	}
+	return move(f, srcKey, dstKey, options)
+}
+
+func (f *Fake) Del(key string) error {
+	if err := f.inject("Del", key); err != nil {
+		return err
+	}
+	return f.m.Del(key)
+}
+
+func (f *Fake) DelVersion(key string, versionID string, options ...DelOptions) error {
+	if err := f.inject("DelVersion", key); err != nil {
+		return err
+	}
+	return f.m.DelVersion(key, versionID, options...)
+}
+
+func (f *Fake) DelMulti(keys []string) (map[string]error, error) {
+	failed := make(map[string]error)
+	deleted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if err := f.inject("DelMulti", key); err != nil {
+			failed[key] = err
+			continue
+		}
+		deleted = append(deleted, key)
+	}
+	if _, err := f.m.DelMulti(deleted); err != nil {
+		return nil, err
+	}
+	return delMultiResult(failed, len(keys))
+}
+
+func (f *Fake) Head(key string, meta []string, options ...GetOptions) (map[string]string, error) {
+	if err := f.inject("Head", key); err != nil {
+		return nil, err
+	}
+	return f.m.Head(key, meta, options...)
+}
+
+func (f *Fake) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	if err := f.inject("HeadObject", key); err != nil {
+		return nil, err
+	}
+	return f.m.HeadObject(key, options...)
+}
+
+func (f *Fake) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	if err := f.inject("ListObject", key); err != nil {
+		return nil, err
+	}
+	return f.m.ListObject(key, prefix, marker, maxKeys, delimiter)
+}
+
+func (f *Fake) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	if err := f.inject("ListObjectsWithDelimiter", key); err != nil {
+		return nil, err
+	}
+	return f.m.ListObjectsWithDelimiter(key, prefix, marker, maxKeys, delimiter)
+}
+
+// ListObjectsIter applies the failures of ListObjectsIter to each page
+func (f *Fake) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return newObjectIterator(f.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		if err := f.inject("ListObjectsIter", key); err != nil {
+			return nil, err
+		}
+		return f.m.listPage(key, prefix, marker, maxKeys)
+	}, options...)
+}
+
+func (f *Fake) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	if err := f.inject("WalkObjects", key); err != nil {
+		return err
+	}
+	return f.m.WalkObjects(key, prefix, fn, options...)
+}
+
+func (f *Fake) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	if err := f.inject("ListObjectVersions", key); err != nil {
+		return nil, err
+	}
+	return f.m.ListObjectVersions(key, prefix, keyMarker, versionIDMarker, maxKeys)
+}
+
+func (f *Fake) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	if err := f.inject("SignURL", key); err != nil {
+		return "", err
+	}
+	return f.m.SignURL(key, expired, options...)
+}
+
+func (f *Fake) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	if err := f.inject("SignURLForPut", key); err != nil {
+		return "", err
+	}
+	return f.m.SignURLForPut(key, expired, options...)
+}
+
+func (f *Fake) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	if err := f.inject("SignPostPolicy", key); err != nil {
+		return nil, err
+	}
+	return f.m.SignPostPolicy(key, expired, options...)
+}
+
+func (f *Fake) GetAndDecompress(key string) (string, error) {
+	if err := f.inject("GetAndDecompress", key); err != nil {
+		return "", err
+	}
+	return f.m.GetAndDecompress(key)
+}
+
+func (f *Fake) GetAndDecompressAsReader(key string) (io.ReadCloser, error) {
+	if err := f.inject("GetAndDecompressAsReader", key); err != nil {
+		return nil, err
+	}
+	return f.m.GetAndDecompressAsReader(key)
+}
+
+func (f *Fake) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	if err := f.inject("CompressAndPut", key); err != nil {
+		return err
+	}
+	return f.m.CompressAndPut(key, reader, meta, options...)
+}
+
+func (f *Fake) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	if err := f.inject("Range", key); err != nil {
+		return nil, err
+	}
+	return f.m.Range(key, offset, length)
+}
+
+func (f *Fake) Exists(key string) (bool, error) {
+	if err := f.inject("Exists", key); err != nil {
+		return false, err
+	}
+	return f.m.Exists(key)
+}
+
+func (f *Fake) GetObjectTagging(key string) (map[string]string, error) {
+	if err := f.inject("GetObjectTagging", key); err != nil {
+		return nil, err
+	}
+	return f.m.GetObjectTagging(key)
+}
+
+func (f *Fake) PutObjectTagging(key string, tags map[string]string) error {
+	if err := f.inject("PutObjectTagging", key); err != nil {
+		return err
+	}
+	return f.m.PutObjectTagging(key, tags)
+}
+
+func (f *Fake) GetObjectACL(key string) (string, error) {
+	if err := f.inject("GetObjectACL", key); err != nil {
+		return "", err
+	}
+	return f.m.GetObjectACL(key)
+}
+
+func (f *Fake) PutObjectACL(key string, acl string) error {
+	if err := f.inject("PutObjectACL", key); err != nil {
+		return err
+	}
+	return f.m.PutObjectACL(key, acl)
+}
+
+func (f *Fake) GetObjectLegalHold(key string) (bool, error) {
+	if err := f.inject("GetObjectLegalHold", key); err != nil {
+		return false, err
+	}
+	return f.m.GetObjectLegalHold(key)
+}
+
+func (f *Fake) PutObjectLegalHold(key string, on bool) error {
+	if err := f.inject("PutObjectLegalHold", key); err != nil {
+		return err
+	}
+	return f.m.PutObjectLegalHold(key, on)
+}
+
+func (f *Fake) GetObjectRetention(key string) (string, time.Time, error) {
+	if err := f.inject("GetObjectRetention", key); err != nil {
+		return "", time.Time{}, err
+	}
+	return f.m.GetObjectRetention(key)
+}
+
+func (f *Fake) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	if err := f.inject("PutObjectRetention", key); err != nil {
+		return err
+	}
+	return f.m.PutObjectRetention(key, mode, retainUntil)
+}
+
+func (f *Fake) Close() error {
+	return f.m.Close()
+}
+
+// ping injects the rules of the operation "Ping", so that readiness probes can be tested
+func (f *Fake) ping() error {
+	if err := f.inject("Ping", ""); err != nil {
+		return err
+	}
+	return f.m.ping()
+}
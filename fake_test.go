@@ -0,0 +1,105 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake_FailNext(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+
+	unavailable := &FakeStatusError{StatusCode: http.StatusServiceUnavailable}
+	client.FailNext("Get", "", 2, unavailable)
+	for i := 0; i < 2; i++ {
+		_, err := client.Get(guid)
+		var statusErr *FakeStatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	}
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.Equal(t, 3, client.Calls("Get"))
+
+	// other operations aren't affected
+	client.FailNext("Put", guid, 1, unavailable)
+	_, err = client.GetBytes(guid)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Put("other", strings.NewReader(content), nil))
+	assert.Equal(t, unavailable, client.Put(guid, strings.NewReader(content), nil))
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+}
+
+func TestFake_FailAndReset(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+
+	client.Fail("HeadObject", guid, ErrObjectNotFound)
+	for i := 0; i < 3; i++ {
+		_, err := client.HeadObject(guid)
+		assert.True(t, errors.Is(err, ErrObjectNotFound))
+	}
+	client.Reset()
+	assert.Equal(t, 0, client.Calls("HeadObject"))
+	_, err := client.HeadObject(guid)
+	assert.NoError(t, err)
+}
+
+func TestFake_Delay(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	client.Delay("Put", guid, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := client.WithContext(ctx).Put(guid, strings.NewReader(content), nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	exists, err := client.Exists(guid)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	client.Reset()
+	client.Delay("Put", guid, 20*time.Millisecond)
+	start = time.Now()
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
+
+func TestFake_Batch(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, client.Put(key, strings.NewReader(key), nil))
+	}
+
+	unavailable := &FakeStatusError{StatusCode: http.StatusServiceUnavailable}
+	client.FailNext("GetMulti", "b", 1, unavailable)
+	res, failed := client.GetMulti([]string{"a", "b", "c"})
+	assert.Equal(t, map[string][]byte{"a": []byte("a"), "c": []byte("c")}, res)
+	assert.Equal(t, map[string]error{"b": unavailable}, failed)
+
+	client.FailNext("DelMulti", "c", 1, unavailable)
+	failed, err := client.DelMulti([]string{"a", "c"})
+	assert.Error(t, err)
+	assert.Equal(t, map[string]error{"c": unavailable}, failed)
+	exists, _ := client.Exists("a")
+	assert.False(t, exists)
+	exists, _ = client.Exists("c")
+	assert.True(t, exists)
+
+	// Move keeps the source if its delete fails
+	client.FailNext("Del", "b", 1, unavailable)
+	err = client.Move("b", "d")
+	assert.True(t, errors.Is(err, unavailable))
+	exists, _ = client.Exists("b")
+	assert.True(t, exists)
+	exists, _ = client.Exists("d")
+	assert.True(t, exists)
+}
@@ -0,0 +1,1026 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// fsMetaSuffix is the suffix of the sidecar files keeping the metadata of objects, keys can't end with it
+const fsMetaSuffix = ".awos-meta.json"
+
+var _ Component = (*FS)(nil)
+
+// FS keeps objects as files under <FSRootDir>/<bucket>, for local development without cloud credentials.
+// Keys are slash separated paths, so a key can't also be the directory of other keys, e.g. "a" and "a/b".
+// The metadata of an object is kept in a json sidecar file next to it, files put there by hand are objects
+// with the content type of their extension. It is safe for concurrent use within a process,
+// but not by processes sharing the directory.
+type FS struct {
+	BucketName string
+	root       string
+	mu         *sync.RWMutex
+	ctx        context.Context
+	// detectContentType is EnableContentTypeDetection of config
+	detectContentType bool
+}
+
+// fsSidecar is the content of the sidecar file, the size and modification time are the ones of the object file
+type fsSidecar struct {
+	Meta              map[string]string `json:"meta,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	Appendable        bool              `json:"appendable,omitempty"`
+	ACL               string            `json:"acl,omitempty"`
+	SSECustomerKeyMD5 string            `json:"sseCustomerKeyMD5,omitempty"`
+}
+
+func newFS(cfg *config) (*FS, error) {
+	if cfg.FSRootDir == "" {
+		return nil, errors.New("fs requires fsRootDir")
+	}
+	root, err := filepath.Abs(cfg.FSRootDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(root, cfg.Bucket), 0755); err != nil {
+		return nil, err
+	}
+	return &FS{
+		BucketName:        cfg.Bucket,
+		root:              root,
+		mu:                &sync.RWMutex{},
+		ctx:               context.Background(),
+		detectContentType: cfg.EnableContentTypeDetection,
+	}, nil
+}
+
+func (f *FS) WithContext(ctx context.Context) Component {
+	return &FS{
+		BucketName:        f.BucketName,
+		root:              f.root,
+		mu:                f.mu,
+		ctx:               ctx,
+		detectContentType: f.detectContentType,
+	}
+}
+
+// path returns the file of key in bucket, keys which aren't clean relative paths are rejected
+func (f *FS) path(bucket string, key string) (string, error) {
+	invalid := key == "" || strings.HasSuffix(key, fsMetaSuffix) || strings.ContainsRune(key, 0)
+	for _, elem := range strings.Split(key, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			invalid = true
+		}
+	}
+	if invalid {
+		return "", fmt.Errorf("fs: invalid key %q", key)
+	}
+	return filepath.Join(f.root, bucket, filepath.FromSlash(key)), nil
+}
+
+// stat returns the metadata of the object file, or ErrObjectNotFound. The caller must hold f.mu.
+func (f *FS) stat(file string) (*memoryObject, error) {
+	info, err := os.Stat(file)
+	// ENOTDIR if a parent directory of the key is an object
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) || (err == nil && !info.Mode().IsRegular()) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &memoryObject{
+		meta:    make(map[string]string),
+		headers: make(map[string]string),
+	}
+	data, err := ioutil.ReadFile(file + fsMetaSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var sidecar fsSidecar
+		if err := json.Unmarshal(data, &sidecar); err != nil {
+			return nil, fmt.Errorf("fs: invalid metadata of %s: %w", file, err)
+		}
+		for k, v := range sidecar.Meta {
+			obj.meta[k] = v
+		}
+		for k, v := range sidecar.Headers {
+			obj.headers[k] = v
+		}
+		obj.tags = sidecar.Tags
+		obj.appendable = sidecar.Appendable
+		obj.acl = sidecar.ACL
+		obj.sseCustomerKeyMD5 = sidecar.SSECustomerKeyMD5
+	}
+	if obj.headers["Content-Type"] == "" {
+		obj.headers["Content-Type"] = mime.TypeByExtension(path.Ext(file))
+		if obj.headers["Content-Type"] == "" {
+			obj.headers["Content-Type"] = "application/octet-stream"
+		}
+	}
+	if obj.headers["ETag"] == "" {
+		etag, err := fsETag(file)
+		if err != nil {
+			return nil, err
+		}
+		obj.headers["ETag"] = etag
+	}
+	obj.headers["Content-Length"] = strconv.FormatInt(info.Size(), 10)
+	obj.headers["Last-Modified"] = info.ModTime().UTC().Format(http.TimeFormat)
+	return obj, nil
+}
+
+// fsETag is the quoted hex md5 of the file, like memoryETag
+func fsETag(file string) (string, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`, nil
+}
+
+// write replaces the object file with the content of r and its sidecar with obj, setting the ETag of obj.
+// The caller must hold f.mu.
+func (f *FS) write(file string, r io.Reader, obj *memoryObject) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".awos-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := md5.New()
+	if r != nil {
+		if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	obj.headers["ETag"] = `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+	if err := f.writeSidecar(file, obj); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// writeSidecar replaces the sidecar of the object file with obj. The caller must hold f.mu.
+func (f *FS) writeSidecar(file string, obj *memoryObject) error {
+	sidecar := fsSidecar{
+		Meta:              obj.meta,
+		Headers:           make(map[string]string, len(obj.headers)),
+		Tags:              obj.tags,
+		Appendable:        obj.appendable,
+		ACL:               obj.acl,
+		SSECustomerKeyMD5: obj.sseCustomerKeyMD5,
+	}
+	for k, v := range obj.headers {
+		// the file itself has them
+		if k != "Content-Length" && k != "Last-Modified" {
+			sidecar.Headers[k] = v
+		}
+	}
+	data, err := json.Marshal(sidecar)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".awos-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file+fsMetaSuffix)
+}
+
+// fsReader reads a section of the object file
+type fsReader struct {
+	io.Reader
+	io.Closer
+}
+
+// open opens the object, respecting the get options like memoryObject.read
+func (f *FS) open(key string, options []GetOptions) (io.ReadCloser, *memoryObject, error) {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if _, err := getOpts.byteRange(); err != nil {
+		return nil, nil, err
+	}
+	if getOpts.versionID != nil {
+		return nil, nil, errFSVersioning
+	}
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	obj, err := f.stat(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := obj.checkSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, nil, err
+	}
+	if obj.archived() {
+		return nil, nil, ErrObjectArchived
+	}
+	if getOpts.ifNoneMatch != nil {
+		etag := strings.Trim(*getOpts.ifNoneMatch, `"`)
+		if etag == "*" || etag == strings.Trim(obj.headers["ETag"], `"`) {
+			return nil, nil, ErrNotModified
+		}
+	}
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if getOpts.rangeStart == nil {
+		body, err := getOpts.decodeBody(r, obj.headers["Content-Encoding"])
+		if err != nil {
+			return nil, nil, err
+		}
+		return body, obj, nil
+	}
+
+	size, _ := strconv.ParseInt(obj.headers["Content-Length"], 10, 64)
+	start, end := *getOpts.rangeStart, getOpts.rangeEnd+1
+	if start >= size {
+		r.Close()
+		return nil, nil, fmt.Errorf("fs range: invalid range start %d, object size %d", start, size)
+	}
+	if getOpts.rangeEnd < 0 || end > size {
+		end = size
+	}
+	return fsReader{Reader: io.NewSectionReader(r, start, end-start), Closer: r}, obj, nil
+}
+
+// object returns the metadata of the object in the bucket of f
+func (f *FS) object(key string) (*memoryObject, error) {
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.stat(file)
+}
+
+// don't forget to call the close() method of the io.ReadCloser
+func (f *FS) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	r, _, err := f.open(key, options)
+	return r, err
+}
+
+// don't forget to call the close() method of the io.ReadCloser
+func (f *FS) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
+	r, obj, err := f.open(key, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, obj.attributes(attributes), nil
+}
+
+func (f *FS) Get(key string, options ...GetOptions) (string, error) {
+	var res string
+	err := f.read(key, options, func(data []byte) error {
+		res = string(data)
+		return nil
+	})
+	return res, err
+}
+
+func (f *FS) GetBytes(key string, options ...GetOptions) ([]byte, error) {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	var res []byte
+	err := f.read(key, options, func(data []byte) error {
+		res = getOpts.copyBytes(data)
+		return nil
+	})
+	return res, err
+}
+
+// read reads the whole object and calls consume with the content, see readBody
+func (f *FS) read(key string, options []GetOptions, consume func(data []byte) error) error {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	r, _, err := f.open(key, options)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return readBody(r, getOpts.buffer, consume)
+}
+
+// GetMulti stops fetching when the context of WithContext is done
+func (f *FS) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(f.ctx, keys, options, f.GetBytes)
+}
+
+func (f *FS) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	return f.GetAsReader(key, GetWithRange(offset, offset+length-1))
+}
+
+func (f *FS) GetAndDecompress(key string) (string, error) {
+	r, obj, err := f.open(key, nil)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	compressor := obj.meta[MetaCompressor]
+	if compressor != "" {
+		if compressor != "snappy" {
+			return "", errors.New("GetAndDecompress only supports snappy for now, got " + compressor)
+		}
+		rawBytes, err := ioutil.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		decodedBytes, err := snappy.Decode(nil, rawBytes)
+		if err != nil {
+			return "", err
+		}
+		return string(decodedBytes), nil
+	}
+	data, err := decompressBody(obj.headers["Content-Encoding"], r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (f *FS) GetAndDecompressAsReader(key string) (io.ReadCloser, error) {
+	result, err := f.GetAndDecompress(key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(result)), nil
+}
+
+func (f *FS) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return err
+	}
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	if putOptions.objectLock() {
+		return errObjectLock
+	}
+	if f.detectContentType {
+		if err := putOptions.detectContentType(key, reader); err != nil {
+			return err
+		}
+	}
+	// fs keeps the backend neutral storage class like memory
+	storageClass, err := backendStorageClass(ossStorageClasses, putOptions)
+	if err != nil {
+		return err
+	}
+	meta, err = putOptions.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	reader, err = putOptions.compressBody(reader)
+	if err != nil {
+		return err
+	}
+
+	obj := &memoryObject{
+		meta:    make(map[string]string),
+		headers: make(map[string]string),
+	}
+	for k, v := range meta {
+		obj.meta[k] = v
+	}
+	obj.headers["Content-Type"] = putOptions.contentType
+	if storageClass != "" {
+		obj.headers["Storage-Class"] = storageClass
+	}
+	if putOptions.contentEncoding != nil {
+		obj.headers["Content-Encoding"] = *putOptions.contentEncoding
+	}
+	if putOptions.contentDisposition != nil {
+		obj.headers["Content-Disposition"] = *putOptions.contentDisposition
+	}
+	if putOptions.cacheControl != nil {
+		obj.headers["Cache-Control"] = *putOptions.cacheControl
+	}
+	if putOptions.expires != nil {
+		obj.headers["Expires"] = putOptions.expires.UTC().Format(time.RFC1123)
+	}
+	if putOptions.sseS3 {
+		obj.headers[HeadServerSideEncryption] = "AES256"
+	}
+	if putOptions.sseKMSKeyID != nil {
+		obj.headers[HeadServerSideEncryption] = "aws:kms"
+		obj.headers[HeadServerSideEncryptionKeyID] = *putOptions.sseKMSKeyID
+	}
+	obj.sseCustomerKeyMD5 = sseCustomerKeyMD5(putOptions.sseCustomerKey)
+	obj.acl = putOptions.acl
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, err := f.stat(file)
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return err
+	}
+	if !matchPutConditions(current, putOptions) {
+		return ErrPreconditionFailed
+	}
+	// a nil io.ReadSeeker must not become a non-nil io.Reader
+	var r io.Reader
+	if reader != nil {
+		r = reader
+	}
+	return f.write(file, r, obj)
+}
+
+func (f *FS) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+
+	var buf bytes.Buffer
+	err := uploadParts(reader, putOptions.partSize, 1, func(partNumber int, data []byte) error {
+		buf.Write(data)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.Put(key, bytes.NewReader(buf.Bytes()), meta, options...)
+}
+
+// Append behaves like oss, objects created by Put are not appendable,
+// and position must be equal to the current object length.
+func (f *FS) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return position, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return position, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, err := f.stat(file)
+	if errors.Is(err, ErrObjectNotFound) {
+		if position != 0 {
+			return position, fmt.Errorf("fs append: position %d is not equal to object length 0", position)
+		}
+		putOptions := DefaultPutOptions()
+		for _, opt := range options {
+			opt(putOptions)
+		}
+		obj = &memoryObject{
+			meta:       make(map[string]string),
+			headers:    map[string]string{"Content-Type": putOptions.contentType},
+			appendable: true,
+		}
+		return int64(len(data)), f.write(file, bytes.NewReader(data), obj)
+	}
+	if err != nil {
+		return position, err
+	}
+	if !obj.appendable {
+		return position, errors.New("fs append: object is not appendable")
+	}
+	size, _ := strconv.ParseInt(obj.headers["Content-Length"], 10, 64)
+	if position != size {
+		return position, fmt.Errorf("fs append: position %d is not equal to object length %d", position, size)
+	}
+
+	w, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return position, err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return position, err
+	}
+	if err := w.Close(); err != nil {
+		return position, err
+	}
+	if obj.headers["ETag"], err = fsETag(file); err != nil {
+		return position, err
+	}
+	return position + int64(len(data)), f.writeSidecar(file, obj)
+}
+
+func (f *FS) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+
+	encodedBytes := snappy.Encode(nil, data)
+
+	meta["Compressor"] = "snappy"
+
+	return f.Put(key, bytes.NewReader(encodedBytes), meta, options...)
+}
+
+// Copy copies srcKey to dstKey, CopyWithDestBucket copies to another bucket directory under FSRootDir.
+func (f *FS) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	copyOpts := DefaultCopyOptions()
+	for _, opt := range options {
+		opt(copyOpts)
+	}
+	if err := copyOpts.validate(); err != nil {
+		return err
+	}
+	src, err := f.path(f.BucketName, srcKey)
+	if err != nil {
+		return err
+	}
+	dstBucket := f.BucketName
+	if copyOpts.destBucket != "" {
+		dstBucket = copyOpts.destBucket
+	}
+	dst, err := f.path(dstBucket, dstKey)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, err := f.stat(src)
+	if err != nil {
+		return err
+	}
+	if err := obj.checkSSECustomerKey(copyOpts.sourceSSECustomerKey); err != nil {
+		return err
+	}
+	obj.sseCustomerKeyMD5 = sseCustomerKeyMD5(copyOpts.sseCustomerKey)
+	obj.appendable = false
+	obj.meta = copyOpts.userMeta(obj.meta)
+	obj.tags = copyOpts.objectTags(obj.tags)
+	if copyOpts.contentType != nil {
+		obj.headers["Content-Type"] = *copyOpts.contentType
+	}
+
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return f.write(dst, r, obj)
+}
+
+// Move copies srcKey to dstKey and deletes srcKey, the source is kept if the copy fails.
+func (f *FS) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return move(f, srcKey, dstKey, options)
+}
+
+// Del deletes the object file, its sidecar and the directories left empty
+func (f *FS) Del(key string) error {
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range []string{file, file + fsMetaSuffix} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return err
+		}
+	}
+	bucketDir := filepath.Join(f.root, f.BucketName)
+	for dir := filepath.Dir(file); dir != bucketDir; dir = filepath.Dir(dir) {
+		// fails if the directory isn't empty
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// RestoreObject restores archived objects immediately, objects of other storage classes can't be restored
+func (f *FS) RestoreObject(key string, options ...RestoreOptions) error {
+	restoreOpts := DefaultRestoreOptions()
+	for _, opt := range options {
+		opt(restoreOpts)
+	}
+	if err := restoreOpts.validate(); err != nil {
+		return err
+	}
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, err := f.stat(file)
+	if err != nil {
+		return err
+	}
+	if class := obj.headers["Storage-Class"]; class != StorageClassArchive && class != StorageClassColdArchive {
+		return fmt.Errorf("fs restore: object of storage class %q isn't archived", class)
+	}
+	obj.headers["Restore"] = formatRestoreStatus(time.Now().AddDate(0, 0, restoreOpts.days))
+	return f.writeSidecar(file, obj)
+}
+
+// GetVersion is not supported, fs objects aren't versioned
+func (f *FS) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return "", errFSVersioning
+}
+
+// DelVersion is not supported, fs objects aren't versioned
+func (f *FS) DelVersion(key string, versionID string, options ...DelOptions) error {
+	return errFSVersioning
+}
+
+func (f *FS) DelMulti(keys []string) (map[string]error, error) {
+	failed := make(map[string]error)
+	for _, key := range keys {
+		if err := f.Del(key); err != nil {
+			failed[key] = err
+		}
+	}
+	return delMultiResult(failed, len(keys))
+}
+
+func (f *FS) Head(key string, attributes []string, options ...GetOptions) (map[string]string, error) {
+	obj, err := f.headObject(key, options)
+	if err != nil {
+		return nil, err
+	}
+	return obj.attributes(attributes), nil
+}
+
+func (f *FS) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	obj, err := f.headObject(key, options)
+	if err != nil {
+		return nil, err
+	}
+	return obj.objectMeta(key)
+}
+
+func (f *FS) headObject(key string, options []GetOptions) (*memoryObject, error) {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if getOpts.versionID != nil {
+		return nil, errFSVersioning
+	}
+	obj, err := f.object(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := obj.checkSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// ListObject walks the directories of prefix and lists keys in lexicographical order like s3 and oss do,
+// keys containing delimiter after the prefix are skipped.
+func (f *FS) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	bucketDir := filepath.Join(f.root, f.BucketName)
+	// only the directory of prefix can have matching keys
+	base := bucketDir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		base = filepath.Join(bucketDir, filepath.FromSlash(prefix[:i]))
+	}
+
+	f.mu.RLock()
+	keys := make([]string, 0)
+	err := filepath.Walk(base, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if file == base && (os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR)) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(bucketDir, file)
+		if err != nil {
+			return err
+		}
+		k := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if file == base {
+				return nil
+			}
+			// skip directories which can't have keys with prefix, or whose keys all contain delimiter
+			dir := k + "/"
+			if !strings.HasPrefix(dir, prefix) && !strings.HasPrefix(prefix, dir) {
+				return filepath.SkipDir
+			}
+			if delimiter != "" && strings.HasPrefix(dir, prefix) && strings.Contains(dir[len(prefix):], delimiter) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || strings.HasSuffix(k, fsMetaSuffix) || !strings.HasPrefix(k, prefix) || k <= marker {
+			return nil
+		}
+		if delimiter != "" && strings.Contains(k[len(prefix):], delimiter) {
+			return nil
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	f.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+	}
+	return keys, nil
+}
+
+// ListObjectVersions is not supported, fs objects aren't versioned
+func (f *FS) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	return nil, errFSVersioning
+}
+
+// WalkObjects also sets the content type and user metadata, which s3 and oss listings don't have
+func (f *FS) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	keys, err := f.ListObject(key, prefix, "", math.MaxInt32, "")
+	if err != nil {
+		return nil, err
+	}
+	objects, prefixes, nextMarker := delimitedPage(keys, prefix, delimiter, marker, maxKeys)
+	res := &ListResult{Objects: make([]ObjectMeta, 0, len(objects)), CommonPrefixes: prefixes, NextMarker: nextMarker}
+	for _, k := range objects {
+		obj, err := f.object(k)
+		// deleted since listed
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		meta, err := obj.objectMeta(k)
+		if err != nil {
+			return nil, err
+		}
+		res.Objects = append(res.Objects, *meta)
+	}
+	return res, nil
+}
+
+func (f *FS) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	return walkObjects(f.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		keys, err := f.ListObject(key, prefix, marker, maxKeys, "")
+		if err != nil {
+			return nil, err
+		}
+		metas := make([]ObjectMeta, 0, len(keys))
+		for _, k := range keys {
+			obj, err := f.object(k)
+			// deleted since listed
+			if errors.Is(err, ErrObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			meta, err := obj.objectMeta(k)
+			if err != nil {
+				return nil, err
+			}
+			metas = append(metas, *meta)
+		}
+		return metas, nil
+	}, fn, options)
+}
+
+func (f *FS) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return newObjectIterator(f.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		keys, err := f.ListObject(key, prefix, marker, maxKeys, "")
+		if err != nil {
+			return nil, err
+		}
+		metas := make([]ObjectMeta, 0, len(keys))
+		for _, k := range keys {
+			obj, err := f.object(k)
+			// deleted since listed, only the key is kept since a short page would end the iteration
+			if errors.Is(err, ErrObjectNotFound) {
+				metas = append(metas, ObjectMeta{Key: k})
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			meta, err := obj.objectMeta(k)
+			if err != nil {
+				return nil, err
+			}
+			metas = append(metas, *meta)
+		}
+		return metas, nil
+	}, options...)
+}
+
+// SignURL returns the file:// url of the object, which never expires, SignOptions are ignored
+func (f *FS) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	return f.fileURL(key)
+}
+
+// SignURLForPut returns the file:// url of the object like SignURL, SignOptions are ignored
+func (f *FS) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	return f.fileURL(key)
+}
+
+// SignPostPolicy is not supported, files can't be uploaded by forms. It always returns ErrUnsupported.
+func (f *FS) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	return nil, ErrUnsupported
+}
+
+func (f *FS) fileURL(key string) (string, error) {
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.ToSlash(file)
+	// e.g. C:/dir on windows
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return u.String(), nil
+}
+
+func (f *FS) Exists(key string) (bool, error) {
+	_, err := f.object(key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (f *FS) GetObjectTagging(key string) (map[string]string, error) {
+	obj, err := f.object(key)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	for k, v := range obj.tags {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func (f *FS) GetObjectACL(key string) (string, error) {
+	obj, err := f.object(key)
+	if err != nil {
+		return "", err
+	}
+	if obj.acl == "" {
+		return ACLPrivate, nil
+	}
+	return obj.acl, nil
+}
+
+func (f *FS) PutObjectACL(key string, acl string) error {
+	if err := validateObjectACL(acl); err != nil {
+		return err
+	}
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, err := f.stat(file)
+	if err != nil {
+		return err
+	}
+	obj.acl = acl
+	return f.writeSidecar(file, obj)
+}
+
+func (f *FS) PutObjectTagging(key string, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	file, err := f.path(f.BucketName, key)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, err := f.stat(file)
+	if err != nil {
+		return err
+	}
+	obj.tags = make(map[string]string)
+	for k, v := range tags {
+		obj.tags[k] = v
+	}
+	return f.writeSidecar(file, obj)
+}
+
+// GetObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (f *FS) GetObjectLegalHold(key string) (bool, error) {
+	return false, errObjectLock
+}
+
+// PutObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (f *FS) PutObjectLegalHold(key string, on bool) error {
+	return errObjectLock
+}
+
+// GetObjectRetention is not supported, it always returns ErrUnsupported.
+func (f *FS) GetObjectRetention(key string) (string, time.Time, error) {
+	return "", time.Time{}, errObjectLock
+}
+
+// PutObjectRetention is not supported, it always returns ErrUnsupported.
+func (f *FS) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return errObjectLock
+}
+
+// Close does nothing, fs has no connections
+func (f *FS) Close() error {
+	return nil
+}
+
+// ping checks that the directory of the bucket still exists
+func (f *FS) ping() error {
+	info, err := os.Stat(filepath.Join(f.root, f.BucketName))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("ping bucket %s: %w", f.BucketName, &wrappedError{kind: ErrBucketNotFound, err: err})
+	}
+	if err != nil {
+		return fmt.Errorf("ping bucket %s: %w", f.BucketName, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("ping bucket %s: %s isn't a directory", f.BucketName, info.Name())
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package awos
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFS(t *testing.T) (Component, string) {
+	root := t.TempDir()
+	return DefaultContainer().Build(WithStorageType(StorageTypeFS), WithBucket("fs-bucket"), WithFSRootDir(root)), root
+}
+
+func TestFS_PutGetDel(t *testing.T) {
+	client, root := newTestFS(t)
+
+	err := client.Put("dir/"+guid, strings.NewReader(content), map[string]string{"Head": "1"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(root, "fs-bucket", "dir", guid))
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	res, err := client.Get("dir/" + guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	reader, meta, err := client.GetWithMeta("dir/"+guid, []string{"head", "Content-Type"})
+	assert.NoError(t, err)
+	data, _ = ioutil.ReadAll(reader)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, map[string]string{"head": "1", "Content-Type": "text/plain"}, meta)
+
+	head, err := client.Head("dir/"+guid, []string{"Content-Length"})
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(expectLength), head["Content-Length"])
+
+	obj, err := client.HeadObject("dir/" + guid)
+	assert.NoError(t, err)
+	assert.Equal(t, memoryETag([]byte(content)), `"`+obj.ETag+`"`)
+	assert.Equal(t, map[string]string{"head": "1"}, obj.UserMeta)
+
+	rangeReader, err := client.Range("dir/"+guid, 3, 10)
+	assert.NoError(t, err)
+	data, _ = ioutil.ReadAll(rangeReader)
+	assert.NoError(t, rangeReader.Close())
+	assert.Equal(t, content[3:], string(data))
+
+	assert.NoError(t, client.Del("dir/"+guid))
+	_, err = client.Get("dir/" + guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	_, err = client.HeadObject("dir/" + guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	exists, err := client.Exists("dir/" + guid)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	// the sidecar and the empty directory are removed too
+	_, err = os.Stat(filepath.Join(root, "fs-bucket", "dir"))
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, client.Del("dir/"+guid))
+}
+
+func TestFS_PlainFile(t *testing.T) {
+	client, root := newTestFS(t)
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "fs-bucket", "static"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "fs-bucket", "static", "index.html"), []byte("<html></html>"), 0644))
+
+	res, err := client.Get("static/index.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<html></html>", res)
+	obj, err := client.HeadObject("static/index.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", obj.ContentType)
+	assert.Equal(t, int64(13), obj.Size)
+
+	// a key under an object isn't found
+	_, err = client.Get("static/index.html/a")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestFS_InvalidKey(t *testing.T) {
+	client, _ := newTestFS(t)
+	for _, key := range []string{"", "/a", "a/", "a//b", "../a", "a/./b", "a" + fsMetaSuffix} {
+		assert.Error(t, client.Put(key, strings.NewReader(content), nil), key)
+	}
+}
+
+func TestFS_ListObject(t *testing.T) {
+	client, _ := newTestFS(t)
+	keys := []string{"a-c", "a/b", "a/c/d", "a/e", "ab", "b"}
+	for _, key := range keys {
+		assert.NoError(t, client.Put(key, strings.NewReader(content), nil))
+	}
+
+	res, err := client.ListObject("", "", "", 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, keys, res)
+
+	res, err = client.ListObject("", "a/", "", 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "a/c/d", "a/e"}, res)
+
+	res, err = client.ListObject("", "a/", "", 0, "/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "a/e"}, res)
+
+	res, err = client.ListObject("", "a", "a/b", 2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/c/d", "a/e"}, res)
+
+	res, err = client.ListObject("", "missing/", "", 0, "")
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	it := client.ListObjectsIter("", "a", ListWithPageSize(2))
+	res = res[:0]
+	for it.Next() {
+		res = append(res, it.Key())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"a-c", "a/b", "a/c/d", "a/e", "ab"}, res)
+
+	var walked []string
+	err = client.WalkObjects("", "a/", func(obj ObjectMeta) error {
+		walked = append(walked, obj.Key)
+		assert.Equal(t, int64(expectLength), obj.Size)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "a/c/d", "a/e"}, walked)
+}
+
+func TestFS_AppendCopy(t *testing.T) {
+	client, root := newTestFS(t)
+
+	next, err := client.Append("log", strings.NewReader("a"), 0)
+	assert.NoError(t, err)
+	next, err = client.Append("log", strings.NewReader("bc"), next)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), next)
+	_, err = client.Append("log", strings.NewReader("d"), 1)
+	assert.Error(t, err)
+
+	err = client.Copy("log", "copied", CopyWithMeta(map[string]string{"Head": "2"}), CopyWithContentType("text/csv"))
+	assert.NoError(t, err)
+	res, err := client.Get("copied")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", res)
+	meta, err := client.Head("copied", []string{"head", "Content-Type"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"head": "2", "Content-Type": "text/csv"}, meta)
+
+	assert.NoError(t, client.Copy("log", "other", CopyWithDestBucket("fs-other")))
+	_, err = os.Stat(filepath.Join(root, "fs-other", "other"))
+	assert.NoError(t, err)
+
+	assert.True(t, errors.Is(client.Copy("missing", "copied"), ErrObjectNotFound))
+}
+
+func TestFS_SignURL(t *testing.T) {
+	client, root := newTestFS(t)
+
+	signed, err := client.SignURL("dir/a b", 60)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signed, "file://"), signed)
+	assert.True(t, strings.HasSuffix(signed, "/fs-bucket/dir/a%20b"), signed)
+	assert.Contains(t, signed, filepath.ToSlash(root))
+}
@@ -0,0 +1,225 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/gotomicro/ego/core/elog"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	gcsDefaultRegion   = "auto"
+	gcsScope           = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+var _ Component = (*GCS)(nil)
+
+// GCS talks to Google Cloud Storage through its S3 compatible XML API.
+// With HMAC keys (AccessKeyID/AccessKeySecret) requests are signed with SigV4,
+// otherwise an OAuth2 bearer token from the service account json file or
+// Application Default Credentials is used.
+type GCS struct {
+	*S3
+	tokenSource oauth2.TokenSource
+}
+
+func newGCS(name string, cfg *config, logger *elog.Component) (*GCS, error) {
+	var ts oauth2.TokenSource
+	if cfg.AccessKeyID == "" || cfg.AccessKeySecret == "" {
+		var creds *google.Credentials
+		var err error
+		if cfg.GCSCredentialsFile != "" {
+			data, err := ioutil.ReadFile(cfg.GCSCredentialsFile)
+			if err != nil {
+				return nil, err
+			}
+			creds, err = google.CredentialsFromJSON(context.Background(), data, gcsScope)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			creds, err = google.FindDefaultCredentials(context.Background(), gcsScope)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ts = creds.TokenSource
+	}
+	return newGCSWithTokenSource(name, cfg, logger, ts), nil
+}
+
+func newGCSWithTokenSource(name string, cfg *config, logger *elog.Component, ts oauth2.TokenSource) *GCS {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = gcsDefaultEndpoint
+	}
+	region := cfg.Region
+	if region == "" {
+		region = gcsDefaultRegion
+	}
+	config := &aws.Config{
+		Region:           aws.String(region),
+		DisableSSL:       aws.Bool(!cfg.SSL),
+		Endpoint:         aws.String(endpoint),
+		S3ForcePathStyle: aws.Bool(cfg.forcePathStyle()),
+	}
+	if ts != nil {
+		config.Credentials = credentials.AnonymousCredentials
+	} else {
+		config.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.AccessKeySecret, "")
+	}
+
+	s3Client := newS3(name, cfg, logger, config)
+	s3Client.storageClasses = gcsStorageClasses
+	s3Client.noChecksumSHA256 = true
+	s3Client.noObjectACL = true
+	s3Client.noObjectLock = true
+	if ts != nil {
+		s3Client.Client.Handlers.Sign.Swap(v4.SignRequestHandler.Name, gcsBearerSignHandler(ts))
+	}
+	return &GCS{
+		S3:          s3Client,
+		tokenSource: ts,
+	}
+}
+
+func gcsBearerSignHandler(ts oauth2.TokenSource) request.NamedHandler {
+	return request.NamedHandler{
+		Name: "awos.GCSBearerSignHandler",
+		Fn: func(r *request.Request) {
+			token, err := ts.Token()
+			if err != nil {
+				r.Error = err
+				return
+			}
+			token.SetAuthHeader(r.HTTPRequest)
+		},
+	}
+}
+
+func (g *GCS) WithContext(ctx context.Context) Component {
+	return &GCS{
+		S3:          g.S3.WithContext(ctx).(*S3),
+		tokenSource: g.tokenSource,
+	}
+}
+
+// DelMulti deletes keys one by one, the XML API doesn't support multi-object delete.
+// Keys which don't exist are not reported as failed, same as s3.
+func (g *GCS) DelMulti(keys []string) (map[string]error, error) {
+	failed := make(map[string]error)
+	for _, key := range keys {
+		if err := g.Del(key); err != nil && !isS3NotFound(err) {
+			failed[key] = err
+		}
+	}
+	return delMultiResult(failed, len(keys))
+}
+
+// SignURL only works with HMAC keys, bearer tokens can't be used to presign urls.
+func (g *GCS) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	if g.tokenSource != nil {
+		return "", errors.New("gcs SignURL requires HMAC keys (accessKeyID/accessKeySecret)")
+	}
+	return g.S3.SignURL(key, expired, options...)
+}
+
+func (g *GCS) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	if g.tokenSource != nil {
+		return "", errors.New("gcs SignURLForPut requires HMAC keys (accessKeyID/accessKeySecret)")
+	}
+	return g.S3.SignURLForPut(key, expired, options...)
+}
+
+func (g *GCS) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	if g.tokenSource != nil {
+		return nil, errors.New("gcs SignPostPolicy requires HMAC keys (accessKeyID/accessKeySecret)")
+	}
+	return g.S3.SignPostPolicy(key, expired, options...)
+}
+
+// Copy doesn't support CopyWithTagging and CopyWithTaggingDirective, the XML API has no tags
+func (g *GCS) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	copyOpts := DefaultCopyOptions()
+	for _, opt := range options {
+		opt(copyOpts)
+	}
+	if copyOpts.tags != nil || copyOpts.taggingDirective != "" {
+		return fmt.Errorf("CopyWithTagging: %w", ErrUnsupported)
+	}
+	return g.S3.Copy(srcKey, dstKey, options...)
+}
+
+func (g *GCS) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return move(g, srcKey, dstKey, options)
+}
+
+// GetObjectTagging is not supported by the XML API, it always returns ErrUnsupported.
+func (g *GCS) GetObjectTagging(key string) (map[string]string, error) {
+	return nil, ErrUnsupported
+}
+
+// PutObjectTagging is not supported by the XML API, it always returns ErrUnsupported.
+func (g *GCS) PutObjectTagging(key string, tags map[string]string) error {
+	return ErrUnsupported
+}
+
+// GetObjectACL is not supported, the acls of the XML API aren't compatible with s3. It always returns ErrUnsupported.
+func (g *GCS) GetObjectACL(key string) (string, error) {
+	return "", ErrUnsupported
+}
+
+// PutObjectACL is not supported, the acls of the XML API aren't compatible with s3. It always returns ErrUnsupported.
+func (g *GCS) PutObjectACL(key string, acl string) error {
+	return ErrUnsupported
+}
+
+// GetObjectLegalHold is not supported, the holds of gcs aren't available in the XML API. It always returns ErrUnsupported.
+func (g *GCS) GetObjectLegalHold(key string) (bool, error) {
+	return false, ErrUnsupported
+}
+
+// PutObjectLegalHold is not supported, the holds of gcs aren't available in the XML API. It always returns ErrUnsupported.
+func (g *GCS) PutObjectLegalHold(key string, on bool) error {
+	return ErrUnsupported
+}
+
+// GetObjectRetention is not supported, gcs retains objects by bucket retention policies. It always returns ErrUnsupported.
+func (g *GCS) GetObjectRetention(key string) (string, time.Time, error) {
+	return "", time.Time{}, ErrUnsupported
+}
+
+// PutObjectRetention is not supported, gcs retains objects by bucket retention policies. It always returns ErrUnsupported.
+func (g *GCS) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return ErrUnsupported
+}
+
+// GetVersion is not supported, the XML API addresses versions by generation. It always returns ErrUnsupported.
+func (g *GCS) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return "", ErrUnsupported
+}
+
+// DelVersion is not supported, the XML API addresses versions by generation. It always returns ErrUnsupported.
+func (g *GCS) DelVersion(key string, versionID string, options ...DelOptions) error {
+	return ErrUnsupported
+}
+
+// ListObjectVersions is not supported by the XML API, it always returns ErrUnsupported.
+func (g *GCS) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	return nil, ErrUnsupported
+}
+
+// RestoreObject is not needed, archived gcs objects are readable directly. It always returns ErrUnsupported.
+func (g *GCS) RestoreObject(key string, options ...RestoreOptions) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,99 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func newTestGCS(t *testing.T, ts oauth2.TokenSource, handler http.HandlerFunc) *GCS {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := DefaultConfig()
+	cfg.StorageType = StorageTypeGCS
+	cfg.Endpoint = server.URL
+	cfg.Bucket = "gcs-bucket"
+	cfg.S3ForcePathStyle = true
+	if ts == nil {
+		cfg.AccessKeyID = "GOOGTEST"
+		cfg.AccessKeySecret = "secret"
+	}
+	return newGCSWithTokenSource("test", cfg, elog.DefaultLogger, ts)
+}
+
+func TestGCS_HMACSigning(t *testing.T) {
+	var auth, path string
+	client := newTestGCS(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		path = r.URL.Path
+		_, _ = w.Write([]byte(content))
+	})
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.Equal(t, "/gcs-bucket/"+guid, path)
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256"), auth)
+
+	signed, err := client.SignURL(guid, 60)
+	assert.NoError(t, err)
+	assert.Contains(t, signed, "X-Amz-Signature=")
+}
+
+func TestGCS_BearerToken(t *testing.T) {
+	var auth string
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "gcs-token", TokenType: "Bearer"})
+	client := newTestGCS(t, ts, func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(content))
+	})
+
+	res, err := client.WithContext(context.Background()).Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.Equal(t, "Bearer gcs-token", auth)
+
+	_, err = client.SignURL(guid, 60)
+	assert.Error(t, err)
+}
+
+// Set AWOS_GCS_BUCKET (and optionally AWOS_GCS_CREDENTIALS_FILE) to run against a real bucket.
+func TestGCS_Integration(t *testing.T) {
+	bucket := os.Getenv("AWOS_GCS_BUCKET")
+	if bucket == "" {
+		t.Skip("AWOS_GCS_BUCKET not set")
+	}
+	client := DefaultContainer().Build(
+		WithStorageType(StorageTypeGCS),
+		WithBucket(bucket),
+		WithGCSCredentialsFile(os.Getenv("AWOS_GCS_CREDENTIALS_FILE")),
+	)
+
+	err := client.Put(guid, strings.NewReader(content), map[string]string{"head": "1"})
+	assert.NoError(t, err)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	meta, err := client.Head(guid, []string{"head"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", meta["head"])
+
+	keys, err := client.ListObject(guid, guid[0:4], "", 10, "")
+	assert.NoError(t, err)
+	assert.Contains(t, keys, guid)
+
+	assert.NoError(t, client.Del(guid))
+	_, err = client.GetAsReader(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
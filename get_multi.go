@@ -0,0 +1,88 @@
+package awos
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultGetMultiConcurrency is the default number of objects GetMulti fetches concurrently
+const DefaultGetMultiConcurrency = 16
+
+type GetMultiOptions func(options *getMultiOptions)
+
+type getMultiOptions struct {
+	concurrency int
+	getOptions  []GetOptions
+}
+
+func DefaultGetMultiOptions() *getMultiOptions {
+	return &getMultiOptions{
+		concurrency: DefaultGetMultiConcurrency,
+	}
+}
+
+// GetMultiWithConcurrency sets the number of objects fetched concurrently
+func GetMultiWithConcurrency(concurrency int) GetMultiOptions {
+	return func(options *getMultiOptions) {
+		if concurrency > 0 {
+			options.concurrency = concurrency
+		}
+	}
+}
+
+// GetMultiWithGetOptions applies options to the get of every key
+func GetMultiWithGetOptions(options ...GetOptions) GetMultiOptions {
+	return func(multiOptions *getMultiOptions) {
+		multiOptions.getOptions = append(multiOptions.getOptions, options...)
+	}
+}
+
+// getMulti fetches keys with a bounded number of workers. A failed key doesn't abort the others,
+// keys not fetched yet when ctx is done fail with the error of ctx.
+func getMulti(ctx context.Context, keys []string, options []GetMultiOptions, getBytes func(key string, options ...GetOptions) ([]byte, error)) (map[string][]byte, map[string]error) {
+	multiOpts := DefaultGetMultiOptions()
+	for _, opt := range options {
+		opt(multiOpts)
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		res    = make(map[string][]byte, len(keys))
+		failed = make(map[string]error)
+	)
+	pending := make(chan string)
+	for i := 0; i < multiOpts.concurrency && i < len(keys); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range pending {
+				var (
+					data []byte
+					err  = ctx.Err()
+				)
+				if err == nil {
+					data, err = getBytes(key, multiOpts.getOptions...)
+				}
+				mu.Lock()
+				if err != nil {
+					failed[key] = err
+				} else {
+					res[key] = data
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			pending <- key
+		}
+	}
+	close(pending)
+	wg.Wait()
+	return res, failed
+}
@@ -0,0 +1,62 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMulti(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var inFlight, maxInFlight int32
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+						break
+					}
+				}
+				if strings.HasSuffix(r.URL.Path, "missing") {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+					return
+				}
+				keyContentHandler(w, r)
+			})
+
+			keys := []string{"a", "b", "missing", "c", "a"}
+			res, failed := client.GetMulti(keys, GetMultiWithConcurrency(2))
+			assert.Len(t, res, 3)
+			for _, key := range []string{"a", "b", "c"} {
+				assert.Equal(t, strings.Repeat(key, 1000), string(res[key]))
+			}
+			assert.Len(t, failed, 1)
+			assert.True(t, errors.Is(failed["missing"], ErrObjectNotFound), failed["missing"])
+			assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+		})
+	}
+}
+
+func TestGetMulti_Cancelled(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, failed := client.WithContext(ctx).GetMulti([]string{guid, "missing"})
+	assert.Empty(t, res)
+	assert.True(t, errors.Is(failed[guid], context.Canceled))
+	assert.True(t, errors.Is(failed["missing"], context.Canceled))
+
+	res, failed = client.GetMulti([]string{guid, "missing"})
+	assert.Equal(t, content, string(res[guid]))
+	assert.True(t, errors.Is(failed["missing"], ErrObjectNotFound))
+}
@@ -0,0 +1,154 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultDownloadChunkSize is the default size of the range gets of GetParallel
+	DefaultDownloadChunkSize int64 = 8 << 20
+	// DefaultDownloadConcurrency is the default number of range gets GetParallel issues concurrently
+	DefaultDownloadConcurrency = 4
+)
+
+type DownloadOptions func(options *downloadOptions)
+
+type downloadOptions struct {
+	chunkSize   int64
+	concurrency int
+	getOptions  []GetOptions
+}
+
+func DefaultDownloadOptions() *downloadOptions {
+	return &downloadOptions{
+		chunkSize:   DefaultDownloadChunkSize,
+		concurrency: DefaultDownloadConcurrency,
+	}
+}
+
+// DownloadWithChunkSize sets the size of the range gets, the objects not larger than it are got at once
+func DownloadWithChunkSize(chunkSize int64) DownloadOptions {
+	return func(options *downloadOptions) {
+		if chunkSize > 0 {
+			options.chunkSize = chunkSize
+		}
+	}
+}
+
+// DownloadWithConcurrency sets the number of range gets issued concurrently,
+// which is also the max number of chunks buffered in memory
+func DownloadWithConcurrency(concurrency int) DownloadOptions {
+	return func(options *downloadOptions) {
+		if concurrency > 0 {
+			options.concurrency = concurrency
+		}
+	}
+}
+
+// DownloadWithGetOptions applies options to the head and the gets of the object, such as GetWithSSECustomerKey.
+// GetWithRange is overridden by the ranges of the chunks.
+func DownloadWithGetOptions(options ...GetOptions) DownloadOptions {
+	return func(downloadOpts *downloadOptions) {
+		downloadOpts.getOptions = append(downloadOpts.getOptions, options...)
+	}
+}
+
+type downloadChunk struct {
+	data []byte
+	err  error
+}
+
+// GetParallel downloads the object of key to w in chunks of DownloadWithChunkSize, which are got by concurrent
+// range gets and written to w in order, so a large object isn't limited by the bandwidth of one connection.
+// Objects not larger than a chunk are got by a single get. The chunks of a versioned object are got from the
+// version of the head, others fail if the object changes its size while downloading. It returns the number
+// of bytes written to w, the first failed get cancels the others and is returned.
+func GetParallel(ctx context.Context, c Component, key string, w io.Writer, options ...DownloadOptions) (int64, error) {
+	downloadOpts := DefaultDownloadOptions()
+	for _, opt := range options {
+		opt(downloadOpts)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	client := c.WithContext(ctx)
+
+	meta, err := client.HeadObject(key, downloadOpts.getOptions...)
+	if err != nil {
+		return 0, err
+	}
+	getOptions := downloadOpts.getOptions
+	if meta.VersionID != "" {
+		getOptions = append(getOptions, GetWithVersionID(meta.VersionID))
+	}
+	// the chunks append their ranges concurrently
+	getOptions = getOptions[:len(getOptions):len(getOptions)]
+	if meta.Size <= downloadOpts.chunkSize {
+		reader, err := client.GetAsReader(key, getOptions...)
+		if err != nil {
+			return 0, err
+		}
+		defer reader.Close()
+		return io.Copy(w, reader)
+	}
+
+	chunkSize := downloadOpts.chunkSize
+	count := int((meta.Size + chunkSize - 1) / chunkSize)
+	chunks := make([]chan downloadChunk, count)
+	for i := range chunks {
+		chunks[i] = make(chan downloadChunk, 1)
+	}
+	// a token is taken for each chunk got and given back once it's written, bounding the buffered chunks
+	tokens := make(chan struct{}, downloadOpts.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range chunks {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				start := int64(i) * chunkSize
+				end := start + chunkSize - 1
+				if end >= meta.Size {
+					end = meta.Size - 1
+				}
+				data, err := client.GetBytes(key, append(getOptions, GetWithRange(start, end))...)
+				if err == nil && int64(len(data)) != end-start+1 {
+					err = fmt.Errorf("awos: got %d bytes of the chunk at %d of %s, expected %d", len(data), start, key, end-start+1)
+				}
+				chunks[i] <- downloadChunk{data: data, err: err}
+			}(i)
+		}
+	}()
+
+	var written int64
+	for i := range chunks {
+		var chunk downloadChunk
+		select {
+		case chunk = <-chunks[i]:
+		case <-ctx.Done():
+			return written, ctx.Err()
+		}
+		if chunk.err != nil {
+			cancel()
+			return written, chunk.err
+		}
+		n, err := w.Write(chunk.data)
+		written += int64(n)
+		if err != nil {
+			cancel()
+			return written, err
+		}
+		<-tokens
+	}
+	return written, nil
+}
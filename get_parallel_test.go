@@ -0,0 +1,69 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetParallel(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil))
+
+	// the last chunk is shorter
+	for _, chunkSize := range []int64{1000, 1024, int64(len(largeContent))} {
+		var buf bytes.Buffer
+		n, err := GetParallel(ctx, client, guid, &buf, DownloadWithChunkSize(chunkSize), DownloadWithConcurrency(3))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(largeContent)), n)
+		assert.Equal(t, largeContent, buf.String(), chunkSize)
+	}
+
+	_, err := GetParallel(ctx, client, "missing", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestGetParallel_Ranges(t *testing.T) {
+	var ranges int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranges, 1)
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(largeContent))
+	})
+
+	var buf bytes.Buffer
+	n, err := GetParallel(context.Background(), client, guid, &buf, DownloadWithChunkSize(4096))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(largeContent)), n)
+	assert.Equal(t, largeContent, buf.String())
+	assert.Equal(t, int32((len(largeContent)+4095)/4096), atomic.LoadInt32(&ranges))
+
+	// small objects are got at once
+	atomic.StoreInt32(&ranges, 0)
+	buf.Reset()
+	_, err = GetParallel(context.Background(), client, guid, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, buf.String())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&ranges))
+}
+
+func TestGetParallel_Error(t *testing.T) {
+	fake := NewFakeClient("test-bucket")
+	assert.NoError(t, fake.Put(guid, strings.NewReader(largeContent), nil))
+	errGet := errors.New("get failed")
+	fake.FailNext("GetBytes", guid, 1, errGet)
+
+	var buf bytes.Buffer
+	_, err := GetParallel(context.Background(), fake, guid, &buf, DownloadWithChunkSize(1000))
+	assert.ErrorIs(t, err, errGet)
+	assert.True(t, buf.Len() < len(largeContent))
+}
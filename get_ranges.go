@@ -0,0 +1,127 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultGetRangesConcurrency is the number of range gets GetRanges issues concurrently
+const DefaultGetRangesConcurrency = 4
+
+// ByteRange is the bytes [Start, End] of an object, both inclusive. A negative End means reading to the end
+// of the object, like GetWithRange.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+func (r ByteRange) validate() error {
+	if r.Start < 0 {
+		return fmt.Errorf("invalid range start %d", r.Start)
+	}
+	if r.End >= 0 && r.End < r.Start {
+		return fmt.Errorf("invalid range, end %d is smaller than start %d", r.End, r.Start)
+	}
+	return nil
+}
+
+// touches reports whether other starts within r or right after it, other doesn't start before r
+func (r ByteRange) touches(other ByteRange) bool {
+	return r.End < 0 || other.Start <= r.End+1
+}
+
+// GetRanges gets ranges of the object of key, the result has the bytes of each range in the requested order.
+// None of the backends supports multipart/byteranges responses, so the overlapping and adjacent ranges are
+// merged and each merged range is got with GetWithRange, DefaultGetRangesConcurrency of them at a time.
+// A range ending beyond the end of the object is cut at the end, the overlapping ranges share their bytes.
+// options apply to every range get, and the first failed get cancels the others and is returned.
+func GetRanges(ctx context.Context, c Component, key string, ranges []ByteRange, options ...GetOptions) ([][]byte, error) {
+	for _, r := range ranges {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+	res := make([][]byte, len(ranges))
+	if len(ranges) == 0 {
+		return res, nil
+	}
+
+	// merged are the ranges to get, sorted by start
+	order := make([]int, len(ranges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return ranges[order[i]].Start < ranges[order[j]].Start
+	})
+	var merged []ByteRange
+	for _, i := range order {
+		r := ranges[i]
+		if last := len(merged) - 1; last >= 0 && merged[last].touches(r) {
+			if merged[last].End >= 0 && (r.End < 0 || r.End > merged[last].End) {
+				merged[last].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	client := c.WithContext(ctx)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		data     = make([][]byte, len(merged))
+	)
+	pending := make(chan int)
+	for i := 0; i < DefaultGetRangesConcurrency && i < len(merged); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				err := ctx.Err()
+				if err == nil {
+					data[i], err = client.GetBytes(key, append(options[:len(options):len(options)], GetWithRange(merged[i].Start, merged[i].End))...)
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range merged {
+		pending <- i
+	}
+	close(pending)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// each range is a slice of the merged range containing it
+	for i, r := range ranges {
+		j := sort.Search(len(merged), func(j int) bool {
+			return merged[j].Start > r.Start
+		}) - 1
+		got := data[j]
+		start := r.Start - merged[j].Start
+		if start > int64(len(got)) {
+			start = int64(len(got))
+		}
+		end := int64(len(got))
+		if r.End >= 0 && r.End-merged[j].Start+1 < end {
+			end = r.End - merged[j].Start + 1
+		}
+		res[i] = got[start:end]
+	}
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRanges(t *testing.T) {
+	data := make([]byte, 1<<16)
+	rand.Read(data)
+	ranges := []ByteRange{
+		{Start: 40000, End: 40999},
+		{Start: 0, End: 99},
+		{Start: 50, End: 149},
+		{Start: 150, End: 150},
+		{Start: 60000, End: -1},
+		{Start: 1000, End: 1999},
+		{Start: 65000, End: 70000},
+		{Start: 1000, End: 1999},
+	}
+
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var (
+				mu        sync.Mutex
+				requested []string
+			)
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requested = append(requested, r.Header.Get("Range"))
+				mu.Unlock()
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+			})
+
+			res, err := GetRanges(context.Background(), client, "key", ranges)
+			assert.NoError(t, err)
+			if assert.Len(t, res, len(ranges)) {
+				for i, r := range ranges {
+					end := r.End + 1
+					if r.End < 0 || end > int64(len(data)) {
+						end = int64(len(data))
+					}
+					assert.True(t, bytes.Equal(data[r.Start:end], res[i]), "range %d", i)
+				}
+			}
+
+			// the overlapping and adjacent ranges are got once
+			sort.Strings(requested)
+			assert.Equal(t, []string{"bytes=0-150", "bytes=1000-1999", "bytes=40000-40999", "bytes=60000-"}, requested)
+		})
+	}
+}
+
+func TestGetRanges_Error(t *testing.T) {
+	client := newTestMemory()
+	_, err := GetRanges(context.Background(), client, "key", []ByteRange{{Start: 0, End: 1}})
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	_, err = GetRanges(context.Background(), client, "key", []ByteRange{{Start: 2, End: 1}})
+	assert.Error(t, err)
+
+	res, err := GetRanges(context.Background(), client, "key", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = GetRanges(ctx, client, "key", []ByteRange{{Start: 0, End: 1}})
+	assert.Equal(t, context.Canceled, err)
+}
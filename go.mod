@@ -1,6 +1,7 @@
 module github.com/ego-component/awos
 
 require (
+	cloud.google.com/go/compute v1.14.0 // indirect
 	github.com/BurntSushi/toml v1.1.0
 	github.com/aliyun/aliyun-oss-go-sdk v2.0.6+incompatible
 	github.com/avast/retry-go v2.5.0+incompatible
@@ -8,12 +9,19 @@ require (
 	github.com/baiyubin/aliyun-sts-go-sdk v0.0.0-20180326062324-cfa1a18b161f // indirect
 	github.com/golang/snappy v0.0.4
 	github.com/gotomicro/ego v1.1.5
+	github.com/klauspost/compress v1.15.12
+	github.com/prometheus/client_golang v1.12.1
 	github.com/satori/go.uuid v1.2.0 // indirect
-	github.com/stretchr/testify v1.8.0
+	github.com/stretchr/testify v1.8.1
 	go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace v0.36.4
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.36.4
 	go.opentelemetry.io/otel v1.11.1
+	go.opentelemetry.io/otel/sdk v1.11.1
 	go.opentelemetry.io/otel/trace v1.11.1
+	go.uber.org/goleak v1.1.12
+	go.uber.org/zap v1.21.0
+	golang.org/x/oauth2 v0.2.0
+	golang.org/x/time v0.1.0
 )
 
 go 1.13
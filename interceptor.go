@@ -1,53 +1,102 @@
 package awos
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gotomicro/ego/core/elog"
-	"github.com/gotomicro/ego/core/emetric"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
+// Interceptor wraps the transport next of a client, it's called once when the client is built.
+// The interceptors of WithInterceptors run around all the built-in ones, in the order they are added:
+// the first one is the outermost and sees each request first. So they see each request of the sdks once,
+// before the headers of WithHeaders are added and without the retries of the retry interceptor,
+// and the responses after the built-in interceptors have handled them.
+type Interceptor func(next http.RoundTripper) http.RoundTripper
+
+// userInterceptors wraps tp with the interceptors of WithInterceptors, the first one is the outermost
+func userInterceptors(config *config, tp http.RoundTripper) http.RoundTripper {
+	for i := len(config.interceptors) - 1; i >= 0; i-- {
+		tp = config.interceptors[i](tp)
+	}
+	return tp
+}
+
 type transport struct {
-	rt          http.RoundTripper
-	onReqBefore func(r *http.Request)
+	rt http.RoundTripper
+	// onReqBefore may return a derived request (e.g. with a new context),
+	// which is used for the rest of the round trip instead of r.
+	onReqBefore func(r *http.Request) *http.Request
 	onReqAfter  func(r *http.Request, res *http.Response, err error)
 	onEnd       func(r *http.Request, res *http.Response, err error)
+	// onError is called when the request fails, either at transport level
+	// (DNS, TLS handshake, timeouts, ...) from RoundTrip, or at body-read
+	// level (connection reset while reading) from the wrapped body.
+	// res is nil for transport-level failures.
+	onError func(r *http.Request, res *http.Response, err error)
+	// onRead is called with the number of bytes of each read of the response body
+	onRead func(r *http.Request, res *http.Response, n int)
 }
 
 type wrappedBody struct {
-	body  io.ReadCloser
-	onEnd func(r *http.Request, res *http.Response, err error)
-	req   *http.Request
-	res   *http.Response
+	body io.ReadCloser
+	// onEnd is only called once, on EOF, a read error or Close, whichever comes first,
+	// also when Close is called concurrently with Read
+	endOnce sync.Once
+	onEnd   func(r *http.Request, res *http.Response, err error)
+	onErr   func(r *http.Request, res *http.Response, err error)
+	onRead  func(r *http.Request, res *http.Response, n int)
+	req     *http.Request
+	res     *http.Response
 }
 
 func (wb *wrappedBody) Read(b []byte) (int, error) {
 	n, err := wb.body.Read(b)
+	if n > 0 && wb.onRead != nil {
+		wb.onRead(wb.req, wb.res, n)
+	}
 
 	switch err {
 	case nil:
 		// nothing to do here but fall through to the return
 	case io.EOF:
-		if wb.onEnd != nil {
-			wb.onEnd(wb.req, wb.res, nil)
-		}
+		wb.end(nil)
 	default:
-		if wb.onEnd != nil {
-			wb.onEnd(wb.req, wb.res, err)
+		if wb.onErr != nil {
+			wb.onErr(wb.req, wb.res, err)
 		}
+		wb.end(err)
 	}
 	return n, err
 }
 
+func (wb *wrappedBody) end(err error) {
+	wb.endOnce.Do(func() {
+		if wb.onEnd != nil {
+			wb.onEnd(wb.req, wb.res, err)
+		}
+	})
+}
+
 func (wb *wrappedBody) Close() error {
-	if wb.onEnd != nil {
-		wb.onEnd(wb.req, wb.res, nil)
-	}
+	wb.end(nil)
 	if wb.body != nil {
 		return wb.body.Close()
 	}
@@ -56,22 +105,38 @@ func (wb *wrappedBody) Close() error {
 
 func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
 	if t.onReqBefore != nil {
-		t.onReqBefore(r)
+		r = t.onReqBefore(r)
 	}
 	res, err := t.rt.RoundTrip(r)
 	if t.onReqAfter != nil {
 		t.onReqAfter(r, res, err)
 	}
 	if err != nil {
+		if t.onError != nil {
+			t.onError(r, res, err)
+		}
 		if t.onEnd != nil {
 			t.onEnd(r, res, err)
 		}
 		return res, err
 	}
-	res.Body = &wrappedBody{body: res.Body, onEnd: t.onEnd, req: r, res: res}
+	// the body isn't wrapped if nothing observes it, to save the overhead on every read
+	if res != nil && res.Body != nil && (t.onEnd != nil || t.onError != nil || t.onRead != nil) {
+		res.Body = &wrappedBody{body: res.Body, onEnd: t.onEnd, onErr: t.onError, onRead: t.onRead, req: r, res: res}
+	}
 	return res, err
 }
 
+// contextTransport sends requests with ctx, for sdks which don't support context
+type contextTransport struct {
+	ctx context.Context
+	rt  http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t.rt.RoundTrip(r.WithContext(t.ctx))
+}
+
 type begKey struct{}
 
 func beg(ctx context.Context) time.Time {
@@ -81,8 +146,8 @@ func beg(ctx context.Context) time.Time {
 
 func fixedInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *transport {
 	t := &transport{rt: base}
-	t.onReqBefore = func(r *http.Request) {
-		*r = *(r.WithContext(context.WithValue(r.Context(), begKey{}, time.Now())))
+	t.onReqBefore = func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), begKey{}, time.Now()))
 	}
 	return t
 }
@@ -94,13 +159,7 @@ func traceLogReqIdInterceptor(name string, config *config, logger *elog.Componen
 		if !span.SpanContext().IsValid() {
 			return
 		}
-		var reqId string
-		switch config.StorageType {
-		case StorageTypeS3:
-			reqId = res.Header.Get("X-Amz-Request-Id")
-		case StorageTypeOSS:
-			reqId = res.Header.Get("X-Oss-Request-Id")
-		}
+		reqId := requestID(config.StorageType, res)
 		if reqId == "" {
 			return
 		}
@@ -109,19 +168,755 @@ func traceLogReqIdInterceptor(name string, config *config, logger *elog.Componen
 	return t
 }
 
+// requestID returns the request id of the backend in the response headers, empty if there is none
+func requestID(storageType string, res *http.Response) string {
+	if res == nil {
+		return ""
+	}
+	switch strings.ToLower(storageType) {
+	case StorageTypeS3:
+		return res.Header.Get("X-Amz-Request-Id")
+	case StorageTypeOSS:
+		return res.Header.Get("X-Oss-Request-Id")
+	case StorageTypeGCS:
+		return res.Header.Get("X-Guploader-Uploadid")
+	case StorageTypeCOS:
+		return res.Header.Get("X-Cos-Request-Id")
+	case StorageTypeAzure:
+		return res.Header.Get("X-Ms-Request-Id")
+	}
+	return ""
+}
+
+// statusCode is the code of metrics and logs, the category of err if the request failed
+func statusCode(res *http.Response, err error) string {
+	if err != nil {
+		return errorCategory(err)
+	}
+	return http.StatusText(res.StatusCode)
+}
+
+// errorCategory classifies transport level errors into stable categories,
+// "request error" if it's none of them
+func errorCategory(err error) string {
+	var (
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		certErr      x509.CertificateInvalidError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		recordErr    tls.RecordHeaderError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.As(err, &certErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &recordErr),
+		strings.Contains(err.Error(), "tls: "):
+		return "tls"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "eof"
+	default:
+		return "request error"
+	}
+}
+
+// isServerFailure reports requests failed at transport level or by the server,
+// which are retried and counted by the circuit breaker
+func isServerFailure(res *http.Response, err error) bool {
+	return err != nil || res.StatusCode >= http.StatusInternalServerError
+}
+
 func metricInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *transport {
+	sink := config.metrics(name)
 	t := &transport{rt: base}
+	t.onReqBefore = func(r *http.Request) *http.Request {
+		sink.RequestStart(r.Method, config.Bucket)
+		return r
+	}
+	// onReqAfter is called for the failed requests as well
 	t.onReqAfter = func(r *http.Request, res *http.Response, err error) {
-		code := ""
-		if err != nil {
-			code = "request error"
-		} else {
-			code = http.StatusText(res.StatusCode)
+		sink.RequestDone(r.Method, config.Bucket, statusCode(res, err))
+		// bodies of unknown length (-1) aren't counted
+		if r.ContentLength > 0 {
+			sink.WrittenBytes(r.Method, config.Bucket, r.ContentLength)
 		}
-		emetric.ClientHandleCounter.Inc("oss", name, r.Method, config.Bucket, code)
+		if config.DisableBodyMetrics {
+			sink.RequestLatency(r.Method, config.Bucket, time.Since(beg(r.Context())))
+		}
+	}
+	if config.DisableBodyMetrics {
+		return t
 	}
+	t.onRead = func(r *http.Request, res *http.Response, n int) {
+		sink.ReadBytes(r.Method, config.Bucket, n)
+	}
+	t.onEnd = func(r *http.Request, res *http.Response, err error) {
+		sink.RequestLatency(r.Method, config.Bucket, time.Since(beg(r.Context())))
+	}
+	return t
+}
+
+// slowLogInterceptor warns about requests taking longer than SlowThreshold, until the body is read or closed
+func slowLogInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *transport {
+	t := &transport{rt: base}
 	t.onEnd = func(r *http.Request, res *http.Response, err error) {
-		emetric.ClientHandleHistogram.Observe(time.Since(beg(r.Context())).Seconds(), "oss", name, r.Method, config.Bucket)
+		cost := time.Since(beg(r.Context()))
+		if cost <= config.SlowThreshold {
+			return
+		}
+		logger.Warn("slow request",
+			elog.FieldName(name),
+			elog.FieldMethod(r.Method),
+			elog.FieldAddr(config.Bucket),
+			elog.FieldKey(r.URL.Path),
+			elog.FieldCost(cost),
+			elog.FieldValue(statusCode(res, err)),
+		)
+	}
+	return t
+}
+
+// retryBudgetReserve is the number of retries allowed before the requests deposit any,
+// which is also the max number of retries saved up by the budget
+const retryBudgetReserve = 10
+
+// retryBudget is a token bucket shared by the requests of a client, each request deposits ratio tokens
+// and each retry withdraws one, so the retries are at most ratio of the requests plus the reserve
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{ratio: ratio, tokens: retryBudgetReserve}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	if b.tokens += b.ratio; b.tokens > retryBudgetReserve {
+		b.tokens = retryBudgetReserve
+	}
+	b.mu.Unlock()
+}
+
+// withdraw takes a token for a retry, it returns false if the budget is spent
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryTransport retries idempotent requests on 5xx and network errors with exponential backoff and jitter
+type retryTransport struct {
+	rt          http.RoundTripper
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	// budget is nil if the retries aren't limited by RetryBudgetRatio
+	budget  *retryBudget
+	onRetry func(r *http.Request, res *http.Response, err error)
+	// retryableFunc is the RetryableFunc of WithRetryableFunc, nil for DefaultRetryable
+	retryableFunc RetryableFunc
+}
+
+func retryInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *retryTransport {
+	t := &retryTransport{
+		rt:            base,
+		maxAttempts:   config.RetryMaxAttempts,
+		baseDelay:     config.RetryBaseDelay,
+		maxDelay:      config.RetryMaxDelay,
+		retryableFunc: config.retryableFunc,
+	}
+	if config.RetryBudgetRatio > 0 {
+		t.budget = newRetryBudget(config.RetryBudgetRatio)
+	}
+	sink := config.metrics(name)
+	t.onRetry = func(r *http.Request, res *http.Response, err error) {
+		code := statusCode(res, err)
+		sink.Retry(r.Method, config.Bucket, code)
+		logger.Warn("retry request", elog.FieldMethod(r.Method), elog.FieldAddr(r.URL.Host), elog.FieldKey(r.URL.Path), elog.FieldValue(code), elog.FieldErr(err))
+	}
+	return t
+}
+
+// retryable requests are idempotent and the body can be rewound
+func retryable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut:
+	default:
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// RetryableFunc reports whether a failed attempt of the retry interceptor is retried, res is nil if err isn't.
+// The body of res has up to the first 4KB of the error bodies, reading it doesn't consume the body of the
+// response. See WithRetryableFunc.
+type RetryableFunc func(r *http.Request, res *http.Response, err error) bool
+
+// DefaultRetryable is the RetryableFunc of the retry interceptor by default, it retries 5xx and network errors
+func DefaultRetryable(r *http.Request, res *http.Response, err error) bool {
+	return isServerFailure(res, err)
+}
+
+// retryablePeekSize is the size of the error bodies a RetryableFunc can read
+const retryablePeekSize = 4096
+
+func (t *retryTransport) shouldRetry(r *http.Request, res *http.Response, err error) bool {
+	if r.Context().Err() != nil {
+		return false
+	}
+	if t.retryableFunc == nil {
+		return DefaultRetryable(r, res, err)
+	}
+	if res == nil || res.StatusCode < http.StatusBadRequest || res.Body == nil {
+		return t.retryableFunc(r, res, err)
+	}
+	// the peeked bytes are read again by the caller if the response isn't retried
+	peeked, readErr := ioutil.ReadAll(io.LimitReader(res.Body, retryablePeekSize))
+	var rest io.Reader = res.Body
+	if readErr != nil {
+		rest = failedReader{err: readErr}
+	}
+	res.Body = CombinedReadCloser{ReadCloser: res.Body, Reader: io.MultiReader(bytes.NewReader(peeked), rest)}
+	peekedRes := *res
+	peekedRes.Body = ioutil.NopCloser(bytes.NewReader(peeked))
+	return t.retryableFunc(r, &peekedRes, err)
+}
+
+// failedReader returns err on every read
+type failedReader struct {
+	err error
+}
+
+func (r failedReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// backoff returns the delay before the nth retry, which is doubled for each retry and capped by maxDelay,
+// with the upper half randomized to avoid retrying in lockstep
+func (t *retryTransport) backoff(n int) time.Duration {
+	d := t.baseDelay << uint(n-1)
+	if d <= 0 || d > t.maxDelay {
+		d = t.maxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.budget != nil {
+		t.budget.deposit()
+	}
+	if !retryable(r) {
+		return t.rt.RoundTrip(r)
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := t.rt.RoundTrip(r)
+		if attempt >= t.maxAttempts || !t.shouldRetry(r, res, err) {
+			return res, err
+		}
+		if t.budget != nil && !t.budget.withdraw() {
+			return res, err
+		}
+		if t.onRetry != nil {
+			t.onRetry(r, res, err)
+		}
+		if res != nil && res.Body != nil {
+			// drain the body to reuse the connection
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+			_ = res.Body.Close()
+		}
+
+		timer := time.NewTimer(t.backoff(attempt))
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+
+		if r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = r.Clone(r.Context())
+			r.Body = body
+		}
+	}
+}
+
+// rateLimitTransport waits for the limiter of the bucket before each request
+type rateLimitTransport struct {
+	rt       http.RoundTripper
+	qps      rate.Limit
+	burst    int
+	bucketFn func(r *http.Request) string
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func rateLimitInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *rateLimitTransport {
+	burst := config.RateLimitBurst
+	if burst < 1 {
+		burst = 1
+	}
+	pathStyle := config.forcePathStyle()
+	return &rateLimitTransport{
+		rt:    base,
+		qps:   rate.Limit(config.RateLimitQPS),
+		burst: burst,
+		// the bucket is the first path segment of path style urls, otherwise it's in the host
+		bucketFn: func(r *http.Request) string {
+			if pathStyle {
+				return r.URL.Host + "/" + strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+			}
+			return r.URL.Host
+		},
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (t *rateLimitTransport) limiter(bucket string) *rate.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.limiters[bucket]
+	if !ok {
+		l = rate.NewLimiter(t.qps, t.burst)
+		t.limiters[bucket] = l
+	}
+	return l
+}
+
+func (t *rateLimitTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := t.limiter(t.bucketFn(r)).Wait(r.Context()); err != nil {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, err
+	}
+	return t.rt.RoundTrip(r)
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	// circuitHalfOpen lets one probe request through after the cooldown
+	circuitHalfOpen
+)
+
+// circuitBreakerTransport fails fast with ErrCircuitOpen after threshold consecutive failures,
+// until a probe request succeeds after the cooldown
+type circuitBreakerTransport struct {
+	rt        http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	state     circuitState
+	failures  int
+	openedAt  time.Time
+}
+
+func circuitBreakerInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *circuitBreakerTransport {
+	return &circuitBreakerTransport{
+		rt:        base,
+		threshold: config.CircuitBreakerThreshold,
+		cooldown:  config.CircuitBreakerCooldown,
+		now:       time.Now,
+	}
+}
+
+func (t *circuitBreakerTransport) allow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.state {
+	case circuitOpen:
+		if t.now().Sub(t.openedAt) < t.cooldown {
+			return false
+		}
+		// this request is the probe
+		t.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+func (t *circuitBreakerTransport) report(failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !failed {
+		t.state = circuitClosed
+		t.failures = 0
+		return
+	}
+	t.failures++
+	if t.state == circuitHalfOpen || t.failures >= t.threshold {
+		t.state = circuitOpen
+		t.openedAt = t.now()
+	}
+}
+
+func (t *circuitBreakerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !t.allow() {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, ErrCircuitOpen
+	}
+	res, err := t.rt.RoundTrip(r)
+	// canceled requests tell nothing about the backend
+	if r.Context().Err() == nil {
+		t.report(isServerFailure(res, err))
+	} else {
+		t.mu.Lock()
+		if t.state == circuitHalfOpen {
+			t.state = circuitOpen
+		}
+		t.mu.Unlock()
+	}
+	return res, err
+}
+
+// reservedHeader reports whether the header is set or signed by the sdks, which the injected headers
+// must not change since they are added after the request is signed
+func reservedHeader(key string) bool {
+	key = strings.ToLower(key)
+	switch key {
+	case "authorization", "host", "date", "content-type", "content-md5", "content-length", "range":
+		return true
+	}
+	for _, prefix := range []string{"x-amz-", "x-oss-", "x-ms-", "x-goog-", "x-cos-"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHeaders checks the names of Headers, which must be valid and not reserved
+func validateHeaders(headers map[string]string) error {
+	for key := range headers {
+		if !validMetaKey(key) {
+			return fmt.Errorf("invalid header %q", key)
+		}
+		if reservedHeader(key) {
+			return fmt.Errorf("header %q is reserved for signing", key)
+		}
+	}
+	return nil
+}
+
+// headerInterceptor adds Headers and the headers of HeadersFunc to every request, the headers of HeadersFunc
+// take precedence, reserved headers and headers already set by the sdks are skipped
+func headerInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *transport {
+	t := &transport{rt: base}
+	t.onReqBefore = func(r *http.Request) *http.Request {
+		headers := config.Headers
+		if config.headersFunc != nil {
+			if ctxHeaders := config.headersFunc(r.Context()); len(ctxHeaders) > 0 {
+				headers = make(map[string]string, len(config.Headers)+len(ctxHeaders))
+				for _, m := range []map[string]string{config.Headers, ctxHeaders} {
+					for k, v := range m {
+						headers[http.CanonicalHeaderKey(k)] = v
+					}
+				}
+			}
+		}
+		if len(headers) == 0 {
+			return r
+		}
+		// a RoundTripper must not modify the request
+		r = r.Clone(r.Context())
+		for k, v := range headers {
+			if !validMetaKey(k) || reservedHeader(k) || r.Header.Get(k) != "" {
+				continue
+			}
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+	return t
+}
+
+// userAgentInterceptor appends UserAgent to the User-Agent of the sdk, none of the signatures covers it
+func userAgentInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *transport {
+	t := &transport{rt: base}
+	t.onReqBefore = func(r *http.Request) *http.Request {
+		userAgent := config.UserAgent
+		if sdkUserAgent := r.Header.Get("User-Agent"); sdkUserAgent != "" {
+			userAgent = sdkUserAgent + " " + userAgent
+		}
+		// a RoundTripper must not modify the request
+		r = r.Clone(r.Context())
+		r.Header.Set("User-Agent", userAgent)
+		return r
 	}
 	return t
 }
+
+// idleTimeoutTransport cancels requests which don't send or receive any bytes for the timeouts,
+// so that a stalled connection doesn't hold the request until the deadline of the context
+type idleTimeoutTransport struct {
+	rt           http.RoundTripper
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func idleTimeoutInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *idleTimeoutTransport {
+	return &idleTimeoutTransport{rt: base, readTimeout: config.ReadTimeout, writeTimeout: config.WriteTimeout}
+}
+
+func (t *idleTimeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	timer := &idleTimer{cancel: cancel}
+	timer.timer = time.AfterFunc(time.Hour, timer.fire)
+
+	r = r.WithContext(ctx)
+	if r.Body != nil && r.Body != http.NoBody {
+		timer.reset(t.writeTimeout)
+		// waiting for the response starts once the body is sent
+		r.Body = &idleBody{body: r.Body, timer: timer, timeout: t.writeTimeout, onEOF: func() { timer.reset(t.readTimeout) }}
+	} else {
+		timer.reset(t.readTimeout)
+	}
+	res, err := t.rt.RoundTrip(r)
+	if err != nil {
+		timer.stop()
+		return res, timer.err(err)
+	}
+	// a custom RoundTripper may return no body, there is nothing to read then
+	if res.Body == nil || res.Body == http.NoBody {
+		timer.stop()
+		return res, nil
+	}
+	timer.reset(t.readTimeout)
+	res.Body = &idleBody{body: res.Body, timer: timer, timeout: t.readTimeout, onEOF: timer.stop, onClose: timer.stop}
+	return res, nil
+}
+
+// idleTimer cancels the request if it isn't reset within the timeout
+type idleTimer struct {
+	timer    *time.Timer
+	cancel   context.CancelFunc
+	timedOut int32
+}
+
+func (t *idleTimer) fire() {
+	atomic.StoreInt32(&t.timedOut, 1)
+	t.cancel()
+}
+
+// reset restarts the timer with timeout, or stops it if timeout is 0
+func (t *idleTimer) reset(timeout time.Duration) {
+	if timeout <= 0 {
+		t.timer.Stop()
+		return
+	}
+	t.timer.Reset(timeout)
+}
+
+// stop releases the context of the request once it's done
+func (t *idleTimer) stop() {
+	t.timer.Stop()
+	t.cancel()
+}
+
+// err returns ErrIdleTimeout instead of the error of the canceled request if the timer fired
+func (t *idleTimer) err(err error) error {
+	if atomic.LoadInt32(&t.timedOut) == 1 {
+		return ErrIdleTimeout
+	}
+	return err
+}
+
+// idleBody resets the timer on every read with bytes
+type idleBody struct {
+	body    io.ReadCloser
+	timer   *idleTimer
+	timeout time.Duration
+	onEOF   func()
+	onClose func()
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.reset(b.timeout)
+	}
+	switch err {
+	case nil:
+	case io.EOF:
+		b.onEOF()
+	default:
+		err = b.timer.err(err)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	if b.onClose != nil {
+		b.onClose()
+	}
+	return b.body.Close()
+}
+
+type noDeadlineKey struct{}
+
+// markNoDeadline marks ctx if it has no deadline, since the http clients of s3 and azure have a Timeout,
+// which gives every request a deadline before the interceptors see it
+func markNoDeadline(ctx context.Context) context.Context {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, noDeadlineKey{}, true)
+}
+
+// defaultTimeoutTransport sends the requests without a deadline with a timeout, the parent context
+// can still cancel them
+type defaultTimeoutTransport struct {
+	rt      http.RoundTripper
+	timeout time.Duration
+}
+
+func defaultTimeoutInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *defaultTimeoutTransport {
+	return &defaultTimeoutTransport{rt: base, timeout: config.DefaultTimeout}
+}
+
+func (t *defaultTimeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if _, ok := r.Context().Deadline(); ok && r.Context().Value(noDeadlineKey{}) == nil {
+		return t.rt.RoundTrip(r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), t.timeout)
+	res, err := t.rt.RoundTrip(r.WithContext(ctx))
+	if err != nil || res.Body == nil {
+		cancel()
+		return res, err
+	}
+	// the body is read with the timeout as well, the context is released once it's closed
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody cancels the context of the request on Close
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// hedgeTransport sends a second GET/HEAD request if the first one has no response headers after delay,
+// the first successful response wins and the other request is canceled
+type hedgeTransport struct {
+	rt      http.RoundTripper
+	delay   time.Duration
+	onHedge func(r *http.Request)
+}
+
+func hedgeInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *hedgeTransport {
+	sink := config.metrics(name)
+	return &hedgeTransport{
+		rt:    base,
+		delay: config.HedgeDelay,
+		onHedge: func(r *http.Request) {
+			sink.Hedge(r.Method, config.Bucket)
+		},
+	}
+}
+
+type hedgeResult struct {
+	// i is the index of the request, 0 for the first one
+	i      int
+	res    *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+// close releases the response and the context of a request which lost
+func (h hedgeResult) close() {
+	if h.res != nil && h.res.Body != nil {
+		_ = h.res.Body.Close()
+	}
+	h.cancel()
+}
+
+func (t *hedgeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return t.rt.RoundTrip(r)
+	}
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(r.Context())
+		i := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			res, err := t.rt.RoundTrip(r.Clone(ctx))
+			results <- hedgeResult{i: i, res: res, err: err, cancel: cancel}
+		}()
+	}
+	send()
+	timer := time.NewTimer(t.delay)
+	defer timer.Stop()
+
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			if t.onHedge != nil {
+				t.onHedge(r)
+			}
+			send()
+			pending++
+		case result := <-results:
+			pending--
+			// a failed request waits for the hedged one, if it's sent or about to be
+			if isServerFailure(result.res, result.err) && pending > 0 {
+				result.close()
+				continue
+			}
+			// the requests left lost
+			for i, cancel := range cancels {
+				if i != result.i {
+					cancel()
+				}
+			}
+			go func(n int) {
+				for ; n > 0; n-- {
+					(<-results).close()
+				}
+			}(pending)
+			if result.err != nil || result.res.Body == nil {
+				result.cancel()
+				return result.res, result.err
+			}
+			// the context of the winner is released once its body is closed, instead of canceling the read
+			result.res.Body = &cancelBody{ReadCloser: result.res.Body, cancel: result.cancel}
+			return result.res, nil
+		}
+	}
+}
@@ -0,0 +1,1063 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestTransport_RoundTripError(t *testing.T) {
+	errConnRefused := errors.New("connection refused")
+	var ended int
+	tp := &transport{
+		rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errConnRefused
+		}),
+		onEnd: func(r *http.Request, res *http.Response, err error) {
+			ended++
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	assert.NotPanics(t, func() {
+		res, err := tp.RoundTrip(req)
+		assert.Nil(t, res)
+		assert.Equal(t, errConnRefused, err)
+	})
+	assert.Equal(t, 1, ended)
+}
+
+func TestTransport_BodyEndOnce(t *testing.T) {
+	var ended int32
+	tp := &transport{
+		rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(content))}, nil
+		}),
+		onEnd: func(r *http.Request, res *http.Response, err error) {
+			atomic.AddInt32(&ended, 1)
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ended))
+	assert.NoError(t, res.Body.Close())
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ended))
+}
+
+func TestTransport_RoundTripNilBody(t *testing.T) {
+	tp := &transport{
+		rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNoContent}, nil
+		}),
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, "http://127.0.0.1/bucket/key", nil)
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Nil(t, res.Body)
+}
+
+func TestTransport_OnError(t *testing.T) {
+	errTimeout := errors.New("i/o timeout")
+	var calls int
+	var gotErr error
+	tp := &transport{
+		rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errTimeout
+		}),
+		onError: func(r *http.Request, res *http.Response, err error) {
+			calls++
+			gotErr = err
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	_, err := tp.RoundTrip(req)
+	assert.Equal(t, errTimeout, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, errTimeout, gotErr)
+}
+
+func TestErrorCategory(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://127.0.0.1/bucket/key", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"canceled", urlErr(context.Canceled), "canceled"},
+		{"deadline exceeded", urlErr(context.DeadlineExceeded), "timeout"},
+		{"dial timeout", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}), "timeout"},
+		{"dns", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "bucket.example.com", IsNotFound: true}}), "dns"},
+		{"dns timeout", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "bucket.example.com", IsTimeout: true}}), "dns"},
+		{"connection refused", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), "connection_refused"},
+		{"unknown authority", urlErr(x509.UnknownAuthorityError{}), "tls"},
+		{"hostname", urlErr(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "bucket.example.com"}), "tls"},
+		{"record header", urlErr(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), "tls"},
+		{"tls handshake", urlErr(errors.New("remote error: tls: handshake failure")), "tls"},
+		{"eof", urlErr(io.EOF), "eof"},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), "eof"},
+		{"other", urlErr(errors.New("something else")), "request error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, errorCategory(tt.err))
+			assert.Equal(t, tt.want, statusCode(nil, tt.err))
+		})
+	}
+	assert.Equal(t, "Not Found", statusCode(&http.Response{StatusCode: http.StatusNotFound}, nil))
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		storageType string
+		header      string
+	}{
+		{StorageTypeS3, "X-Amz-Request-Id"},
+		{StorageTypeOSS, "X-Oss-Request-Id"},
+		{StorageTypeGCS, "X-Guploader-Uploadid"},
+		{StorageTypeCOS, "x-cos-request-id"},
+		{"COS", "x-cos-request-id"},
+		{StorageTypeAzure, "x-ms-request-id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.storageType, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			res.Header.Set(tt.header, "req-1")
+			// headers of other backends are ignored
+			res.Header.Set("X-Request-Id", "other")
+			assert.Equal(t, "req-1", requestID(tt.storageType, res))
+			assert.Equal(t, "", requestID(tt.storageType, &http.Response{Header: http.Header{}}))
+		})
+	}
+	assert.Equal(t, "", requestID(StorageTypeCOS, nil))
+	assert.Equal(t, "", requestID(StorageTypeMemory, &http.Response{Header: http.Header{"X-Cos-Request-Id": {"req-1"}}}))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestFixedInterceptor_BegTime(t *testing.T) {
+	var elapsed time.Duration
+	inner := &transport{
+		rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			time.Sleep(10 * time.Millisecond)
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+		onEnd: func(r *http.Request, res *http.Response, err error) {
+			elapsed = time.Since(beg(r.Context()))
+		},
+	}
+	tp := fixedInterceptor("test", DefaultConfig(), nil, inner)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.True(t, elapsed >= 10*time.Millisecond && elapsed < time.Minute, "unexpected elapsed %s", elapsed)
+	// the caller's request must not be modified
+	assert.True(t, beg(req.Context()).IsZero())
+}
+
+func TestSlowLogInterceptor(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		delay time.Duration
+		slow  bool
+	}{
+		{"slow", 30 * time.Millisecond, true},
+		{"fast", 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			cfg := DefaultConfig()
+			cfg.Bucket = "test-bucket"
+			cfg.SlowThreshold = 20 * time.Millisecond
+			slow := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				time.Sleep(tt.delay)
+				return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok"))}, nil
+			})
+			tp := fixedInterceptor("test", cfg, nil, slowLogInterceptor("test", cfg, elog.DefaultContainer().Build(elog.WithZapCore(core)), slow))
+
+			req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/test-bucket/key", nil)
+			res, err := tp.RoundTrip(req)
+			assert.NoError(t, err)
+			_, err = ioutil.ReadAll(res.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, res.Body.Close())
+
+			if !tt.slow {
+				assert.Equal(t, 0, logs.Len())
+				return
+			}
+			// logged once although the body is read to EOF and closed
+			assert.Equal(t, 1, logs.Len())
+			entry := logs.All()[0]
+			assert.Equal(t, "slow request", entry.Message)
+			fields := entry.ContextMap()
+			assert.Equal(t, http.MethodGet, fields["method"])
+			assert.Equal(t, "test-bucket", fields["addr"])
+			assert.Equal(t, "/test-bucket/key", fields["key"])
+			assert.Contains(t, fields, "cost")
+		})
+	}
+}
+
+func newTestRetryInterceptor(rt http.RoundTripper) *retryTransport {
+	cfg := DefaultConfig()
+	cfg.RetryBaseDelay = time.Millisecond
+	cfg.RetryMaxDelay = 5 * time.Millisecond
+	return retryInterceptor("test", cfg, elog.EgoLogger, rt)
+}
+
+// failingRoundTripper fails n times with the given status or error, then succeeds
+func failingRoundTripper(n int, status int, err error, bodies *[]string) roundTripperFunc {
+	calls := 0
+	return func(r *http.Request) (*http.Response, error) {
+		calls++
+		if r.Body != nil && bodies != nil {
+			body, _ := ioutil.ReadAll(r.Body)
+			*bodies = append(*bodies, string(body))
+		}
+		if calls <= n {
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader("error"))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok"))}, nil
+	}
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	errReset := errors.New("connection reset by peer")
+	tests := []struct {
+		name       string
+		failures   int
+		status     int
+		err        error
+		wantStatus int
+		wantErr    error
+	}{
+		{name: "5xx", failures: 2, status: http.StatusServiceUnavailable, wantStatus: http.StatusOK},
+		{name: "network error", failures: 2, err: errReset, wantStatus: http.StatusOK},
+		{name: "too many failures", failures: 3, status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError},
+		{name: "4xx", failures: 1, status: http.StatusForbidden, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := newTestRetryInterceptor(failingRoundTripper(tt.failures, tt.status, tt.err, nil))
+			req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+			res, err := tp.RoundTrip(req)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestRetryInterceptor_RewindBody(t *testing.T) {
+	var bodies []string
+	tp := newTestRetryInterceptor(failingRoundTripper(2, http.StatusBadGateway, nil, &bodies))
+	req, _ := http.NewRequest(http.MethodPut, "http://127.0.0.1/bucket/key", strings.NewReader(content))
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{content, content, content}, bodies)
+
+	// the body can't be rewound without GetBody
+	bodies = nil
+	tp = newTestRetryInterceptor(failingRoundTripper(2, http.StatusBadGateway, nil, &bodies))
+	req, _ = http.NewRequest(http.MethodPut, "http://127.0.0.1/bucket/key", strings.NewReader(content))
+	req.GetBody = nil
+	res, err = tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	assert.Len(t, bodies, 1)
+}
+
+func TestRetryInterceptor_NotIdempotent(t *testing.T) {
+	tp := newTestRetryInterceptor(failingRoundTripper(1, http.StatusInternalServerError, nil, nil))
+	req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1/bucket/key?uploads", nil)
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+}
+
+func TestRetryInterceptor_Cancel(t *testing.T) {
+	tp := newTestRetryInterceptor(failingRoundTripper(1, http.StatusInternalServerError, nil, nil))
+	tp.baseDelay, tp.maxDelay = time.Hour, time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	_, err := tp.RoundTrip(req)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestRetryInterceptor_Component(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var calls int32
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write([]byte(content))
+			}, WithEnableRetryInterceptor(true), WithRetryBaseDelay(time.Millisecond))
+
+			res, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+			assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestRetryInterceptor_RetryableFunc(t *testing.T) {
+	retryable := func(r *http.Request, res *http.Response, err error) bool {
+		if res != nil && res.StatusCode == http.StatusTooManyRequests {
+			body, _ := ioutil.ReadAll(res.Body)
+			return strings.Contains(string(body), "QuirkySlowDown")
+		}
+		if res != nil && res.StatusCode == http.StatusInternalServerError && res.Header.Get("X-Gateway-Final") != "" {
+			return false
+		}
+		return DefaultRetryable(r, res, err)
+	}
+
+	var calls int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		switch n := atomic.AddInt32(&calls, 1); {
+		case r.URL.Path == "/test-bucket/final":
+			w.Header().Set("X-Gateway-Final", "1")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`<Error><Code>GatewayFinal</Code><Message>don't retry</Message></Error>`))
+		case n <= 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`<Error><Code>QuirkySlowDown</Code><Message>retry later</Message></Error>`))
+		default:
+			_, _ = w.Write([]byte(content))
+		}
+	}, WithEnableRetryInterceptor(true), WithRetryBaseDelay(time.Millisecond), WithRetryableFunc(retryable))
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// the body read by the predicate is still parsed into the error
+	atomic.StoreInt32(&calls, 0)
+	_, err = client.Get("final")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "GatewayFinal")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryInterceptor_Budget(t *testing.T) {
+	attempts := 0
+	tp := newTestRetryInterceptor(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: ioutil.NopCloser(strings.NewReader("error"))}, nil
+	}))
+	tp.budget = newRetryBudget(0.1)
+
+	// the sustained failures spend the reserve, then a retry per 10 requests
+	const requests = 100
+	for i := 0; i < requests; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+		res, err := tp.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+	retries := attempts - requests
+	assert.True(t, retries <= retryBudgetReserve+requests/10, "%d retries", retries)
+	assert.True(t, retries > retryBudgetReserve, "%d retries", retries)
+
+	// the budget is refilled by the requests
+	for i := 0; i < 20; i++ {
+		tp.budget.deposit()
+	}
+	attempts = 0
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	_, _ = tp.RoundTrip(req)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryInterceptor_BudgetComponent(t *testing.T) {
+	var calls int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithEnableRetryInterceptor(true), WithRetryBaseDelay(time.Millisecond), WithRetryMaxDelay(time.Millisecond), WithRetryBudgetRatio(0.2))
+
+	const requests = 50
+	for i := 0; i < requests; i++ {
+		_, err := client.Head(guid, nil)
+		assert.Error(t, err)
+	}
+	retries := int(atomic.LoadInt32(&calls)) - requests
+	assert.True(t, retries <= retryBudgetReserve+requests/5, "%d retries", retries)
+}
+
+func newTestRateLimitInterceptor(qps float64) *rateLimitTransport {
+	cfg := DefaultConfig()
+	cfg.RateLimitQPS = qps
+	cfg.S3ForcePathStyle = true
+	return rateLimitInterceptor("test", cfg, elog.EgoLogger, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	tp := newTestRateLimitInterceptor(10)
+
+	begin := time.Now()
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+		_, err := tp.RoundTrip(req)
+		assert.NoError(t, err)
+	}
+	assert.True(t, time.Since(begin) >= 90*time.Millisecond, time.Since(begin))
+
+	// other buckets have their own limiters
+	begin = time.Now()
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/other-bucket/key", nil)
+	_, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(begin) < 50*time.Millisecond, time.Since(begin))
+}
+
+func TestRateLimitInterceptor_Cancel(t *testing.T) {
+	tp := newTestRateLimitInterceptor(0.1)
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	_, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	begin := time.Now()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	_, err = tp.RoundTrip(req)
+	assert.Error(t, err)
+	assert.True(t, time.Since(begin) < time.Second, time.Since(begin))
+}
+
+func TestHedgeInterceptor(t *testing.T) {
+	var calls int32
+	canceled := make(chan struct{})
+	tp := &hedgeTransport{rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		// the first request is slow, the hedged one is fast
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-r.Context().Done()
+			close(canceled)
+			return nil, r.Context().Err()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(content))}, nil
+	}), delay: 10 * time.Millisecond}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the slow request isn't canceled")
+	}
+
+	// fast requests and writes aren't hedged
+	tp.delay = time.Minute
+	atomic.StoreInt32(&calls, 1)
+	req, _ = http.NewRequest(http.MethodHead, "http://127.0.0.1/bucket/key", nil)
+	_, err = tp.RoundTrip(req)
+	assert.NoError(t, err)
+	tp.delay = time.Millisecond
+	req, _ = http.NewRequest(http.MethodPut, "http://127.0.0.1/bucket/key", strings.NewReader(content))
+	_, err = tp.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeInterceptor_Component(t *testing.T) {
+	var calls int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			_, _ = w.Write([]byte("slow"))
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}, WithHedgeDelay(20*time.Millisecond))
+
+	start := time.Now()
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCircuitBreakerInterceptor(t *testing.T) {
+	now := time.Now()
+	failing := true
+	calls := 0
+	cfg := DefaultConfig()
+	cfg.CircuitBreakerThreshold = 2
+	cfg.CircuitBreakerCooldown = time.Minute
+	tp := circuitBreakerInterceptor("test", cfg, elog.EgoLogger, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if failing {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+	tp.now = func() time.Time { return now }
+	roundTrip := func() error {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+		_, err := tp.RoundTrip(req)
+		return err
+	}
+
+	// closed, failed responses are still returned
+	assert.NoError(t, roundTrip())
+	assert.Equal(t, circuitClosed, tp.state)
+	assert.NoError(t, roundTrip())
+	assert.Equal(t, circuitOpen, tp.state)
+	assert.Equal(t, 2, calls)
+
+	// open, fails fast
+	assert.Equal(t, ErrCircuitOpen, roundTrip())
+	assert.Equal(t, 2, calls)
+
+	// half open after the cooldown, the failed probe opens it again
+	now = now.Add(time.Minute)
+	assert.NoError(t, roundTrip())
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, circuitOpen, tp.state)
+	assert.Equal(t, ErrCircuitOpen, roundTrip())
+
+	// the succeeded probe closes it
+	now = now.Add(time.Minute)
+	failing = false
+	assert.True(t, tp.allow())
+	assert.Equal(t, circuitHalfOpen, tp.state)
+	// only one probe at a time
+	assert.False(t, tp.allow())
+	tp.report(false)
+	assert.Equal(t, circuitClosed, tp.state)
+	assert.NoError(t, roundTrip())
+	assert.Equal(t, circuitClosed, tp.state)
+}
+
+func TestCircuitBreakerInterceptor_Component(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var calls int32
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusInternalServerError)
+			}, WithCircuitBreaker(2, time.Minute))
+
+			for i := 0; i < 2; i++ {
+				_, err := client.Get(guid)
+				assert.Error(t, err)
+			}
+			_, err := client.Get(guid)
+			assert.True(t, errors.Is(err, ErrCircuitOpen), err)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestMetricInterceptor_Bytes(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(largeContent))
+		}
+	}, WithBucket("metric-bucket"))
+	// the counters are global, so the deltas are checked for the test to be run several times
+	readBytes := readBytesCounter.WithLabelValues("oss", "", http.MethodGet, "metric-bucket")
+	writtenBytes := writtenBytesCounter.WithLabelValues("oss", "", http.MethodPut, "metric-bucket")
+	read, written := testutil.ToFloat64(readBytes), testutil.ToFloat64(writtenBytes)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, res)
+	assert.Equal(t, float64(len(largeContent)), testutil.ToFloat64(readBytes)-read)
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	assert.Equal(t, float64(len(content)), testutil.ToFloat64(writtenBytes)-written)
+}
+
+func TestMetricInterceptor_DisableBodyMetrics(t *testing.T) {
+	body := ioutil.NopCloser(strings.NewReader(content))
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+	})
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	cfg := DefaultConfig()
+	cfg.DisableBodyMetrics = true
+	res, err := fixedInterceptor("test", cfg, elog.DefaultLogger, metricInterceptor("test", cfg, elog.DefaultLogger, rt)).RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, body, res.Body)
+
+	// the interceptors without body callbacks don't wrap the body either
+	res, err = headerInterceptor("test", cfg, elog.DefaultLogger, rt).RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, body, res.Body)
+
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(largeContent))
+		}
+	}, WithBucket("no-body-metric-bucket"), WithDisableBodyMetrics(true))
+	readBytes := readBytesCounter.WithLabelValues("oss", "", http.MethodGet, "no-body-metric-bucket")
+	writtenBytes := writtenBytesCounter.WithLabelValues("oss", "", http.MethodPut, "no-body-metric-bucket")
+	read, written := testutil.ToFloat64(readBytes), testutil.ToFloat64(writtenBytes)
+	got, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, got)
+	assert.Equal(t, float64(0), testutil.ToFloat64(readBytes)-read)
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	assert.Equal(t, float64(len(content)), testutil.ToFloat64(writtenBytes)-written)
+}
+
+func BenchmarkMetricInterceptor_Body(b *testing.B) {
+	data := []byte(largeContent)
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(data))}, nil
+	})
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bench-bucket/key", nil)
+	for _, disable := range []bool{false, true} {
+		cfg := DefaultConfig()
+		cfg.Bucket = "bench-bucket"
+		cfg.DisableBodyMetrics = disable
+		tp := fixedInterceptor("bench", cfg, elog.DefaultLogger, metricInterceptor("bench", cfg, elog.DefaultLogger, rt))
+		b.Run(fmt.Sprintf("disableBodyMetrics=%v", disable), func(b *testing.B) {
+			b.ReportAllocs()
+			buf := make([]byte, 512)
+			for i := 0; i < b.N; i++ {
+				res, _ := tp.RoundTrip(req)
+				for {
+					if _, err := res.Body.Read(buf); err != nil {
+						break
+					}
+				}
+				_ = res.Body.Close()
+			}
+		})
+	}
+}
+
+func TestMetricInterceptor_InFlight(t *testing.T) {
+	const n = 5
+	var (
+		arrived = make(chan struct{}, n)
+		release = make(chan struct{})
+	)
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			// fails the request at transport level
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		arrived <- struct{}{}
+		<-release
+		_, _ = w.Write([]byte(content))
+	}, WithBucket("in-flight-bucket"))
+	inFlight := func(method string) float64 {
+		return testutil.ToFloat64(inFlightGauge.WithLabelValues("oss", "", method, "in-flight-bucket"))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(guid)
+			assert.NoError(t, err)
+		}()
+	}
+	for i := 0; i < n; i++ {
+		<-arrived
+	}
+	assert.Equal(t, float64(n), inFlight(http.MethodGet))
+	close(release)
+	wg.Wait()
+	assert.Equal(t, float64(0), inFlight(http.MethodGet))
+
+	assert.Error(t, client.Del(guid))
+	assert.Equal(t, float64(0), inFlight(http.MethodDelete))
+}
+
+type tenantKey struct{}
+
+func TestHeaderInterceptor_Component(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			var header http.Header
+			options := []BuildOption{
+				WithHeaders(map[string]string{"X-Tenant-Id": "default", "X-Gateway": "awos"}),
+				WithHeadersFunc(func(ctx context.Context) map[string]string {
+					tenant, _ := ctx.Value(tenantKey{}).(string)
+					if tenant == "" {
+						return nil
+					}
+					// the signature isn't clobbered
+					return map[string]string{"x-tenant-id": tenant, "Authorization": "forged"}
+				}),
+			}
+			if storageType == StorageTypeAzure {
+				options = append(options, WithAccessKeySecret(testAzureKey))
+			}
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				_, _ = w.Write([]byte(content))
+			}, options...)
+
+			_, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, "default", header.Get("X-Tenant-Id"))
+			assert.Equal(t, "awos", header.Get("X-Gateway"))
+
+			ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-1")
+			_, err = client.WithContext(ctx).Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, "tenant-1", header.Get("X-Tenant-Id"))
+			assert.Equal(t, "awos", header.Get("X-Gateway"))
+			assert.NotEqual(t, "forged", header.Get("Authorization"))
+			assert.NotEmpty(t, header.Get("Authorization"))
+		})
+	}
+}
+
+func TestUserAgentInterceptor_Component(t *testing.T) {
+	sdkUserAgents := map[string]string{StorageTypeS3: "aws-sdk-go/", StorageTypeOSS: "aliyun-sdk-go/", StorageTypeAzure: ""}
+	for storageType, sdkUserAgent := range sdkUserAgents {
+		t.Run(storageType, func(t *testing.T) {
+			var userAgent string
+			options := []BuildOption{WithUserAgent("myapp/1.2.0")}
+			if storageType == StorageTypeAzure {
+				options = append(options, WithAccessKeySecret(testAzureKey))
+			}
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				userAgent = r.Header.Get("User-Agent")
+				_, _ = w.Write([]byte(content))
+			}, options...)
+
+			_, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasSuffix(userAgent, "myapp/1.2.0"), userAgent)
+			// the token of the sdk is kept
+			assert.True(t, strings.HasPrefix(userAgent, sdkUserAgent), userAgent)
+			if sdkUserAgent == "" {
+				assert.Equal(t, "myapp/1.2.0", userAgent)
+			}
+		})
+	}
+}
+
+func TestValidateHeaders(t *testing.T) {
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"X-Tenant-Id", true},
+		{"X-Amz-Meta-Tenant", false},
+		{"x-oss-tenant", false},
+		{"X-Ms-Tenant", false},
+		{"X-Goog-User-Project", false},
+		{"x-cos-tenant", false},
+		{"content-type", false},
+		{"Authorization", false},
+		{"X Tenant", false},
+	}
+	for _, tt := range tests {
+		err := validateHeaders(map[string]string{tt.header: "1"})
+		assert.Equal(t, tt.valid, err == nil, "%s: %v", tt.header, err)
+	}
+
+	_, err := newStorage("test", &config{bucketConfig: bucketConfig{StorageType: StorageTypeS3, Headers: map[string]string{"x-oss-tenant": "1"}}}, elog.DefaultLogger)
+	assert.Error(t, err)
+}
+
+// stallingBody returns data and blocks on the next read until the request is canceled, like a stalled connection
+type stallingBody struct {
+	ctx  context.Context
+	data []byte
+}
+
+func (b *stallingBody) Read(p []byte) (int, error) {
+	if len(b.data) > 0 {
+		n := copy(p, b.data)
+		b.data = b.data[n:]
+		return n, nil
+	}
+	<-b.ctx.Done()
+	return 0, b.ctx.Err()
+}
+
+func (b *stallingBody) Close() error {
+	return nil
+}
+
+func newTestIdleTimeoutInterceptor(rt http.RoundTripper) *idleTimeoutTransport {
+	cfg := DefaultConfig()
+	cfg.ReadTimeout = 50 * time.Millisecond
+	cfg.WriteTimeout = 50 * time.Millisecond
+	return idleTimeoutInterceptor("test", cfg, elog.DefaultLogger, rt)
+}
+
+func TestIdleTimeoutInterceptor_Read(t *testing.T) {
+	tp := newTestIdleTimeoutInterceptor(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: &stallingBody{ctx: r.Context(), data: []byte("partial")}}, nil
+	}))
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/bucket/key", nil)
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	start := time.Now()
+	data, err := ioutil.ReadAll(res.Body)
+	assert.Equal(t, ErrIdleTimeout, err)
+	assert.Equal(t, "partial", string(data))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout())
+	assert.NoError(t, res.Body.Close())
+}
+
+func TestIdleTimeoutInterceptor_NoBody(t *testing.T) {
+	for _, body := range []io.ReadCloser{nil, http.NoBody} {
+		tp := newTestIdleTimeoutInterceptor(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNoContent, Body: body}, nil
+		}))
+		req, _ := http.NewRequest(http.MethodDelete, "http://127.0.0.1/bucket/key", nil)
+		res, err := tp.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, body, res.Body)
+	}
+}
+
+// slowBody returns a byte per interval, which is progress within the idle timeout
+type slowBody struct {
+	n        int
+	interval time.Duration
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if b.n == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(b.interval)
+	b.n--
+	p[0] = 'a'
+	return 1, nil
+}
+
+func (b *slowBody) Close() error {
+	return nil
+}
+
+func TestIdleTimeoutInterceptor_Progress(t *testing.T) {
+	tp := newTestIdleTimeoutInterceptor(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		// the request body is sent slowly as well
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: &slowBody{n: 5, interval: 20 * time.Millisecond}}, nil
+	}))
+	req, _ := http.NewRequest(http.MethodPut, "http://127.0.0.1/bucket/key", &slowBody{n: 5, interval: 20 * time.Millisecond})
+	res, err := tp.RoundTrip(req)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", string(data))
+	assert.NoError(t, res.Body.Close())
+}
+
+func TestIdleTimeoutInterceptor_Write(t *testing.T) {
+	tp := newTestIdleTimeoutInterceptor(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		// the connection stalls after sending a part of the body
+		buf := make([]byte, 3)
+		if _, err := r.Body.Read(buf); err != nil {
+			return nil, err
+		}
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	}))
+	req, _ := http.NewRequest(http.MethodPut, "http://127.0.0.1/bucket/key", strings.NewReader(content))
+	_, err := tp.RoundTrip(req)
+	assert.Equal(t, ErrIdleTimeout, err)
+}
+
+func TestIdleTimeoutInterceptor_Component(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			done := make(chan struct{})
+			defer close(done)
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "100")
+				_, _ = w.Write([]byte(content))
+				w.(http.Flusher).Flush()
+				select {
+				case <-done:
+				case <-r.Context().Done():
+				}
+			}, WithReadTimeout(50*time.Millisecond))
+
+			_, err := client.Get(guid)
+			assert.True(t, errors.Is(err, ErrIdleTimeout), "%v", err)
+		})
+	}
+}
+
+func TestDefaultTimeoutInterceptor_Component(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			done := make(chan struct{})
+			defer close(done)
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/slow") {
+					_, _ = w.Write([]byte(content))
+					return
+				}
+				select {
+				case <-done:
+				case <-r.Context().Done():
+				}
+			}, WithDefaultTimeout(100*time.Millisecond), WithAccessKeySecret(testAzureKey))
+
+			begin := time.Now()
+			_, err := client.Get("slow")
+			assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+			assert.True(t, time.Since(begin) < 5*time.Second)
+
+			res, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+
+			// the deadline of the caller is kept
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+			begin = time.Now()
+			_, err = client.WithContext(ctx).Get("slow")
+			assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+			assert.True(t, time.Since(begin) >= 300*time.Millisecond)
+
+			// the cancellation of the parent still propagates
+			ctx, cancel = context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			_, err = client.WithContext(ctx).Get("slow")
+			assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+		})
+	}
+}
+
+func TestWithInterceptors(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeAzure} {
+		t.Run(storageType, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				calls    []string
+				attempts int
+			)
+			record := func(name string) Interceptor {
+				return func(next http.RoundTripper) http.RoundTripper {
+					return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+						mu.Lock()
+						// the headers of WithHeaders are added by a built-in interceptor inside
+						calls = append(calls, name+" before "+r.Header.Get("X-Tenant-Id"))
+						mu.Unlock()
+						res, err := next.RoundTrip(r)
+						if err != nil {
+							return res, err
+						}
+						mu.Lock()
+						calls = append(calls, name+" after "+strconv.Itoa(res.StatusCode))
+						mu.Unlock()
+						return res, nil
+					})
+				}
+			}
+			options := []BuildOption{
+				WithInterceptors(record("a"), record("b")),
+				WithInterceptors(record("c")),
+				WithHeaders(map[string]string{"X-Tenant-Id": "1"}),
+				WithEnableRetryInterceptor(true),
+				WithRetryBaseDelay(time.Millisecond),
+			}
+			if storageType == StorageTypeAzure {
+				options = append(options, WithAccessKeySecret(testAzureKey))
+			}
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.Equal(t, "1", r.Header.Get("X-Tenant-Id"))
+				attempts++
+				if attempts == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write([]byte(content))
+			}, options...)
+
+			res, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+
+			// the retries are inside
+			mu.Lock()
+			assert.Equal(t, 2, attempts)
+			assert.Equal(t, []string{"a before ", "b before ", "c before ", "c after 200", "b after 200", "a after 200"}, calls)
+			mu.Unlock()
+		})
+	}
+}
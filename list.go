@@ -0,0 +1,305 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultListPageSize is the number of keys fetched by each request of ObjectIterator
+const DefaultListPageSize = 1000
+
+const (
+	// s3MaxListKeys is the max keys of a listing request of s3 and oss
+	s3MaxListKeys = 1000
+	// azureMaxListKeys is the max results of a listing request of azure
+	azureMaxListKeys = 5000
+)
+
+type ListOptions func(options *listOptions)
+
+type listOptions struct {
+	pageSize int
+	// maxPageSize is the max keys of the backend, 0 if unlimited
+	maxPageSize int
+	pageDelay   time.Duration
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+func DefaultListOptions() *listOptions {
+	return &listOptions{
+		pageSize: DefaultListPageSize,
+	}
+}
+
+// ListWithPageSize sets the number of keys fetched by each request,
+// it's clamped to the max keys of the backend, 1000 for s3 and oss and 5000 for azure
+func ListWithPageSize(pageSize int) ListOptions {
+	return func(options *listOptions) {
+		if pageSize > 0 {
+			options.pageSize = pageSize
+		}
+	}
+}
+
+// ListWithPageDelay waits delay between the pages, which paces big scans to avoid being throttled
+func ListWithPageDelay(delay time.Duration) ListOptions {
+	return func(options *listOptions) {
+		if delay > 0 {
+			options.pageDelay = delay
+		}
+	}
+}
+
+// ListWithBackoff retries the pages throttled by the backend, with 429 or 503 responses, after base doubled
+// for each retry until it exceeds max. The delay between the pages is raised to the last backoff then,
+// and halved after each page back to the delay of ListWithPageDelay.
+func ListWithBackoff(base time.Duration, max time.Duration) ListOptions {
+	return func(options *listOptions) {
+		if base > 0 && max >= base {
+			options.backoffBase = base
+			options.backoffMax = max
+		}
+	}
+}
+
+// listWithMaxPageSize is appended to the options by the backends, so the page size is clamped
+// whatever the order of the options is
+func listWithMaxPageSize(options []ListOptions, maxPageSize int) []ListOptions {
+	return append(options[:len(options):len(options)], func(options *listOptions) {
+		options.maxPageSize = maxPageSize
+	})
+}
+
+func newListOptions(options []ListOptions) *listOptions {
+	listOpts := DefaultListOptions()
+	for _, opt := range options {
+		opt(listOpts)
+	}
+	if listOpts.maxPageSize > 0 && listOpts.pageSize > listOpts.maxPageSize {
+		listOpts.pageSize = listOpts.maxPageSize
+	}
+	return listOpts
+}
+
+// listPacer waits between the pages and retries the throttled ones
+type listPacer struct {
+	opts *listOptions
+	// delay is the wait before the next page
+	delay   time.Duration
+	started bool
+}
+
+func newListPacer(opts *listOptions) *listPacer {
+	return &listPacer{opts: opts, delay: opts.pageDelay}
+}
+
+// do calls list for the next page, after the delay if it's not the first page
+func (p *listPacer) do(ctx context.Context, list func() error) error {
+	backoff := p.opts.backoffBase
+	for {
+		if p.started && p.delay > 0 {
+			timer := time.NewTimer(p.delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		p.started = true
+		err := list()
+		if err == nil {
+			if p.delay /= 2; p.delay < p.opts.pageDelay {
+				p.delay = p.opts.pageDelay
+			}
+			return nil
+		}
+		if backoff <= 0 || backoff > p.opts.backoffMax || !isThrottled(err) {
+			return err
+		}
+		p.delay = backoff
+		backoff *= 2
+	}
+}
+
+// ListResult is a page of ListObjectsWithDelimiter
+type ListResult struct {
+	// Objects are the objects at the level of the prefix, with the key, etag, size, last modified time and storage class
+	Objects []ObjectMeta
+	// CommonPrefixes are the sub-prefixes ending with the delimiter, in lexicographical order
+	CommonPrefixes []string
+	// NextMarker is the marker of the next page, empty if it's the last page.
+	// It's the last key or common prefix of the page, except for azure whose markers are opaque.
+	NextMarker string
+}
+
+// delimitedPage groups sorted keys into the keys and common prefixes of a delimited listing like s3 does,
+// the keys and prefixes up to marker are skipped and at most maxKeys of them are returned.
+func delimitedPage(keys []string, prefix string, delimiter string, marker string, maxKeys int) ([]string, []string, string) {
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	var (
+		objects  = make([]string, 0)
+		prefixes = make([]string, 0)
+		last     string
+	)
+	for _, k := range keys {
+		entry := k
+		if delimiter != "" {
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				entry = k[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		// the keys of a common prefix are sorted together
+		if entry <= marker || entry == last {
+			continue
+		}
+		if len(objects)+len(prefixes) == maxKeys {
+			return objects, prefixes, last
+		}
+		if entry == k {
+			objects = append(objects, k)
+		} else {
+			prefixes = append(prefixes, entry)
+		}
+		last = entry
+	}
+	return objects, prefixes, ""
+}
+
+// listMetaPageFunc lists at most maxKeys objects after marker
+type listMetaPageFunc func(marker string, maxKeys int) ([]ObjectMeta, error)
+
+// walkObjects calls fn for each object listed page by page until fn returns an error or ctx is done,
+// ErrStopWalk stops walking without an error.
+func walkObjects(ctx context.Context, list listMetaPageFunc, fn func(ObjectMeta) error, options []ListOptions) error {
+	listOpts := newListOptions(options)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	pacer := newListPacer(listOpts)
+	marker := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var objects []ObjectMeta
+		err := pacer.do(ctx, func() (err error) {
+			objects, err = list(marker, listOpts.pageSize)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(obj); err != nil {
+				if errors.Is(err, ErrStopWalk) {
+					return nil
+				}
+				return err
+			}
+		}
+		// a short page is the last one
+		if len(objects) < listOpts.pageSize {
+			return nil
+		}
+		marker = objects[len(objects)-1].Key
+	}
+}
+
+// ObjectIterator walks objects lazily page by page, keys are in lexicographical order.
+//
+//	it := client.ListObjectsIter(key, prefix)
+//	for it.Next() {
+//		fmt.Println(it.Key(), it.Object().Size)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ObjectIterator struct {
+	ctx      context.Context
+	list     listMetaPageFunc
+	pageSize int
+	pacer    *listPacer
+	objects  []ObjectMeta
+	object   ObjectMeta
+	marker   string
+	last     bool
+	err      error
+	// trimPrefix is stripped from the keys, the KeyPrefix of config
+	trimPrefix string
+}
+
+func newObjectIterator(ctx context.Context, list listMetaPageFunc, options ...ListOptions) *ObjectIterator {
+	listOpts := newListOptions(options)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &ObjectIterator{
+		ctx:      ctx,
+		list:     list,
+		pageSize: listOpts.pageSize,
+		pacer:    newListPacer(listOpts),
+	}
+}
+
+// Next advances to the next object, it returns false when all objects are walked, an error occurs or the context is done.
+func (it *ObjectIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.objects) == 0 {
+		if it.last {
+			return false
+		}
+		var objects []ObjectMeta
+		err := it.pacer.do(it.ctx, func() (err error) {
+			objects, err = it.list(it.marker, it.pageSize)
+			return err
+		})
+		if err != nil {
+			it.err = err
+			return false
+		}
+		// a short page is the last one
+		it.last = len(objects) < it.pageSize
+		if len(objects) == 0 {
+			return false
+		}
+		it.objects = objects
+		it.marker = objects[len(objects)-1].Key
+	}
+	it.object = it.objects[0]
+	it.objects = it.objects[1:]
+	return true
+}
+
+// Key returns the key of the current object
+func (it *ObjectIterator) Key() string {
+	return strings.TrimPrefix(it.object.Key, it.trimPrefix)
+}
+
+// Object returns the current object with the fields of the listing, the key, etag, size, last modified time
+// and storage class, so the objects don't need a head each. The content type and user metadata are only set
+// by the backends whose listings have them, see WalkObjects.
+func (it *ObjectIterator) Object() ObjectMeta {
+	obj := it.object
+	obj.Key = it.Key()
+	return obj
+}
+
+// Err returns the error which stopped the iteration, nil if all keys are walked
+func (it *ObjectIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,426 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_ListObjectsIter(t *testing.T) {
+	client := newTestMemory()
+	expected := make([]string, 0)
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("dir/%02d", i)
+		expected = append(expected, key)
+		assert.NoError(t, client.Put(key, strings.NewReader(content), nil))
+	}
+	assert.NoError(t, client.Put("other", strings.NewReader(content), nil))
+
+	it := client.ListObjectsIter(guid, "dir/", ListWithPageSize(10))
+	keys := make([]string, 0)
+	for it.Next() {
+		keys = append(keys, it.Key())
+		assert.Equal(t, int64(len(content)), it.Object().Size)
+		assert.NotEmpty(t, it.Object().ETag)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, expected, keys)
+}
+
+func TestObjectIterator_Pages(t *testing.T) {
+	client := newTestMemory()
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, client.Put(fmt.Sprintf("%02d", i), strings.NewReader(content), nil))
+	}
+
+	pages := 0
+	it := newObjectIterator(context.Background(), func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		pages++
+		return client.(*Memory).listPage(guid, "", marker, maxKeys)
+	}, ListWithPageSize(10))
+	count := 0
+	for it.Next() {
+		count++
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, 20, count)
+	// the third page is empty
+	assert.Equal(t, 3, pages)
+}
+
+func TestObjectIterator_Cancel(t *testing.T) {
+	client := newTestMemory()
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, client.Put(fmt.Sprintf("%02d", i), strings.NewReader(content), nil))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := client.WithContext(ctx).ListObjectsIter(guid, "", ListWithPageSize(5))
+	assert.True(t, it.Next())
+	assert.Equal(t, "00", it.Key())
+	cancel()
+	assert.False(t, it.Next())
+	assert.True(t, errors.Is(it.Err(), context.Canceled))
+	assert.False(t, it.Next())
+}
+
+func TestObjectIterator_Error(t *testing.T) {
+	listErr := errors.New("list failed")
+	it := newObjectIterator(context.Background(), func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		if marker != "" {
+			return nil, listErr
+		}
+		return []ObjectMeta{{Key: "a"}, {Key: "b"}}, nil
+	}, ListWithPageSize(2))
+	assert.True(t, it.Next())
+	assert.True(t, it.Next())
+	assert.False(t, it.Next())
+	assert.Equal(t, listErr, it.Err())
+}
+
+func TestMemory_WalkObjects(t *testing.T) {
+	client := newTestMemory()
+	for i := 0; i < 25; i++ {
+		assert.NoError(t, client.Put(fmt.Sprintf("dir/%02d", i), strings.NewReader(content), map[string]string{"index": fmt.Sprint(i)}))
+	}
+	assert.NoError(t, client.Put("other", strings.NewReader(content), nil))
+
+	objects := make([]ObjectMeta, 0)
+	err := client.WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+		objects = append(objects, obj)
+		return nil
+	}, ListWithPageSize(10))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 25)
+	assert.Equal(t, "dir/00", objects[0].Key)
+	assert.Equal(t, "dir/24", objects[24].Key)
+	assert.Equal(t, int64(len(content)), objects[24].Size)
+	assert.Equal(t, "24", objects[24].UserMeta["index"])
+
+	// ErrStopWalk stops early without an error
+	keys := make([]string, 0)
+	err = client.WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+		keys = append(keys, obj.Key)
+		if len(keys) == 12 {
+			return ErrStopWalk
+		}
+		return nil
+	}, ListWithPageSize(10))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 12)
+	assert.Equal(t, "dir/11", keys[11])
+
+	// other errors abort the walk
+	walkErr := errors.New("process failed")
+	calls := 0
+	err = client.WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+		calls++
+		return walkErr
+	})
+	assert.Equal(t, walkErr, err)
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = client.WithContext(ctx).WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+		calls++
+		cancel()
+		return nil
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, calls)
+}
+
+func TestWalkObjects(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			markers := make([]string, 0)
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				assert.Equal(t, "dir/", query.Get("prefix"))
+				assert.Equal(t, "2", query.Get("max-keys"))
+				marker := query.Get("marker")
+				markers = append(markers, marker)
+				var contents string
+				switch marker {
+				case "":
+					contents = `<Contents><Key>dir/a</Key><LastModified>2026-10-14T08:30:00.000Z</LastModified><ETag>"etag-a"</ETag><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>` +
+						`<Contents><Key>dir/b</Key><LastModified>2026-10-14T08:30:00.000Z</LastModified><ETag>"etag-b"</ETag><Size>2</Size><StorageClass>GLACIER</StorageClass></Contents>`
+				case "dir/b":
+					contents = `<Contents><Key>dir/c</Key><LastModified>2026-10-14T08:30:00.000Z</LastModified><ETag>"etag-c"</ETag><Size>3</Size><StorageClass>STANDARD</StorageClass></Contents>`
+				}
+				_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>test-bucket</Name><Prefix>dir/</Prefix>%s</ListBucketResult>`, contents)
+			})
+
+			objects := make([]ObjectMeta, 0)
+			err := client.WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+				objects = append(objects, obj)
+				return nil
+			}, ListWithPageSize(2))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"", "dir/b"}, markers)
+			assert.Len(t, objects, 3)
+			assert.Equal(t, ObjectMeta{
+				Key:          "dir/b",
+				ETag:         "etag-b",
+				Size:         2,
+				LastModified: time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC),
+				StorageClass: "GLACIER",
+			}, objects[1])
+		})
+	}
+}
+
+func TestListObjectsIter_Object(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				var contents string
+				switch r.URL.Query().Get("marker") {
+				case "":
+					contents = `<Contents><Key>dir/a</Key><LastModified>2026-10-14T08:30:00.000Z</LastModified><ETag>"etag-a"</ETag><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>` +
+						`<Contents><Key>dir/b</Key><LastModified>2026-10-14T09:30:00.000Z</LastModified><ETag>"etag-b"</ETag><Size>2</Size><StorageClass>GLACIER</StorageClass></Contents>`
+				case "dir/b":
+					contents = `<Contents><Key>dir/c</Key><LastModified>2026-10-14T10:30:00.000Z</LastModified><ETag>"etag-c"</ETag><Size>3</Size><StorageClass>STANDARD_IA</StorageClass></Contents>`
+				}
+				_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>test-bucket</Name><Prefix>dir/</Prefix>%s</ListBucketResult>`, contents)
+			})
+
+			it := client.ListObjectsIter(guid, "dir/", ListWithPageSize(2))
+			objects := make([]ObjectMeta, 0)
+			for it.Next() {
+				assert.Equal(t, it.Key(), it.Object().Key)
+				objects = append(objects, it.Object())
+			}
+			assert.NoError(t, it.Err())
+			assert.Equal(t, []ObjectMeta{
+				{Key: "dir/a", ETag: "etag-a", Size: 1, LastModified: time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC), StorageClass: "STANDARD"},
+				{Key: "dir/b", ETag: "etag-b", Size: 2, LastModified: time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC), StorageClass: "GLACIER"},
+				{Key: "dir/c", ETag: "etag-c", Size: 3, LastModified: time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC), StorageClass: "STANDARD_IA"},
+			}, objects)
+		})
+	}
+}
+
+func TestListObjectsWithDelimiter(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				assert.Equal(t, "photos/", query.Get("prefix"))
+				assert.Equal(t, "/", query.Get("delimiter"))
+				var contents string
+				switch query.Get("marker") {
+				case "":
+					contents = `<IsTruncated>true</IsTruncated><NextMarker>photos/2024/</NextMarker>` +
+						`<CommonPrefixes><Prefix>photos/2023/</Prefix></CommonPrefixes><CommonPrefixes><Prefix>photos/2024/</Prefix></CommonPrefixes>`
+				case "photos/2024/":
+					contents = `<IsTruncated>false</IsTruncated>` +
+						`<Contents><Key>photos/a.jpg</Key><LastModified>2026-10-14T08:30:00.000Z</LastModified><ETag>"etag-a"</ETag><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>`
+				}
+				_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>test-bucket</Name><Prefix>photos/</Prefix>%s</ListBucketResult>`, contents)
+			})
+
+			res, err := client.ListObjectsWithDelimiter(guid, "photos/", "", 2, "/")
+			assert.NoError(t, err)
+			assert.Empty(t, res.Objects)
+			assert.Equal(t, []string{"photos/2023/", "photos/2024/"}, res.CommonPrefixes)
+			assert.Equal(t, "photos/2024/", res.NextMarker)
+
+			res, err = client.ListObjectsWithDelimiter(guid, "photos/", res.NextMarker, 2, "/")
+			assert.NoError(t, err)
+			assert.Empty(t, res.CommonPrefixes)
+			assert.Empty(t, res.NextMarker)
+			assert.Equal(t, []ObjectMeta{{
+				Key:          "photos/a.jpg",
+				ETag:         "etag-a",
+				Size:         1,
+				LastModified: time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC),
+				StorageClass: "STANDARD",
+			}}, res.Objects)
+		})
+	}
+}
+
+func TestAzure_ListObjectsWithDelimiter(t *testing.T) {
+	client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "photos/", query.Get("prefix"))
+		assert.Equal(t, "/", query.Get("delimiter"))
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>` +
+			`<BlobPrefix><Name>photos/2023/</Name></BlobPrefix>` +
+			`<Blob><Name>photos/a.jpg</Name><Properties><Content-Length>1</Content-Length><Etag>0x1</Etag></Properties></Blob>` +
+			`</Blobs><NextMarker></NextMarker></EnumerationResults>`))
+	})
+
+	res, err := client.ListObjectsWithDelimiter(guid, "photos/", "", 10, "/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"photos/2023/"}, res.CommonPrefixes)
+	if assert.Len(t, res.Objects, 1) {
+		assert.Equal(t, "photos/a.jpg", res.Objects[0].Key)
+		assert.Equal(t, int64(1), res.Objects[0].Size)
+	}
+	assert.Empty(t, res.NextMarker)
+}
+
+func TestListObjectsWithDelimiter_Local(t *testing.T) {
+	fs, _ := newTestFS(t)
+	for name, client := range map[string]Component{"memory": newTestMemory(), "fs": fs} {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{"photos/a.jpg", "photos/b.jpg", "photos/2023/x.jpg", "photos/2023/y/z.jpg", "photos/2024/x.jpg", "photosx", "videos/v.mp4"} {
+				assert.NoError(t, client.Put(key, strings.NewReader(content), nil))
+			}
+
+			res, err := client.ListObjectsWithDelimiter(guid, "photos/", "", 0, "/")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"photos/2023/", "photos/2024/"}, res.CommonPrefixes)
+			keys := make([]string, 0)
+			for _, obj := range res.Objects {
+				keys = append(keys, obj.Key)
+				assert.Equal(t, int64(len(content)), obj.Size)
+			}
+			assert.Equal(t, []string{"photos/a.jpg", "photos/b.jpg"}, keys)
+			assert.Empty(t, res.NextMarker)
+
+			// the root level
+			res, err = client.ListObjectsWithDelimiter(guid, "", "", 0, "/")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"photos/", "videos/"}, res.CommonPrefixes)
+			assert.Len(t, res.Objects, 1)
+			assert.Equal(t, "photosx", res.Objects[0].Key)
+
+			// the prefixes and keys share the pages
+			res, err = client.ListObjectsWithDelimiter(guid, "photos/", "", 3, "/")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"photos/2023/", "photos/2024/"}, res.CommonPrefixes)
+			assert.Len(t, res.Objects, 1)
+			assert.Equal(t, "photos/a.jpg", res.NextMarker)
+			res, err = client.ListObjectsWithDelimiter(guid, "photos/", "photos/2023/", 1, "/")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"photos/2024/"}, res.CommonPrefixes)
+			assert.Empty(t, res.Objects)
+			assert.Equal(t, "photos/2024/", res.NextMarker)
+			res, err = client.ListObjectsWithDelimiter(guid, "photos/", "photos/a.jpg", 3, "/")
+			assert.NoError(t, err)
+			assert.Empty(t, res.CommonPrefixes)
+			assert.Len(t, res.Objects, 1)
+			assert.Empty(t, res.NextMarker)
+
+			// all the keys without a delimiter
+			res, err = client.ListObjectsWithDelimiter(guid, "photos/", "", 0, "")
+			assert.NoError(t, err)
+			assert.Empty(t, res.CommonPrefixes)
+			assert.Len(t, res.Objects, 5)
+		})
+	}
+}
+
+func TestListWithPageSize(t *testing.T) {
+	for _, tc := range []struct {
+		storageType string
+		pageSize    int
+		want        string
+	}{
+		{storageType: StorageTypeS3, pageSize: 200, want: "200"},
+		{storageType: StorageTypeS3, pageSize: 5000, want: "1000"},
+		{storageType: StorageTypeOSS, pageSize: 200, want: "200"},
+		{storageType: StorageTypeOSS, pageSize: 5000, want: "1000"},
+		{storageType: StorageTypeAzure, pageSize: 2000, want: "2000"},
+		{storageType: StorageTypeAzure, pageSize: 10000, want: "5000"},
+	} {
+		t.Run(fmt.Sprintf("%s/%d", tc.storageType, tc.pageSize), func(t *testing.T) {
+			var requested []string
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				if tc.storageType == StorageTypeAzure {
+					requested = append(requested, query.Get("maxresults"))
+					_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs></Blobs><NextMarker></NextMarker></EnumerationResults>`))
+					return
+				}
+				requested = append(requested, query.Get("max-keys"))
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>test-bucket</Name></ListBucketResult>`))
+			}
+			var client Component
+			if tc.storageType == StorageTypeAzure {
+				client = newTestAzure(t, handler)
+			} else {
+				client = newTestComponent(t, tc.storageType, handler)
+			}
+
+			err := client.WalkObjects(guid, "dir/", func(ObjectMeta) error { return nil }, ListWithPageSize(tc.pageSize))
+			assert.NoError(t, err)
+			it := client.ListObjectsIter(guid, "dir/", ListWithPageSize(tc.pageSize))
+			for it.Next() {
+			}
+			assert.NoError(t, it.Err())
+			assert.Equal(t, []string{tc.want, tc.want}, requested)
+		})
+	}
+}
+
+func TestListWithBackoff(t *testing.T) {
+	var (
+		calls int
+		times []time.Time
+	)
+	client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		times = append(times, time.Now())
+		switch calls {
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<Error><Code>ServiceUnavailable</Code><Message>slow down</Message></Error>`))
+			return
+		case 3:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`<Error><Code>TooManyRequests</Code><Message>slow down</Message></Error>`))
+			return
+		}
+		contents := fmt.Sprintf(`<Contents><Key>dir/%d</Key></Contents>`, calls)
+		if calls < 5 {
+			// a full page of 2 keys
+			contents += fmt.Sprintf(`<Contents><Key>dir/%d-</Key></Contents>`, calls)
+		}
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>test-bucket</Name>%s</ListBucketResult>`, contents)
+	})
+
+	keys := make([]string, 0)
+	err := client.WalkObjects(guid, "dir/", func(obj ObjectMeta) error {
+		keys = append(keys, obj.Key)
+		return nil
+	}, ListWithPageSize(2), ListWithPageDelay(10*time.Millisecond), ListWithBackoff(20*time.Millisecond, time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/1", "dir/1-", "dir/4", "dir/4-", "dir/5"}, keys)
+	if assert.Len(t, times, 5) {
+		// the page delay, then the backoffs of the throttled pages, then the halved backoff
+		for i, want := range []time.Duration{10, 20, 40, 20} {
+			assert.True(t, times[i+1].Sub(times[i]) >= want*time.Millisecond, "wait %d: %v", i, times[i+1].Sub(times[i]))
+		}
+	}
+
+	// the throttled pages fail when the backoff exceeds the max, the other errors aren't retried
+	calls = 0
+	it := newObjectIterator(context.Background(), func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		calls++
+		return nil, &FakeStatusError{StatusCode: http.StatusServiceUnavailable}
+	}, ListWithBackoff(time.Millisecond, 4*time.Millisecond))
+	assert.False(t, it.Next())
+	assert.True(t, isThrottled(it.Err()), "%v", it.Err())
+	// retried after 1ms, 2ms and 4ms
+	assert.Equal(t, 4, calls)
+
+	calls = 0
+	it = newObjectIterator(context.Background(), func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		calls++
+		return nil, &FakeStatusError{StatusCode: http.StatusInternalServerError}
+	}, ListWithBackoff(time.Millisecond, time.Second))
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+	assert.Equal(t, 1, calls)
+}
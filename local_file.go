@@ -0,0 +1,75 @@
+package awos
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// GetToFile downloads the object of key to localPath. It's written to a temporary file in the same directory,
+// which is renamed to localPath once the download completes, so localPath is either unchanged or complete,
+// and the temporary file is removed if it fails. The parent directories are created if they don't exist.
+func GetToFile(ctx context.Context, c Component, key string, localPath string, options ...GetOptions) (err error) {
+	reader, err := c.WithContext(ctx).GetAsReader(key, options...)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	dir, name := filepath.Split(localPath)
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	// TempFile creates it only readable by the owner
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, reader); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), localPath)
+}
+
+// PutFromFile uploads the file at localPath as key by PutFromReader, so large files are uploaded in parts.
+// The content type is detected by the extension of localPath or the content unless PutWithContentType is given,
+// the user metadata can be given by PutWithMeta.
+func PutFromFile(ctx context.Context, c Component, key string, localPath string, options ...PutOptions) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	putOpts := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOpts)
+	}
+	if !putOpts.contentTypeSet {
+		if err := putOpts.detectContentType(localPath, f); err != nil {
+			return err
+		}
+		options = append(options[:len(options):len(options)], PutWithContentType(putOpts.contentType))
+	}
+	return PutFromReader(ctx, c, key, f, nil, options...)
+}
@@ -0,0 +1,114 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutFromFile_GetToFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.json")
+	assert.NoError(t, ioutil.WriteFile(src, []byte(`{"a":1}`), 0644))
+	client := NewFakeClient("fake-bucket")
+
+	err := PutFromFile(context.Background(), client, "obj", src, PutWithMeta(map[string]string{"head": "1"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.Calls("Put"))
+	obj, err := client.HeadObject("obj")
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", obj.ContentType)
+	assert.Equal(t, int64(7), obj.Size)
+	assert.Equal(t, map[string]string{"head": "1"}, obj.UserMeta)
+
+	dst := filepath.Join(dir, "sub", "dst.json")
+	assert.NoError(t, GetToFile(context.Background(), client, "obj", dst))
+	data, err := ioutil.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(data))
+
+	// the content is sniffed without an extension, PutWithContentType takes precedence
+	plain := filepath.Join(dir, "plain")
+	assert.NoError(t, ioutil.WriteFile(plain, []byte("hello"), 0644))
+	assert.NoError(t, PutFromFile(context.Background(), client, "plain", plain))
+	obj, err = client.HeadObject("plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", obj.ContentType)
+	assert.NoError(t, PutFromFile(context.Background(), client, "csv", plain, PutWithContentType("text/csv")))
+	obj, err = client.HeadObject("csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/csv", obj.ContentType)
+
+	_, err = os.Stat(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, PutFromFile(context.Background(), client, "missing", filepath.Join(dir, "missing")))
+}
+
+func TestPutFromFile_Multipart(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "large.bin")
+	data := strings.Repeat("a", 3*1024)
+	assert.NoError(t, ioutil.WriteFile(src, []byte(data), 0644))
+	client := NewFakeClient("fake-bucket")
+
+	assert.NoError(t, PutFromFile(context.Background(), client, "large", src, PutWithMultipartThreshold(1024)))
+	assert.Equal(t, 1, client.Calls("MultipartUpload"))
+	res, err := client.Get("large")
+	assert.NoError(t, err)
+	assert.Equal(t, data, res)
+}
+
+var errBody = errors.New("body read failed")
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errBody
+}
+
+// failingBodyComponent returns bodies failing after the first bytes of the object
+type failingBodyComponent struct {
+	Component
+}
+
+func (c failingBodyComponent) WithContext(ctx context.Context) Component {
+	return failingBodyComponent{c.Component.WithContext(ctx)}
+}
+
+func (c failingBodyComponent) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	reader, err := c.Component.GetAsReader(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(io.LimitReader(reader, 3), errReader{}), reader}, nil
+}
+
+func TestGetToFile_Error(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "dst")
+	assert.NoError(t, ioutil.WriteFile(dst, []byte("old"), 0644))
+	client := NewFakeClient("fake-bucket")
+
+	err := GetToFile(context.Background(), client, "missing", dst)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	// a failed read keeps the existing file and removes the temporary file
+	assert.NoError(t, client.Put("obj", strings.NewReader(content), nil))
+	err = GetToFile(context.Background(), failingBodyComponent{client}, "obj", dst)
+	assert.Equal(t, errBody, err)
+	data, err := ioutil.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
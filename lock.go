@@ -0,0 +1,55 @@
+package awos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// object lock retention modes of PutObjectRetention and PutWithRetention, only s3 supports object lock.
+// A version locked in governance mode can be deleted with DelWithBypassGovernance, one in compliance mode
+// can't be deleted by anyone until the retention expires.
+const (
+	RetentionGovernance = "GOVERNANCE"
+	RetentionCompliance = "COMPLIANCE"
+)
+
+var errObjectLock = fmt.Errorf("object lock is only supported by s3: %w", ErrUnsupported)
+
+func validateRetention(mode string, retainUntil time.Time) error {
+	if mode != RetentionGovernance && mode != RetentionCompliance {
+		return fmt.Errorf("unsupported retention mode %q", mode)
+	}
+	if retainUntil.IsZero() {
+		return fmt.Errorf("retention %s without the retain until date", mode)
+	}
+	return nil
+}
+
+type DelOptions func(options *delOptions)
+
+type delOptions struct {
+	bypassGovernance bool
+}
+
+func DefaultDelOptions() *delOptions {
+	return &delOptions{}
+}
+
+// DelWithBypassGovernance deletes a version locked in governance mode, which needs the s3:BypassGovernanceRetention
+// permission. It's ignored by the backends without object lock.
+func DelWithBypassGovernance() DelOptions {
+	return func(options *delOptions) {
+		options.bypassGovernance = true
+	}
+}
+
+// isS3NoObjectLockConfiguration checks the error of getting the legal hold or the retention of
+// an object which has none of them
+func isS3NoObjectLockConfiguration(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == "NoSuchObjectLockConfiguration"
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package awos
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// objectLockServer keeps the legal hold and the retention of one object, a version under legal hold
+// or retention can't be deleted, except the governance retention with the bypass header
+type objectLockServer struct {
+	mu          sync.Mutex
+	header      http.Header
+	legalHold   string
+	mode        string
+	retainUntil time.Time
+	deleted     bool
+}
+
+type objectLockXML struct {
+	Status          string `xml:"Status"`
+	Mode            string `xml:"Mode"`
+	RetainUntilDate string `xml:"RetainUntilDate"`
+}
+
+func (s *objectLockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	_, legalHold := query["legal-hold"]
+	_, retention := query["retention"]
+	switch {
+	case r.Method == http.MethodPut && (legalHold || retention):
+		var body objectLockXML
+		data, _ := ioutil.ReadAll(r.Body)
+		_ = xml.Unmarshal(data, &body)
+		if legalHold {
+			s.legalHold = body.Status
+			return
+		}
+		s.mode = body.Mode
+		s.retainUntil, _ = time.Parse(time.RFC3339, body.RetainUntilDate)
+	case r.Method == http.MethodGet && (legalHold || retention):
+		if (legalHold && s.legalHold == "") || (retention && s.mode == "") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchObjectLockConfiguration</Code><Message>none</Message></Error>`))
+			return
+		}
+		if legalHold {
+			_, _ = fmt.Fprintf(w, `<LegalHold><Status>%s</Status></LegalHold>`, s.legalHold)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<Retention><Mode>%s</Mode><RetainUntilDate>%s</RetainUntilDate></Retention>`,
+			s.mode, s.retainUntil.Format(time.RFC3339))
+	case r.Method == http.MethodPut:
+		_, _ = ioutil.ReadAll(r.Body)
+		s.header = r.Header.Clone()
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodDelete:
+		locked := s.legalHold == "ON" || s.mode == RetentionCompliance ||
+			(s.mode == RetentionGovernance && r.Header.Get("X-Amz-Bypass-Governance-Retention") != "true")
+		if locked && query.Get("versionId") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>locked</Message></Error>`))
+			return
+		}
+		s.deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestPutWithRetention(t *testing.T) {
+	server := &objectLockServer{}
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	retainUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assert.NoError(t, client.Put("key", strings.NewReader(content), nil, PutWithRetention(RetentionGovernance, retainUntil), PutWithLegalHold()))
+	assert.Equal(t, RetentionGovernance, server.header.Get("X-Amz-Object-Lock-Mode"))
+	assert.Equal(t, "2030-01-02T03:04:05Z", server.header.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	assert.Equal(t, "ON", server.header.Get("X-Amz-Object-Lock-Legal-Hold"))
+	// s3 requires the Content-MD5 of locked objects
+	assert.NotEmpty(t, server.header.Get("Content-MD5"))
+
+	assert.NoError(t, client.Put("key", strings.NewReader(content), nil))
+	assert.Empty(t, server.header.Get("X-Amz-Object-Lock-Mode"))
+	assert.Empty(t, server.header.Get("X-Amz-Object-Lock-Legal-Hold"))
+
+	assert.Error(t, client.Put("key", strings.NewReader(content), nil, PutWithRetention("FOREVER", retainUntil)))
+	assert.Error(t, client.Put("key", strings.NewReader(content), nil, PutWithRetention(RetentionCompliance, time.Time{})))
+}
+
+func TestObjectLegalHold(t *testing.T) {
+	server := &objectLockServer{}
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+
+	on, err := client.GetObjectLegalHold("key")
+	assert.NoError(t, err)
+	assert.False(t, on)
+
+	assert.NoError(t, client.PutObjectLegalHold("key", true))
+	on, err = client.GetObjectLegalHold("key")
+	assert.NoError(t, err)
+	assert.True(t, on)
+	assert.Error(t, client.DelVersion("key", "v1", DelWithBypassGovernance()))
+
+	assert.NoError(t, client.PutObjectLegalHold("key", false))
+	on, err = client.GetObjectLegalHold("key")
+	assert.NoError(t, err)
+	assert.False(t, on)
+	assert.NoError(t, client.DelVersion("key", "v1"))
+}
+
+func TestObjectRetention(t *testing.T) {
+	server := &objectLockServer{}
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	retainUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mode, until, err := client.GetObjectRetention("key")
+	assert.NoError(t, err)
+	assert.Empty(t, mode)
+	assert.True(t, until.IsZero())
+
+	assert.NoError(t, client.PutObjectRetention("key", RetentionGovernance, retainUntil))
+	mode, until, err = client.GetObjectRetention("key")
+	assert.NoError(t, err)
+	assert.Equal(t, RetentionGovernance, mode)
+	assert.True(t, retainUntil.Equal(until), "%v", until)
+
+	// the governance locked version can only be deleted with the bypass
+	assert.Error(t, client.DelVersion("key", "v1"))
+	assert.False(t, server.deleted)
+	assert.NoError(t, client.DelVersion("key", "v1", DelWithBypassGovernance()))
+	assert.True(t, server.deleted)
+
+	server.deleted = false
+	assert.NoError(t, client.PutObjectRetention("key", RetentionCompliance, retainUntil))
+	assert.Error(t, client.DelVersion("key", "v1", DelWithBypassGovernance()))
+	assert.False(t, server.deleted)
+
+	assert.Error(t, client.PutObjectRetention("key", "governance", retainUntil))
+}
+
+func TestObjectLock_Unsupported(t *testing.T) {
+	retainUntil := time.Now().Add(time.Hour)
+	fs, _ := newTestFS(t)
+	for name, client := range map[string]Component{
+		StorageTypeAzure: newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {}),
+		"memory":         newTestMemory(),
+		"fs":             fs,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.GetObjectLegalHold("key")
+			assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+			assert.True(t, errors.Is(client.PutObjectLegalHold("key", true), ErrUnsupported))
+			_, _, err = client.GetObjectRetention("key")
+			assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+			assert.True(t, errors.Is(client.PutObjectRetention("key", RetentionGovernance, retainUntil), ErrUnsupported))
+			err = client.Put("key", strings.NewReader(content), nil, PutWithLegalHold())
+			assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+		})
+	}
+
+	client := newTestComponent(t, StorageTypeGCS, func(w http.ResponseWriter, r *http.Request) {})
+	err := client.Put("key", strings.NewReader(content), nil, PutWithRetention(RetentionGovernance, retainUntil))
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+	assert.True(t, errors.Is(client.PutObjectLegalHold("key", true), ErrUnsupported))
+}
@@ -0,0 +1,862 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+var _ Component = (*Memory)(nil)
+
+// Memory is an in-memory Component, mainly for unit testing code which depends on awos.
+// It is safe for concurrent use and behaves like the real backends, e.g. Get returns
+// ErrObjectNotFound when the object doesn't exist.
+type Memory struct {
+	BucketName string
+	store      *memoryStore
+	ctx        context.Context
+	// detectContentType is EnableContentTypeDetection of config
+	detectContentType bool
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	// user meta, keys are lower cased
+	meta map[string]string
+	// http standard headers, such as Content-Type
+	headers map[string]string
+	// objects created by Append are appendable, like oss
+	appendable bool
+	tags       map[string]string
+	// acl of PutWithACL and PutObjectACL, empty means private
+	acl string
+	// md5 of the SSE-C key, empty if not encrypted with a customer key
+	sseCustomerKeyMD5 string
+}
+
+func newMemory(bucket string) *Memory {
+	return &Memory{
+		BucketName: bucket,
+		store: &memoryStore{
+			objects: make(map[string]*memoryObject),
+		},
+		ctx: context.Background(),
+	}
+}
+
+func (m *Memory) WithContext(ctx context.Context) Component {
+	return &Memory{
+		BucketName:        m.BucketName,
+		store:             m.store,
+		ctx:               ctx,
+		detectContentType: m.detectContentType,
+	}
+}
+
+func (m *Memory) object(key string) *memoryObject {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	return m.store.objects[key]
+}
+
+func (o *memoryObject) attributes(attributes []string) map[string]string {
+	res := make(map[string]string)
+	for _, v := range attributes {
+		if value, ok := o.headers[v]; ok {
+			res[v] = value
+			continue
+		}
+		if value, ok := o.meta[strings.ToLower(v)]; ok {
+			res[v] = value
+			continue
+		}
+		// the headers are looked up case-insensitively like http headers
+		for k, value := range o.headers {
+			if strings.EqualFold(k, v) {
+				res[v] = value
+				break
+			}
+		}
+	}
+	return res
+}
+
+// memoryETag is the quoted hex md5 of data, like s3 and oss etags of objects uploaded by Put
+func memoryETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func sseCustomerKeyMD5(key []byte) string {
+	if key == nil {
+		return ""
+	}
+	sum := md5.Sum(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkSSECustomerKey behaves like s3, the key must be given if and only if the object is encrypted with it
+func (o *memoryObject) checkSSECustomerKey(key []byte) error {
+	if err := validateSSECustomerKey(key); err != nil {
+		return err
+	}
+	if o.sseCustomerKeyMD5 != sseCustomerKeyMD5(key) {
+		return errors.New("awos: the SSE-C key doesn't match the object")
+	}
+	return nil
+}
+
+// archived reports whether the object is archived and not restored, so it can't be read
+func (o *memoryObject) archived() bool {
+	class := o.headers["Storage-Class"]
+	return (class == StorageClassArchive || class == StorageClassColdArchive) && o.headers["Restore"] == ""
+}
+
+// read returns the object data, respecting the range option
+func (o *memoryObject) read(options []GetOptions) ([]byte, error) {
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if _, err := getOpts.byteRange(); err != nil {
+		return nil, err
+	}
+	if getOpts.versionID != nil {
+		return nil, errMemoryVersioning
+	}
+	if err := o.checkSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, err
+	}
+	if o.archived() {
+		return nil, ErrObjectArchived
+	}
+	if getOpts.ifNoneMatch != nil {
+		etag := strings.Trim(*getOpts.ifNoneMatch, `"`)
+		if etag == "*" || etag == strings.Trim(o.headers["ETag"], `"`) {
+			return nil, ErrNotModified
+		}
+	}
+	if getOpts.rangeStart == nil {
+		if getOpts.decompress() {
+			return decompressBody(o.headers["Content-Encoding"], bytes.NewReader(o.data))
+		}
+		return o.data, nil
+	}
+
+	size := int64(len(o.data))
+	start, end := *getOpts.rangeStart, getOpts.rangeEnd+1
+	if start >= size {
+		return nil, fmt.Errorf("memory range: invalid range start %d, object size %d", start, size)
+	}
+	if getOpts.rangeEnd < 0 || end > size {
+		end = size
+	}
+	return o.data[start:end], nil
+}
+
+// don't forget to call the close() method of the io.ReadCloser
+func (m *Memory) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return nil, ErrObjectNotFound
+	}
+	data, err := obj.read(options)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// don't forget to call the close() method of the io.ReadCloser
+func (m *Memory) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return nil, nil, ErrObjectNotFound
+	}
+	data, err := obj.read(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), obj.attributes(attributes), nil
+}
+
+func (m *Memory) Get(key string, options ...GetOptions) (string, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return "", ErrObjectNotFound
+	}
+	data, err := obj.read(options)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (m *Memory) GetBytes(key string, options ...GetOptions) ([]byte, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return nil, ErrObjectNotFound
+	}
+	data, err := obj.read(options)
+	if err != nil {
+		return nil, err
+	}
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if getOpts.buffer != nil {
+		return append(getOpts.buffer, data...), nil
+	}
+	return append(make([]byte, 0, len(data)), data...), nil
+}
+
+// GetMulti stops fetching when the context of WithContext is done
+func (m *Memory) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(m.ctx, keys, options, m.GetBytes)
+}
+
+func (m *Memory) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	return m.GetAsReader(key, GetWithRange(offset, offset+length-1))
+}
+
+func (m *Memory) GetAndDecompress(key string) (string, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return "", ErrObjectNotFound
+	}
+	if obj.archived() {
+		return "", ErrObjectArchived
+	}
+
+	compressor := obj.meta[MetaCompressor]
+	if compressor != "" {
+		if compressor != "snappy" {
+			return "", errors.New("GetAndDecompress only supports snappy for now, got " + compressor)
+		}
+		decodedBytes, err := snappy.Decode(nil, obj.data)
+		if err != nil {
+			return "", err
+		}
+		return string(decodedBytes), nil
+	}
+	data, err := decompressBody(obj.headers["Content-Encoding"], bytes.NewReader(obj.data))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (m *Memory) GetAndDecompressAsReader(key string) (io.ReadCloser, error) {
+	result, err := m.GetAndDecompress(key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(result)), nil
+}
+
+func (m *Memory) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	if putOptions.objectLock() {
+		return errObjectLock
+	}
+	if m.detectContentType {
+		if err := putOptions.detectContentType(key, reader); err != nil {
+			return err
+		}
+	}
+	// memory keeps the backend neutral storage class, which is the same as oss
+	storageClass, err := backendStorageClass(ossStorageClasses, putOptions)
+	if err != nil {
+		return err
+	}
+	meta, err = putOptions.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	reader, err = putOptions.compressBody(reader)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if reader != nil {
+		var err error
+		data, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	obj := &memoryObject{
+		data:    data,
+		meta:    make(map[string]string),
+		headers: make(map[string]string),
+	}
+	for k, v := range meta {
+		obj.meta[k] = v
+	}
+	obj.headers["Content-Length"] = strconv.Itoa(len(data))
+	obj.headers["Content-Type"] = putOptions.contentType
+	obj.headers["ETag"] = memoryETag(data)
+	obj.headers["Last-Modified"] = time.Now().UTC().Format(http.TimeFormat)
+	if storageClass != "" {
+		obj.headers["Storage-Class"] = storageClass
+	}
+	if putOptions.contentEncoding != nil {
+		obj.headers["Content-Encoding"] = *putOptions.contentEncoding
+	}
+	if putOptions.contentDisposition != nil {
+		obj.headers["Content-Disposition"] = *putOptions.contentDisposition
+	}
+	if putOptions.cacheControl != nil {
+		obj.headers["Cache-Control"] = *putOptions.cacheControl
+	}
+	if putOptions.expires != nil {
+		obj.headers["Expires"] = putOptions.expires.UTC().Format(time.RFC1123)
+	}
+	if putOptions.sseS3 {
+		obj.headers[HeadServerSideEncryption] = "AES256"
+	}
+	if putOptions.sseKMSKeyID != nil {
+		obj.headers[HeadServerSideEncryption] = "aws:kms"
+		obj.headers[HeadServerSideEncryptionKeyID] = *putOptions.sseKMSKeyID
+	}
+	obj.sseCustomerKeyMD5 = sseCustomerKeyMD5(putOptions.sseCustomerKey)
+	obj.acl = putOptions.acl
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if !matchPutConditions(m.store.objects[key], putOptions) {
+		return ErrPreconditionFailed
+	}
+	m.store.objects[key] = obj
+	return nil
+}
+
+// matchPutConditions checks If-Match/If-None-Match against the current object, which may be nil
+func matchPutConditions(o *memoryObject, putOptions *putOptions) bool {
+	etag := ""
+	if o != nil {
+		etag = strings.Trim(o.headers["ETag"], `"`)
+	}
+	if putOptions.ifMatch != nil {
+		want := strings.Trim(*putOptions.ifMatch, `"`)
+		if o == nil || (want != "*" && want != etag) {
+			return false
+		}
+	}
+	if putOptions.ifNoneMatch != nil && o != nil {
+		want := strings.Trim(*putOptions.ifNoneMatch, `"`)
+		if want == "*" || want == etag {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Memory) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
+
+	var buf bytes.Buffer
+	err := uploadParts(reader, putOptions.partSize, 1, func(partNumber int, data []byte) error {
+		buf.Write(data)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return m.Put(key, bytes.NewReader(buf.Bytes()), meta, options...)
+}
+
+// Append behaves like oss, objects created by Put are not appendable,
+// and position must be equal to the current object length.
+func (m *Memory) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return position, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	obj := m.store.objects[key]
+	if obj == nil {
+		if position != 0 {
+			return position, fmt.Errorf("memory append: position %d is not equal to object length 0", position)
+		}
+		putOptions := DefaultPutOptions()
+		for _, opt := range options {
+			opt(putOptions)
+		}
+		obj = &memoryObject{
+			meta:       make(map[string]string),
+			headers:    map[string]string{"Content-Type": putOptions.contentType},
+			appendable: true,
+		}
+	}
+	if !obj.appendable {
+		return position, errors.New("memory append: object is not appendable")
+	}
+	if position != int64(len(obj.data)) {
+		return position, fmt.Errorf("memory append: position %d is not equal to object length %d", position, len(obj.data))
+	}
+
+	// copy on write, readers may still hold the old object
+	newObj := &memoryObject{
+		data:       make([]byte, 0, len(obj.data)+len(data)),
+		meta:       obj.meta,
+		headers:    make(map[string]string),
+		appendable: true,
+		tags:       obj.tags,
+		acl:        obj.acl,
+	}
+	newObj.data = append(append(newObj.data, obj.data...), data...)
+	for k, v := range obj.headers {
+		newObj.headers[k] = v
+	}
+	newObj.headers["Content-Length"] = strconv.Itoa(len(newObj.data))
+	newObj.headers["ETag"] = memoryETag(newObj.data)
+	newObj.headers["Last-Modified"] = time.Now().UTC().Format(http.TimeFormat)
+	m.store.objects[key] = newObj
+	return int64(len(newObj.data)), nil
+}
+
+func (m *Memory) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+
+	encodedBytes := snappy.Encode(nil, data)
+
+	meta["Compressor"] = "snappy"
+
+	return m.Put(key, bytes.NewReader(encodedBytes), meta, options...)
+}
+
+// Copy copies srcKey to dstKey, only within BucketName.
+func (m *Memory) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	copyOpts := DefaultCopyOptions()
+	for _, opt := range options {
+		opt(copyOpts)
+	}
+	if err := copyOpts.validate(); err != nil {
+		return err
+	}
+	if copyOpts.destBucket != "" && copyOpts.destBucket != m.BucketName {
+		return fmt.Errorf("memory can't copy to bucket %q: %w", copyOpts.destBucket, ErrUnsupported)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	src := m.store.objects[srcKey]
+	if src == nil {
+		return ErrObjectNotFound
+	}
+	if err := src.checkSSECustomerKey(copyOpts.sourceSSECustomerKey); err != nil {
+		return err
+	}
+
+	obj := &memoryObject{
+		data:              append([]byte(nil), src.data...),
+		meta:              copyOpts.userMeta(src.meta),
+		headers:           make(map[string]string, len(src.headers)),
+		tags:              copyOpts.objectTags(src.tags),
+		sseCustomerKeyMD5: sseCustomerKeyMD5(copyOpts.sseCustomerKey),
+	}
+	for k, v := range src.headers {
+		obj.headers[k] = v
+	}
+	if copyOpts.contentType != nil {
+		obj.headers["Content-Type"] = *copyOpts.contentType
+	}
+	obj.headers["Last-Modified"] = time.Now().UTC().Format(http.TimeFormat)
+	m.store.objects[dstKey] = obj
+	return nil
+}
+
+// Move copies srcKey to dstKey and deletes srcKey, the source is kept if the copy fails.
+func (m *Memory) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return move(m, srcKey, dstKey, options)
+}
+
+func (m *Memory) Del(key string) error {
+	m.store.mu.Lock()
+	delete(m.store.objects, key)
+	m.store.mu.Unlock()
+	return nil
+}
+
+// RestoreObject restores archived objects immediately, objects of other storage classes can't be restored
+func (m *Memory) RestoreObject(key string, options ...RestoreOptions) error {
+	restoreOpts := DefaultRestoreOptions()
+	for _, opt := range options {
+		opt(restoreOpts)
+	}
+	if err := restoreOpts.validate(); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	obj := m.store.objects[key]
+	if obj == nil {
+		return ErrObjectNotFound
+	}
+	if class := obj.headers["Storage-Class"]; class != StorageClassArchive && class != StorageClassColdArchive {
+		return fmt.Errorf("memory restore: object of storage class %q isn't archived", class)
+	}
+
+	// copy on write, readers may still hold the old object
+	newObj := *obj
+	newObj.headers = make(map[string]string, len(obj.headers)+1)
+	for k, v := range obj.headers {
+		newObj.headers[k] = v
+	}
+	newObj.headers["Restore"] = formatRestoreStatus(time.Now().AddDate(0, 0, restoreOpts.days))
+	m.store.objects[key] = &newObj
+	return nil
+}
+
+// GetVersion is not supported, memory objects aren't versioned
+func (m *Memory) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return "", errMemoryVersioning
+}
+
+// DelVersion is not supported, memory objects aren't versioned
+func (m *Memory) DelVersion(key string, versionID string, options ...DelOptions) error {
+	return errMemoryVersioning
+}
+
+func (m *Memory) DelMulti(keys []string) (map[string]error, error) {
+	m.store.mu.Lock()
+	for _, key := range keys {
+		delete(m.store.objects, key)
+	}
+	m.store.mu.Unlock()
+	return nil, nil
+}
+
+func (m *Memory) Head(key string, attributes []string, options ...GetOptions) (map[string]string, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return nil, ErrObjectNotFound
+	}
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if err := obj.checkSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, err
+	}
+	if getOpts.versionID != nil {
+		return nil, errMemoryVersioning
+	}
+	return obj.attributes(attributes), nil
+}
+
+func (m *Memory) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return nil, ErrObjectNotFound
+	}
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if err := obj.checkSSECustomerKey(getOpts.sseCustomerKey); err != nil {
+		return nil, err
+	}
+	if getOpts.versionID != nil {
+		return nil, errMemoryVersioning
+	}
+	return obj.objectMeta(key)
+}
+
+func (o *memoryObject) objectMeta(key string) (*ObjectMeta, error) {
+	header := make(http.Header)
+	for k, v := range o.headers {
+		header.Set(k, v)
+	}
+	meta, err := parseObjectMeta(header, "")
+	if err != nil {
+		return nil, err
+	}
+	meta.Key = key
+	if meta.StorageClass == "" {
+		meta.StorageClass = StorageClassStandard
+	}
+	for k, v := range o.meta {
+		meta.UserMeta[k] = v
+	}
+	return meta, nil
+}
+
+// ListObject lists keys in lexicographical order like s3 and oss do,
+// keys containing delimiter after the prefix are skipped.
+func (m *Memory) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	m.store.mu.RLock()
+	keys := make([]string, 0)
+	for k := range m.store.objects {
+		if !strings.HasPrefix(k, prefix) || k <= marker {
+			continue
+		}
+		if delimiter != "" && strings.Contains(k[len(prefix):], delimiter) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	m.store.mu.RUnlock()
+
+	sort.Strings(keys)
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+	}
+	return keys, nil
+}
+
+func (m *Memory) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	keys, err := m.ListObject(key, prefix, "", math.MaxInt32, "")
+	if err != nil {
+		return nil, err
+	}
+	objects, prefixes, nextMarker := delimitedPage(keys, prefix, delimiter, marker, maxKeys)
+	res := &ListResult{Objects: make([]ObjectMeta, 0, len(objects)), CommonPrefixes: prefixes, NextMarker: nextMarker}
+	for _, k := range objects {
+		// deleted since listed
+		obj := m.object(k)
+		if obj == nil {
+			continue
+		}
+		meta, err := obj.objectMeta(k)
+		if err != nil {
+			return nil, err
+		}
+		res.Objects = append(res.Objects, *meta)
+	}
+	return res, nil
+}
+
+// ListObjectVersions is not supported, memory objects aren't versioned
+func (m *Memory) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	return nil, errMemoryVersioning
+}
+
+// WalkObjects also sets the content type and user metadata, which s3 and oss listings don't have
+func (m *Memory) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	return walkObjects(m.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		keys, err := m.ListObject(key, prefix, marker, maxKeys, "")
+		if err != nil {
+			return nil, err
+		}
+		metas := make([]ObjectMeta, 0, len(keys))
+		for _, k := range keys {
+			// deleted since listed
+			obj := m.object(k)
+			if obj == nil {
+				continue
+			}
+			meta, err := obj.objectMeta(k)
+			if err != nil {
+				return nil, err
+			}
+			metas = append(metas, *meta)
+		}
+		return metas, nil
+	}, fn, options)
+}
+
+func (m *Memory) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return newObjectIterator(m.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		return m.listPage(key, prefix, marker, maxKeys)
+	}, options...)
+}
+
+// listPage lists a page of the objects of ListObjectsIter, the objects deleted since listed only have the key
+// since a short page would end the iteration
+func (m *Memory) listPage(key string, prefix string, marker string, maxKeys int) ([]ObjectMeta, error) {
+	keys, err := m.ListObject(key, prefix, marker, maxKeys, "")
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]ObjectMeta, 0, len(keys))
+	for _, k := range keys {
+		obj := m.object(k)
+		if obj == nil {
+			metas = append(metas, ObjectMeta{Key: k})
+			continue
+		}
+		meta, err := obj.objectMeta(k)
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, *meta)
+	}
+	return metas, nil
+}
+
+// SignURL returns a fake url of the object, SignOptions are only validated
+func (m *Memory) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+	if err := signOptions.validate(); err != nil {
+		return "", err
+	}
+	return m.signURL(key, http.MethodGet, expired), nil
+}
+
+func (m *Memory) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	return m.signURL(key, http.MethodPut, expired), nil
+}
+
+func (m *Memory) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	return &PostPolicy{
+		URL:    m.signURL("", http.MethodPost, expired),
+		Fields: map[string]string{"key": key},
+	}, nil
+}
+
+func (m *Memory) signURL(key string, method string, expired int64) string {
+	u := url.URL{
+		Scheme: StorageTypeMemory,
+		Host:   m.BucketName,
+		Path:   "/" + key,
+		RawQuery: url.Values{
+			"Method":  []string{method},
+			"Expires": []string{strconv.FormatInt(time.Now().Unix()+expired, 10)},
+		}.Encode(),
+	}
+	return u.String()
+}
+
+func (m *Memory) Exists(key string) (bool, error) {
+	return m.object(key) != nil, nil
+}
+
+func (m *Memory) GetObjectTagging(key string) (map[string]string, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return nil, ErrObjectNotFound
+	}
+	tags := make(map[string]string)
+	for k, v := range obj.tags {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func (m *Memory) GetObjectACL(key string) (string, error) {
+	obj := m.object(key)
+	if obj == nil {
+		return "", ErrObjectNotFound
+	}
+	if obj.acl == "" {
+		return ACLPrivate, nil
+	}
+	return obj.acl, nil
+}
+
+func (m *Memory) PutObjectACL(key string, acl string) error {
+	if err := validateObjectACL(acl); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	obj := m.store.objects[key]
+	if obj == nil {
+		return ErrObjectNotFound
+	}
+	newObj := *obj
+	newObj.acl = acl
+	m.store.objects[key] = &newObj
+	return nil
+}
+
+func (m *Memory) PutObjectTagging(key string, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	obj := m.store.objects[key]
+	if obj == nil {
+		return ErrObjectNotFound
+	}
+	newObj := *obj
+	newObj.tags = make(map[string]string)
+	for k, v := range tags {
+		newObj.tags[k] = v
+	}
+	m.store.objects[key] = &newObj
+	return nil
+}
+
+// GetObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (m *Memory) GetObjectLegalHold(key string) (bool, error) {
+	return false, errObjectLock
+}
+
+// PutObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (m *Memory) PutObjectLegalHold(key string, on bool) error {
+	return errObjectLock
+}
+
+// GetObjectRetention is not supported, it always returns ErrUnsupported.
+func (m *Memory) GetObjectRetention(key string) (string, time.Time, error) {
+	return "", time.Time{}, errObjectLock
+}
+
+// PutObjectRetention is not supported, it always returns ErrUnsupported.
+func (m *Memory) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return errObjectLock
+}
+
+// Close does nothing, the objects are kept for the clients sharing the store
+func (m *Memory) Close() error {
+	return nil
+}
+
+// ping always succeeds, the bucket of memory always exists
+func (m *Memory) ping() error {
+	return nil
+}
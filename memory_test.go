@@ -0,0 +1,139 @@
+package awos
+
+import (
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMemory() Component {
+	return DefaultContainer().Build(WithStorageType(StorageTypeMemory), WithBucket("memory-bucket"))
+}
+
+func TestMemory_PutGet(t *testing.T) {
+	client := newTestMemory()
+
+	err := client.Put(guid, strings.NewReader(content), map[string]string{"Head": "1"})
+	assert.NoError(t, err)
+
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	reader, meta, err := client.GetWithMeta(guid, []string{"head", "Content-Type"})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(reader)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, "1", meta["head"])
+	assert.Equal(t, "text/plain", meta["Content-Type"])
+
+	head, err := client.Head(guid, []string{"head", "Content-Length"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", head["head"])
+	assert.Equal(t, strconv.Itoa(expectLength), head["Content-Length"])
+
+	rangeReader, err := client.Range(guid, 3, 10)
+	assert.NoError(t, err)
+	data, _ = ioutil.ReadAll(rangeReader)
+	assert.Equal(t, content[3:], string(data))
+}
+
+func TestMemory_CompressAndPut(t *testing.T) {
+	client := newTestMemory()
+
+	err := client.CompressAndPut(compressGUID, strings.NewReader(compressContent), nil)
+	assert.NoError(t, err)
+
+	meta, err := client.Head(compressGUID, []string{MetaCompressor})
+	assert.NoError(t, err)
+	assert.Equal(t, "snappy", meta[MetaCompressor])
+
+	res, err := client.GetAndDecompress(compressGUID)
+	assert.NoError(t, err)
+	assert.Equal(t, compressContent, res)
+}
+
+func TestMemory_NotExist(t *testing.T) {
+	client := newTestMemory()
+
+	res, err := client.Get(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	assert.Empty(t, res)
+
+	reader, err := client.GetAsReader(guid)
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	assert.Nil(t, reader)
+
+	meta, err := client.Head(guid, []string{"head"})
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+	assert.Nil(t, meta)
+
+	ok, err := client.Exists(guid)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemory_ListObject(t *testing.T) {
+	client := newTestMemory()
+	for _, key := range []string{"a/1", "a/2", "a/b/3", "a/b/4", "c/5"} {
+		assert.NoError(t, client.Put(key, strings.NewReader(key), nil))
+	}
+
+	keys, err := client.ListObject("", "a/", "", 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/1", "a/2", "a/b/3", "a/b/4"}, keys)
+
+	keys, err = client.ListObject("", "a/", "", 0, "/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/1", "a/2"}, keys)
+
+	keys, err = client.ListObject("", "a/", "a/1", 2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/2", "a/b/3"}, keys)
+}
+
+func TestMemory_Del(t *testing.T) {
+	client := newTestMemory()
+	keys := []string{"aaa", "bbb", "ccc"}
+	for _, key := range keys {
+		assert.NoError(t, client.Put(key, strings.NewReader("2333333"), nil))
+	}
+
+	assert.NoError(t, client.Del("aaa"))
+	ok, _ := client.Exists("aaa")
+	assert.False(t, ok)
+
+	failed, err := client.DelMulti(keys)
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	for _, key := range keys {
+		ok, _ := client.Exists(key)
+		assert.False(t, ok)
+	}
+}
+
+func TestMemory_Concurrent(t *testing.T) {
+	client := newTestMemory()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := "key" + strconv.Itoa(i)
+			assert.NoError(t, client.Put(key, strings.NewReader(key), nil))
+			res, err := client.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, key, res)
+		}(i)
+	}
+	wg.Wait()
+
+	keys, err := client.ListObject("", "key", "", 0, "")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 50)
+}
@@ -0,0 +1,130 @@
+package awos
+
+import (
+	"time"
+
+	"github.com/gotomicro/ego/core/emetric"
+)
+
+// MetricsSink receives the metrics of the requests, see WithMetricsSink. The methods are called concurrently,
+// bucket is the configured bucket of the component. Embed NopMetricsSink to implement only some of them.
+type MetricsSink interface {
+	// RequestStart is called before a request is sent
+	RequestStart(method string, bucket string)
+	// RequestDone is called once the response headers are received or the request failed,
+	// code is the status text or the category of the error
+	RequestDone(method string, bucket string, code string)
+	// RequestLatency is called once the response body is read to the end or closed,
+	// or with RequestDone if DisableBodyMetrics is set
+	RequestLatency(method string, bucket string, latency time.Duration)
+	// WrittenBytes is called with the size of the request bodies of known length
+	WrittenBytes(method string, bucket string, n int64)
+	// ReadBytes is called for each read of the response bodies, unless DisableBodyMetrics is set
+	ReadBytes(method string, bucket string, n int)
+	// Retry is called before each retry of the retry interceptor, code is the one of the failed attempt
+	Retry(method string, bucket string, code string)
+	// Hedge is called when a hedged request of HedgeDelay is sent
+	Hedge(method string, bucket string)
+	// FailoverServed is called with the endpoint which served a read of FailoverEndpoints
+	FailoverServed(operation string, bucket string, endpoint string)
+}
+
+// NopMetricsSink drops all the metrics
+type NopMetricsSink struct{}
+
+func (NopMetricsSink) RequestStart(method string, bucket string)                          {}
+func (NopMetricsSink) RequestDone(method string, bucket string, code string)              {}
+func (NopMetricsSink) RequestLatency(method string, bucket string, latency time.Duration) {}
+func (NopMetricsSink) WrittenBytes(method string, bucket string, n int64)                 {}
+func (NopMetricsSink) ReadBytes(method string, bucket string, n int)                      {}
+func (NopMetricsSink) Retry(method string, bucket string, code string)                    {}
+func (NopMetricsSink) Hedge(method string, bucket string)                                 {}
+func (NopMetricsSink) FailoverServed(operation string, bucket string, endpoint string)    {}
+
+var readBytesCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_read_bytes_total",
+	Labels:    []string{"type", "name", "method", "peer"},
+}.Build()
+
+var writtenBytesCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_written_bytes_total",
+	Labels:    []string{"type", "name", "method", "peer"},
+}.Build()
+
+// inFlightGauge is the number of requests sent and waiting for the response headers
+var inFlightGauge = emetric.GaugeVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_in_flight_requests",
+	Labels:    []string{"type", "name", "method", "peer"},
+}.Build()
+
+var retryCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_retry_total",
+	Labels:    []string{"type", "name", "method", "peer", "code"},
+}.Build()
+
+var hedgeCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_hedge_total",
+	Labels:    []string{"type", "name", "method", "peer"},
+}.Build()
+
+// failoverServedCounter counts the reads by the endpoint which served them
+var failoverServedCounter = emetric.CounterVecOpts{
+	Namespace: emetric.DefaultNamespace,
+	Name:      "client_failover_served_total",
+	Labels:    []string{"type", "name", "peer", "operation", "endpoint"},
+}.Build()
+
+// emetricSink is the default sink, which records the metrics in the ego metric registry
+type emetricSink struct {
+	name string
+}
+
+func (s emetricSink) RequestStart(method string, bucket string) {
+	inFlightGauge.Inc("oss", s.name, method, bucket)
+}
+
+func (s emetricSink) RequestDone(method string, bucket string, code string) {
+	inFlightGauge.Add(-1, "oss", s.name, method, bucket)
+	emetric.ClientHandleCounter.Inc("oss", s.name, method, bucket, code)
+}
+
+func (s emetricSink) RequestLatency(method string, bucket string, latency time.Duration) {
+	emetric.ClientHandleHistogram.Observe(latency.Seconds(), "oss", s.name, method, bucket)
+}
+
+func (s emetricSink) WrittenBytes(method string, bucket string, n int64) {
+	writtenBytesCounter.Add(float64(n), "oss", s.name, method, bucket)
+}
+
+func (s emetricSink) ReadBytes(method string, bucket string, n int) {
+	readBytesCounter.Add(float64(n), "oss", s.name, method, bucket)
+}
+
+func (s emetricSink) Retry(method string, bucket string, code string) {
+	retryCounter.Inc("oss", s.name, method, bucket, code)
+}
+
+func (s emetricSink) Hedge(method string, bucket string) {
+	hedgeCounter.Inc("oss", s.name, method, bucket)
+}
+
+func (s emetricSink) FailoverServed(operation string, bucket string, endpoint string) {
+	failoverServedCounter.Inc("oss", s.name, bucket, operation, endpoint)
+}
+
+// metrics returns the sink of WithMetricsSink, the ego metric registry by default,
+// or a NopMetricsSink if EnableMetricInterceptor is disabled
+func (c *config) metrics(name string) MetricsSink {
+	switch {
+	case !c.EnableMetricInterceptor:
+		return NopMetricsSink{}
+	case c.metricsSink != nil:
+		return c.metricsSink
+	}
+	return emetricSink{name: name}
+}
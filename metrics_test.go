@@ -0,0 +1,100 @@
+package awos
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	NopMetricsSink
+	mu      sync.Mutex
+	started int
+	codes   []string
+	read    int
+	retries int
+}
+
+func (s *recordingSink) RequestStart(method string, bucket string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started++
+}
+
+func (s *recordingSink) RequestDone(method string, bucket string, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes = append(s.codes, method+" "+code)
+}
+
+func (s *recordingSink) ReadBytes(method string, bucket string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read += n
+}
+
+func (s *recordingSink) Retry(method string, bucket string, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries++
+}
+
+func newMetricsTestComponent(t *testing.T, bucket string, options ...BuildOption) Component {
+	failed := false
+	return newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		// the first get is retried
+		if r.Method == http.MethodGet && !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(content))
+		}
+	}, append([]BuildOption{WithBucket(bucket), WithEnableRetryInterceptor(true), WithRetryBaseDelay(time.Millisecond)}, options...)...)
+}
+
+func TestWithMetricsSink(t *testing.T) {
+	sink := &recordingSink{}
+	client := newMetricsTestComponent(t, "sink-bucket", WithMetricsSink(sink))
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Equal(t, 2, sink.started)
+	assert.Equal(t, []string{"PUT OK", "GET OK"}, sink.codes)
+	assert.Equal(t, len(content), sink.read)
+	assert.Equal(t, 1, sink.retries)
+	// the ego metric registry isn't used
+	assert.Equal(t, float64(0), testutil.ToFloat64(readBytesCounter.WithLabelValues("oss", "", http.MethodGet, "sink-bucket")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(retryCounter.WithLabelValues("oss", "", http.MethodGet, "sink-bucket", http.StatusText(http.StatusServiceUnavailable))))
+}
+
+func TestDisableMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	client := newMetricsTestComponent(t, "no-metric-bucket", WithEnableMetricInterceptor(false), WithMetricsSink(sink))
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Equal(t, 0, sink.started)
+	assert.Equal(t, 0, sink.retries)
+	assert.Equal(t, float64(0), testutil.ToFloat64(writtenBytesCounter.WithLabelValues("oss", "", http.MethodPut, "no-metric-bucket")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(readBytesCounter.WithLabelValues("oss", "", http.MethodGet, "no-metric-bucket")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(retryCounter.WithLabelValues("oss", "", http.MethodGet, "no-metric-bucket", http.StatusText(http.StatusServiceUnavailable))))
+}
@@ -0,0 +1,84 @@
+package awos
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultPartSize is the default part size of MultipartUpload
+	DefaultPartSize int64 = 8 << 20
+	// MinPartSize is the minimum part size of MultipartUpload, except for the last part
+	MinPartSize int64 = 5 << 20
+	// DefaultPartConcurrency is the default number of parts uploaded concurrently
+	DefaultPartConcurrency = 4
+)
+
+// uploadParts reads reader in parts of partSize and calls upload for each part with at most
+// concurrency uploads in flight, part numbers start at 1.
+// Memory usage is bounded to concurrency+1 parts. The first error stops reading and is returned.
+func uploadParts(reader io.Reader, partSize int64, concurrency int, upload func(partNumber int, data []byte) error) error {
+	if partSize < MinPartSize {
+		return fmt.Errorf("part size %d is smaller than the minimum %d", partSize, MinPartSize)
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	type part struct {
+		number int
+		data   []byte
+	}
+	parts := make(chan part)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	setErr := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range parts {
+				if failed() {
+					continue
+				}
+				if err := upload(p.number, p.data); err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+
+	for number := 1; !failed(); number++ {
+		data := make([]byte, partSize)
+		n, err := io.ReadFull(reader, data)
+		if n > 0 || number == 1 {
+			parts <- part{number: number, data: data[:n]}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			setErr(err)
+			break
+		}
+	}
+	close(parts)
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,102 @@
+package awos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type multipartServer struct {
+	mu        sync.Mutex
+	parts     map[int][]byte
+	completed []byte
+	aborted   bool
+}
+
+func (s *multipartServer) handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Get("uploadId") == "":
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><InitiateMultipartUploadResult><Bucket>test-bucket</Bucket><Key>key</Key><UploadId>upload-id</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut && query.Get("partNumber") != "":
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		s.parts[number] = data
+		s.mu.Unlock()
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost:
+		s.mu.Lock()
+		var buf bytes.Buffer
+		for i := 1; i <= len(s.parts); i++ {
+			buf.Write(s.parts[i])
+		}
+		s.completed = buf.Bytes()
+		s.mu.Unlock()
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><CompleteMultipartUploadResult><Bucket>test-bucket</Bucket><Key>key</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		s.aborted = true
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	data := make([]byte, 20<<20)
+	rand.Read(data)
+
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := &multipartServer{parts: make(map[int][]byte)}
+			client := newTestComponent(t, storageType, server.handle)
+
+			err := client.MultipartUpload("key", bytes.NewReader(data), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(3))
+			assert.NoError(t, err)
+			assert.Len(t, server.parts, 4)
+			for _, part := range server.parts {
+				assert.Len(t, part, int(MinPartSize))
+			}
+			assert.True(t, bytes.Equal(data, server.completed))
+			assert.False(t, server.aborted)
+		})
+	}
+}
+
+func TestMultipartUpload_PartSize(t *testing.T) {
+	client := newTestMemory()
+	err := client.MultipartUpload("key", bytes.NewReader([]byte(content)), nil, PutWithPartSize(1024))
+	assert.Error(t, err)
+
+	err = client.MultipartUpload("key", bytes.NewReader([]byte(content)), nil)
+	assert.NoError(t, err)
+	res, err := client.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+}
+
+func TestMultipartUpload_Abort(t *testing.T) {
+	server := &multipartServer{parts: make(map[int][]byte)}
+	client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("partNumber") == "2" {
+			_, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		server.handle(w, r)
+	})
+
+	err := client.MultipartUpload("key", bytes.NewReader(make([]byte, 3*MinPartSize)), nil, PutWithPartSize(MinPartSize))
+	assert.Error(t, err)
+	assert.True(t, server.aborted)
+	assert.Nil(t, server.completed)
+}
@@ -0,0 +1,76 @@
+package awos
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObjectMeta is the metadata of an object returned by HeadObject, normalized across backends
+type ObjectMeta struct {
+	Key string
+	// ETag without quotes
+	ETag         string
+	Size         int64
+	LastModified time.Time
+	ContentType  string
+	// ContentEncoding is the encoding of the stored bytes, such as gzip, Size is the size of them
+	ContentEncoding string
+	StorageClass    string
+	// VersionID is empty if the bucket isn't versioned
+	VersionID string
+	// Restore is nil unless the archived object is being restored or restored
+	Restore *RestoreStatus
+	// Expiration is nil unless a lifecycle rule of the bucket expires the object
+	Expiration *ObjectExpiration
+	// UserMeta keys are lower cased and without the x-amz-meta-, x-oss-meta- or x-ms-meta- prefix,
+	// whatever the casing of the metadata headers returned by the backend
+	UserMeta map[string]string
+}
+
+func trimETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+// parseObjectMeta parses the http headers of a head response, vendorPrefix is the prefix of
+// the backend specific headers, such as "X-Oss-".
+func parseObjectMeta(header http.Header, vendorPrefix string) (*ObjectMeta, error) {
+	meta := &ObjectMeta{
+		ETag:            trimETag(header.Get("ETag")),
+		ContentType:     header.Get("Content-Type"),
+		ContentEncoding: header.Get("Content-Encoding"),
+		StorageClass:    header.Get(vendorPrefix + "Storage-Class"),
+		VersionID:       header.Get(vendorPrefix + "Version-Id"),
+		UserMeta:        make(map[string]string),
+	}
+	if v := header.Get("Content-Length"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		meta.Size = size
+	}
+	if v := header.Get("Last-Modified"); v != "" {
+		lastModified, err := http.ParseTime(v)
+		if err != nil {
+			return nil, err
+		}
+		meta.LastModified = lastModified
+	}
+	restore, err := parseRestoreStatus(header.Get(vendorPrefix + "Restore"))
+	if err != nil {
+		return nil, err
+	}
+	meta.Restore = restore
+	if meta.Expiration, err = parseObjectExpiration(header.Get(vendorPrefix + "Expiration")); err != nil {
+		return nil, err
+	}
+	metaPrefix := vendorPrefix + "Meta-"
+	for k := range header {
+		if strings.HasPrefix(k, metaPrefix) {
+			meta.UserMeta[strings.ToLower(k[len(metaPrefix):])] = header.Get(k)
+		}
+	}
+	return meta, nil
+}
@@ -0,0 +1,153 @@
+package awos
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// headHandler serves head responses in the header format of the backend
+func headHandler(prefix, storageClass string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "not-exist") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 08:30:00 GMT")
+		w.Header().Set(prefix+"Meta-Owner", "a")
+		w.Header().Set(prefix+"Meta-Trace-Id", "t")
+		w.Header().Set(prefix+"Version-Id", "v1")
+		if storageClass != "" {
+			w.Header().Set(prefix+"Storage-Class", storageClass)
+		}
+	}
+}
+
+func TestHeadObject(t *testing.T) {
+	tests := []struct {
+		storageType  string
+		prefix       string
+		storageClass string
+		expectClass  string
+	}{
+		{storageType: StorageTypeS3, prefix: "X-Amz-", storageClass: "", expectClass: "STANDARD"},
+		{storageType: StorageTypeS3, prefix: "X-Amz-", storageClass: "GLACIER", expectClass: "GLACIER"},
+		{storageType: StorageTypeOSS, prefix: "X-Oss-", storageClass: "Archive", expectClass: "Archive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.storageType+"/"+tt.expectClass, func(t *testing.T) {
+			client := newTestComponent(t, tt.storageType, headHandler(tt.prefix, tt.storageClass))
+
+			meta, err := client.HeadObject(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, &ObjectMeta{
+				Key:          guid,
+				ETag:         "5d41402abc4b2a76b9719d911017c592",
+				Size:         5,
+				LastModified: time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC),
+				ContentType:  "application/json",
+				StorageClass: tt.expectClass,
+				VersionID:    "v1",
+				UserMeta:     map[string]string{"owner": "a", "trace-id": "t"},
+			}, meta)
+
+			_, err = client.HeadObject("not-exist")
+			assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+		})
+	}
+}
+
+func TestHead_MetaCase(t *testing.T) {
+	names := []string{"mixedcase", "MIXEDCASE", "MixedCase", "content-type", "etag"}
+	for _, tt := range []struct {
+		storageType string
+		prefix      string
+	}{
+		{storageType: StorageTypeS3, prefix: "x-amz-meta-"},
+		{storageType: StorageTypeOSS, prefix: "x-oss-meta-"},
+		{storageType: StorageTypeAzure, prefix: "x-ms-meta-"},
+	} {
+		t.Run(tt.storageType, func(t *testing.T) {
+			client := newTestComponent(t, tt.storageType, func(w http.ResponseWriter, r *http.Request) {
+				// not canonical header names, as sent by some backends and proxies
+				w.Header()["etag"] = []string{`"etag"`}
+				w.Header()["content-type"] = []string{"application/json"}
+				w.Header()[tt.prefix+"MixedCase"] = []string{"v"}
+				w.Header()[strings.ToUpper(tt.prefix)+"UPPER"] = []string{"u"}
+			}, WithAccessKeySecret(testAzureKey))
+
+			meta, err := client.Head(guid, names)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{
+				"mixedcase":    "v",
+				"MIXEDCASE":    "v",
+				"MixedCase":    "v",
+				"content-type": "application/json",
+				"etag":         `"etag"`,
+			}, meta)
+
+			obj, err := client.HeadObject(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"mixedcase": "v", "upper": "u"}, obj.UserMeta)
+		})
+	}
+
+	fs, _ := newTestFS(t)
+	for name, client := range map[string]Component{"memory": newTestMemory(), "fs": fs} {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), map[string]string{"MixedCase": "v"}, PutWithContentType("application/json")))
+			meta, err := client.Head(guid, names)
+			assert.NoError(t, err)
+			assert.Equal(t, "v", meta["mixedcase"])
+			assert.Equal(t, "v", meta["MIXEDCASE"])
+			assert.Equal(t, "v", meta["MixedCase"])
+			assert.Equal(t, "application/json", meta["content-type"])
+			assert.NotEmpty(t, meta["etag"])
+
+			obj, err := client.HeadObject(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"mixedcase": "v"}, obj.UserMeta)
+		})
+	}
+}
+
+func TestParseObjectMeta(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Content-Length", "x")
+	_, err := parseObjectMeta(header, "X-Oss-")
+	assert.Error(t, err)
+
+	header = make(http.Header)
+	header.Set("Last-Modified", "yesterday")
+	_, err = parseObjectMeta(header, "X-Oss-")
+	assert.Error(t, err)
+}
+
+func TestMemory_HeadObject(t *testing.T) {
+	client := newTestMemory()
+	before := time.Now().Add(-time.Second)
+	assert.NoError(t, client.Put("head-object", strings.NewReader(content), map[string]string{"Owner": "a"}, PutWithContentType("application/json")))
+
+	meta, err := client.HeadObject("head-object")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Trim(memoryETag([]byte(content)), `"`), meta.ETag)
+	assert.Equal(t, int64(len(content)), meta.Size)
+	assert.Equal(t, "application/json", meta.ContentType)
+	assert.Equal(t, StorageClassStandard, meta.StorageClass)
+	assert.Equal(t, map[string]string{"owner": "a"}, meta.UserMeta)
+	assert.True(t, meta.LastModified.After(before), meta.LastModified)
+
+	_, err = client.HeadObject("not-exist")
+	assert.True(t, errors.Is(err, ErrObjectNotFound), err)
+}
@@ -0,0 +1,138 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// DefaultObjectReaderConcurrency is the default number of range gets an ObjectReader issues concurrently
+const DefaultObjectReaderConcurrency = 4
+
+type ObjectReaderOptions func(options *objectReaderOptions)
+
+type objectReaderOptions struct {
+	concurrency int
+	getOptions  []GetOptions
+}
+
+func DefaultObjectReaderOptions() *objectReaderOptions {
+	return &objectReaderOptions{
+		concurrency: DefaultObjectReaderConcurrency,
+	}
+}
+
+// ObjectReaderWithConcurrency bounds the range gets of the concurrent ReadAt calls, the others wait for them
+func ObjectReaderWithConcurrency(concurrency int) ObjectReaderOptions {
+	return func(options *objectReaderOptions) {
+		if concurrency > 0 {
+			options.concurrency = concurrency
+		}
+	}
+}
+
+// ObjectReaderWithGetOptions applies options to the head and the gets of the object, such as GetWithSSECustomerKey.
+// GetWithRange is overridden by the ranges of the reads.
+func ObjectReaderWithGetOptions(options ...GetOptions) ObjectReaderOptions {
+	return func(readerOpts *objectReaderOptions) {
+		readerOpts.getOptions = append(readerOpts.getOptions, options...)
+	}
+}
+
+// ObjectReader reads an object by a range get for each read, so a part of a large object is read without
+// downloading it, e.g. to serve the range requests of media. ReadAt is safe for concurrent use,
+// Read and Seek share an offset like an io.SectionReader and aren't.
+type ObjectReader struct {
+	client     Component
+	ctx        context.Context
+	key        string
+	size       int64
+	getOptions []GetOptions
+	tokens     chan struct{}
+	section    *io.SectionReader
+}
+
+// NewObjectReaderAt heads the object of key and returns a reader of it and its size, the gets are done
+// with the context ctx. The reads of a versioned object are got from the version of the head.
+func NewObjectReaderAt(ctx context.Context, c Component, key string, options ...ObjectReaderOptions) (*ObjectReader, int64, error) {
+	readerOpts := DefaultObjectReaderOptions()
+	for _, opt := range options {
+		opt(readerOpts)
+	}
+	client := c.WithContext(ctx)
+	meta, err := client.HeadObject(key, readerOpts.getOptions...)
+	if err != nil {
+		return nil, 0, err
+	}
+	getOptions := readerOpts.getOptions
+	if meta.VersionID != "" {
+		getOptions = append(getOptions, GetWithVersionID(meta.VersionID))
+	}
+	r := &ObjectReader{
+		client: client,
+		ctx:    ctx,
+		key:    key,
+		size:   meta.Size,
+		// the reads append their ranges concurrently
+		getOptions: getOptions[:len(getOptions):len(getOptions)],
+		tokens:     make(chan struct{}, readerOpts.concurrency),
+	}
+	r.section = io.NewSectionReader(r, 0, meta.Size)
+	return r, meta.Size, nil
+}
+
+// Size returns the size of the object at the time of the head
+func (r *ObjectReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt gets the bytes of the object at off, it returns io.EOF if they're fewer than len(p)
+// since the end of the object is reached.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("awos: negative offset %d of %s", off, r.key)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := len(p)
+	if remaining := r.size - off; int64(n) > remaining {
+		n = int(remaining)
+	}
+
+	select {
+	case r.tokens <- struct{}{}:
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	}
+	defer func() { <-r.tokens }()
+	reader, err := r.client.GetAsReader(r.key, append(r.getOptions, GetWithRange(off, off+int64(n)-1))...)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	read, err := io.ReadFull(reader, p[:n])
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return read, fmt.Errorf("awos: got %d bytes at %d of %s, expected %d: %w", read, off, r.key, n, io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return read, err
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read reads from the offset of Seek and advances it
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	return r.section.Read(p)
+}
+
+// Seek sets the offset of Read, it doesn't send any request
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	return r.section.Seek(offset, whence)
+}
@@ -0,0 +1,97 @@
+package awos
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectReader(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(largeContent), nil))
+
+	r, size, err := NewObjectReaderAt(ctx, client, guid)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(largeContent)), size)
+	assert.Equal(t, size, r.Size())
+
+	for _, off := range []int64{0, 1, 1000, size / 2, size - 10} {
+		buf := make([]byte, 10)
+		n, err := r.ReadAt(buf, off)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, n)
+		assert.Equal(t, largeContent[off:off+10], string(buf), off)
+	}
+	// a read beyond the end is cut
+	buf := make([]byte, 10)
+	n, err := r.ReadAt(buf, size-4)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, largeContent[size-4:], string(buf[:n]))
+	_, err = r.ReadAt(buf, size)
+	assert.Equal(t, io.EOF, err)
+	_, err = r.ReadAt(buf, -1)
+	assert.Error(t, err)
+
+	// Read continues from Seek
+	pos, err := r.Seek(-100, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, size-100, pos)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent[size-100:], string(data))
+	_, err = r.Seek(5, io.SeekStart)
+	assert.NoError(t, err)
+	data = make([]byte, 3)
+	_, err = io.ReadFull(r, data)
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent[5:8], string(data))
+
+	_, _, err = NewObjectReaderAt(ctx, client, "missing")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestObjectReader_Concurrency(t *testing.T) {
+	var inFlight, maxInFlight, ranges int32
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranges, 1)
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(largeContent))
+	})
+
+	r, size, err := NewObjectReaderAt(context.Background(), client, guid, ObjectReaderWithConcurrency(2))
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			buf := make([]byte, 100)
+			n, err := r.ReadAt(buf, off)
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent[off:off+int64(n)], string(buf[:n]))
+		}(size * int64(i) / 10)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(8), atomic.LoadInt32(&ranges))
+	assert.True(t, atomic.LoadInt32(&maxInFlight) <= 2, "%d", maxInFlight)
+}
@@ -0,0 +1,74 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ObjectWriter uploads the bytes written to it as an object, see NewObjectWriter
+type ObjectWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error
+	once sync.Once
+	// closeErr is the error of the first Close or Abort
+	closeErr error
+	aborted  bool
+}
+
+// NewObjectWriter returns a writer uploading the bytes written to it to key with PutFromReader, so the size
+// doesn't need to be known. A small object is put on Close, a larger one is uploaded by MultipartUpload
+// while it's written. The parts are uploaded one at a time unless PutWithConcurrency is given, the memory is
+// bounded by PutWithPartSize and the parts in flight. Close completes the upload and returns its error,
+// Abort or a failed write gives up the upload and aborts the multipart upload, if any.
+func NewObjectWriter(ctx context.Context, c Component, key string, meta map[string]string, options ...PutOptions) *ObjectWriter {
+	pr, pw := io.Pipe()
+	w := &ObjectWriter{pw: pw, done: make(chan struct{})}
+	options = append([]PutOptions{PutWithConcurrency(1)}, options...)
+	go func() {
+		defer close(w.done)
+		w.err = PutFromReader(ctx, c, key, pr, meta, options...)
+		// the writes fail once the upload fails
+		if w.err != nil {
+			_ = pr.CloseWithError(w.err)
+		} else {
+			_ = pr.Close()
+		}
+	}()
+	return w
+}
+
+// Write writes p to the upload, it blocks while a part is uploaded. It returns the error of the upload
+// once it failed, and ErrWriterAborted after Abort. It isn't safe for concurrent use with Close and Abort.
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	if w.aborted {
+		return 0, ErrWriterAborted
+	}
+	return w.pw.Write(p)
+}
+
+// Close completes the upload and returns its error, it's the only way to tell whether the object is uploaded
+func (w *ObjectWriter) Close() error {
+	w.once.Do(func() {
+		_ = w.pw.Close()
+		<-w.done
+		w.closeErr = w.err
+	})
+	return w.closeErr
+}
+
+// Abort gives up the upload and returns nil, or the error of the upload if it failed before.
+// It does nothing after Close.
+func (w *ObjectWriter) Abort() error {
+	w.once.Do(func() {
+		w.aborted = true
+		_ = w.pw.CloseWithError(ErrWriterAborted)
+		<-w.done
+		if !errors.Is(w.err, ErrWriterAborted) {
+			w.closeErr = w.err
+		}
+	})
+	return w.closeErr
+}
@@ -0,0 +1,89 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectWriter(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemory()
+
+	w := NewObjectWriter(ctx, client, guid, map[string]string{"a": "1"})
+	for _, s := range []string{"aa", "bb", "cc"} {
+		n, err := w.Write([]byte(s))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+	}
+	assert.NoError(t, w.Close())
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, "aabbcc", res)
+	meta, err := client.Head(guid, []string{"a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", meta["a"])
+	// Close is idempotent
+	assert.NoError(t, w.Close())
+
+	// nothing is put after Abort
+	w = NewObjectWriter(ctx, client, "aborted", nil)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Abort())
+	_, err = w.Write([]byte(content))
+	assert.ErrorIs(t, err, ErrWriterAborted)
+	ok, err := client.Exists("aborted")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestObjectWriter_Multipart(t *testing.T) {
+	data := make([]byte, 3*MinPartSize+100)
+	rand.Read(data)
+
+	server := &multipartServer{parts: make(map[int][]byte)}
+	client := newTestComponent(t, StorageTypeS3, server.handle)
+	w := NewObjectWriter(context.Background(), client, "key", nil, PutWithPartSize(MinPartSize))
+	// written in chunks not aligned to the parts
+	for off := 0; off < len(data); off += 1 << 20 {
+		end := off + 1<<20
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := w.Write(data[off:end])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.Len(t, server.parts, 4)
+	assert.True(t, bytes.Equal(data, server.completed))
+	assert.False(t, server.aborted)
+
+	// the multipart upload is aborted
+	server = &multipartServer{parts: make(map[int][]byte)}
+	client = newTestComponent(t, StorageTypeS3, server.handle)
+	w = NewObjectWriter(context.Background(), client, "key", nil, PutWithPartSize(MinPartSize))
+	_, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Abort())
+	assert.True(t, server.aborted)
+	assert.Nil(t, server.completed)
+}
+
+func TestObjectWriter_Error(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	w := NewObjectWriter(context.Background(), client, "key", nil)
+	_, err := w.Write([]byte(content))
+	assert.NoError(t, err)
+	err = w.Close()
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrWriterAborted))
+	assert.Equal(t, err, w.Abort())
+}
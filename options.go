@@ -1,13 +1,43 @@
 package awos
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
 
 type putOptions struct {
 	contentType        string
+	contentTypeSet     bool
 	contentEncoding    *string
 	contentDisposition *string
 	cacheControl       *string
 	expires            *time.Time
+	ifMatch            *string
+	ifNoneMatch        *string
+	sseS3              bool
+	sseKMSKeyID        *string
+	sseCustomerKey     []byte
+	compression        string
+	compressionLevel   *int
+	storageClass       string
+	checksumAlgorithm  string
+	acl                string
+	meta               map[string]string
+	progress           *progressTracker
+	retentionMode      string
+	retainUntil        *time.Time
+	legalHold          bool
+	result             *PutResult
+	// only for MultipartUpload
+	partSize    int64
+	concurrency int
+	// only for PutFromReader, 0 means partSize
+	multipartThreshold int64
+	// only for PutFromReader, the size of readers of unknown size
+	contentLength *int64
 }
 
 type PutOptions func(options *putOptions)
@@ -15,9 +45,56 @@ type PutOptions func(options *putOptions)
 func PutWithContentType(contentType string) PutOptions {
 	return func(options *putOptions) {
 		options.contentType = contentType
+		options.contentTypeSet = true
 	}
 }
 
+// PutWithMeta sets user metadata, merged with the meta argument of Put and MultipartUpload, which it overrides
+func PutWithMeta(meta map[string]string) PutOptions {
+	return func(options *putOptions) {
+		if options.meta == nil {
+			options.meta = make(map[string]string, len(meta))
+		}
+		for k, v := range meta {
+			options.meta[k] = v
+		}
+	}
+}
+
+// userMeta merges meta with the metadata of PutWithMeta. Keys are trimmed and lower cased
+// the same way for every backend, they must be valid http header names.
+func (o *putOptions) userMeta(meta map[string]string) (map[string]string, error) {
+	if len(meta) == 0 && len(o.meta) == 0 {
+		return nil, nil
+	}
+	res := make(map[string]string, len(meta)+len(o.meta))
+	for _, m := range []map[string]string{meta, o.meta} {
+		for k, v := range m {
+			name := strings.ToLower(strings.TrimSpace(k))
+			if !validMetaKey(name) {
+				return nil, fmt.Errorf("invalid metadata key %q", k)
+			}
+			res[name] = v
+		}
+	}
+	return res, nil
+}
+
+// validMetaKey reports whether key is a token of RFC 7230, which is a valid header name
+func validMetaKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0 {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
 func PutWithContentEncoding(contentEncoding string) PutOptions {
 	return func(options *putOptions) {
 		options.contentEncoding = &contentEncoding
@@ -36,15 +113,214 @@ func PutWithCacheControl(cacheControl string) PutOptions {
 	}
 }
 
+// PutWithExpireTime sets the Expires header of the object, which is only returned to the clients and doesn't
+// delete the object, see ObjectMeta.Expiration for the expiration of the lifecycle rules
 func PutWithExpireTime(expires time.Time) PutOptions {
 	return func(options *putOptions) {
 		options.expires = &expires
 	}
 }
 
+// PutWithIfMatch only puts the object if its current etag matches,
+// otherwise ErrPreconditionFailed is returned. Not supported by oss.
+func PutWithIfMatch(etag string) PutOptions {
+	return func(options *putOptions) {
+		options.ifMatch = &etag
+	}
+}
+
+// PutWithIfNoneMatch only puts the object if its current etag doesn't match, use "*" to only create new objects,
+// otherwise ErrPreconditionFailed is returned. oss only supports "*".
+func PutWithIfNoneMatch(etag string) PutOptions {
+	return func(options *putOptions) {
+		options.ifNoneMatch = &etag
+	}
+}
+
+// PutWithStorageClass sets the storage class of the object, such as StorageClassIA,
+// which is mapped to the class of the backend. Classes the backend doesn't have are rejected with ErrUnsupported.
+func PutWithStorageClass(storageClass string) PutOptions {
+	return func(options *putOptions) {
+		options.storageClass = storageClass
+	}
+}
+
+// PutWithACL sets the acl of the object, such as ACLPublicRead, azure and gcs don't support object acls.
+func PutWithACL(acl string) PutOptions {
+	return func(options *putOptions) {
+		options.acl = acl
+	}
+}
+
+// PutWithProgress calls fn as the body is sent by Put, MultipartUpload, PutFromReader and ResumeUpload, whose skipped parts
+// count as sent. total is the size of the body if it's known, which is the compressed size with PutWithCompression.
+// It's supported by s3, gcs, cos, oss and azure.
+func PutWithProgress(fn ProgressFunc) PutOptions {
+	return func(options *putOptions) {
+		options.progress = newProgressTracker(fn, false)
+	}
+}
+
+// PutWithRetention locks the object until retainUntil in RetentionGovernance or RetentionCompliance mode,
+// the bucket must have object lock enabled. Only s3 supports it.
+func PutWithRetention(mode string, retainUntil time.Time) PutOptions {
+	return func(options *putOptions) {
+		options.retentionMode = mode
+		options.retainUntil = &retainUntil
+	}
+}
+
+// PutWithLegalHold puts the object under legal hold, the bucket must have object lock enabled. Only s3 supports it.
+func PutWithLegalHold() PutOptions {
+	return func(options *putOptions) {
+		options.legalHold = true
+	}
+}
+
+// objectLock reports whether the object is locked by PutWithRetention or PutWithLegalHold
+func (o *putOptions) objectLock() bool {
+	return o.retentionMode != "" || o.legalHold
+}
+
+// conditionalHeaders returns the If-Match/If-None-Match headers
+func (o *putOptions) conditionalHeaders() map[string]string {
+	headers := make(map[string]string)
+	if o.ifMatch != nil {
+		headers["If-Match"] = *o.ifMatch
+	}
+	if o.ifNoneMatch != nil {
+		headers["If-None-Match"] = *o.ifNoneMatch
+	}
+	return headers
+}
+
+// PutWithSSES3 encrypts the object with keys managed by the storage service (AES256)
+func PutWithSSES3() PutOptions {
+	return func(options *putOptions) {
+		options.sseS3 = true
+	}
+}
+
+// PutWithSSEKMS encrypts the object with the kms key, the default kms key of the service is used if keyID is empty
+func PutWithSSEKMS(keyID string) PutOptions {
+	return func(options *putOptions) {
+		options.sseKMSKeyID = &keyID
+	}
+}
+
+// PutWithChecksum sends the checksum of the uploaded content computed with algorithm, ChecksumMD5 or ChecksumSHA256,
+// for the backend to reject corrupted uploads, and checks the etag or the checksum in the response. Both fail with
+// ErrChecksumMismatch. ChecksumSHA256 is s3 only and also sends Content-MD5, MultipartUpload only sends the md5 of each part.
+// It's a no-op for memory and fs, which don't transfer the content.
+func PutWithChecksum(algorithm string) PutOptions {
+	return func(options *putOptions) {
+		options.checksumAlgorithm = algorithm
+	}
+}
+
+// PutWithCompression compresses the body with the codec, CompressionGzip or CompressionZstd, and sets it as the Content-Encoding.
+// GetAndDecompress and GetAndDecompressAsReader inflate it transparently.
+func PutWithCompression(codec string) PutOptions {
+	return func(options *putOptions) {
+		options.compression = codec
+	}
+}
+
+// PutWithCompressionLevel sets the level of PutWithCompression, e.g. gzip.BestSpeed, or 1 to 22 for zstd
+func PutWithCompressionLevel(level int) PutOptions {
+	return func(options *putOptions) {
+		options.compressionLevel = &level
+	}
+}
+
+// SSECustomerKeySize is the size of customer provided keys of SSE-C, which are 256 bits
+const SSECustomerKeySize = 32
+
+// PutWithSSECustomerKey encrypts the object with the customer provided 256-bit key (SSE-C),
+// the same key must be given by GetWithSSECustomerKey to read it. Not supported by oss.
+func PutWithSSECustomerKey(key []byte) PutOptions {
+	return func(options *putOptions) {
+		options.sseCustomerKey = key
+	}
+}
+
+func validateSSECustomerKey(key []byte) error {
+	if key != nil && len(key) != SSECustomerKeySize {
+		return fmt.Errorf("invalid SSE-C key size %d, it must be %d bytes", len(key), SSECustomerKeySize)
+	}
+	return nil
+}
+
+// validate checks options which can't be combined
+func (o *putOptions) validate() error {
+	sse := 0
+	if o.sseS3 {
+		sse++
+	}
+	if o.sseKMSKeyID != nil {
+		sse++
+	}
+	if o.sseCustomerKey != nil {
+		sse++
+	}
+	if sse > 1 {
+		return errors.New("PutWithSSES3, PutWithSSEKMS and PutWithSSECustomerKey can't be combined")
+	}
+	if err := validateCompression(o.compression); err != nil {
+		return err
+	}
+	if err := validateChecksum(o.checksumAlgorithm); err != nil {
+		return err
+	}
+	if err := validateACL(o.acl); err != nil {
+		return err
+	}
+	if o.retainUntil != nil {
+		if err := validateRetention(o.retentionMode, *o.retainUntil); err != nil {
+			return err
+		}
+	}
+	return validateSSECustomerKey(o.sseCustomerKey)
+}
+
+// PutWithPartSize sets the part size of MultipartUpload, it can't be smaller than MinPartSize
+func PutWithPartSize(partSize int64) PutOptions {
+	return func(options *putOptions) {
+		options.partSize = partSize
+	}
+}
+
+// PutWithConcurrency sets the number of parts uploaded concurrently by MultipartUpload
+func PutWithConcurrency(concurrency int) PutOptions {
+	return func(options *putOptions) {
+		options.concurrency = concurrency
+	}
+}
+
+// PutWithMultipartThreshold sets the size above which PutFromReader uploads by MultipartUpload,
+// it defaults to the part size
+func PutWithMultipartThreshold(threshold int64) PutOptions {
+	return func(options *putOptions) {
+		options.multipartThreshold = threshold
+	}
+}
+
+// PutWithContentLength is the size of the reader of PutFromReader, for readers of unknown size such as pipes.
+// A reader larger than the threshold is streamed to MultipartUpload right away, with the part size grown to fit
+// the maximum number of parts, instead of being buffered up to the threshold first. A smaller one is read
+// into a buffer of its size for Put, which needs to rewind the body for retries. PutFromReader fails if the
+// reader has more or less bytes.
+func PutWithContentLength(n int64) PutOptions {
+	return func(options *putOptions) {
+		options.contentLength = &n
+	}
+}
+
 func DefaultPutOptions() *putOptions {
 	return &putOptions{
 		contentType: "text/plain",
+		partSize:    DefaultPartSize,
+		concurrency: DefaultPartConcurrency,
 	}
 }
 
@@ -52,6 +328,17 @@ type getOptions struct {
 	contentType         *string
 	contentEncoding     *string
 	enableCRCValidation bool
+	rangeStart          *int64
+	rangeEnd            int64
+	ifNoneMatch         *string
+	sseCustomerKey      []byte
+	versionID           *string
+	buffer              []byte
+	checksumValidation  bool
+	progress            *progressTracker
+	decompression       bool
+	// rawContent keeps the stored bytes of the encoded objects, see getWithRawContent
+	rawContent bool
 }
 
 func DefaultGetOptions() *getOptions {
@@ -77,3 +364,177 @@ func EnableCRCValidation() GetOptions {
 		options.enableCRCValidation = true
 	}
 }
+
+// GetWithChecksumValidation verifies the content against the md5 etag, the Content-MD5 or the sha256 checksum
+// of the object when it's read to the end, the read fails with ErrChecksumMismatch if it's corrupted.
+// Ranges and objects without any of them, such as multipart uploads without a sha256 checksum, aren't verified.
+// The gzip encoded objects are requested as stored to verify them, then inflated as the http transport does.
+// It's a no-op for memory and fs.
+func GetWithChecksumValidation() GetOptions {
+	return func(options *getOptions) {
+		options.checksumValidation = true
+	}
+}
+
+// GetWithProgress calls fn as the body of Get, GetBytes, GetAsReader and GetWithMeta is received, total is the
+// Content-Length of the response. It's supported by s3, gcs, cos, oss and azure.
+func GetWithProgress(fn ProgressFunc) GetOptions {
+	return func(options *getOptions) {
+		options.progress = newProgressTracker(fn, true)
+	}
+}
+
+// GetWithDecompression inflates the objects stored with Content-Encoding gzip or zstd, e.g. by PutWithCompression
+// or by other tools, as they are read. The objects without them and the ranges are returned untouched.
+// GetWithChecksumValidation still verifies the stored bytes.
+func GetWithDecompression() GetOptions {
+	return func(options *getOptions) {
+		options.decompression = true
+	}
+}
+
+// GetWithRange only gets bytes [start, end] of the object, both inclusive.
+// A negative end means reading to the end of the object.
+func GetWithRange(start int64, end int64) GetOptions {
+	return func(options *getOptions) {
+		options.rangeStart = &start
+		options.rangeEnd = end
+	}
+}
+
+// GetWithIfNoneMatch only gets the object if its etag doesn't match, otherwise ErrNotModified is returned
+func GetWithIfNoneMatch(etag string) GetOptions {
+	return func(options *getOptions) {
+		options.ifNoneMatch = &etag
+	}
+}
+
+// GetWithSSECustomerKey decrypts the object encrypted by PutWithSSECustomerKey, also works for Head
+func GetWithSSECustomerKey(key []byte) GetOptions {
+	return func(options *getOptions) {
+		options.sseCustomerKey = key
+	}
+}
+
+// GetWithVersionID gets a specific version of the object in a versioned bucket, also works for Head. s3 and oss only
+func GetWithVersionID(versionID string) GetOptions {
+	return func(options *getOptions) {
+		options.versionID = &versionID
+	}
+}
+
+// GetWithBuffer makes GetBytes read the object into buf, which grows if it's too small,
+// so that the buffer can be reused across calls. The returned slice shares buf if it's large enough.
+func GetWithBuffer(buf []byte) GetOptions {
+	return func(options *getOptions) {
+		options.buffer = buf[:0]
+	}
+}
+
+// byteRange returns the value of the http Range header, or "" if no range is set
+func (o *getOptions) byteRange() (string, error) {
+	if o.rangeStart == nil {
+		return "", nil
+	}
+	start := *o.rangeStart
+	if start < 0 {
+		return "", fmt.Errorf("invalid range start %d", start)
+	}
+	if o.rangeEnd < 0 {
+		return fmt.Sprintf("bytes=%d-", start), nil
+	}
+	if o.rangeEnd < start {
+		return "", fmt.Errorf("invalid range, end %d is smaller than start %d", o.rangeEnd, start)
+	}
+	return fmt.Sprintf("bytes=%d-%d", start, o.rangeEnd), nil
+}
+
+type signOptions struct {
+	contentType      *string
+	contentLength    *int64
+	maxContentLength *int64
+	// the overridden response headers of SignURL
+	responseContentType        *string
+	responseContentDisposition *string
+	responseCacheControl       *string
+}
+
+func DefaultSignOptions() *signOptions {
+	return &signOptions{}
+}
+
+type SignOptions func(options *signOptions)
+
+// SignWithContentType requires the uploader to send this Content-Type
+func SignWithContentType(contentType string) SignOptions {
+	return func(options *signOptions) {
+		options.contentType = &contentType
+	}
+}
+
+// SignWithContentLength requires the uploader to send this Content-Length, only enforced by s3
+func SignWithContentLength(contentLength int64) SignOptions {
+	return func(options *signOptions) {
+		options.contentLength = &contentLength
+	}
+}
+
+// SignWithMaxContentLength limits the size of the uploaded object, only for SignPostPolicy
+func SignWithMaxContentLength(maxContentLength int64) SignOptions {
+	return func(options *signOptions) {
+		options.maxContentLength = &maxContentLength
+	}
+}
+
+// SignWithResponseContentType overrides the Content-Type of the response of SignURL, the object isn't changed
+func SignWithResponseContentType(contentType string) SignOptions {
+	return func(options *signOptions) {
+		options.responseContentType = &contentType
+	}
+}
+
+// SignWithResponseContentDisposition overrides the Content-Disposition of the response of SignURL,
+// see AttachmentContentDisposition to download the object as a file
+func SignWithResponseContentDisposition(contentDisposition string) SignOptions {
+	return func(options *signOptions) {
+		options.responseContentDisposition = &contentDisposition
+	}
+}
+
+// SignWithResponseCacheControl overrides the Cache-Control of the response of SignURL
+func SignWithResponseCacheControl(cacheControl string) SignOptions {
+	return func(options *signOptions) {
+		options.responseCacheControl = &cacheControl
+	}
+}
+
+// AttachmentContentDisposition returns the Content-Disposition downloading the object as filename,
+// which is escaped by RFC 6266 with a plain ascii fallback for old browsers
+func AttachmentContentDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback, url.PathEscape(filename))
+}
+
+// validate checks the overridden response headers, which must be printable ascii
+func (o *signOptions) validate() error {
+	for name, value := range map[string]*string{
+		"Content-Type":        o.responseContentType,
+		"Content-Disposition": o.responseContentDisposition,
+		"Cache-Control":       o.responseCacheControl,
+	} {
+		if value == nil {
+			continue
+		}
+		for i := 0; i < len(*value); i++ {
+			if c := (*value)[i]; c < 0x20 || c > 0x7e {
+				return fmt.Errorf("response %s %q must be printable ascii, see AttachmentContentDisposition for filenames", name, *value)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package awos
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutWithMeta(t *testing.T) {
+	clients := map[string]Component{
+		StorageTypeS3:     newTestComponent(t, StorageTypeS3, newBucketServer("X-Amz-").ServeHTTP),
+		StorageTypeOSS:    newTestComponent(t, StorageTypeOSS, newBucketServer("X-Oss-").ServeHTTP),
+		StorageTypeMemory: newTestMemory(),
+	}
+	for storageType, client := range clients {
+		t.Run(storageType, func(t *testing.T) {
+			err := client.Put(guid, strings.NewReader(content), map[string]string{"Owner": "a", "Team": "storage"},
+				PutWithMeta(map[string]string{" owner ": "b", "Env": "test"}),
+				PutWithContentType("application/json"),
+				PutWithCacheControl("max-age=60"),
+				PutWithContentDisposition(`attachment; filename="a.json"`),
+			)
+			assert.NoError(t, err)
+
+			head, err := client.Head(guid, []string{"owner", "team", "env", "Content-Type", "Cache-Control", "Content-Disposition"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{
+				// PutWithMeta overrides the meta argument, keys are normalized
+				"owner":               "b",
+				"team":                "storage",
+				"env":                 "test",
+				"Content-Type":        "application/json",
+				"Cache-Control":       "max-age=60",
+				"Content-Disposition": `attachment; filename="a.json"`,
+			}, head)
+
+			meta, err := client.HeadObject(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"owner": "b", "team": "storage", "env": "test"}, meta.UserMeta)
+		})
+	}
+}
+
+func TestPutWithMeta_InvalidKey(t *testing.T) {
+	client := newTestMemory()
+	for _, key := range []string{"", " ", "with space", "colon:", "中文"} {
+		err := client.Put(guid, strings.NewReader(content), nil, PutWithMeta(map[string]string{key: "v"}))
+		assert.Error(t, err, key)
+	}
+	err := client.Put(guid, strings.NewReader(content), map[string]string{"bad key": "v"})
+	assert.Error(t, err)
+
+	exists, err := client.Exists(guid)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
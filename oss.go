@@ -3,12 +3,20 @@ package awos
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -21,11 +29,48 @@ var _ Component = (*OSS)(nil)
 type OSS struct {
 	Bucket *oss.Bucket
 	Shards map[string]*oss.Bucket
+	ctx    context.Context
+	// transport is shared by the clients of all contexts
+	transport http.RoundTripper
+	// detectContentType is EnableContentTypeDetection of config
+	detectContentType bool
 }
 
-func (ossClient *OSS) WithContext(context.Context) Component {
-	// oss 暂时不好支持context，先忽略
-	return ossClient
+// WithContext returns an OSS whose requests are sent with ctx, since oss sdk doesn't support context,
+// the buckets are bound to ctx by an http client with the same transport.
+func (ossClient *OSS) WithContext(ctx context.Context) Component {
+	b := &OSS{
+		ctx:               ctx,
+		transport:         ossClient.transport,
+		detectContentType: ossClient.detectContentType,
+	}
+	httpClient := &http.Client{Transport: &contextTransport{ctx: ctx, rt: ossClient.transport}}
+	buckets := make(map[string]*oss.Bucket)
+	withContext := func(bucket *oss.Bucket) *oss.Bucket {
+		if bucket == nil {
+			return nil
+		}
+		if res, ok := buckets[bucket.BucketName]; ok {
+			return res
+		}
+		conf := bucket.Client.Config
+		// the endpoint was parsed by newComponent already, so it can't fail
+		client, err := oss.New(conf.Endpoint, conf.AccessKeyID, conf.AccessKeySecret, oss.HTTPClient(httpClient))
+		if err != nil {
+			return bucket
+		}
+		res := &oss.Bucket{Client: *client, BucketName: bucket.BucketName}
+		buckets[bucket.BucketName] = res
+		return res
+	}
+	b.Bucket = withContext(ossClient.Bucket)
+	if ossClient.Shards != nil {
+		b.Shards = make(map[string]*oss.Bucket, len(ossClient.Shards))
+		for k, bucket := range ossClient.Shards {
+			b.Shards[k] = withContext(bucket)
+		}
+	}
+	return b
 }
 
 func (ossClient *OSS) getBucket(key string) (*oss.Bucket, error) {
@@ -42,28 +87,27 @@ func (ossClient *OSS) getBucket(key string) (*oss.Bucket, error) {
 	return ossClient.Bucket, nil
 }
 
-// don't forget to call the close() method of the io.ReadCloser
-func (ossClient *OSS) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
-	bucket, err := ossClient.getBucket(key)
-	if err != nil {
-		return nil, err
+// progressBucket returns the bucket of key whose requests report their bytes to p, the bucket is bound
+// to a new context since the context of oss requests is the one of the client
+func (ossClient *OSS) progressBucket(key string, p *progressTracker) (*oss.Bucket, error) {
+	if p == nil {
+		return ossClient.getBucket(key)
 	}
+	return ossClient.WithContext(withProgress(ossClient.ctx, p)).(*OSS).getBucket(key)
+}
 
+// don't forget to call the close() method of the io.ReadCloser
+func (ossClient *OSS) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
 	getOpts := DefaultGetOptions()
 	for _, opt := range options {
 		opt(getOpts)
 	}
-	readCloser, err := bucket.GetObject(key, getOSSOptions(getOpts)...)
+	result, err := ossClient.get(key, getOpts)
 	if err != nil {
-		if oerr, ok := err.(oss.ServiceError); ok {
-			if oerr.StatusCode == 404 {
-				return nil, nil
-			}
-		}
 		return nil, err
 	}
 
-	return readCloser, nil
+	return result.Response, nil
 }
 
 // don't forget to call the close() method of the io.ReadCloser
@@ -76,19 +120,21 @@ func (ossClient *OSS) GetWithMeta(key string, attributes []string, options ...Ge
 	if err != nil {
 		return nil, nil, err
 	}
-	if result == nil {
-		return nil, nil, nil
-	}
 
 	return result.Response.Body, getOSSMeta(attributes, result.Response.Headers), nil
 }
 
 func (ossClient *OSS) Get(key string, options ...GetOptions) (string, error) {
-	data, err := ossClient.GetBytes(key, options...)
-	if err != nil {
-		return "", err
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
 	}
-	return string(data), nil
+	var res string
+	err := ossClient.read(key, getOpts, func(data []byte) error {
+		res = string(data)
+		return nil
+	})
+	return res, err
 }
 
 func (ossClient *OSS) GetBytes(key string, options ...GetOptions) ([]byte, error) {
@@ -96,35 +142,39 @@ func (ossClient *OSS) GetBytes(key string, options ...GetOptions) ([]byte, error
 	for _, opt := range options {
 		opt(getOpts)
 	}
-	result, err := ossClient.get(key, getOpts)
-	if err != nil {
-		return nil, err
-	}
-	if result == nil {
-		return nil, nil
-	}
+	var res []byte
+	err := ossClient.read(key, getOpts, func(data []byte) error {
+		res = getOpts.copyBytes(data)
+		return nil
+	})
+	return res, err
+}
 
-	body := result.Response
-	defer func() {
-		if body != nil {
-			body.Close()
-		}
-	}()
+// GetMulti stops fetching when the context of WithContext is done
+func (ossClient *OSS) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	return getMulti(ossClient.ctx, keys, options, ossClient.GetBytes)
+}
 
-	data, err := ioutil.ReadAll(body)
+// read reads the whole object and calls consume with the validated content, see readBody
+func (ossClient *OSS) read(key string, getOpts *getOptions, consume func(data []byte) error) error {
+	result, err := ossClient.get(key, getOpts)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer result.Response.Close()
 
-	if getOpts.enableCRCValidation && result.ServerCRC > 0 && result.ClientCRC.Sum64() != result.ServerCRC {
-		return nil, fmt.Errorf("crc64 check failed, reqId:%s, serverCRC:%d, clientCRC:%d", extractOSSRequestID(result.Response),
-			result.ServerCRC, result.ClientCRC.Sum64())
-	}
-	return data, err
+	return readBody(result.Response, getOpts.buffer, func(data []byte) error {
+		// the server crc is of the whole object, so don't validate partial content
+		if getOpts.enableCRCValidation && getOpts.rangeStart == nil && result.ServerCRC > 0 && result.ClientCRC.Sum64() != result.ServerCRC {
+			return fmt.Errorf("crc64 check failed, reqId:%s, serverCRC:%d, clientCRC:%d", extractOSSRequestID(result.Response),
+				result.ServerCRC, result.ClientCRC.Sum64())
+		}
+		return consume(data)
+	})
 }
 
 func (ossClient *OSS) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
-	return ossClient.Bucket.GetObject(key, oss.Range(offset, offset+length-1))
+	return ossClient.GetAsReader(key, GetWithRange(offset, offset+length-1))
 }
 
 func (ossClient *OSS) GetAndDecompress(key string) (string, error) {
@@ -132,9 +182,6 @@ func (ossClient *OSS) GetAndDecompress(key string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if result == nil {
-		return "", nil
-	}
 
 	body := result.Response
 	defer func() {
@@ -171,7 +218,7 @@ func (ossClient *OSS) GetAndDecompress(key string) (string, error) {
 		return string(decodedBytes), err
 	}
 
-	data, err := ioutil.ReadAll(body)
+	data, err := decompressBody(body.Headers.Get(oss.HTTPHeaderContentEncoding), body)
 	if err != nil {
 		return "", err
 	}
@@ -188,189 +235,1125 @@ func (ossClient *OSS) GetAndDecompressAsReader(key string) (io.ReadCloser, error
 }
 
 func (ossClient *OSS) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
-	bucket, err := ossClient.getBucket(key)
-	if err != nil {
-		return err
-	}
-
 	putOptions := DefaultPutOptions()
 	for _, opt := range options {
 		opt(putOptions)
 	}
-
-	ossOptions := make([]oss.Option, 0)
-	if meta != nil {
-		for k, v := range meta {
-			ossOptions = append(ossOptions, oss.Meta(k, v))
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	bucket, err := ossClient.progressBucket(key, putOptions.progress)
+	if err != nil {
+		return err
+	}
+	if putOptions.checksumAlgorithm == ChecksumSHA256 {
+		return fmt.Errorf("PutWithChecksum(ChecksumSHA256): %w", ErrUnsupported)
+	}
+	if ossClient.detectContentType {
+		if err := putOptions.detectContentType(key, reader); err != nil {
+			return err
 		}
 	}
-	ossOptions = append(ossOptions, oss.ContentType(putOptions.contentType))
-	if putOptions.contentEncoding != nil {
-		ossOptions = append(ossOptions, oss.ContentEncoding(*putOptions.contentEncoding))
+	reader, err = putOptions.compressBody(reader)
+	if err != nil {
+		return err
 	}
-	if putOptions.contentDisposition != nil {
-		ossOptions = append(ossOptions, oss.ContentDisposition(*putOptions.contentDisposition))
+	ossOptions, err := getOSSPutOptions(meta, putOptions)
+	if err != nil {
+		return err
 	}
-	if putOptions.cacheControl != nil {
-		ossOptions = append(ossOptions, oss.CacheControl(*putOptions.cacheControl))
+	var (
+		checksum *contentChecksum
+		header   http.Header
+	)
+	if reader != nil {
+		if checksum, err = putOptions.checksum(reader); err != nil {
+			return err
+		}
+		putOptions.progress.setReaderTotal(reader)
 	}
-	if putOptions.expires != nil {
-		ossOptions = append(ossOptions, oss.Expires(*putOptions.expires))
+	if checksum != nil {
+		ossOptions = append(ossOptions, oss.ContentMD5(*checksum.contentMD5()))
+	}
+	if checksum != nil || putOptions.result != nil {
+		ossOptions = append(ossOptions, oss.GetResponseHeader(&header))
 	}
 
 	return retry.Do(func() error {
 		err := bucket.PutObject(key, reader, ossOptions...)
+		if err == nil {
+			err = checksum.verify(header.Get(oss.HTTPHeaderEtag), header)
+		}
+		if err == nil {
+			putOptions.setPutResult(header, "X-Oss-")
+		}
 		if err != nil && reader != nil {
 			// Reset the body reader after the request since at this point it's already read
 			// Note that it's safe to ignore the error here since the 0,0 position is always valid
 			_, _ = reader.Seek(0, 0)
 		}
-		return err
-	}, retry.Attempts(3), retry.Delay(1*time.Second))
+		return ossClient.wrapError(err)
+	}, putRetryOptions()...)
 }
 
-func (ossClient *OSS) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
-	data, err := ioutil.ReadAll(reader)
+// MultipartUpload uploads reader in parts, which is suitable for large objects.
+// The upload is aborted if any part fails.
+func (ossClient *OSS) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	bucket, err := ossClient.getBucket(key)
 	if err != nil {
 		return err
 	}
-	if meta == nil {
-		meta = make(map[string]string)
-	}
-
-	encodedBytes := snappy.Encode(nil, data)
 
-	meta["Compressor"] = "snappy"
-
-	return ossClient.Put(key, bytes.NewReader(encodedBytes), meta, options...)
-}
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
+	}
 
-func (ossClient *OSS) Del(key string) error {
-	bucket, err := ossClient.getBucket(key)
+	if err := putOptions.validate(); err != nil {
+		return err
+	}
+	if putOptions.compression == "" {
+		putOptions.progress.setReaderTotal(reader)
+	}
+	partBucket, err := ossClient.progressBucket(key, putOptions.progress)
 	if err != nil {
 		return err
 	}
-
-	return bucket.DeleteObject(key)
-}
-
-func (ossClient *OSS) DelMulti(keys []string) error {
-	bucketsKeys := make(map[*oss.Bucket][]string)
-	for _, key := range keys {
-		bucket, err := ossClient.getBucket(key)
-		if err != nil {
+	if ossClient.detectContentType {
+		if reader, err = putOptions.detectStreamContentType(key, reader); err != nil {
 			return err
 		}
-		bucketsKeys[bucket] = append(bucketsKeys[bucket], key)
 	}
+	body := putOptions.compressStream(reader)
+	defer body.Close()
 
-	for bucket, bKeys := range bucketsKeys {
-		_, err := bucket.DeleteObjects(bKeys)
+	ossOptions, err := getOSSPutOptions(meta, putOptions)
+	if err != nil {
+		return err
+	}
+	imur, err := bucket.InitiateMultipartUpload(key, ossOptions...)
+	if err != nil {
+		return ossClient.wrapError(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		parts []oss.UploadPart
+	)
+	err = uploadParts(body, putOptions.partSize, putOptions.concurrency, func(partNumber int, data []byte) error {
+		part, err := ossUploadPart(partBucket, imur, partNumber, data, putOptions)
 		if err != nil {
 			return err
 		}
+		mu.Lock()
+		parts = append(parts, part)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		_ = bucket.AbortMultipartUpload(imur)
+		return ossClient.wrapError(err)
 	}
 
-	return nil
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	_, err = bucket.CompleteMultipartUpload(imur, parts)
+	return ossClient.wrapError(err)
 }
 
-func (ossClient *OSS) Head(key string, attributes []string) (map[string]string, error) {
+func (ossClient *OSS) createResumableUpload(key string, meta map[string]string, putOptions *putOptions) (string, error) {
 	bucket, err := ossClient.getBucket(key)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	ossOptions, err := getOSSPutOptions(meta, putOptions)
+	if err != nil {
+		return "", err
+	}
+	imur, err := bucket.InitiateMultipartUpload(key, ossOptions...)
+	if err != nil {
+		return "", ossClient.wrapError(err)
+	}
+	return imur.UploadID, nil
+}
+
+// ossUpload is the multipart upload of key in bucket
+func ossUpload(bucket *oss.Bucket, key string, uploadID string) oss.InitiateMultipartUploadResult {
+	return oss.InitiateMultipartUploadResult{Bucket: bucket.BucketName, Key: key, UploadID: uploadID}
+}
 
-	headers, err := bucket.GetObjectDetailedMeta(key)
+func (ossClient *OSS) uploadResumablePart(key string, uploadID string, partNumber int, data []byte, putOptions *putOptions) (string, error) {
+	bucket, err := ossClient.progressBucket(key, putOptions.progress)
 	if err != nil {
-		if oerr, ok := err.(oss.ServiceError); ok {
-			if oerr.StatusCode == 404 {
-				return nil, nil
-			}
-		}
-		return nil, err
+		return "", err
 	}
+	part, err := ossUploadPart(bucket, ossUpload(bucket, key, uploadID), partNumber, data, putOptions)
+	if err != nil {
+		return "", ossClient.wrapError(err)
+	}
+	return part.ETag, nil
+}
 
-	return getOSSMeta(attributes, headers), nil
+// ossUploadPart uploads a part, which is verified by its md5 with PutWithChecksum
+func ossUploadPart(bucket *oss.Bucket, imur oss.InitiateMultipartUploadResult, partNumber int, data []byte, putOptions *putOptions) (oss.UploadPart, error) {
+	checksum := putOptions.partChecksum(data)
+	var (
+		ossOptions []oss.Option
+		header     http.Header
+	)
+	if checksum != nil {
+		ossOptions = append(ossOptions, oss.ContentMD5(*checksum.contentMD5()), oss.GetResponseHeader(&header))
+	}
+	part, err := bucket.UploadPart(imur, bytes.NewReader(data), int64(len(data)), partNumber, ossOptions...)
+	if err != nil {
+		return part, err
+	}
+	return part, checksum.verify(part.ETag, header)
 }
 
-func (ossClient *OSS) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+func (ossClient *OSS) listResumableParts(key string, uploadID string) ([]UploadedPart, error) {
 	bucket, err := ossClient.getBucket(key)
 	if err != nil {
 		return nil, err
 	}
-
-	res, err := bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker), oss.MaxKeys(maxKeys), oss.Delimiter(delimiter))
-	keys := make([]string, 0)
-	for _, v := range res.Objects {
-		keys = append(keys, v.Key)
+	parts := make([]UploadedPart, 0)
+	marker := 0
+	for {
+		res, err := bucket.ListUploadedParts(ossUpload(bucket, key, uploadID), oss.PartNumberMarker(marker))
+		if oerr, ok := err.(oss.ServiceError); ok && oerr.Code == "NoSuchUpload" {
+			return nil, errUploadNotFound
+		}
+		if err != nil {
+			return nil, ossClient.wrapError(err)
+		}
+		for _, part := range res.UploadedParts {
+			parts = append(parts, UploadedPart{PartNumber: part.PartNumber, ETag: part.ETag, Size: int64(part.Size)})
+		}
+		if !res.IsTruncated {
+			return parts, nil
+		}
+		if marker, err = strconv.Atoi(res.NextPartNumberMarker); err != nil {
+			return nil, err
+		}
 	}
-
-	return keys, nil
 }
 
-func (ossClient *OSS) SignURL(key string, expired int64) (string, error) {
+func (ossClient *OSS) completeResumableUpload(key string, uploadID string, parts []UploadedPart, putOptions *putOptions) error {
 	bucket, err := ossClient.getBucket(key)
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	return bucket.SignURL(key, oss.HTTPGet, expired)
+	uploaded := make([]oss.UploadPart, 0, len(parts))
+	for _, part := range parts {
+		uploaded = append(uploaded, oss.UploadPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	_, err = bucket.CompleteMultipartUpload(ossUpload(bucket, key, uploadID), uploaded)
+	return ossClient.wrapError(err)
 }
 
-func (ossClient *OSS) Exists(key string) (bool, error) {
+// Append appends reader to the appendable object at position, and returns the next append position.
+// The object is created by the first Append with position 0, objects uploaded by Put are not appendable.
+// Append is not retried since it's not idempotent.
+func (ossClient *OSS) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
 	bucket, err := ossClient.getBucket(key)
 	if err != nil {
-		return false, err
+		return position, err
 	}
-	return bucket.IsObjectExist(key)
-}
 
-func getOSSMeta(attributes []string, headers http.Header) map[string]string {
-	meta := make(map[string]string)
-	for _, v := range attributes {
-		meta[v] = headers.Get(v)
-		if headers.Get(v) == "" {
-			meta[v] = headers.Get(oss.HTTPHeaderOssMetaPrefix + v)
-		}
+	putOptions := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOptions)
 	}
-	return meta
+
+	if putOptions.compression != "" {
+		return position, fmt.Errorf("Append doesn't support PutWithCompression: %w", ErrUnsupported)
+	}
+	ossOptions, err := getOSSPutOptions(nil, putOptions)
+	if err != nil {
+		return position, err
+	}
+	next, err := bucket.AppendObject(key, reader, position, ossOptions...)
+	return next, ossClient.wrapError(err)
 }
 
-func getOSSOptions(getOpts *getOptions) []oss.Option {
-	ossOpts := make([]oss.Option, 0)
-	if getOpts.contentEncoding != nil {
-		ossOpts = append(ossOpts, oss.ContentEncoding(*getOpts.contentEncoding))
+func (ossClient *OSS) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
 	}
-	if getOpts.contentType != nil {
-		ossOpts = append(ossOpts, oss.ContentEncoding(*getOpts.contentType))
+	if meta == nil {
+		meta = make(map[string]string)
 	}
 
-	return ossOpts
+	encodedBytes := snappy.Encode(nil, data)
+
+	meta["Compressor"] = "snappy"
+
+	return ossClient.Put(key, bytes.NewReader(encodedBytes), meta, options...)
 }
 
-func (ossClient *OSS) get(key string, options *getOptions) (*oss.GetObjectResult, error) {
-	bucket, err := ossClient.getBucket(key)
+// Copy copies srcKey to dstKey on the server side, objects larger than 1GiB are copied in parts.
+// The metadata of the source object is copied unless CopyWithMeta or CopyWithContentType is given.
+func (ossClient *OSS) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	srcBucket, err := ossClient.getBucket(srcKey)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	result, err := bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: key}, getOSSOptions(options))
+	copyOpts := DefaultCopyOptions()
+	for _, opt := range options {
+		opt(copyOpts)
+	}
+	if err := copyOpts.validate(); err != nil {
+		return err
+	}
+	if copyOpts.sourceSSECustomerKey != nil || copyOpts.sseCustomerKey != nil {
+		return errOSSSSECustomerKey
+	}
+	var dstBucket *oss.Bucket
+	if copyOpts.destBucket != "" {
+		dstBucket, err = srcBucket.Client.Bucket(copyOpts.destBucket)
+	} else {
+		dstBucket, err = ossClient.getBucket(dstKey)
+	}
+	if err != nil {
+		return err
+	}
 
+	header, err := srcBucket.GetObjectDetailedMeta(srcKey)
 	if err != nil {
-		if oerr, ok := err.(oss.ServiceError); ok {
-			if oerr.StatusCode == 404 {
-				return nil, nil
+		return ossClient.wrapError(err)
+	}
+	size, err := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	var tagging []oss.Option
+	if tags := copyOpts.objectTags(nil); len(tags) > 0 {
+		ossTagging := oss.Tagging{Tags: make([]oss.Tag, 0, len(tags))}
+		for _, k := range sortedTagKeys(tags) {
+			ossTagging.Tags = append(ossTagging.Tags, oss.Tag{Key: k, Value: tags[k]})
+		}
+		tagging = append(tagging, oss.SetTagging(ossTagging))
+	}
+
+	if size > ossMaxCopySize {
+		// the parts don't carry the tags and the storage class, the ones of the source are set at the upload
+		// like CopyObject copies them
+		if !copyOpts.replaceTags() {
+			result, err := srcBucket.GetObjectTagging(srcKey)
+			if err != nil {
+				return ossClient.wrapError(err)
+			}
+			if len(result.Tags) > 0 {
+				tagging = append(tagging, oss.SetTagging(oss.Tagging{Tags: result.Tags}))
 			}
 		}
-		return nil, err
+		ossOptions := append(getOSSCopyMetaOptions(header, copyOpts), tagging...)
+		if v := header.Get(oss.HTTPHeaderOssStorageClass); v != "" {
+			ossOptions = append(ossOptions, oss.ObjectStorageClass(oss.StorageClassType(v)))
+		}
+		return ossClient.wrapError(ossMultipartCopy(srcBucket, srcKey, dstBucket, dstKey, size, ossOptions, copyOpts))
 	}
 
-	return result, nil
+	var ossOptions []oss.Option
+	if copyOpts.replaceMeta() {
+		ossOptions = append(getOSSCopyMetaOptions(header, copyOpts), oss.MetadataDirective(oss.MetaReplace))
+	}
+	if copyOpts.replaceTags() {
+		ossOptions = append(append(ossOptions, tagging...), oss.TaggingDirective(oss.TaggingReplace))
+	}
+	_, err = srcBucket.CopyObjectTo(dstBucket.BucketName, dstKey, srcKey, ossOptions...)
+	return ossClient.wrapError(err)
 }
 
-func extractOSSRequestID(resp *oss.Response) string {
-	if resp == nil {
-		return ""
+// ossMultipartCopy copies the source object of size in parts with UploadPartCopy, the upload is aborted if any part fails
+func ossMultipartCopy(srcBucket *oss.Bucket, srcKey string, dstBucket *oss.Bucket, dstKey string, size int64, ossOptions []oss.Option, copyOpts *copyOptions) error {
+	imur, err := dstBucket.InitiateMultipartUpload(dstKey, ossOptions...)
+	if err != nil {
+		return err
 	}
-	return resp.Headers.Get(oss.HTTPHeaderOssRequestID)
+
+	var (
+		mu    sync.Mutex
+		parts []oss.UploadPart
+	)
+	err = copyParts(size, copyOpts.partSize, copyOpts.concurrency, func(partNumber int, start, end int64) error {
+		part, err := dstBucket.UploadPartCopy(imur, srcBucket.BucketName, srcKey, start, end-start+1, partNumber)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		parts = append(parts, part)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		_ = dstBucket.AbortMultipartUpload(imur)
+		return wrapOSSError(err)
+	}
+
+	_, err = dstBucket.CompleteMultipartUpload(imur, parts)
+	return wrapOSSError(err)
+}
+
+// getOSSCopyMetaOptions returns the metadata of the destination object, the ones not replaced are taken from the source header
+func getOSSCopyMetaOptions(header http.Header, copyOpts *copyOptions) []oss.Option {
+	ossOptions := make([]oss.Option, 0)
+	if copyOpts.meta != nil || copyOpts.metadataDirective == DirectiveReplace {
+		for k, v := range copyOpts.meta {
+			ossOptions = append(ossOptions, oss.Meta(k, v))
+		}
+	} else {
+		for k := range header {
+			if strings.HasPrefix(k, oss.HTTPHeaderOssMetaPrefix) {
+				ossOptions = append(ossOptions, oss.Meta(k[len(oss.HTTPHeaderOssMetaPrefix):], header.Get(k)))
+			}
+		}
+	}
+	contentType := header.Get(oss.HTTPHeaderContentType)
+	if copyOpts.contentType != nil {
+		contentType = *copyOpts.contentType
+	}
+	if contentType != "" {
+		ossOptions = append(ossOptions, oss.ContentType(contentType))
+	}
+	if v := header.Get(oss.HTTPHeaderContentEncoding); v != "" {
+		ossOptions = append(ossOptions, oss.ContentEncoding(v))
+	}
+	if v := header.Get(oss.HTTPHeaderContentDisposition); v != "" {
+		ossOptions = append(ossOptions, oss.ContentDisposition(v))
+	}
+	if v := header.Get(oss.HTTPHeaderCacheControl); v != "" {
+		ossOptions = append(ossOptions, oss.CacheControl(v))
+	}
+	return ossOptions
+}
+
+// Move copies srcKey to dstKey and deletes srcKey, the source is kept if the copy fails.
+func (ossClient *OSS) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return move(ossClient, srcKey, dstKey, options)
+}
+
+func (ossClient *OSS) Del(key string) error {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	return ossClient.wrapError(bucket.DeleteObject(key))
+}
+
+// RestoreObject restores an archived object for reading, check ObjectMeta.Restore of HeadObject for the status
+func (ossClient *OSS) RestoreObject(key string, options ...RestoreOptions) error {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	restoreOpts := DefaultRestoreOptions()
+	for _, opt := range options {
+		opt(restoreOpts)
+	}
+	if err := restoreOpts.validate(); err != nil {
+		return err
+	}
+	body, err := xml.Marshal(ossRestoreRequest{Days: restoreOpts.days, Tier: restoreOpts.tier})
+	if err != nil {
+		return err
+	}
+	// bucket.RestoreObject can't send the days and tier
+	resp, err := bucket.Client.Conn.Do(http.MethodPost, bucket.BucketName, key, map[string]interface{}{"restore": nil}, nil, bytes.NewReader(body), 0, nil)
+	if err != nil {
+		return ossClient.wrapError(err)
+	}
+	return resp.Body.Close()
+}
+
+// GetVersion gets a specific version of the object in a versioned bucket
+func (ossClient *OSS) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return ossClient.Get(key, append(options, GetWithVersionID(versionID))...)
+}
+
+// DelVersion permanently deletes a specific version of the object in a versioned bucket
+func (ossClient *OSS) DelVersion(key string, versionID string, options ...DelOptions) error {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	return ossClient.wrapError(bucket.DeleteObject(key, oss.VersionId(versionID)))
+}
+
+func (ossClient *OSS) DelMulti(keys []string) (map[string]error, error) {
+	failed := make(map[string]error)
+	bucketsKeys := make(map[*oss.Bucket][]string)
+	for _, key := range keys {
+		bucket, err := ossClient.getBucket(key)
+		if err != nil {
+			failed[key] = err
+			continue
+		}
+		bucketsKeys[bucket] = append(bucketsKeys[bucket], key)
+	}
+
+	for bucket, bKeys := range bucketsKeys {
+		for _, chunk := range chunkKeys(bKeys, MaxDeleteKeys) {
+			res, err := bucket.DeleteObjects(chunk)
+			if err != nil {
+				err = ossClient.wrapError(err)
+				for _, key := range chunk {
+					failed[key] = err
+				}
+				continue
+			}
+			// oss only reports the deleted keys, including the ones which don't exist
+			deleted := make(map[string]bool, len(res.DeletedObjects))
+			for _, key := range res.DeletedObjects {
+				deleted[key] = true
+			}
+			for _, key := range chunk {
+				if !deleted[key] {
+					failed[key] = fmt.Errorf("oss didn't delete key %q", key)
+				}
+			}
+		}
+	}
+
+	return delMultiResult(failed, len(keys))
+}
+
+// Head ignores options, except that GetWithSSECustomerKey is unsupported
+func (ossClient *OSS) Head(key string, attributes []string, options ...GetOptions) (map[string]string, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if getOpts.sseCustomerKey != nil {
+		return nil, errOSSSSECustomerKey
+	}
+	ossOptions := make([]oss.Option, 0)
+	if getOpts.versionID != nil {
+		ossOptions = append(ossOptions, oss.VersionId(*getOpts.versionID))
+	}
+
+	headers, err := bucket.GetObjectDetailedMeta(key, ossOptions...)
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+
+	return getOSSMeta(attributes, headers), nil
+}
+
+func (ossClient *OSS) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+	getOpts := DefaultGetOptions()
+	for _, opt := range options {
+		opt(getOpts)
+	}
+	if getOpts.sseCustomerKey != nil {
+		return nil, errOSSSSECustomerKey
+	}
+	ossOptions := make([]oss.Option, 0)
+	if getOpts.versionID != nil {
+		ossOptions = append(ossOptions, oss.VersionId(*getOpts.versionID))
+	}
+
+	headers, err := bucket.GetObjectDetailedMeta(key, ossOptions...)
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+	meta, err := parseObjectMeta(headers, "X-Oss-")
+	if err != nil {
+		return nil, err
+	}
+	meta.Key = key
+	return meta, nil
+}
+
+func (ossClient *OSS) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker), oss.MaxKeys(maxKeys), oss.Delimiter(delimiter))
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+	keys := make([]string, 0)
+	for _, v := range res.Objects {
+		keys = append(keys, v.Key)
+	}
+
+	return keys, nil
+}
+
+func (ossClient *OSS) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ossOptions := []oss.Option{oss.Prefix(prefix), oss.Marker(marker), oss.Delimiter(delimiter)}
+	if maxKeys > 0 {
+		ossOptions = append(ossOptions, oss.MaxKeys(maxKeys))
+	}
+	result, err := bucket.ListObjects(ossOptions...)
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+
+	res := &ListResult{
+		Objects:        make([]ObjectMeta, 0, len(result.Objects)),
+		CommonPrefixes: append(make([]string, 0, len(result.CommonPrefixes)), result.CommonPrefixes...),
+	}
+	for _, v := range result.Objects {
+		res.Objects = append(res.Objects, ObjectMeta{
+			Key:          v.Key,
+			ETag:         trimETag(v.ETag),
+			Size:         v.Size,
+			LastModified: v.LastModified,
+			StorageClass: v.StorageClass,
+		})
+	}
+	if result.IsTruncated {
+		res.NextMarker = result.NextMarker
+	}
+	return res, nil
+}
+
+func (ossClient *OSS) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return err
+	}
+	return walkObjects(ossClient.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		res, err := bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker), oss.MaxKeys(maxKeys))
+		if err != nil {
+			return nil, ossClient.wrapError(err)
+		}
+		objects := make([]ObjectMeta, 0, len(res.Objects))
+		for _, v := range res.Objects {
+			objects = append(objects, ObjectMeta{
+				Key:          v.Key,
+				ETag:         trimETag(v.ETag),
+				Size:         v.Size,
+				LastModified: v.LastModified,
+				StorageClass: v.StorageClass,
+			})
+		}
+		return objects, nil
+	}, fn, listWithMaxPageSize(options, s3MaxListKeys))
+}
+
+// ListObjectVersions lists at most maxKeys versions and delete markers of keys with prefix,
+// pass the Key and VersionID of the last version as markers to get the next page.
+func (ossClient *OSS) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ossOptions := []oss.Option{oss.Prefix(prefix), oss.KeyMarker(keyMarker), oss.VersionIdMarker(versionIDMarker)}
+	if maxKeys > 0 {
+		ossOptions = append(ossOptions, oss.MaxKeys(maxKeys))
+	}
+	res, err := bucket.ListObjectVersions(ossOptions...)
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+
+	versions := make([]ObjectVersion, 0, len(res.ObjectVersions)+len(res.ObjectDeleteMarkers))
+	for _, v := range res.ObjectVersions {
+		versions = append(versions, ObjectVersion{
+			Key:          v.Key,
+			VersionID:    v.VersionId,
+			IsLatest:     v.IsLatest,
+			LastModified: v.LastModified,
+			Size:         v.Size,
+			ETag:         v.ETag,
+		})
+	}
+	for _, v := range res.ObjectDeleteMarkers {
+		versions = append(versions, ObjectVersion{
+			Key:            v.Key,
+			VersionID:      v.VersionId,
+			IsLatest:       v.IsLatest,
+			IsDeleteMarker: true,
+			LastModified:   v.LastModified,
+		})
+	}
+	sortObjectVersions(versions)
+	return versions, nil
+}
+
+func (ossClient *OSS) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return newObjectIterator(ossClient.ctx, func(marker string, maxKeys int) ([]ObjectMeta, error) {
+		res, err := ossClient.ListObjectsWithDelimiter(key, prefix, marker, maxKeys, "")
+		if err != nil {
+			return nil, err
+		}
+		return res.Objects, nil
+	}, listWithMaxPageSize(options, s3MaxListKeys)...)
+}
+
+func (ossClient *OSS) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return "", err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+	if err := signOptions.validate(); err != nil {
+		return "", err
+	}
+
+	// the response-* query parameters are signed as sub resources
+	ossOptions := make([]oss.Option, 0)
+	if signOptions.responseContentType != nil {
+		ossOptions = append(ossOptions, oss.ResponseContentType(*signOptions.responseContentType))
+	}
+	if signOptions.responseContentDisposition != nil {
+		ossOptions = append(ossOptions, oss.ResponseContentDisposition(*signOptions.responseContentDisposition))
+	}
+	if signOptions.responseCacheControl != nil {
+		ossOptions = append(ossOptions, oss.ResponseCacheControl(*signOptions.responseCacheControl))
+	}
+	return bucket.SignURL(key, oss.HTTPGet, expired, ossOptions...)
+}
+
+// SignURLForPut returns a presigned url for uploading the object with http PUT,
+// the uploader must send the Content-Type given in options.
+func (ossClient *OSS) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return "", err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+
+	ossOptions := make([]oss.Option, 0)
+	if signOptions.contentType != nil {
+		ossOptions = append(ossOptions, oss.ContentType(*signOptions.contentType))
+	}
+	return bucket.SignURL(key, oss.HTTPPut, expired, ossOptions...)
+}
+
+// SignPostPolicy returns a signed post policy for browser form uploads.
+func (ossClient *OSS) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	signOptions := DefaultSignOptions()
+	for _, opt := range options {
+		opt(signOptions)
+	}
+
+	conf := bucket.Client.Config
+	conditions := postPolicyConditions(bucket.BucketName, key, signOptions)
+	policy, err := encodePostPolicy(time.Now().Add(time.Duration(expired)*time.Second), conditions)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha1.New, []byte(conf.AccessKeySecret))
+	h.Write([]byte(policy))
+
+	fields := map[string]string{
+		"key":            key,
+		"OSSAccessKeyId": conf.AccessKeyID,
+		"policy":         policy,
+		"Signature":      base64.StdEncoding.EncodeToString(h.Sum(nil)),
+	}
+	if conf.SecurityToken != "" {
+		fields["x-oss-security-token"] = conf.SecurityToken
+	}
+	if signOptions.contentType != nil {
+		fields["Content-Type"] = *signOptions.contentType
+	}
+
+	// the signed url of empty key is the bucket url, which respects cname and ip endpoints
+	signed, err := bucket.SignURL("", oss.HTTPPost, expired)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = ""
+	return &PostPolicy{URL: u.String(), Fields: fields}, nil
+}
+
+func (ossClient *OSS) Exists(key string) (bool, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return false, err
+	}
+	// only a missing object means false, other failures such as 403 and 5xx are errors
+	exists, err := bucket.IsObjectExist(key)
+	return exists, ossClient.wrapError(err)
+}
+
+func (ossClient *OSS) GetObjectTagging(key string) (map[string]string, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := bucket.GetObjectTagging(key)
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range result.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// PutObjectTagging replaces all tags of the object
+func (ossClient *OSS) PutObjectTagging(key string, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return err
+	}
+
+	tagging := oss.Tagging{Tags: make([]oss.Tag, 0, len(tags))}
+	for _, k := range sortedTagKeys(tags) {
+		tagging.Tags = append(tagging.Tags, oss.Tag{Key: k, Value: tags[k]})
+	}
+	return ossClient.wrapError(bucket.PutObjectTagging(key, tagging))
+}
+
+// GetObjectACL returns ACLDefault if the object inherits the acl of the bucket
+func (ossClient *OSS) GetObjectACL(key string) (string, error) {
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := bucket.GetObjectACL(key)
+	if err != nil {
+		return "", ossClient.wrapError(err)
+	}
+	return result.ACL, nil
+}
+
+func (ossClient *OSS) PutObjectACL(key string, acl string) error {
+	if err := validateObjectACL(acl); err != nil {
+		return err
+	}
+	bucket, err := ossClient.getBucket(key)
+	if err != nil {
+		return err
+	}
+	return ossClient.wrapError(bucket.SetObjectACL(key, oss.ACLType(acl)))
+}
+
+func getOSSMeta(attributes []string, headers http.Header) map[string]string {
+	meta := make(map[string]string)
+	for _, v := range attributes {
+		meta[v] = headers.Get(v)
+		if headers.Get(v) == "" {
+			meta[v] = headers.Get(oss.HTTPHeaderOssMetaPrefix + v)
+		}
+		// oss specific headers such as X-Oss-Server-Side-Encryption
+		if meta[v] == "" {
+			meta[v] = headers.Get("X-Oss-" + v)
+		}
+	}
+	return meta
+}
+
+func getOSSPutOptions(meta map[string]string, putOptions *putOptions) ([]oss.Option, error) {
+	if err := putOptions.validate(); err != nil {
+		return nil, err
+	}
+	if putOptions.sseCustomerKey != nil {
+		return nil, errOSSSSECustomerKey
+	}
+	if putOptions.objectLock() {
+		return nil, errObjectLock
+	}
+	ossOptions := make([]oss.Option, 0)
+	if putOptions.sseS3 {
+		ossOptions = append(ossOptions, oss.ServerSideEncryption("AES256"))
+	}
+	if putOptions.sseKMSKeyID != nil {
+		ossOptions = append(ossOptions, oss.ServerSideEncryption("KMS"))
+		if *putOptions.sseKMSKeyID != "" {
+			ossOptions = append(ossOptions, oss.ServerSideEncryptionKeyID(*putOptions.sseKMSKeyID))
+		}
+	}
+	// oss only supports forbidding overwrite
+	if putOptions.ifMatch != nil || (putOptions.ifNoneMatch != nil && *putOptions.ifNoneMatch != "*") {
+		return nil, fmt.Errorf("oss only supports PutWithIfNoneMatch(\"*\"): %w", ErrUnsupported)
+	}
+	if putOptions.ifNoneMatch != nil {
+		ossOptions = append(ossOptions, oss.ForbidOverWrite(true))
+	}
+	meta, err := putOptions.userMeta(meta)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range meta {
+		ossOptions = append(ossOptions, oss.Meta(k, v))
+	}
+	storageClass, err := backendStorageClass(ossStorageClasses, putOptions)
+	if err != nil {
+		return nil, err
+	}
+	if storageClass != "" {
+		ossOptions = append(ossOptions, oss.ObjectStorageClass(oss.StorageClassType(storageClass)))
+	}
+	if putOptions.acl != "" {
+		ossOptions = append(ossOptions, oss.ObjectACL(oss.ACLType(putOptions.acl)))
+	}
+	ossOptions = append(ossOptions, oss.ContentType(putOptions.contentType))
+	if putOptions.contentEncoding != nil {
+		ossOptions = append(ossOptions, oss.ContentEncoding(*putOptions.contentEncoding))
+	}
+	if putOptions.contentDisposition != nil {
+		ossOptions = append(ossOptions, oss.ContentDisposition(*putOptions.contentDisposition))
+	}
+	if putOptions.cacheControl != nil {
+		ossOptions = append(ossOptions, oss.CacheControl(*putOptions.cacheControl))
+	}
+	if putOptions.expires != nil {
+		ossOptions = append(ossOptions, oss.Expires(*putOptions.expires))
+	}
+	return ossOptions, nil
+}
+
+var errOSSSSECustomerKey = fmt.Errorf("oss doesn't support SSE-C: %w", ErrUnsupported)
+
+func getOSSOptions(getOpts *getOptions) ([]oss.Option, error) {
+	if getOpts.sseCustomerKey != nil {
+		return nil, errOSSSSECustomerKey
+	}
+	ossOpts := make([]oss.Option, 0)
+	if getOpts.contentEncoding != nil {
+		ossOpts = append(ossOpts, oss.ContentEncoding(*getOpts.contentEncoding))
+	}
+	if getOpts.contentType != nil {
+		ossOpts = append(ossOpts, oss.ContentEncoding(*getOpts.contentType))
+	}
+	if getOpts.ifNoneMatch != nil {
+		ossOpts = append(ossOpts, oss.IfNoneMatch(*getOpts.ifNoneMatch))
+	}
+	if getOpts.versionID != nil {
+		ossOpts = append(ossOpts, oss.VersionId(*getOpts.versionID))
+	}
+	if getOpts.acceptIdentity() {
+		ossOpts = append(ossOpts, oss.AcceptEncoding("identity"))
+	}
+	byteRange, err := getOpts.byteRange()
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		ossOpts = append(ossOpts, oss.NormalizedRange(strings.TrimPrefix(byteRange, "bytes=")))
+	}
+
+	return ossOpts, nil
+}
+
+func (ossClient *OSS) get(key string, options *getOptions) (*oss.GetObjectResult, error) {
+	bucket, err := ossClient.progressBucket(key, options.progress)
+	if err != nil {
+		return nil, err
+	}
+
+	ossOptions, err := getOSSOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	result, err := bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: key}, ossOptions)
+	if err != nil {
+		return nil, ossClient.wrapError(err)
+	}
+	headers := result.Response.Headers
+	result.Response.Body = options.verifyBody(result.Response.Body, headers.Get(oss.HTTPHeaderEtag), headers)
+	var inflated bool
+	if result.Response.Body, inflated, err = options.inflateIdentityGzip(result.Response.Body, headers.Get(oss.HTTPHeaderContentEncoding)); err != nil {
+		return nil, err
+	}
+	if inflated {
+		headers.Del(oss.HTTPHeaderContentEncoding)
+		headers.Del(oss.HTTPHeaderContentLength)
+	}
+	if result.Response.Body, err = options.decodeBody(result.Response.Body, headers.Get(oss.HTTPHeaderContentEncoding)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func extractOSSRequestID(resp *oss.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Headers.Get(oss.HTTPHeaderOssRequestID)
+}
+
+// wrapError translates err to awos errors, with the request id and the trace id of the context
+func (ossClient *OSS) wrapError(err error) error {
+	var serviceErr oss.ServiceError
+	if errors.As(err, &serviceErr) {
+		return withRequestID(ossClient.ctx, wrapOSSError(err), serviceErr.RequestID)
+	}
+	return withRequestID(ossClient.ctx, wrapOSSError(err), "")
+}
+
+// buckets returns the buckets of the shards, or the bucket if there are no shards
+func (ossClient *OSS) buckets() []*oss.Bucket {
+	if len(ossClient.Shards) == 0 {
+		return []*oss.Bucket{ossClient.Bucket}
+	}
+	buckets := make([]*oss.Bucket, 0, len(ossClient.Shards))
+	seen := make(map[string]bool, len(ossClient.Shards))
+	for _, bucket := range ossClient.Shards {
+		if !seen[bucket.BucketName] {
+			seen[bucket.BucketName] = true
+			buckets = append(buckets, bucket)
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].BucketName < buckets[j].BucketName
+	})
+	return buckets
+}
+
+func (ossClient *OSS) bucketExists() (bool, error) {
+	for _, bucket := range ossClient.buckets() {
+		if _, err := bucket.Client.GetBucketInfo(bucket.BucketName); err != nil {
+			if isOSSNotFound(err) {
+				return false, nil
+			}
+			return false, ossClient.wrapError(err)
+		}
+	}
+	return true, nil
+}
+
+// ping gets the bucket info, the sdk has no HEAD bucket
+func (ossClient *OSS) ping() error {
+	for _, bucket := range ossClient.buckets() {
+		_, err := bucket.Client.GetBucketInfo(bucket.BucketName)
+		if serviceErr, ok := err.(oss.ServiceError); ok {
+			switch serviceErr.StatusCode {
+			case http.StatusForbidden:
+				continue
+			case http.StatusNotFound:
+				err = withRequestID(ossClient.ctx, &wrappedError{kind: ErrBucketNotFound, err: err}, serviceErr.RequestID)
+				return fmt.Errorf("ping bucket %s: %w", bucket.BucketName, err)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("ping bucket %s: %w", bucket.BucketName, ossClient.wrapError(err))
+		}
+	}
+	return nil
+}
+
+func (ossClient *OSS) createBucket(options *createBucketOptions) error {
+	if options.region != "" {
+		return fmt.Errorf("CreateBucketWithRegion, the region of oss is the one of the endpoint: %w", ErrUnsupported)
+	}
+	var ossOptions []oss.Option
+	if options.acl != "" {
+		ossOptions = append(ossOptions, oss.ACL(oss.ACLType(options.acl)))
+	}
+	for _, bucket := range ossClient.buckets() {
+		err := bucket.Client.CreateBucket(bucket.BucketName, ossOptions...)
+		if serviceErr, ok := err.(oss.ServiceError); ok && serviceErr.Code == "BucketAlreadyExists" {
+			// oss returns the same error whoever owns the bucket, only the owner can get its info
+			if _, infoErr := bucket.Client.GetBucketInfo(bucket.BucketName); infoErr == nil {
+				continue
+			}
+			return withRequestID(ossClient.ctx, &wrappedError{kind: ErrBucketAlreadyExists, err: err}, serviceErr.RequestID)
+		}
+		if err != nil {
+			return ossClient.wrapError(err)
+		}
+	}
+	return nil
+}
+
+// GetObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (ossClient *OSS) GetObjectLegalHold(key string) (bool, error) {
+	return false, errObjectLock
+}
+
+// PutObjectLegalHold is not supported, it always returns ErrUnsupported.
+func (ossClient *OSS) PutObjectLegalHold(key string, on bool) error {
+	return errObjectLock
+}
+
+// GetObjectRetention is not supported, it always returns ErrUnsupported.
+func (ossClient *OSS) GetObjectRetention(key string) (string, time.Time, error) {
+	return "", time.Time{}, errObjectLock
+}
+
+// PutObjectRetention is not supported, it always returns ErrUnsupported.
+func (ossClient *OSS) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return errObjectLock
+}
+
+func (ossClient *OSS) Close() error {
+	if tp, ok := ossClient.transport.(interface{ CloseIdleConnections() }); ok {
+		tp.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (ossClient *OSS) putSymlink(symlinkKey string, targetKey string) error {
+	bucket, err := ossClient.getBucket(symlinkKey)
+	if err != nil {
+		return err
+	}
+	target, err := ossClient.getBucket(targetKey)
+	if err != nil {
+		return err
+	}
+	// the target of a symlink is in the bucket of the symlink
+	if target.BucketName != bucket.BucketName {
+		return fmt.Errorf("symlink %q and target %q are in the different shard buckets %s and %s",
+			symlinkKey, targetKey, bucket.BucketName, target.BucketName)
+	}
+	return ossClient.wrapError(bucket.PutSymlink(symlinkKey, targetKey))
+}
+
+func (ossClient *OSS) getSymlink(symlinkKey string) (string, error) {
+	bucket, err := ossClient.getBucket(symlinkKey)
+	if err != nil {
+		return "", err
+	}
+	header, err := bucket.GetSymlink(symlinkKey)
+	if err != nil {
+		return "", ossClient.wrapError(err)
+	}
+	return header.Get(oss.HTTPHeaderOssSymlinkTarget), nil
 }
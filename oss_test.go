@@ -6,6 +6,7 @@ Put your environment configuration in ".env-oss"
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"strconv"
 	"strings"
@@ -268,7 +269,7 @@ func TestOSS_DelMulti(t *testing.T) {
 		ossClient.Put(key, strings.NewReader("2333333"), nil)
 	}
 
-	err := ossClient.DelMulti(keys)
+	_, err := ossClient.DelMulti(keys)
 	if err != nil {
 		t.Log("aws del multi keys fail, err:", err)
 		t.Fail()
@@ -276,7 +277,7 @@ func TestOSS_DelMulti(t *testing.T) {
 
 	for _, key := range keys {
 		res, err := ossClient.Get(key)
-		if res != "" || err != nil {
+		if res != "" || !errors.Is(err, ErrObjectNotFound) {
 			t.Logf("key:%s should not be exist", key)
 			t.Fail()
 		}
@@ -285,7 +286,7 @@ func TestOSS_DelMulti(t *testing.T) {
 
 func TestOSS_GetNotExist(t *testing.T) {
 	res1, err := ossClient.Get(guid + "123")
-	if res1 != "" || err != nil {
+	if res1 != "" || !errors.Is(err, ErrObjectNotFound) {
 		t.Log("oss get not exist key fail, res:", res1, "err:", err)
 		t.Fail()
 	}
@@ -293,7 +294,7 @@ func TestOSS_GetNotExist(t *testing.T) {
 	attributes := make([]string, 0)
 	attributes = append(attributes, "head")
 	res2, err := ossClient.Head(guid+"123", attributes)
-	if res2 != nil || err != nil {
+	if res2 != nil || !errors.Is(err, ErrObjectNotFound) {
 		t.Log("oss head not exist key fail, res:", res2, "err:", err)
 		t.Fail()
 	}
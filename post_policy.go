@@ -0,0 +1,42 @@
+package awos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// PostPolicy is used by browsers to upload objects with html forms,
+// post the Fields and the file (as the last field "file") to URL.
+type PostPolicy struct {
+	URL    string
+	Fields map[string]string
+}
+
+// encodePostPolicy returns the base64 encoded policy document
+func encodePostPolicy(expiration time.Time, conditions []interface{}) (string, error) {
+	doc, err := json.Marshal(map[string]interface{}{
+		"expiration": expiration.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(doc), nil
+}
+
+// postPolicyConditions returns the key, content-type and content-length-range conditions
+// which are the same for s3 and oss.
+func postPolicyConditions(bucket string, key string, signOptions *signOptions) []interface{} {
+	conditions := []interface{}{
+		map[string]string{"bucket": bucket},
+		[]interface{}{"eq", "$key", key},
+	}
+	if signOptions.contentType != nil {
+		conditions = append(conditions, []interface{}{"eq", "$Content-Type", *signOptions.contentType})
+	}
+	if signOptions.maxContentLength != nil {
+		conditions = append(conditions, []interface{}{"content-length-range", 0, *signOptions.maxContentLength})
+	}
+	return conditions
+}
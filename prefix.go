@@ -0,0 +1,327 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// prefixedComponent prepends prefix to the keys of every operation of c and strips it from the listed keys,
+// so the callers work with the keys in the namespace of prefix, see KeyPrefix of config
+type prefixedComponent struct {
+	c      Component
+	prefix string
+}
+
+func newPrefixedComponent(prefix string, c Component) Component {
+	return &prefixedComponent{c: c, prefix: prefix}
+}
+
+func (p *prefixedComponent) key(key string) string {
+	return p.prefix + key
+}
+
+func (p *prefixedComponent) keys(keys []string) []string {
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, p.key(key))
+	}
+	return res
+}
+
+// marker prefixes a key marker, an empty marker starts from the first key of the namespace
+func (p *prefixedComponent) marker(marker string) string {
+	if marker == "" {
+		return ""
+	}
+	return p.key(marker)
+}
+
+func (p *prefixedComponent) trim(key string) string {
+	return strings.TrimPrefix(key, p.prefix)
+}
+
+func (p *prefixedComponent) WithContext(ctx context.Context) Component {
+	return &prefixedComponent{c: p.c.WithContext(ctx), prefix: p.prefix}
+}
+
+func (p *prefixedComponent) Get(key string, options ...GetOptions) (string, error) {
+	return p.c.Get(p.key(key), options...)
+}
+
+func (p *prefixedComponent) GetBytes(key string, options ...GetOptions) ([]byte, error) {
+	return p.c.GetBytes(p.key(key), options...)
+}
+
+func (p *prefixedComponent) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	return p.c.GetAsReader(p.key(key), options...)
+}
+
+func (p *prefixedComponent) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	res, failed := p.c.GetMulti(p.keys(keys), options...)
+	trimmed := make(map[string][]byte, len(res))
+	for k, v := range res {
+		trimmed[p.trim(k)] = v
+	}
+	trimmedFailed := make(map[string]error, len(failed))
+	for k, err := range failed {
+		trimmedFailed[p.trim(k)] = err
+	}
+	return trimmed, trimmedFailed
+}
+
+func (p *prefixedComponent) RestoreObject(key string, options ...RestoreOptions) error {
+	return p.c.RestoreObject(p.key(key), options...)
+}
+
+func (p *prefixedComponent) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	return p.c.GetVersion(p.key(key), versionID, options...)
+}
+
+func (p *prefixedComponent) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
+	return p.c.GetWithMeta(p.key(key), attributes, options...)
+}
+
+func (p *prefixedComponent) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	return p.c.Put(p.key(key), reader, meta, options...)
+}
+
+func (p *prefixedComponent) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	return p.c.MultipartUpload(p.key(key), reader, meta, options...)
+}
+
+func (p *prefixedComponent) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	return p.c.Append(p.key(key), reader, position, options...)
+}
+
+// Copy copies within the namespace, also to the bucket of CopyWithDestBucket
+func (p *prefixedComponent) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	return p.c.Copy(p.key(srcKey), p.key(dstKey), options...)
+}
+
+func (p *prefixedComponent) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	return p.c.Move(p.key(srcKey), p.key(dstKey), options...)
+}
+
+func (p *prefixedComponent) Del(key string) error {
+	return p.c.Del(p.key(key))
+}
+
+func (p *prefixedComponent) DelVersion(key string, versionID string, options ...DelOptions) error {
+	return p.c.DelVersion(p.key(key), versionID, options...)
+}
+
+func (p *prefixedComponent) DelMulti(keys []string) (map[string]error, error) {
+	failed, err := p.c.DelMulti(p.keys(keys))
+	if failed == nil {
+		return nil, err
+	}
+	trimmed := make(map[string]error, len(failed))
+	for k, e := range failed {
+		trimmed[p.trim(k)] = e
+	}
+	return trimmed, err
+}
+
+func (p *prefixedComponent) Head(key string, meta []string, options ...GetOptions) (map[string]string, error) {
+	return p.c.Head(p.key(key), meta, options...)
+}
+
+func (p *prefixedComponent) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	res, err := p.c.HeadObject(p.key(key), options...)
+	if res != nil {
+		res.Key = p.trim(res.Key)
+	}
+	return res, err
+}
+
+func (p *prefixedComponent) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	keys, err := p.c.ListObject(p.key(key), p.key(prefix), p.marker(marker), maxKeys, delimiter)
+	for i := range keys {
+		keys[i] = p.trim(keys[i])
+	}
+	return keys, err
+}
+
+// ListObjectsWithDelimiter strips the prefix from the objects and the common prefixes,
+// NextMarker and marker are passed through as is since they are opaque for azure
+func (p *prefixedComponent) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	res, err := p.c.ListObjectsWithDelimiter(p.key(key), p.key(prefix), marker, maxKeys, delimiter)
+	if res == nil {
+		return nil, err
+	}
+	for i := range res.Objects {
+		res.Objects[i].Key = p.trim(res.Objects[i].Key)
+	}
+	for i := range res.CommonPrefixes {
+		res.CommonPrefixes[i] = p.trim(res.CommonPrefixes[i])
+	}
+	return res, err
+}
+
+func (p *prefixedComponent) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	it := p.c.ListObjectsIter(p.key(key), p.key(prefix), options...)
+	it.trimPrefix = p.prefix
+	return it
+}
+
+func (p *prefixedComponent) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	return p.c.WalkObjects(p.key(key), p.key(prefix), func(obj ObjectMeta) error {
+		obj.Key = p.trim(obj.Key)
+		return fn(obj)
+	}, options...)
+}
+
+func (p *prefixedComponent) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	versions, err := p.c.ListObjectVersions(p.key(key), p.key(prefix), p.marker(keyMarker), versionIDMarker, maxKeys)
+	for i := range versions {
+		versions[i].Key = p.trim(versions[i].Key)
+	}
+	return versions, err
+}
+
+func (p *prefixedComponent) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	return p.c.SignURL(p.key(key), expired, options...)
+}
+
+func (p *prefixedComponent) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	return p.c.SignURLForPut(p.key(key), expired, options...)
+}
+
+// SignPostPolicy signs the prefixed key, which is the key field of the form
+func (p *prefixedComponent) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	return p.c.SignPostPolicy(p.key(key), expired, options...)
+}
+
+func (p *prefixedComponent) GetAndDecompress(key string) (string, error) {
+	return p.c.GetAndDecompress(p.key(key))
+}
+
+func (p *prefixedComponent) GetAndDecompressAsReader(key string) (io.ReadCloser, error) {
+	return p.c.GetAndDecompressAsReader(p.key(key))
+}
+
+func (p *prefixedComponent) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	return p.c.CompressAndPut(p.key(key), reader, meta, options...)
+}
+
+func (p *prefixedComponent) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	return p.c.Range(p.key(key), offset, length)
+}
+
+func (p *prefixedComponent) Exists(key string) (bool, error) {
+	return p.c.Exists(p.key(key))
+}
+
+func (p *prefixedComponent) GetObjectTagging(key string) (map[string]string, error) {
+	return p.c.GetObjectTagging(p.key(key))
+}
+
+func (p *prefixedComponent) PutObjectTagging(key string, tags map[string]string) error {
+	return p.c.PutObjectTagging(p.key(key), tags)
+}
+
+func (p *prefixedComponent) GetObjectACL(key string) (string, error) {
+	return p.c.GetObjectACL(p.key(key))
+}
+
+func (p *prefixedComponent) PutObjectACL(key string, acl string) error {
+	return p.c.PutObjectACL(p.key(key), acl)
+}
+
+func (p *prefixedComponent) GetObjectLegalHold(key string) (bool, error) {
+	return p.c.GetObjectLegalHold(p.key(key))
+}
+
+func (p *prefixedComponent) PutObjectLegalHold(key string, on bool) error {
+	return p.c.PutObjectLegalHold(p.key(key), on)
+}
+
+func (p *prefixedComponent) GetObjectRetention(key string) (string, time.Time, error) {
+	return p.c.GetObjectRetention(p.key(key))
+}
+
+func (p *prefixedComponent) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	return p.c.PutObjectRetention(p.key(key), mode, retainUntil)
+}
+
+func (p *prefixedComponent) Close() error {
+	return p.c.Close()
+}
+
+func (p *prefixedComponent) createResumableUpload(key string, meta map[string]string, putOptions *putOptions) (string, error) {
+	u, ok := p.c.(resumableUploader)
+	if !ok {
+		return "", fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.createResumableUpload(p.key(key), meta, putOptions)
+}
+
+func (p *prefixedComponent) uploadResumablePart(key string, uploadID string, partNumber int, data []byte, putOptions *putOptions) (string, error) {
+	u, ok := p.c.(resumableUploader)
+	if !ok {
+		return "", fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.uploadResumablePart(p.key(key), uploadID, partNumber, data, putOptions)
+}
+
+func (p *prefixedComponent) listResumableParts(key string, uploadID string) ([]UploadedPart, error) {
+	u, ok := p.c.(resumableUploader)
+	if !ok {
+		return nil, fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.listResumableParts(p.key(key), uploadID)
+}
+
+func (p *prefixedComponent) completeResumableUpload(key string, uploadID string, parts []UploadedPart, putOptions *putOptions) error {
+	u, ok := p.c.(resumableUploader)
+	if !ok {
+		return fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	return u.completeResumableUpload(p.key(key), uploadID, parts, putOptions)
+}
+
+// the bucket operations aren't namespaced
+func (p *prefixedComponent) bucketExists() (bool, error) {
+	m, ok := p.c.(bucketManager)
+	if !ok {
+		return false, fmt.Errorf("BucketExists: %w", ErrUnsupported)
+	}
+	return m.bucketExists()
+}
+
+func (p *prefixedComponent) createBucket(options *createBucketOptions) error {
+	m, ok := p.c.(bucketManager)
+	if !ok {
+		return fmt.Errorf("CreateBucket: %w", ErrUnsupported)
+	}
+	return m.createBucket(options)
+}
+
+func (p *prefixedComponent) ping() error {
+	pi, ok := p.c.(pinger)
+	if !ok {
+		return fmt.Errorf("Ping: %w", ErrUnsupported)
+	}
+	return pi.ping()
+}
+
+// the target of a symlink is in the namespace as well
+func (p *prefixedComponent) putSymlink(symlinkKey string, targetKey string) error {
+	s, ok := p.c.(symlinker)
+	if !ok {
+		return fmt.Errorf("PutSymlink: %w", ErrUnsupported)
+	}
+	return s.putSymlink(p.key(symlinkKey), p.key(targetKey))
+}
+
+func (p *prefixedComponent) getSymlink(symlinkKey string) (string, error) {
+	s, ok := p.c.(symlinker)
+	if !ok {
+		return "", fmt.Errorf("GetSymlink: %w", ErrUnsupported)
+	}
+	target, err := s.getSymlink(p.key(symlinkKey))
+	return p.trim(target), err
+}
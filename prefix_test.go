@@ -0,0 +1,112 @@
+package awos
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	m := newTestMemory()
+	client := newPrefixedComponent("tenant-1/", m)
+	other := newPrefixedComponent("tenant-2/", m)
+
+	assert.NoError(t, client.Put("dir/a", strings.NewReader(content), nil))
+	assert.NoError(t, client.Put("dir/sub/b", strings.NewReader(content), nil))
+	assert.NoError(t, client.Put("c", strings.NewReader(content), nil))
+	assert.NoError(t, other.Put("dir/a", strings.NewReader("other"), nil))
+
+	// the objects are in the namespace of the prefix
+	res, err := m.Get("tenant-1/dir/a")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	res, err = client.Get("dir/a")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	res, err = other.Get("dir/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "other", res)
+
+	keys, err := client.ListObject("dir/a", "", "", 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "dir/a", "dir/sub/b"}, keys)
+	keys, err = client.ListObject("dir/a", "dir/", "dir/a", 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/sub/b"}, keys)
+
+	list, err := client.ListObjectsWithDelimiter("dir/a", "dir/", "", 0, "/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/sub/"}, list.CommonPrefixes)
+	if assert.Len(t, list.Objects, 1) {
+		assert.Equal(t, "dir/a", list.Objects[0].Key)
+	}
+
+	it := client.ListObjectsIter("dir/a", "dir/")
+	keys = nil
+	for it.Next() {
+		keys = append(keys, it.Key())
+		assert.Equal(t, it.Key(), it.Object().Key)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"dir/a", "dir/sub/b"}, keys)
+
+	keys = nil
+	assert.NoError(t, client.WalkObjects("dir/a", "", func(obj ObjectMeta) error {
+		keys = append(keys, obj.Key)
+		return nil
+	}))
+	assert.Equal(t, []string{"c", "dir/a", "dir/sub/b"}, keys)
+
+	meta, err := client.HeadObject("c")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", meta.Key)
+
+	got, failed := client.GetMulti([]string{"c", "none"})
+	assert.Equal(t, map[string][]byte{"c": []byte(content)}, got)
+	assert.Contains(t, failed, "none")
+
+	assert.NoError(t, client.Copy("c", "d"))
+	exists, err := m.Exists("tenant-1/d")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, client.Del("d"))
+	exists, err = client.Exists("d")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestKeyPrefix_Component(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = ioutil.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		if r.Method == http.MethodGet && r.URL.Query().Get("prefix") != "" {
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>test-bucket</Name>` +
+				`<Contents><Key>tenant-1/dir/a</Key></Contents></ListBucketResult>`))
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}, WithKeyPrefix("tenant-1/"), WithEnableOperationTrace(true))
+
+	assert.NoError(t, client.Put("dir/a", strings.NewReader(content), nil))
+	res, err := client.WithContext(context.Background()).Get("dir/a")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	keys, err := client.ListObject("dir/a", "dir/", "", 10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/a"}, keys)
+
+	mu.Lock()
+	assert.Equal(t, []string{"/test-bucket/tenant-1/dir/a", "/test-bucket/tenant-1/dir/a", "/test-bucket"}, paths)
+	mu.Unlock()
+}
@@ -0,0 +1,165 @@
+package awos
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gotomicro/ego/core/elog"
+)
+
+// ProgressFunc is called with the bytes transferred so far and the total bytes, which is -1 if it's unknown.
+// The transferred bytes are strictly increasing, the bytes of a failed request aren't reported again when it's retried.
+// It's called synchronously as the bytes flow, by the goroutines uploading the parts of MultipartUpload as well,
+// so it shouldn't block.
+type ProgressFunc func(transferred, total int64)
+
+// progressTracker sums the bytes of the requests of one upload or download
+type progressTracker struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	download bool
+	total    int64
+	// sent is the bytes of the requests which haven't failed, reported is the max of it,
+	// so a retried request isn't reported until it has sent more bytes than the failed one
+	sent     int64
+	reported int64
+}
+
+func newProgressTracker(fn ProgressFunc, download bool) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, download: download, total: -1}
+}
+
+// setTotal sets the total bytes, a negative total is unknown
+func (p *progressTracker) setTotal(total int64) {
+	if p == nil {
+		return
+	}
+	if total < 0 {
+		total = -1
+	}
+	p.mu.Lock()
+	p.total = total
+	p.mu.Unlock()
+}
+
+// setReaderTotal sets the total bytes to the size of reader if it's known
+func (p *progressTracker) setReaderTotal(reader io.Reader) {
+	if p == nil {
+		return
+	}
+	if size, ok := readerSize(reader); ok {
+		p.setTotal(size)
+	}
+}
+
+func (p *progressTracker) read(body *progressBody, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if body.failed {
+		return
+	}
+	body.n += int64(n)
+	p.add(int64(n))
+}
+
+// skip reports n bytes which aren't sent, such as the uploaded parts of a resumed upload
+func (p *progressTracker) skip(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.add(int64(n))
+}
+
+// add must be called with p.mu held
+func (p *progressTracker) add(n int64) {
+	p.sent += n
+	if p.sent > p.reported {
+		p.reported = p.sent
+		p.fn(p.reported, p.total)
+	}
+}
+
+// fail discards the bytes of the failed request of body
+func (p *progressTracker) fail(body *progressBody) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !body.failed {
+		body.failed = true
+		p.sent -= body.n
+	}
+}
+
+// progressBody reports the bytes read from body to p
+type progressBody struct {
+	body io.ReadCloser
+	p    *progressTracker
+	// n and failed are guarded by p.mu
+	n      int64
+	failed bool
+}
+
+func (b *progressBody) Read(buf []byte) (int, error) {
+	n, err := b.body.Read(buf)
+	if n > 0 {
+		b.p.read(b, n)
+	}
+	return n, err
+}
+
+func (b *progressBody) Close() error {
+	return b.body.Close()
+}
+
+type progressKey struct{}
+
+// withProgress returns ctx whose requests report their bytes to p, ctx is returned as is if p is nil
+func withProgress(ctx context.Context, p *progressTracker) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progressTransport reports the request bodies of uploads and the response bodies of downloads to the
+// progressTracker of the request context. It's the innermost interceptor, so each retry is counted on its own.
+type progressTransport struct {
+	rt http.RoundTripper
+}
+
+func progressInterceptor(name string, config *config, logger *elog.Component, base http.RoundTripper) *progressTransport {
+	return &progressTransport{rt: base}
+}
+
+func (t *progressTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	p, _ := r.Context().Value(progressKey{}).(*progressTracker)
+	if p == nil {
+		return t.rt.RoundTrip(r)
+	}
+	if p.download {
+		res, err := t.rt.RoundTrip(r)
+		if err == nil && res.StatusCode < http.StatusMultipleChoices && res.Body != nil {
+			p.setTotal(res.ContentLength)
+			res.Body = &progressBody{body: res.Body, p: p}
+		}
+		return res, err
+	}
+
+	var body *progressBody
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &progressBody{body: r.Body, p: p}
+		r = r.Clone(r.Context())
+		r.Body = body
+	}
+	res, err := t.rt.RoundTrip(r)
+	if body != nil && (err != nil || res.StatusCode >= http.StatusMultipleChoices) {
+		p.fail(body)
+	}
+	return res, err
+}
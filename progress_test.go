@@ -0,0 +1,132 @@
+package awos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// progressRecorder records the calls of a ProgressFunc
+type progressRecorder struct {
+	mu          sync.Mutex
+	transferred []int64
+	totals      []int64
+}
+
+func (r *progressRecorder) record(transferred, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transferred = append(r.transferred, transferred)
+	r.totals = append(r.totals, total)
+}
+
+// assertProgress checks the counts are strictly increasing up to size, and the total of each call
+func (r *progressRecorder) assertProgress(t *testing.T, size int64, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !assert.NotEmpty(t, r.transferred) {
+		return
+	}
+	for i := 1; i < len(r.transferred); i++ {
+		assert.Greater(t, r.transferred[i], r.transferred[i-1])
+	}
+	assert.Equal(t, size, r.transferred[len(r.transferred)-1])
+	for _, n := range r.totals {
+		assert.Equal(t, total, n)
+	}
+}
+
+func TestPutWithProgress(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	clients := map[string]func(handler http.HandlerFunc) Component{
+		StorageTypeS3:  func(handler http.HandlerFunc) Component { return newTestComponent(t, StorageTypeS3, handler) },
+		StorageTypeOSS: func(handler http.HandlerFunc) Component { return newTestComponent(t, StorageTypeOSS, handler) },
+		StorageTypeAzure: func(handler http.HandlerFunc) Component {
+			return newTestAzure(t, handler)
+		},
+	}
+	for storageType, newClient := range clients {
+		t.Run(storageType, func(t *testing.T) {
+			server := &checksumServer{}
+			client := newClient(server.ServeHTTP)
+
+			recorder := &progressRecorder{}
+			assert.NoError(t, client.Put("key", bytes.NewReader(data), nil, PutWithProgress(recorder.record)))
+			assert.True(t, bytes.Equal(data, server.object))
+			recorder.assertProgress(t, int64(len(data)), int64(len(data)))
+
+			recorder = &progressRecorder{}
+			reader, err := client.GetAsReader("key", GetWithProgress(recorder.record))
+			assert.NoError(t, err)
+			res, err := ioutil.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.NoError(t, reader.Close())
+			assert.True(t, bytes.Equal(data, res))
+			recorder.assertProgress(t, int64(len(data)), int64(len(data)))
+
+			recorder = &progressRecorder{}
+			res, err = client.GetBytes("key", GetWithProgress(recorder.record))
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(data, res))
+			recorder.assertProgress(t, int64(len(data)), int64(len(data)))
+		})
+	}
+}
+
+func TestPutWithProgress_Retry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := &checksumServer{}
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		attempt := attempts
+		mu.Unlock()
+		if attempt == 1 {
+			_, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`<Error><Code>InternalError</Code><Message>retry</Message></Error>`))
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+
+	// the bytes of the failed request aren't reported twice
+	recorder := &progressRecorder{}
+	assert.NoError(t, client.Put("key", bytes.NewReader([]byte(content)), nil, PutWithProgress(recorder.record)))
+	assert.Equal(t, content, string(server.object))
+	recorder.assertProgress(t, int64(len(content)), int64(len(content)))
+}
+
+func TestMultipartUploadWithProgress(t *testing.T) {
+	data := make([]byte, 3*MinPartSize+1024)
+	rand.Read(data)
+
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := &multipartServer{parts: make(map[int][]byte)}
+			client := newTestComponent(t, storageType, server.handle)
+
+			recorder := &progressRecorder{}
+			err := client.MultipartUpload("key", bytes.NewReader(data), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(3),
+				PutWithProgress(recorder.record))
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(data, server.completed))
+			// the requests to initiate and complete the upload aren't counted
+			recorder.assertProgress(t, int64(len(data)), int64(len(data)))
+
+			// the size of a stream is unknown
+			recorder = &progressRecorder{}
+			err = client.MultipartUpload("key", ioutil.NopCloser(bytes.NewReader(data)), nil, PutWithPartSize(MinPartSize),
+				PutWithProgress(recorder.record))
+			assert.NoError(t, err)
+			recorder.assertProgress(t, int64(len(data)), -1)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultPutDirConcurrency is the default number of files PutDir uploads concurrently
+const DefaultPutDirConcurrency = 8
+
+type PutDirOptions func(options *putDirOptions)
+
+type putDirOptions struct {
+	concurrency    int
+	followSymlinks bool
+	putOptions     []PutOptions
+}
+
+func DefaultPutDirOptions() *putDirOptions {
+	return &putDirOptions{
+		concurrency: DefaultPutDirConcurrency,
+	}
+}
+
+// PutDirWithConcurrency sets the number of files uploaded concurrently
+func PutDirWithConcurrency(concurrency int) PutDirOptions {
+	return func(options *putDirOptions) {
+		if concurrency > 0 {
+			options.concurrency = concurrency
+		}
+	}
+}
+
+// PutDirWithFollowSymlinks uploads the targets of symlinks instead of skipping them,
+// linked directories are walked unless the link points to a parent directory.
+func PutDirWithFollowSymlinks(follow bool) PutDirOptions {
+	return func(options *putDirOptions) {
+		options.followSymlinks = follow
+	}
+}
+
+// PutDirWithPutOptions applies options to the put of every file
+func PutDirWithPutOptions(options ...PutOptions) PutDirOptions {
+	return func(dirOptions *putDirOptions) {
+		dirOptions.putOptions = append(dirOptions.putOptions, options...)
+	}
+}
+
+// PutDirStats is the result of PutDir
+type PutDirStats struct {
+	// Files is the number of uploaded files
+	Files int
+	// Bytes is the total size of uploaded files
+	Bytes int64
+	// Errors are the failed files by local path
+	Errors map[string]error
+}
+
+type putDirFile struct {
+	path string
+	key  string
+}
+
+// PutDir uploads the files in localDir with keys of keyPrefix and the slash separated path relative to localDir.
+// The content type of each file is detected by the extension or the content unless PutWithContentType is given.
+// A failed file doesn't abort the others, the returned error is non-nil if any file failed
+// or ctx is done, which stops walking and uploading.
+func PutDir(ctx context.Context, c Component, localDir string, keyPrefix string, options ...PutDirOptions) (*PutDirStats, error) {
+	dirOpts := DefaultPutDirOptions()
+	for _, opt := range options {
+		opt(dirOpts)
+	}
+	putOpts := DefaultPutOptions()
+	for _, opt := range dirOpts.putOptions {
+		opt(putOpts)
+	}
+	c = c.WithContext(ctx)
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		bytes int64
+		files int64
+		stats = &PutDirStats{Errors: make(map[string]error)}
+	)
+	pending := make(chan putDirFile)
+	for i := 0; i < dirOpts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range pending {
+				size, err := putDirUpload(c, file, dirOpts.putOptions, !putOpts.contentTypeSet)
+				if err != nil {
+					mu.Lock()
+					stats.Errors[file.path] = err
+					mu.Unlock()
+					continue
+				}
+				atomic.AddInt64(&files, 1)
+				atomic.AddInt64(&bytes, size)
+			}
+		}()
+	}
+
+	total := 0
+	walkErr := walkDir(ctx, localDir, "", dirOpts.followSymlinks, make(map[string]bool), func(path string, rel string) error {
+		select {
+		case pending <- putDirFile{path: path, key: keyPrefix + rel}:
+			total++
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, func(path string, err error) {
+		total++
+		mu.Lock()
+		stats.Errors[path] = err
+		mu.Unlock()
+	})
+	close(pending)
+	wg.Wait()
+
+	stats.Files = int(files)
+	stats.Bytes = bytes
+	if walkErr != nil {
+		return stats, walkErr
+	}
+	if len(stats.Errors) > 0 {
+		return stats, fmt.Errorf("awos: failed to upload %d of %d files", len(stats.Errors), total)
+	}
+	return stats, nil
+}
+
+// putDirUpload puts a file and returns its size
+func putDirUpload(c Component, file putDirFile, options []PutOptions, detectContentType bool) (int64, error) {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if detectContentType {
+		putOpts := DefaultPutOptions()
+		if err := putOpts.detectContentType(file.key, f); err != nil {
+			return 0, err
+		}
+		options = append(options[:len(options):len(options)], PutWithContentType(putOpts.contentType))
+	}
+	if err := c.Put(file.key, f, nil, options...); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// walkDir calls fn for each regular file in dir with the slash separated path relative to the walked root,
+// and onErr for followed symlinks which can't be resolved. visited keeps the directories being walked,
+// a symlink to one of them would loop.
+func walkDir(ctx context.Context, dir string, rel string, followSymlinks bool, visited map[string]bool, fn func(path string, rel string) error, onErr func(path string, err error)) error {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+		defer delete(visited, real)
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, info.Name())
+		childRel := rel + info.Name()
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !followSymlinks {
+				continue
+			}
+			if info, err = os.Stat(path); err != nil {
+				// e.g. a broken link, which fails the file but not the walk
+				onErr(path, err)
+				continue
+			}
+		}
+		switch {
+		case info.IsDir():
+			if err := walkDir(ctx, path, childRel+"/", followSymlinks, visited, fn, onErr); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := fn(path, childRel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
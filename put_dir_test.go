@@ -0,0 +1,104 @@
+package awos
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDir creates a tree with regular files and symlinks, a link to a file, a directory and a parent directory
+func newTestDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":         "<html><body>hello</body></html>",
+		"css/site.css":       "body { color: red; }",
+		"data/deep/raw.bin":  "\x00\x01\x02\x03",
+		"data/deep/notes.md": "# notes",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+	}
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "index.html"), filepath.Join(dir, "home.html")))
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "css"), filepath.Join(dir, "styles")))
+	assert.NoError(t, os.Symlink(dir, filepath.Join(dir, "data", "loop")))
+	return dir
+}
+
+func TestPutDir(t *testing.T) {
+	client := newTestMemory()
+	dir := newTestDir(t)
+
+	stats, err := PutDir(context.Background(), client, dir, "assets/", PutDirWithConcurrency(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Files)
+	assert.Equal(t, int64(31+20+4+7), stats.Bytes)
+	assert.Empty(t, stats.Errors)
+
+	keys, err := client.ListObject("", "assets/", "", 100, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"assets/css/site.css", "assets/data/deep/notes.md", "assets/data/deep/raw.bin", "assets/index.html"}, keys)
+
+	res, err := client.Get("assets/css/site.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "body { color: red; }", res)
+	for key, contentType := range map[string]string{
+		"assets/index.html":        "text/html; charset=utf-8",
+		"assets/css/site.css":      "text/css; charset=utf-8",
+		"assets/data/deep/raw.bin": "application/octet-stream",
+	} {
+		meta, err := client.HeadObject(key)
+		assert.NoError(t, err)
+		assert.Equal(t, contentType, meta.ContentType, key)
+	}
+
+	// PutWithContentType disables the detection
+	_, err = PutDir(context.Background(), client, dir, "plain/", PutDirWithPutOptions(PutWithContentType("text/plain")))
+	assert.NoError(t, err)
+	meta, err := client.HeadObject("plain/index.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", meta.ContentType)
+}
+
+func TestPutDir_FollowSymlinks(t *testing.T) {
+	client := newTestMemory()
+	dir := newTestDir(t)
+
+	stats, err := PutDir(context.Background(), client, dir, "", PutDirWithFollowSymlinks(true))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, stats.Files)
+
+	keys, err := client.ListObject("", "", "", 100, "")
+	assert.NoError(t, err)
+	// data/loop points to the root, which isn't walked again
+	assert.Equal(t, []string{"css/site.css", "data/deep/notes.md", "data/deep/raw.bin", "home.html", "index.html", "styles/site.css"}, keys)
+}
+
+func TestPutDir_Errors(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	dir := newTestDir(t)
+
+	client.FailNext("Put", "css/site.css", 1, ErrUnsupported)
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "broken")))
+	stats, err := PutDir(context.Background(), client, dir, "", PutDirWithFollowSymlinks(true))
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "2 of 7"), err)
+	assert.Equal(t, 5, stats.Files)
+	assert.Len(t, stats.Errors, 2)
+	assert.Equal(t, ErrUnsupported, stats.Errors[filepath.Join(dir, "css", "site.css")])
+	assert.Contains(t, stats.Errors, filepath.Join(dir, "broken"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = PutDir(ctx, client, dir, "canceled/")
+	assert.Equal(t, context.Canceled, err)
+	keys, err := client.ListObject("", "canceled/", "", 100, "")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,117 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// lenReader is implemented by readers knowing the number of unread bytes, such as bytes.Buffer
+type lenReader interface {
+	Len() int
+}
+
+// PutFromReader uploads reader with Put if it's not larger than the threshold of PutWithMultipartThreshold,
+// otherwise with MultipartUpload. The size is known for io.Seeker and readers with Len, such as files and
+// bytes.Buffer, or given by PutWithContentLength. Readers of unknown size are buffered up to the threshold,
+// so the memory is bounded by the threshold and the parts of MultipartUpload.
+func PutFromReader(ctx context.Context, c Component, key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	putOpts := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOpts)
+	}
+	threshold := putOpts.multipartThreshold
+	if threshold <= 0 {
+		threshold = putOpts.partSize
+	}
+	c = c.WithContext(ctx)
+
+	size, known := readerSize(reader)
+	if n := putOpts.contentLength; n != nil {
+		if *n < 0 {
+			return fmt.Errorf("invalid content length %d", *n)
+		}
+		if known && size != *n {
+			return fmt.Errorf("content length %d, the reader has %d bytes", *n, size)
+		}
+		if !known {
+			size, known = *n, true
+			reader = &contentLengthReader{reader: reader, remaining: size}
+		}
+	}
+	if known && size > threshold {
+		if minSize := (size + maxPartCount - 1) / maxPartCount; putOpts.partSize < minSize {
+			options = append(options[:len(options):len(options)], PutWithPartSize(minSize))
+		}
+		return c.MultipartUpload(key, reader, meta, options...)
+	}
+	if seeker, ok := reader.(io.ReadSeeker); ok && known {
+		return c.Put(key, seeker, meta, options...)
+	}
+	if known {
+		var buf bytes.Buffer
+		buf.Grow(int(size))
+		if _, err := buf.ReadFrom(reader); err != nil {
+			return err
+		}
+		return c.Put(key, bytes.NewReader(buf.Bytes()), meta, options...)
+	}
+
+	// one more byte tells whether the object is larger than the threshold
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, reader, threshold+1)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if n > threshold {
+		return c.MultipartUpload(key, io.MultiReader(&buf, reader), meta, options...)
+	}
+	return c.Put(key, bytes.NewReader(buf.Bytes()), meta, options...)
+}
+
+// contentLengthReader fails reading if reader doesn't have exactly remaining bytes
+type contentLengthReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *contentLengthReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// reading one more byte tells whether the reader is longer
+		var b [1]byte
+		n, err := r.reader.Read(b[:])
+		if n > 0 {
+			return 0, errors.New("the reader is longer than the content length")
+		}
+		if err == nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if err == io.EOF && r.remaining > 0 {
+		return n, fmt.Errorf("the reader is shorter than the content length, %d bytes missing: %w", r.remaining, io.ErrUnexpectedEOF)
+	}
+	if err == io.EOF {
+		// the next read checks the end of reader
+		err = nil
+	}
+	return n, err
+}
+
+// readerSize returns the number of unread bytes of reader if it's known
+func readerSize(reader io.Reader) (int64, bool) {
+	if r, ok := reader.(lenReader); ok {
+		return int64(r.Len()), true
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		return seekerSize(seeker)
+	}
+	return 0, false
+}
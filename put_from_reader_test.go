@@ -0,0 +1,102 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutFromReader(t *testing.T) {
+	const threshold = 1024
+	readers := map[string]func(data string) io.Reader{
+		"seeker":  func(data string) io.Reader { return strings.NewReader(data) },
+		"len":     func(data string) io.Reader { return bytes.NewBufferString(data) },
+		"unknown": func(data string) io.Reader { return struct{ io.Reader }{strings.NewReader(data)} },
+	}
+	for name, newReader := range readers {
+		for _, size := range []int{0, threshold - 1, threshold, threshold + 1, 3 * threshold} {
+			data := strings.Repeat("a", size)
+			client := NewFakeClient("fake-bucket")
+			err := PutFromReader(context.Background(), client, guid, newReader(data), map[string]string{"head": "1"},
+				PutWithMultipartThreshold(threshold))
+			assert.NoError(t, err, name, size)
+
+			multipart := 0
+			if size > threshold {
+				multipart = 1
+			}
+			assert.Equal(t, multipart, client.Calls("MultipartUpload"), name, size)
+			assert.Equal(t, 1-multipart, client.Calls("Put"), name, size)
+			res, err := client.Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, data, res, name, size)
+			meta, err := client.Head(guid, []string{"head"})
+			assert.NoError(t, err)
+			assert.Equal(t, "1", meta["head"])
+		}
+	}
+}
+
+func TestPutFromReader_DefaultThreshold(t *testing.T) {
+	client := NewFakeClient("fake-bucket")
+	// the threshold defaults to the part size
+	data := strings.Repeat("a", int(MinPartSize)+1)
+	err := PutFromReader(context.Background(), client, guid, struct{ io.Reader }{strings.NewReader(data)}, nil, PutWithPartSize(MinPartSize))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.Calls("MultipartUpload"))
+
+	client.FailNext("Put", guid, 1, ErrPreconditionFailed)
+	err = PutFromReader(context.Background(), client, guid, strings.NewReader(data[:10]), nil)
+	assert.True(t, errors.Is(err, ErrPreconditionFailed))
+}
+
+func TestPutFromReader_ContentLength(t *testing.T) {
+	const threshold = 1024
+	pipe := func(data string) io.Reader {
+		pr, pw := io.Pipe()
+		go func() {
+			_, err := io.WriteString(pw, data)
+			pw.CloseWithError(err)
+		}()
+		return pr
+	}
+	for _, size := range []int{0, threshold, threshold + 1, 3 * threshold} {
+		data := strings.Repeat("a", size)
+		client := NewFakeClient("fake-bucket")
+		err := PutFromReader(context.Background(), client, guid, pipe(data), nil,
+			PutWithMultipartThreshold(threshold), PutWithContentLength(int64(size)))
+		assert.NoError(t, err, size)
+
+		multipart := 0
+		if size > threshold {
+			multipart = 1
+		}
+		assert.Equal(t, multipart, client.Calls("MultipartUpload"), size)
+		assert.Equal(t, 1-multipart, client.Calls("Put"), size)
+		res, err := client.Get(guid)
+		assert.NoError(t, err)
+		assert.Equal(t, data, res, size)
+	}
+
+	// the reader must have exactly the content length
+	for _, size := range []int{10, 3 * threshold} {
+		client := NewFakeClient("fake-bucket")
+		err := PutFromReader(context.Background(), client, guid, pipe(strings.Repeat("a", size-1)), nil,
+			PutWithMultipartThreshold(threshold), PutWithContentLength(int64(size)))
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+		err = PutFromReader(context.Background(), client, guid, pipe(strings.Repeat("a", size+1)), nil,
+			PutWithMultipartThreshold(threshold), PutWithContentLength(int64(size)))
+		assert.Error(t, err, size)
+		exists, err := client.Exists(guid)
+		assert.NoError(t, err)
+		assert.False(t, exists, size)
+	}
+	client := NewFakeClient("fake-bucket")
+	assert.Error(t, PutFromReader(context.Background(), client, guid, strings.NewReader(content), nil, PutWithContentLength(1)))
+	assert.Error(t, PutFromReader(context.Background(), client, guid, struct{ io.Reader }{strings.NewReader(content)}, nil, PutWithContentLength(-1)))
+}
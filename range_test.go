@@ -0,0 +1,62 @@
+package awos
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rangeHandler(ranges *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("0123456789"))
+	}
+}
+
+func TestGetWithRange(t *testing.T) {
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var ranges []string
+			client := newTestComponent(t, storageType, rangeHandler(&ranges))
+
+			reader, err := client.GetAsReader(guid, GetWithRange(2, 4))
+			assert.NoError(t, err)
+			data, _ := ioutil.ReadAll(reader)
+			assert.Equal(t, "234", string(data))
+
+			res, err := client.Get(guid, GetWithRange(7, -1))
+			assert.NoError(t, err)
+			assert.Equal(t, "789", res)
+
+			assert.Equal(t, []string{"bytes=2-4", "bytes=7-"}, ranges)
+
+			_, err = client.GetAsReader(guid, GetWithRange(4, 2))
+			assert.Error(t, err)
+			assert.Len(t, ranges, 2)
+		})
+	}
+}
+
+func TestMemory_GetWithRange(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader("0123456789"), nil))
+
+	res, err := client.Get(guid, GetWithRange(2, 4))
+	assert.NoError(t, err)
+	assert.Equal(t, "234", res)
+
+	res, err = client.Get(guid, GetWithRange(7, -1))
+	assert.NoError(t, err)
+	assert.Equal(t, "789", res)
+
+	res, err = client.Get(guid, GetWithRange(8, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, "89", res)
+
+	_, err = client.Get(guid, GetWithRange(4, 2))
+	assert.Error(t, err)
+}
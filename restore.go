@@ -0,0 +1,105 @@
+package awos
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// tiers of RestoreWithTier, the same for s3 and oss
+const (
+	RestoreTierExpedited = "Expedited"
+	RestoreTierStandard  = "Standard"
+	RestoreTierBulk      = "Bulk"
+)
+
+// DefaultRestoreDays is the default number of days a restored copy is kept
+const DefaultRestoreDays = 1
+
+type RestoreOptions func(options *restoreOptions)
+
+type restoreOptions struct {
+	days int
+	tier string
+}
+
+func DefaultRestoreOptions() *restoreOptions {
+	return &restoreOptions{
+		days: DefaultRestoreDays,
+	}
+}
+
+// RestoreWithDays sets the number of days the restored copy is kept
+func RestoreWithDays(days int) RestoreOptions {
+	return func(options *restoreOptions) {
+		options.days = days
+	}
+}
+
+// RestoreWithTier sets the retrieval tier, one of RestoreTierExpedited, RestoreTierStandard and RestoreTierBulk.
+// The backend default is used if not set, oss only supports tiers for cold archive objects.
+func RestoreWithTier(tier string) RestoreOptions {
+	return func(options *restoreOptions) {
+		options.tier = tier
+	}
+}
+
+func (o *restoreOptions) validate() error {
+	if o.days <= 0 {
+		return fmt.Errorf("invalid restore days %d", o.days)
+	}
+	switch o.tier {
+	case "", RestoreTierExpedited, RestoreTierStandard, RestoreTierBulk:
+		return nil
+	}
+	return fmt.Errorf("invalid restore tier %q", o.tier)
+}
+
+// ossRestoreRequest is the body of the oss RestoreObject request, which the oss sdk doesn't support yet
+type ossRestoreRequest struct {
+	XMLName xml.Name `xml:"RestoreRequest"`
+	Days    int      `xml:"Days"`
+	Tier    string   `xml:"JobParameters>Tier,omitempty"`
+}
+
+// RestoreStatus is the status of restoring an archived object
+type RestoreStatus struct {
+	// Ongoing is true until the object is restored
+	Ongoing bool
+	// ExpiryDate is when the restored copy expires, zero while ongoing
+	ExpiryDate time.Time
+}
+
+var (
+	restoreOngoingRegexp    = regexp.MustCompile(`ongoing-request="(true|false)"`)
+	restoreExpiryDateRegexp = regexp.MustCompile(`expiry-date="([^"]+)"`)
+)
+
+// parseRestoreStatus parses the restore header of s3 and oss, such as
+// ongoing-request="false", expiry-date="Fri, 23 Dec 2012 00:00:00 GMT".
+// It returns nil if the header is empty, which means the object isn't being restored.
+func parseRestoreStatus(header string) (*RestoreStatus, error) {
+	if header == "" {
+		return nil, nil
+	}
+	ongoing := restoreOngoingRegexp.FindStringSubmatch(header)
+	if ongoing == nil {
+		return nil, fmt.Errorf("invalid restore header %q", header)
+	}
+	status := &RestoreStatus{Ongoing: ongoing[1] == "true"}
+	if expiryDate := restoreExpiryDateRegexp.FindStringSubmatch(header); expiryDate != nil {
+		t, err := http.ParseTime(expiryDate[1])
+		if err != nil {
+			return nil, err
+		}
+		status.ExpiryDate = t
+	}
+	return status, nil
+}
+
+// formatRestoreStatus formats the restore header of a restored object
+func formatRestoreStatus(expiryDate time.Time) string {
+	return fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`, expiryDate.UTC().Format(http.TimeFormat))
+}
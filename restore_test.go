@@ -0,0 +1,124 @@
+package awos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRestoreHeader = `ongoing-request="false", expiry-date="Fri, 16 Oct 2026 00:00:00 GMT"`
+
+// restoreHandler records the body of restore requests, gets fail until an object is restored
+func restoreHandler(prefix string, body *string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			if _, ok := r.URL.Query()["restore"]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := ioutil.ReadAll(r.Body)
+			*body = string(data)
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>InvalidObjectState</Code><Message>The operation is not valid for the object's state</Message></Error>`))
+		case http.MethodHead:
+			w.Header().Set(prefix+"Storage-Class", "Archive")
+			w.Header().Set(prefix+"Restore", testRestoreHeader)
+		}
+	}
+}
+
+func TestRestoreObject(t *testing.T) {
+	tests := []struct {
+		storageType string
+		prefix      string
+		tierXML     string
+	}{
+		{storageType: StorageTypeS3, prefix: "X-Amz-", tierXML: "<GlacierJobParameters><Tier>Bulk</Tier></GlacierJobParameters>"},
+		{storageType: StorageTypeOSS, prefix: "X-Oss-", tierXML: "<JobParameters><Tier>Bulk</Tier></JobParameters>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.storageType, func(t *testing.T) {
+			var body string
+			client := newTestComponent(t, tt.storageType, restoreHandler(tt.prefix, &body))
+
+			_, err := client.Get(guid)
+			assert.True(t, errors.Is(err, ErrObjectArchived), err)
+
+			assert.NoError(t, client.RestoreObject(guid))
+			assert.Contains(t, body, "<Days>1</Days>")
+			assert.NotContains(t, body, "Tier")
+
+			assert.NoError(t, client.RestoreObject(guid, RestoreWithDays(3), RestoreWithTier(RestoreTierBulk)))
+			assert.Contains(t, body, "<Days>3</Days>")
+			assert.Contains(t, body, tt.tierXML)
+
+			meta, err := client.HeadObject(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, &RestoreStatus{ExpiryDate: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}, meta.Restore)
+
+			assert.Error(t, client.RestoreObject(guid, RestoreWithDays(0)))
+			assert.Error(t, client.RestoreObject(guid, RestoreWithTier("Fast")))
+		})
+	}
+}
+
+func TestParseRestoreStatus(t *testing.T) {
+	status, err := parseRestoreStatus("")
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	status, err = parseRestoreStatus(`ongoing-request="true"`)
+	assert.NoError(t, err)
+	assert.Equal(t, &RestoreStatus{Ongoing: true}, status)
+
+	expiryDate := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	status, err = parseRestoreStatus(formatRestoreStatus(expiryDate))
+	assert.NoError(t, err)
+	assert.Equal(t, &RestoreStatus{ExpiryDate: expiryDate}, status)
+
+	_, err = parseRestoreStatus("restored")
+	assert.Error(t, err)
+	_, err = parseRestoreStatus(`ongoing-request="false", expiry-date="tomorrow"`)
+	assert.Error(t, err)
+}
+
+func TestMemory_RestoreObject(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put("archived", bytes.NewReader([]byte(content)), nil, PutWithStorageClass(StorageClassArchive)))
+
+	_, err := client.Get("archived")
+	assert.True(t, errors.Is(err, ErrObjectArchived), err)
+	_, err = client.GetAndDecompress("archived")
+	assert.True(t, errors.Is(err, ErrObjectArchived), err)
+	meta, err := client.HeadObject("archived")
+	assert.NoError(t, err)
+	assert.Nil(t, meta.Restore)
+
+	assert.NoError(t, client.RestoreObject("archived", RestoreWithDays(2)))
+	res, err := client.Get("archived")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	meta, err = client.HeadObject("archived")
+	assert.NoError(t, err)
+	if assert.NotNil(t, meta.Restore) {
+		assert.False(t, meta.Restore.Ongoing)
+		assert.True(t, meta.Restore.ExpiryDate.After(time.Now().AddDate(0, 0, 1)))
+	}
+
+	assert.NoError(t, client.Put("standard", bytes.NewReader([]byte(content)), nil))
+	assert.Error(t, client.RestoreObject("standard"))
+	assert.True(t, errors.Is(client.RestoreObject("not-exist"), ErrObjectNotFound))
+}
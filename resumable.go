@@ -0,0 +1,243 @@
+package awos
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errUploadNotFound is returned when the multipart upload is completed, aborted or expired
+var errUploadNotFound = errors.New("awos: multipart upload not found")
+
+// UploadState is the progress of a resumable upload, which is saved after each uploaded part
+type UploadState struct {
+	UploadID string `json:"uploadId"`
+	// PartSize is used when resuming, instead of PutWithPartSize
+	PartSize int64          `json:"partSize"`
+	Parts    []UploadedPart `json:"parts"`
+}
+
+// UploadedPart is an uploaded part of a resumable upload
+type UploadedPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// UploadStateStore persists the states of resumable uploads by key
+type UploadStateStore interface {
+	// Load returns the saved state of key, or nil if there is none
+	Load(key string) (*UploadState, error)
+	Save(key string, state *UploadState) error
+	Delete(key string) error
+}
+
+// resumableUploader is implemented by the backends supporting ResumeUpload
+type resumableUploader interface {
+	// createResumableUpload starts a multipart upload with the headers of putOptions and returns its id
+	createResumableUpload(key string, meta map[string]string, putOptions *putOptions) (string, error)
+	// uploadResumablePart uploads a part and returns its etag
+	uploadResumablePart(key string, uploadID string, partNumber int, data []byte, putOptions *putOptions) (string, error)
+	// listResumableParts returns the uploaded parts, or errUploadNotFound
+	listResumableParts(key string, uploadID string) ([]UploadedPart, error)
+	// completeResumableUpload completes the upload with the parts sorted by part number
+	completeResumableUpload(key string, uploadID string, parts []UploadedPart, putOptions *putOptions) error
+}
+
+// ResumeUpload uploads r by a multipart upload whose progress is saved in store, so an interrupted upload
+// resumes by calling it again with r from the start. The parts uploaded before are skipped if the backend
+// still has them with the saved etags, and if their content is unchanged when the etag is the md5 of the part,
+// other parts are uploaded again. A new upload is started if the saved one doesn't exist anymore.
+// The state is deleted after the upload completes, and kept if it fails.
+// It's supported by s3, gcs, cos and oss, PutWithCompression isn't supported.
+func ResumeUpload(ctx context.Context, c Component, key string, store UploadStateStore, r io.Reader, meta map[string]string, options ...PutOptions) error {
+	putOpts := DefaultPutOptions()
+	for _, opt := range options {
+		opt(putOpts)
+	}
+	if err := putOpts.validate(); err != nil {
+		return err
+	}
+	if putOpts.compression != "" {
+		return fmt.Errorf("ResumeUpload doesn't support PutWithCompression: %w", ErrUnsupported)
+	}
+	uploader, ok := c.WithContext(ctx).(resumableUploader)
+	if !ok {
+		return fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+	}
+	meta, err := putOpts.userMeta(meta)
+	if err != nil {
+		return err
+	}
+	putOpts.progress.setReaderTotal(r)
+
+	state, err := store.Load(key)
+	if err != nil {
+		return err
+	}
+	// the saved parts which the backend still has
+	done := make(map[int]UploadedPart)
+	if state != nil {
+		uploaded, err := uploader.listResumableParts(key, state.UploadID)
+		if err != nil && !errors.Is(err, errUploadNotFound) {
+			return err
+		}
+		if errors.Is(err, errUploadNotFound) {
+			state = nil
+		} else {
+			etags := make(map[int]string, len(uploaded))
+			for _, part := range uploaded {
+				etags[part.PartNumber] = trimETag(part.ETag)
+			}
+			for _, part := range state.Parts {
+				if etags[part.PartNumber] == trimETag(part.ETag) {
+					done[part.PartNumber] = part
+				}
+			}
+		}
+	}
+	if state == nil {
+		uploadID, err := uploader.createResumableUpload(key, meta, putOpts)
+		if err != nil {
+			return err
+		}
+		state = &UploadState{UploadID: uploadID, PartSize: putOpts.partSize}
+		if err := store.Save(key, state); err != nil {
+			return err
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		parts = make(map[int]UploadedPart)
+	)
+	for number, part := range done {
+		parts[number] = part
+	}
+	read := 0
+	err = uploadParts(r, state.PartSize, putOpts.concurrency, func(partNumber int, data []byte) error {
+		mu.Lock()
+		if partNumber > read {
+			read = partNumber
+		}
+		mu.Unlock()
+		if part, ok := done[partNumber]; ok && part.Size == int64(len(data)) && partContentMatches(part.ETag, data) {
+			putOpts.progress.skip(len(data))
+			return nil
+		}
+
+		etag, err := uploader.uploadResumablePart(key, state.UploadID, partNumber, data, putOpts)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		parts[partNumber] = UploadedPart{PartNumber: partNumber, ETag: etag, Size: int64(len(data))}
+		return store.Save(key, &UploadState{UploadID: state.UploadID, PartSize: state.PartSize, Parts: sortedParts(parts, 0)})
+	})
+	if err != nil {
+		return err
+	}
+
+	// the saved parts after the end of r are left out
+	if err := uploader.completeResumableUpload(key, state.UploadID, sortedParts(parts, read), putOpts); err != nil {
+		return err
+	}
+	return store.Delete(key)
+}
+
+// sortedParts returns the parts up to the part number last, 0 means all of them
+func sortedParts(parts map[int]UploadedPart, last int) []UploadedPart {
+	res := make([]UploadedPart, 0, len(parts))
+	for number, part := range parts {
+		if last == 0 || number <= last {
+			res = append(res, part)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].PartNumber < res[j].PartNumber
+	})
+	return res
+}
+
+// partContentMatches compares data with etag if it's the hex md5 of the part, which isn't the case with SSE-KMS or SSE-C
+func partContentMatches(etag string, data []byte) bool {
+	etag = strings.ToLower(trimETag(etag))
+	if _, err := hex.DecodeString(etag); err != nil || len(etag) != 2*md5.Size {
+		return true
+	}
+	sum := md5.Sum(data)
+	return etag == hex.EncodeToString(sum[:])
+}
+
+type fileUploadStateStore struct {
+	dir string
+}
+
+// NewFileUploadStateStore returns an UploadStateStore keeping each state in a json file in dir
+func NewFileUploadStateStore(dir string) UploadStateStore {
+	return &fileUploadStateStore{dir: dir}
+}
+
+// path names the file by the hash of key, which may not be a valid file name
+func (s *fileUploadStateStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *fileUploadStateStore) Load(key string) (*UploadState, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state UploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Save writes a temporary file and renames it, so the saved state is never partially written
+func (s *fileUploadStateStore) Save(key string, state *UploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+func (s *fileUploadStateStore) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resumableServer is a fake multipart upload api of s3 and oss, failPart fails the first upload of the part
+type resumableServer struct {
+	mu       sync.Mutex
+	uploads  map[string]map[int][]byte
+	created  int
+	uploaded []int
+	failPart int
+	object   []byte
+}
+
+func newResumableServer(failPart int) *resumableServer {
+	return &resumableServer{uploads: make(map[string]map[int][]byte), failPart: failPart}
+}
+
+func partETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (s *resumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	if _, ok := query["uploads"]; ok {
+		s.created++
+		uploadID = "upload-" + strconv.Itoa(s.created)
+		s.uploads[uploadID] = make(map[int][]byte)
+		_, _ = fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>test-bucket</Bucket><Key>key</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, uploadID)
+		return
+	}
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<Error><Code>NoSuchUpload</Code><Message>not found</Message></Error>`))
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		if partNumber == s.failPart {
+			s.failPart = 0
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`<Error><Code>BadDigest</Code><Message>failed</Message></Error>`))
+			return
+		}
+		s.uploaded = append(s.uploaded, partNumber)
+		parts[partNumber] = data
+		w.Header().Set("ETag", partETag(data))
+	case http.MethodGet:
+		numbers := make([]int, 0, len(parts))
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var res strings.Builder
+		res.WriteString(`<ListPartsResult><Bucket>test-bucket</Bucket><Key>key</Key><UploadId>` + uploadID + `</UploadId><IsTruncated>false</IsTruncated>`)
+		for _, number := range numbers {
+			_, _ = fmt.Fprintf(&res, `<Part><PartNumber>%d</PartNumber><ETag>%s</ETag><Size>%d</Size></Part>`, number, partETag(parts[number]), len(parts[number]))
+		}
+		res.WriteString(`</ListPartsResult>`)
+		_, _ = w.Write([]byte(res.String()))
+	case http.MethodPost:
+		var object []byte
+		for number := 1; number <= len(parts); number++ {
+			object = append(object, parts[number]...)
+		}
+		s.object = object
+		delete(s.uploads, uploadID)
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><Bucket>test-bucket</Bucket><Key>key</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+	}
+}
+
+// memoryStateStore is an UploadStateStore keeping the states in memory
+type memoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]UploadState
+}
+
+func (s *memoryStateStore) Load(key string) (*UploadState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (s *memoryStateStore) Save(key string, state *UploadState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = make(map[string]UploadState)
+	}
+	s.states[key] = *state
+	return nil
+}
+
+func (s *memoryStateStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+func TestResumeUpload(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), int(MinPartSize)/16*2+10)
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			server := newResumableServer(2)
+			client := newTestComponent(t, storageType, server.ServeHTTP)
+			store := &memoryStateStore{}
+
+			// part 2 fails, and the state keeps part 1
+			err := ResumeUpload(context.Background(), client, "key", store, bytes.NewReader(data), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(1))
+			assert.Error(t, err)
+			state, _ := store.Load("key")
+			if assert.NotNil(t, state) {
+				assert.Equal(t, "upload-1", state.UploadID)
+				assert.Equal(t, []UploadedPart{{PartNumber: 1, ETag: partETag(data[:MinPartSize]), Size: MinPartSize}}, state.Parts)
+			}
+			assert.Equal(t, []int{1}, server.uploaded)
+
+			// the resumed upload skips part 1
+			server.uploaded = nil
+			err = ResumeUpload(context.Background(), client, "key", store, bytes.NewReader(data), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(1))
+			assert.NoError(t, err)
+			assert.Equal(t, []int{2, 3}, server.uploaded)
+			assert.Equal(t, 1, server.created)
+			assert.Equal(t, data, server.object)
+			state, _ = store.Load("key")
+			assert.Nil(t, state)
+		})
+	}
+}
+
+func TestResumeUpload_ChangedPart(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), int(MinPartSize)/16+10)
+	server := newResumableServer(2)
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	store := &memoryStateStore{}
+	assert.Error(t, ResumeUpload(context.Background(), client, "key", store, bytes.NewReader(data), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(1)))
+
+	// part 1 is uploaded again since its content changed
+	server.uploaded = nil
+	changed := append([]byte("x"), data[1:]...)
+	assert.NoError(t, ResumeUpload(context.Background(), client, "key", store, bytes.NewReader(changed), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(1)))
+	assert.Equal(t, []int{1, 2}, server.uploaded)
+	assert.Equal(t, changed, server.object)
+}
+
+func TestResumeUpload_ExpiredUpload(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), int(MinPartSize)/16+10)
+	server := newResumableServer(0)
+	client := newTestComponent(t, StorageTypeS3, server.ServeHTTP)
+	store := NewFileUploadStateStore(t.TempDir())
+	assert.NoError(t, store.Save("key", &UploadState{UploadID: "expired", PartSize: MinPartSize, Parts: []UploadedPart{{PartNumber: 1, ETag: "etag", Size: MinPartSize}}}))
+
+	// a new upload is started
+	assert.NoError(t, ResumeUpload(context.Background(), client, "key", store, bytes.NewReader(data), nil, PutWithPartSize(MinPartSize), PutWithConcurrency(1)))
+	assert.Equal(t, 1, server.created)
+	assert.Equal(t, []int{1, 2}, server.uploaded)
+	assert.Equal(t, data, server.object)
+	state, err := store.Load("key")
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+}
+
+func TestResumeUpload_Unsupported(t *testing.T) {
+	err := ResumeUpload(context.Background(), newMemory("bucket"), "key", &memoryStateStore{}, strings.NewReader(content), nil)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
@@ -25,11 +25,16 @@ func (h *HeadGetObjectOutputWrapper) getContentEncoding() *string {
 }
 
 func (h *HeadGetObjectOutputWrapper) getContentLength() *string {
+	var contentLength *int64
 	if h.getObjectOutput != nil {
-		clStr := strconv.FormatInt(*h.getObjectOutput.ContentLength, 10)
-		return &clStr
+		contentLength = h.getObjectOutput.ContentLength
+	} else {
+		contentLength = h.headObjectOutput.ContentLength
 	}
-	clStr := strconv.FormatInt(*h.headObjectOutput.ContentLength, 10)
+	if contentLength == nil {
+		return nil
+	}
+	clStr := strconv.FormatInt(*contentLength, 10)
 	return &clStr
 }
 
@@ -40,6 +45,41 @@ func (h *HeadGetObjectOutputWrapper) getContentDisposition() *string {
 	return h.headObjectOutput.ContentDisposition
 }
 
+func (h *HeadGetObjectOutputWrapper) getCacheControl() *string {
+	if h.getObjectOutput != nil {
+		return h.getObjectOutput.CacheControl
+	}
+	return h.headObjectOutput.CacheControl
+}
+
+func (h *HeadGetObjectOutputWrapper) getETag() *string {
+	if h.getObjectOutput != nil {
+		return h.getObjectOutput.ETag
+	}
+	return h.headObjectOutput.ETag
+}
+
+func (h *HeadGetObjectOutputWrapper) getServerSideEncryption() *string {
+	if h.getObjectOutput != nil {
+		return h.getObjectOutput.ServerSideEncryption
+	}
+	return h.headObjectOutput.ServerSideEncryption
+}
+
+func (h *HeadGetObjectOutputWrapper) getSSEKMSKeyID() *string {
+	if h.getObjectOutput != nil {
+		return h.getObjectOutput.SSEKMSKeyId
+	}
+	return h.headObjectOutput.SSEKMSKeyId
+}
+
+func (h *HeadGetObjectOutputWrapper) getVersionID() *string {
+	if h.getObjectOutput != nil {
+		return h.getObjectOutput.VersionId
+	}
+	return h.headObjectOutput.VersionId
+}
+
 func (h *HeadGetObjectOutputWrapper) metaData() map[string]*string {
 	if h.getObjectOutput != nil {
 		return h.getObjectOutput.Metadata
@@ -57,6 +97,11 @@ func mergeHttpStandardHeaders(output *HeadGetObjectOutputWrapper) map[string]*st
 	res["Content-Encoding"] = output.getContentEncoding()
 	res["Content-Type"] = output.getContentType()
 	res["Content-Disposition"] = output.getContentDisposition()
+	res["Cache-Control"] = output.getCacheControl()
+	res["ETag"] = output.getETag()
+	res[HeadServerSideEncryption] = output.getServerSideEncryption()
+	res[HeadServerSideEncryptionKeyID] = output.getSSEKMSKeyID()
+	res[HeadVersionID] = output.getVersionID()
 
 	return res
 }
@@ -0,0 +1,180 @@
+package awos
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3_SignURLForPut(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {})
+
+	signed, err := client.SignURLForPut(guid, 60, SignWithContentType("image/png"), SignWithContentLength(1024))
+	assert.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "/test-bucket/"+guid, u.Path)
+	query := u.Query()
+	assert.Equal(t, "60", query.Get("X-Amz-Expires"))
+	assert.Equal(t, "content-length;content-type;host", query.Get("X-Amz-SignedHeaders"))
+	assert.NotEmpty(t, query.Get("X-Amz-Signature"))
+}
+
+func TestOSS_SignURLForPut(t *testing.T) {
+	client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {})
+
+	signed, err := client.SignURLForPut(guid, 60, SignWithContentType("image/png"))
+	assert.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	assert.NoError(t, err)
+	query := u.Query()
+	expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Unix()+60, expires, 5)
+	assert.Equal(t, "ak", query.Get("OSSAccessKeyId"))
+
+	h := hmac.New(sha1.New, []byte("sk"))
+	h.Write([]byte("PUT\n\nimage/png\n" + query.Get("Expires") + "\n/test-bucket/" + guid))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), query.Get("Signature"))
+}
+
+func decodePostPolicy(t *testing.T, policy string) map[string]interface{} {
+	data, err := base64.StdEncoding.DecodeString(policy)
+	assert.NoError(t, err)
+	doc := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	return doc
+}
+
+func TestS3_SignPostPolicy(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {})
+
+	policy, err := client.SignPostPolicy(guid, 3600, SignWithContentType("image/png"), SignWithMaxContentLength(1<<20))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(policy.URL, "/test-bucket"), policy.URL)
+	assert.Equal(t, guid, policy.Fields["key"])
+	assert.Equal(t, "image/png", policy.Fields["Content-Type"])
+	assert.Equal(t, "AWS4-HMAC-SHA256", policy.Fields["x-amz-algorithm"])
+
+	doc := decodePostPolicy(t, policy.Fields["policy"])
+	expiration, err := time.Parse("2006-01-02T15:04:05.000Z", doc["expiration"].(string))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), expiration.Unix(), 5)
+	conditions := doc["conditions"].([]interface{})
+	assert.Contains(t, conditions, map[string]interface{}{"bucket": "test-bucket"})
+	assert.Contains(t, conditions, []interface{}{"eq", "$key", guid})
+	assert.Contains(t, conditions, []interface{}{"eq", "$Content-Type", "image/png"})
+	assert.Contains(t, conditions, []interface{}{"content-length-range", float64(0), float64(1 << 20)})
+	assert.Contains(t, conditions, map[string]interface{}{"x-amz-credential": policy.Fields["x-amz-credential"]})
+
+	date := policy.Fields["x-amz-date"][:8]
+	assert.Equal(t, "ak/"+date+"/us-east-1/s3/aws4_request", policy.Fields["x-amz-credential"])
+	key := hmacSHA256([]byte("AWS4sk"), date)
+	key = hmacSHA256(key, "us-east-1")
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	assert.Equal(t, hex.EncodeToString(hmacSHA256(key, policy.Fields["policy"])), policy.Fields["x-amz-signature"])
+}
+
+func TestOSS_SignPostPolicy(t *testing.T) {
+	client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {})
+
+	policy, err := client.SignPostPolicy(guid, 3600, SignWithMaxContentLength(1<<20))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(policy.URL, "/test-bucket/"), policy.URL)
+	assert.Equal(t, guid, policy.Fields["key"])
+	assert.Equal(t, "ak", policy.Fields["OSSAccessKeyId"])
+
+	doc := decodePostPolicy(t, policy.Fields["policy"])
+	expiration, err := time.Parse("2006-01-02T15:04:05.000Z", doc["expiration"].(string))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), expiration.Unix(), 5)
+	conditions := doc["conditions"].([]interface{})
+	assert.Contains(t, conditions, []interface{}{"eq", "$key", guid})
+	assert.Contains(t, conditions, []interface{}{"content-length-range", float64(0), float64(1 << 20)})
+
+	h := hmac.New(sha1.New, []byte("sk"))
+	h.Write([]byte(policy.Fields["policy"]))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), policy.Fields["Signature"])
+}
+
+func TestSignURL_ResponseHeaders(t *testing.T) {
+	disposition := AttachmentContentDisposition("报告 2024.pdf")
+	assert.Equal(t, `attachment; filename="__ 2024.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%202024.pdf`, disposition)
+	options := []SignOptions{
+		SignWithResponseContentType("application/pdf"),
+		SignWithResponseContentDisposition(disposition),
+		SignWithResponseCacheControl("no-cache"),
+	}
+
+	t.Run(StorageTypeS3, func(t *testing.T) {
+		client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {})
+		signed, err := client.SignURL(guid, 60, options...)
+		assert.NoError(t, err)
+		u, err := url.Parse(signed)
+		assert.NoError(t, err)
+		query := u.Query()
+		assert.Equal(t, "application/pdf", query.Get("response-content-type"))
+		assert.Equal(t, disposition, query.Get("response-content-disposition"))
+		assert.Equal(t, "no-cache", query.Get("response-cache-control"))
+
+		// the parameters are a part of the signature
+		plain, err := client.SignURL(guid, 60, SignWithResponseContentType("application/pdf"))
+		assert.NoError(t, err)
+		u, err = url.Parse(plain)
+		assert.NoError(t, err)
+		assert.NotEqual(t, query.Get("X-Amz-Signature"), u.Query().Get("X-Amz-Signature"))
+	})
+
+	t.Run(StorageTypeOSS, func(t *testing.T) {
+		client := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {})
+		signed, err := client.SignURL(guid, 60, options...)
+		assert.NoError(t, err)
+		u, err := url.Parse(signed)
+		assert.NoError(t, err)
+		query := u.Query()
+		assert.Equal(t, disposition, query.Get("response-content-disposition"))
+
+		h := hmac.New(sha1.New, []byte("sk"))
+		h.Write([]byte("GET\n\n\n" + query.Get("Expires") + "\n/test-bucket/" + guid +
+			"?response-cache-control=no-cache&response-content-disposition=" + disposition + "&response-content-type=application/pdf"))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), query.Get("Signature"))
+	})
+
+	t.Run(StorageTypeAzure, func(t *testing.T) {
+		client := newTestAzure(t, func(w http.ResponseWriter, r *http.Request) {})
+		signed, err := client.SignURL("dir/a", 60, options...)
+		assert.NoError(t, err)
+		u, err := url.Parse(signed)
+		assert.NoError(t, err)
+		query := u.Query()
+		assert.Equal(t, "application/pdf", query.Get("rsct"))
+		assert.Equal(t, disposition, query.Get("rscd"))
+		assert.Equal(t, "no-cache", query.Get("rscc"))
+
+		key, _ := base64.StdEncoding.DecodeString(testAzureKey)
+		stringToSign := "r\n\n" + query.Get("se") + "\n/blob/devstoreaccount1/test-container/dir/a\n\n\n\n2020-10-02\nb\n\nno-cache\n" + disposition + "\n\n\napplication/pdf"
+		assert.Equal(t, base64.StdEncoding.EncodeToString(hmacSHA256(key, stringToSign)), query.Get("sig"))
+	})
+
+	// filenames must be escaped
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS, StorageTypeMemory} {
+		client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {})
+		_, err := client.SignURL(guid, 60, SignWithResponseContentDisposition(`attachment; filename="报告.pdf"`))
+		assert.Error(t, err, storageType)
+		_, err = client.SignURL(guid, 60, SignWithResponseCacheControl("no-cache\r\nX-Injected: 1"))
+		assert.Error(t, err, storageType)
+	}
+}
@@ -0,0 +1,173 @@
+package awos
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// signature versions of SignatureVersion
+const (
+	SignatureV2 = "v2"
+	SignatureV4 = "v4"
+)
+
+// s3V2SubResources are the query parameters in the canonicalized resource of sigv2
+var s3V2SubResources = map[string]bool{
+	"acl": true, "cors": true, "delete": true, "lifecycle": true, "location": true, "logging": true,
+	"notification": true, "partNumber": true, "policy": true, "requestPayment": true, "restore": true,
+	"tagging": true, "torrent": true, "uploadId": true, "uploads": true, "versionId": true,
+	"versioning": true, "versions": true, "website": true,
+	"response-cache-control": true, "response-content-disposition": true, "response-content-encoding": true,
+	"response-content-language": true, "response-content-type": true, "response-expires": true,
+}
+
+// s3V2SignRequestHandler signs the requests and the presigned urls of s3 with sigv2 at the time of now
+func s3V2SignRequestHandler(now func() time.Time) request.NamedHandler {
+	return request.NamedHandler{Name: "awos.s3V2SignRequestHandler", Fn: func(r *request.Request) {
+		signS3V2(r, now)
+	}}
+}
+
+// setS3SignatureVersion replaces the sigv4 signer of client if version is SignatureV2,
+// now is the signing time of sigv2
+func setS3SignatureVersion(client *s3.S3, version string, now func() time.Time) error {
+	switch strings.ToLower(version) {
+	case "", SignatureV4:
+		return nil
+	case SignatureV2:
+		if !client.Handlers.Sign.Swap(v4.SignRequestHandler.Name, s3V2SignRequestHandler(now)) {
+			return errors.New("awos: the sigv4 signer isn't found")
+		}
+		return nil
+	}
+	return fmt.Errorf("awos: unsupported signature version %q, only %s and %s", version, SignatureV2, SignatureV4)
+}
+
+func signS3V2(r *request.Request, now func() time.Time) {
+	if r.Config.Credentials == credentials.AnonymousCredentials {
+		return
+	}
+	creds, err := r.Config.Credentials.GetWithContext(r.Context())
+	if err != nil {
+		r.Error = err
+		return
+	}
+	req := r.HTTPRequest
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	var bucket string
+	if values, err := awsutil.ValuesAtPath(r.Params, "Bucket"); err == nil && len(values) > 0 {
+		if name, ok := values[0].(*string); ok {
+			bucket = aws.StringValue(name)
+		}
+	}
+
+	if r.ExpireTime > 0 {
+		expires := strconv.FormatInt(r.Time.Add(r.ExpireTime).Unix(), 10)
+		signature := s3V2Signature(creds.SecretAccessKey, s3V2StringToSign(req, bucket, expires))
+		query := req.URL.Query()
+		query.Set("AWSAccessKeyId", creds.AccessKeyID)
+		query.Set("Expires", expires)
+		query.Set("Signature", signature)
+		req.URL.RawQuery = query.Encode()
+		return
+	}
+	req.Header.Set("Date", now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Authorization", "AWS "+creds.AccessKeyID+":"+s3V2Signature(creds.SecretAccessKey, s3V2StringToSign(req, bucket, "")))
+}
+
+// s3V2Signature returns the base64 hmac-sha1 of stringToSign
+func s3V2Signature(secretAccessKey string, stringToSign string) string {
+	h := hmac.New(sha1.New, []byte(secretAccessKey))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// s3V2StringToSign returns the sigv2 string to sign of req, the date is the Date header of requests or
+// the Expires of presigned urls, and empty if the request has X-Amz-Date. bucket is the bucket of the request,
+// which is prepended to the path of virtual-hosted style urls.
+func s3V2StringToSign(req *http.Request, bucket string, date string) string {
+	if date == "" && req.Header.Get("X-Amz-Date") == "" {
+		date = req.Header.Get("Date")
+	}
+	var buf strings.Builder
+	buf.WriteString(req.Method + "\n")
+	buf.WriteString(req.Header.Get("Content-MD5") + "\n")
+	buf.WriteString(req.Header.Get("Content-Type") + "\n")
+	buf.WriteString(date + "\n")
+
+	// the x-amz- headers sorted by their lowercase names, with the values joined by commas
+	amzHeaders := make(map[string]string)
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+		trimmed := make([]string, 0, len(values))
+		for _, v := range values {
+			trimmed = append(trimmed, strings.TrimSpace(v))
+		}
+		if v, ok := amzHeaders[name]; ok {
+			trimmed = append([]string{v}, trimmed...)
+		}
+		amzHeaders[name] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(amzHeaders))
+	for name := range amzHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		buf.WriteString(name + ":" + amzHeaders[name] + "\n")
+	}
+
+	buf.WriteString(s3V2CanonicalizedResource(req.URL, bucket))
+	return buf.String()
+}
+
+// s3V2CanonicalizedResource returns the escaped path with the bucket, and the sub-resources sorted by name
+// with their values unescaped
+func s3V2CanonicalizedResource(u *url.URL, bucket string) string {
+	resource := u.EscapedPath()
+	if resource == "" {
+		resource = "/"
+	}
+	if bucket != "" && strings.HasPrefix(u.Host, bucket+".") {
+		resource = "/" + bucket + resource
+	}
+
+	query := u.Query()
+	subResources := make([]string, 0, len(query))
+	for name := range query {
+		if s3V2SubResources[name] {
+			subResources = append(subResources, name)
+		}
+	}
+	if len(subResources) == 0 {
+		return resource
+	}
+	sort.Strings(subResources)
+	for i, name := range subResources {
+		if value := query.Get(name); value != "" {
+			subResources[i] = name + "=" + value
+		}
+	}
+	return resource + "?" + strings.Join(subResources, "&")
+}
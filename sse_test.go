@@ -0,0 +1,180 @@
+package awos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sseHandler echoes the encryption headers of the last put in head responses
+func sseHandler(prefix string, headers http.Header) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			for k := range headers {
+				delete(headers, k)
+			}
+			for k, v := range r.Header {
+				if strings.HasPrefix(k, prefix+"Server-Side-Encryption") {
+					headers[k] = v
+				}
+			}
+		case http.MethodHead:
+			for k, v := range headers {
+				w.Header()[k] = v
+			}
+		}
+	}
+}
+
+func TestPutWithSSE(t *testing.T) {
+	tests := []struct {
+		storageType string
+		prefix      string
+		kms         string
+		keyIDHeader string
+	}{
+		{storageType: StorageTypeS3, prefix: "X-Amz-", kms: "aws:kms", keyIDHeader: "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"},
+		{storageType: StorageTypeOSS, prefix: "X-Oss-", kms: "KMS", keyIDHeader: "X-Oss-Server-Side-Encryption-Key-Id"},
+	}
+	attributes := []string{HeadServerSideEncryption, HeadServerSideEncryptionKeyID}
+	for _, tt := range tests {
+		t.Run(tt.storageType, func(t *testing.T) {
+			headers := make(http.Header)
+			client := newTestComponent(t, tt.storageType, sseHandler(tt.prefix, headers))
+
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSES3()))
+			assert.Equal(t, "AES256", headers.Get(tt.prefix+"Server-Side-Encryption"))
+			assert.Empty(t, headers.Get(tt.keyIDHeader))
+			meta, err := client.Head(guid, attributes)
+			assert.NoError(t, err)
+			assert.Equal(t, "AES256", meta[HeadServerSideEncryption])
+
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSEKMS("key-id")))
+			assert.Equal(t, tt.kms, headers.Get(tt.prefix+"Server-Side-Encryption"))
+			assert.Equal(t, "key-id", headers.Get(tt.keyIDHeader))
+			meta, err = client.Head(guid, attributes)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kms, meta[HeadServerSideEncryption])
+			assert.Equal(t, "key-id", meta[HeadServerSideEncryptionKeyID])
+
+			err = client.Put(guid, strings.NewReader(content), nil, PutWithSSES3(), PutWithSSEKMS("key-id"))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMemory_PutWithSSE(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSEKMS("key-id")))
+	meta, err := client.Head(guid, []string{HeadServerSideEncryption, HeadServerSideEncryptionKeyID})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:kms", meta[HeadServerSideEncryption])
+	assert.Equal(t, "key-id", meta[HeadServerSideEncryptionKeyID])
+
+	assert.Error(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSES3(), PutWithSSEKMS("")))
+}
+
+// newTestTLSComponent serves s3 with https, the aws sdk refuses to send SSE-C keys over http
+func newTestTLSComponent(t *testing.T, handler http.HandlerFunc) Component {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+	return DefaultContainer().Build(
+		WithStorageType(StorageTypeS3),
+		WithEndpoint(server.URL),
+		WithSSL(true),
+		WithBucket("test-bucket"),
+		WithAccessKeyID("ak"),
+		WithAccessKeySecret("sk"),
+		WithRegion("us-east-1"),
+		WithS3ForcePathStyle(true),
+	)
+}
+
+// sseCustomerHandler stores one object and checks the SSE-C key md5 like s3 does
+func sseCustomerHandler() http.HandlerFunc {
+	var mu sync.Mutex
+	var data []byte
+	var keyMD5 string
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		algorithm := r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm")
+		md5 := r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5")
+		switch r.Method {
+		case http.MethodPut:
+			if algorithm != "AES256" || r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Key") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ = ioutil.ReadAll(r.Body)
+			keyMD5 = md5
+		case http.MethodGet, http.MethodHead:
+			if md5 != keyMD5 {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code></Error>`))
+				return
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(data)
+			}
+		}
+	}
+}
+
+func testSSECustomerKey(t *testing.T, client Component) {
+	key := bytes.Repeat([]byte("k"), SSECustomerKeySize)
+	wrongKey := bytes.Repeat([]byte("w"), SSECustomerKeySize)
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSECustomerKey(key)))
+
+	res, err := client.Get(guid, GetWithSSECustomerKey(key))
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	meta, err := client.Head(guid, []string{"Content-Length"}, GetWithSSECustomerKey(key))
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(len(content)), meta["Content-Length"])
+
+	_, err = client.Get(guid, GetWithSSECustomerKey(wrongKey))
+	assert.Error(t, err)
+	_, err = client.Get(guid)
+	assert.Error(t, err)
+	_, err = client.Head(guid, nil, GetWithSSECustomerKey(wrongKey))
+	assert.Error(t, err)
+
+	assert.Error(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSECustomerKey([]byte("short"))))
+	assert.Error(t, client.Put(guid, strings.NewReader(content), nil, PutWithSSECustomerKey(key), PutWithSSES3()))
+}
+
+func TestS3_PutWithSSECustomerKey(t *testing.T) {
+	testSSECustomerKey(t, newTestTLSComponent(t, sseCustomerHandler()))
+}
+
+func TestMemory_PutWithSSECustomerKey(t *testing.T) {
+	testSSECustomerKey(t, newTestMemory())
+}
+
+func TestOSS_PutWithSSECustomerKey(t *testing.T) {
+	client := newTestComponent(t, StorageTypeOSS, sseCustomerHandler())
+	key := bytes.Repeat([]byte("k"), SSECustomerKeySize)
+	err := client.Put(guid, strings.NewReader(content), nil, PutWithSSECustomerKey(key))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+	_, err = client.Get(guid, GetWithSSECustomerKey(key))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+}
@@ -0,0 +1,58 @@
+package awos
+
+import "fmt"
+
+// backend neutral storage classes of PutWithStorageClass
+const (
+	StorageClassStandard = "Standard"
+	// StorageClassIA is infrequent access
+	StorageClassIA          = "IA"
+	StorageClassArchive     = "Archive"
+	StorageClassColdArchive = "ColdArchive"
+)
+
+var (
+	s3StorageClasses = map[string]string{
+		StorageClassStandard:    "STANDARD",
+		StorageClassIA:          "STANDARD_IA",
+		StorageClassArchive:     "GLACIER",
+		StorageClassColdArchive: "DEEP_ARCHIVE",
+	}
+	gcsStorageClasses = map[string]string{
+		StorageClassStandard:    "STANDARD",
+		StorageClassIA:          "NEARLINE",
+		StorageClassArchive:     "COLDLINE",
+		StorageClassColdArchive: "ARCHIVE",
+	}
+	cosStorageClasses = map[string]string{
+		StorageClassStandard:    "STANDARD",
+		StorageClassIA:          "STANDARD_IA",
+		StorageClassArchive:     "ARCHIVE",
+		StorageClassColdArchive: "DEEP_ARCHIVE",
+	}
+	// azure has no cold archive, archived blobs are rehydrated by RestoreObject
+	azureStorageClasses = map[string]string{
+		StorageClassStandard: "Hot",
+		StorageClassIA:       "Cool",
+		StorageClassArchive:  "Archive",
+	}
+	ossStorageClasses = map[string]string{
+		StorageClassStandard:    "Standard",
+		StorageClassIA:          "IA",
+		StorageClassArchive:     "Archive",
+		StorageClassColdArchive: "ColdArchive",
+	}
+)
+
+// backendStorageClass maps the storage class of putOptions to the one of the backend,
+// it returns "" if no storage class is set.
+func backendStorageClass(classes map[string]string, putOptions *putOptions) (string, error) {
+	if putOptions.storageClass == "" {
+		return "", nil
+	}
+	class, ok := classes[putOptions.storageClass]
+	if !ok {
+		return "", fmt.Errorf("storage class %q isn't supported: %w", putOptions.storageClass, ErrUnsupported)
+	}
+	return class, nil
+}
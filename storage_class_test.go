@@ -0,0 +1,96 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// storageClassHandler records the storage class header of puts and multipart uploads
+func storageClassHandler(header string, storageClass *string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := r.URL.Query()["uploads"]; ok {
+			*storageClass = r.Header.Get(header)
+			_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`))
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost:
+			_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+		case r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "":
+			*storageClass = r.Header.Get(header)
+		}
+	}
+}
+
+func TestPutWithStorageClass(t *testing.T) {
+	tests := []struct {
+		storageType string
+		header      string
+		expects     map[string]string
+	}{
+		{storageType: StorageTypeS3, header: "X-Amz-Storage-Class", expects: map[string]string{
+			StorageClassStandard:    "STANDARD",
+			StorageClassIA:          "STANDARD_IA",
+			StorageClassArchive:     "GLACIER",
+			StorageClassColdArchive: "DEEP_ARCHIVE",
+		}},
+		{storageType: StorageTypeOSS, header: "X-Oss-Storage-Class", expects: map[string]string{
+			StorageClassStandard:    "Standard",
+			StorageClassIA:          "IA",
+			StorageClassArchive:     "Archive",
+			StorageClassColdArchive: "ColdArchive",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.storageType, func(t *testing.T) {
+			var storageClass string
+			client := newTestComponent(t, tt.storageType, storageClassHandler(tt.header, &storageClass))
+
+			for class, expect := range tt.expects {
+				assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithStorageClass(class)))
+				assert.Equal(t, expect, storageClass, class)
+			}
+			assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+			assert.Empty(t, storageClass)
+
+			assert.NoError(t, client.MultipartUpload(guid, strings.NewReader(content), nil, PutWithStorageClass(StorageClassIA)))
+			assert.Equal(t, tt.expects[StorageClassIA], storageClass)
+
+			err := client.Put(guid, strings.NewReader(content), nil, PutWithStorageClass("REDUCED_REDUNDANCY"))
+			assert.True(t, errors.Is(err, ErrUnsupported), err)
+		})
+	}
+}
+
+func TestGCS_PutWithStorageClass(t *testing.T) {
+	var storageClass string
+	client := newTestGCS(t, nil, storageClassHandler("X-Amz-Storage-Class", &storageClass))
+
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil, PutWithStorageClass(StorageClassIA)))
+	assert.Equal(t, "NEARLINE", storageClass)
+	// WithContext keeps the gcs storage classes
+	assert.NoError(t, client.WithContext(context.Background()).Put(guid, strings.NewReader(content), nil, PutWithStorageClass(StorageClassColdArchive)))
+	assert.Equal(t, "ARCHIVE", storageClass)
+}
+
+func TestMemory_PutWithStorageClass(t *testing.T) {
+	client := newTestMemory()
+
+	assert.NoError(t, client.Put("storage-class", bytes.NewReader(nil), nil, PutWithStorageClass(StorageClassArchive)))
+	meta, err := client.HeadObject("storage-class")
+	assert.NoError(t, err)
+	assert.Equal(t, StorageClassArchive, meta.StorageClass)
+
+	err = client.Put("storage-class", bytes.NewReader(nil), nil, PutWithStorageClass("GLACIER"))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+}
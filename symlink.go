@@ -0,0 +1,33 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+)
+
+// symlinker is implemented by the backends supporting PutSymlink and GetSymlink
+type symlinker interface {
+	putSymlink(symlinkKey string, targetKey string) error
+	getSymlink(symlinkKey string) (string, error)
+}
+
+// PutSymlink creates or replaces the symlink object symlinkKey pointing to targetKey, which doesn't have to exist.
+// Get, GetAsReader, Head and the other reads of the symlink follow it to the target transparently.
+// Only oss supports it, both keys must be in the same bucket if the component has shards.
+func PutSymlink(ctx context.Context, c Component, symlinkKey string, targetKey string) error {
+	s, ok := c.WithContext(ctx).(symlinker)
+	if !ok {
+		return fmt.Errorf("PutSymlink: %w", ErrUnsupported)
+	}
+	return s.putSymlink(symlinkKey, targetKey)
+}
+
+// GetSymlink returns the target key of the symlink object symlinkKey, ErrObjectNotFound if it doesn't exist.
+// Only oss supports it.
+func GetSymlink(ctx context.Context, c Component, symlinkKey string) (string, error) {
+	s, ok := c.WithContext(ctx).(symlinker)
+	if !ok {
+		return "", fmt.Errorf("GetSymlink: %w", ErrUnsupported)
+	}
+	return s.getSymlink(symlinkKey)
+}
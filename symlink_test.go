@@ -0,0 +1,92 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// symlinkServer is an oss bucket with symlinks, the reads of a symlink get its target like oss does
+type symlinkServer struct {
+	mu       sync.Mutex
+	objects  map[string]string
+	symlinks map[string]string
+}
+
+func (s *symlinkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/test-bucket/")
+	_, symlink := r.URL.Query()["symlink"]
+	switch {
+	case r.Method == http.MethodPut && symlink:
+		target, _ := url.QueryUnescape(r.Header.Get("X-Oss-Symlink-Target"))
+		s.symlinks[key] = target
+	case r.Method == http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		s.objects[key] = string(data)
+	case r.Method == http.MethodGet && symlink:
+		target, ok := s.symlinks[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`))
+			return
+		}
+		w.Header().Set("X-Oss-Symlink-Target", url.QueryEscape(target))
+	case r.Method == http.MethodGet:
+		if target, ok := s.symlinks[key]; ok {
+			key = target
+		}
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	server := &symlinkServer{objects: make(map[string]string), symlinks: make(map[string]string)}
+	client := newTestComponent(t, StorageTypeOSS, server.ServeHTTP)
+	ctx := context.Background()
+
+	assert.NoError(t, client.Put("releases/v1.2.0", strings.NewReader(content), nil))
+	assert.NoError(t, PutSymlink(ctx, client, "releases/latest", "releases/v1.2.0"))
+	target, err := GetSymlink(ctx, client, "releases/latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "releases/v1.2.0", target)
+
+	// reading the symlink reads the target
+	res, err := client.Get("releases/latest")
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	// the target is escaped in the header
+	assert.NoError(t, PutSymlink(ctx, client, "releases/latest", "releases/v 1.3.0+rc"))
+	target, err = GetSymlink(ctx, client, "releases/latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "releases/v 1.3.0+rc", target)
+
+	_, err = GetSymlink(ctx, client, "releases/none")
+	assert.True(t, errors.Is(err, ErrObjectNotFound), "%v", err)
+}
+
+func TestSymlink_Unsupported(t *testing.T) {
+	client := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {})
+	err := PutSymlink(context.Background(), client, "latest", "v1")
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+	_, err = GetSymlink(context.Background(), client, "latest")
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+
+	_, err = GetSymlink(context.Background(), newTestMemory(), "latest")
+	assert.True(t, errors.Is(err, ErrUnsupported), "%v", err)
+}
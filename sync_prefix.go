@@ -0,0 +1,185 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSyncConcurrency is the default number of objects SyncPrefix copies concurrently
+const DefaultSyncConcurrency = 8
+
+type SyncOptions func(options *syncOptions)
+
+type syncOptions struct {
+	concurrency int
+	delete      bool
+	destBucket  string
+	listOptions []ListOptions
+}
+
+func DefaultSyncOptions() *syncOptions {
+	return &syncOptions{
+		concurrency: DefaultSyncConcurrency,
+	}
+}
+
+// SyncWithConcurrency sets the number of objects copied concurrently
+func SyncWithConcurrency(concurrency int) SyncOptions {
+	return func(options *syncOptions) {
+		if concurrency > 0 {
+			options.concurrency = concurrency
+		}
+	}
+}
+
+// SyncWithDelete deletes the destination objects which don't exist in the source,
+// they are kept if listing the source fails
+func SyncWithDelete(del bool) SyncOptions {
+	return func(options *syncOptions) {
+		options.delete = del
+	}
+}
+
+// SyncWithDestBucket copies on the server side by Copy with CopyWithDestBucket(bucket), bucket is the bucket
+// of the destination component, which must be of the same account and endpoint as the source.
+// Without it, the objects are streamed through the client with their user metadata and content type.
+func SyncWithDestBucket(bucket string) SyncOptions {
+	return func(options *syncOptions) {
+		options.destBucket = bucket
+	}
+}
+
+// SyncWithListOptions applies options to the listings of the source and the destination, such as ListWithPageSize
+func SyncWithListOptions(options ...ListOptions) SyncOptions {
+	return func(syncOpts *syncOptions) {
+		syncOpts.listOptions = append(syncOpts.listOptions, options...)
+	}
+}
+
+// SyncStats is the result of SyncPrefix
+type SyncStats struct {
+	// Copied is the number of new or changed objects copied to the destination
+	Copied int
+	// Bytes is the total size of the copied objects
+	Bytes int64
+	// Skipped is the number of objects which are unchanged in the destination
+	Skipped int
+	// Deleted is the number of destination objects deleted by SyncWithDelete
+	Deleted int
+	// Errors are the failed copies by source key and the failed deletes by destination key
+	Errors map[string]error
+}
+
+type syncObject struct {
+	src    ObjectMeta
+	dstKey string
+}
+
+// SyncPrefix copies the objects of src with srcPrefix to dst, with dstPrefix instead of srcPrefix,
+// e.g. to mirror a bucket to a backup bucket. The destination is listed first, and only the objects which
+// are missing in it or whose size or etag differ are copied. Note that the etags of multipart uploads depend
+// on the part size, so such objects may be copied again. A failed object doesn't abort the others,
+// the returned error is non-nil if any object failed or a listing failed, which stops syncing.
+func SyncPrefix(ctx context.Context, src Component, srcPrefix string, dst Component, dstPrefix string, options ...SyncOptions) (*SyncStats, error) {
+	syncOpts := DefaultSyncOptions()
+	for _, opt := range options {
+		opt(syncOpts)
+	}
+	src = src.WithContext(ctx)
+	dst = dst.WithContext(ctx)
+	stats := &SyncStats{Errors: make(map[string]error)}
+
+	// the destination objects by the key relative to dstPrefix, the ones left are extraneous
+	existing := make(map[string]ObjectMeta)
+	if err := dst.WalkObjects(dstPrefix, dstPrefix, func(obj ObjectMeta) error {
+		existing[strings.TrimPrefix(obj.Key, dstPrefix)] = obj
+		return nil
+	}, syncOpts.listOptions...); err != nil {
+		return stats, err
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		bytes  int64
+		copied int64
+	)
+	pending := make(chan syncObject)
+	for i := 0; i < syncOpts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range pending {
+				if err := syncCopy(ctx, src, dst, obj, syncOpts.destBucket); err != nil {
+					mu.Lock()
+					stats.Errors[obj.src.Key] = err
+					mu.Unlock()
+					continue
+				}
+				atomic.AddInt64(&copied, 1)
+				atomic.AddInt64(&bytes, obj.src.Size)
+			}
+		}()
+	}
+
+	total := 0
+	walkErr := src.WalkObjects(srcPrefix, srcPrefix, func(obj ObjectMeta) error {
+		rel := strings.TrimPrefix(obj.Key, srcPrefix)
+		dstObj, ok := existing[rel]
+		delete(existing, rel)
+		total++
+		if ok && dstObj.Size == obj.Size && dstObj.ETag == obj.ETag {
+			stats.Skipped++
+			return nil
+		}
+		select {
+		case pending <- syncObject{src: obj, dstKey: dstPrefix + rel}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, syncOpts.listOptions...)
+	close(pending)
+	wg.Wait()
+
+	stats.Copied = int(copied)
+	stats.Bytes = bytes
+	if walkErr != nil {
+		return stats, walkErr
+	}
+
+	if syncOpts.delete && len(existing) > 0 {
+		keys := make([]string, 0, len(existing))
+		for rel := range existing {
+			keys = append(keys, dstPrefix+rel)
+		}
+		total += len(keys)
+		failed, err := dst.DelMulti(keys)
+		// the error usually only summarizes the failed keys
+		if err != nil && len(failed) == 0 {
+			failed = make(map[string]error, len(keys))
+			for _, key := range keys {
+				failed[key] = err
+			}
+		}
+		stats.Deleted = len(keys) - len(failed)
+		for key, err := range failed {
+			stats.Errors[key] = err
+		}
+	}
+	if len(stats.Errors) > 0 {
+		return stats, fmt.Errorf("awos: failed to sync %d of %d objects", len(stats.Errors), total)
+	}
+	return stats, nil
+}
+
+// syncCopy copies obj on the server side if destBucket is set, otherwise it streams the object from src to dst by Transfer
+func syncCopy(ctx context.Context, src Component, dst Component, obj syncObject, destBucket string) error {
+	if destBucket != "" {
+		return src.Copy(obj.src.Key, obj.dstKey, CopyWithDestBucket(destBucket))
+	}
+	return Transfer(ctx, src, obj.src.Key, dst, obj.dstKey)
+}
@@ -0,0 +1,129 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPrefix(t *testing.T) {
+	src, dst := newTestMemory(), newTestMemory()
+	ctx := context.Background()
+	for _, key := range []string{"src/a", "src/b", "src/dir/c", "other/d"} {
+		assert.NoError(t, src.Put(key, strings.NewReader(content+key), map[string]string{"name": key}, PutWithContentType("text/plain")))
+	}
+
+	stats, err := SyncPrefix(ctx, src, "src/", dst, "backup/", SyncWithConcurrency(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Copied)
+	assert.Equal(t, 0, stats.Skipped)
+	assert.Equal(t, int64(3*len(content)+len("src/a")+len("src/b")+len("src/dir/c")), stats.Bytes)
+	meta, err := dst.HeadObject("backup/dir/c")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "src/dir/c", meta.UserMeta["name"])
+		assert.Equal(t, "text/plain", meta.ContentType)
+	}
+	data, err := dst.GetBytes("backup/a")
+	assert.NoError(t, err)
+	assert.Equal(t, content+"src/a", string(data))
+	_, err = dst.HeadObject("backup/d")
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	// only the new and changed objects are copied
+	assert.NoError(t, src.Put("src/a", strings.NewReader("changed"), nil))
+	assert.NoError(t, src.Put("src/e", strings.NewReader(content), nil))
+	assert.NoError(t, dst.Put("backup/extra", strings.NewReader(content), nil))
+	stats, err = SyncPrefix(ctx, src, "src/", dst, "backup/")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Copied)
+	assert.Equal(t, 2, stats.Skipped)
+	assert.Equal(t, 0, stats.Deleted)
+	data, err = dst.GetBytes("backup/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", string(data))
+	_, err = dst.HeadObject("backup/extra")
+	assert.NoError(t, err)
+
+	// the extraneous objects are deleted with SyncWithDelete
+	stats, err = SyncPrefix(ctx, src, "src/", dst, "backup/", SyncWithDelete(true))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Copied)
+	assert.Equal(t, 4, stats.Skipped)
+	assert.Equal(t, 1, stats.Deleted)
+	_, err = dst.HeadObject("backup/extra")
+	assert.Equal(t, ErrObjectNotFound, err)
+	assert.Empty(t, stats.Errors)
+}
+
+func TestSyncPrefix_ContentEncoding(t *testing.T) {
+	src := newTestMemory()
+	assert.NoError(t, src.Put("src/a", strings.NewReader(largeContent), nil, PutWithCompression(CompressionGzip)))
+	server := newBucketServer("X-Amz-")
+	dst := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		// the destination is empty
+		if r.URL.Path == "/test-bucket" || r.URL.Path == "/test-bucket/" {
+			_, _ = w.Write([]byte(`<ListBucketResult><IsTruncated>false</IsTruncated></ListBucketResult>`))
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+
+	stats, err := SyncPrefix(context.Background(), src, "src/", dst, "backup/")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Copied)
+	assert.Empty(t, stats.Errors)
+	stored, err := src.GetBytes("src/a")
+	assert.NoError(t, err)
+	obj := server.object("/test-bucket/backup/a")
+	if assert.NotNil(t, obj) {
+		assert.Equal(t, CompressionGzip, obj.header.Get("Content-Encoding"))
+		assert.Equal(t, stored, obj.data)
+	}
+	// the http transport inflates the gzip object
+	res, err := dst.Get("backup/a")
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, res)
+}
+
+func TestSyncPrefix_Error(t *testing.T) {
+	src := NewFakeClient("src")
+	dst := newTestMemory()
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, src.Put(key, strings.NewReader(content), nil))
+	}
+	assert.NoError(t, dst.Put("stale", strings.NewReader(content), nil))
+
+	src.FailNext("GetAsReader", "b", 1, errors.New("get failed"))
+	stats, err := SyncPrefix(ctx, src, "", dst, "", SyncWithDelete(true))
+	assert.EqualError(t, err, "awos: failed to sync 1 of 4 objects")
+	assert.Equal(t, 2, stats.Copied)
+	assert.Equal(t, 1, stats.Deleted)
+	if assert.Len(t, stats.Errors, 1) {
+		assert.EqualError(t, stats.Errors["b"], "get failed")
+	}
+
+	// the failed object is copied by the next sync
+	stats, err = SyncPrefix(ctx, src, "", dst, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Copied)
+	assert.Equal(t, 2, stats.Skipped)
+
+	// the destination is kept if listing the source fails
+	assert.NoError(t, dst.Put("stale", strings.NewReader(content), nil))
+	src.FailNext("WalkObjects", "", 1, errors.New("list failed"))
+	_, err = SyncPrefix(ctx, src, "", dst, "", SyncWithDelete(true))
+	assert.EqualError(t, err, "list failed")
+	_, err = dst.HeadObject("stale")
+	assert.NoError(t, err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.NoError(t, src.Put("d", strings.NewReader(content), nil))
+	_, err = SyncPrefix(cancelled, src, "", dst, "")
+	assert.Error(t, err)
+}
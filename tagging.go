@@ -0,0 +1,53 @@
+package awos
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+const (
+	// MaxObjectTags is the max number of tags of an object, same for s3 and oss
+	MaxObjectTags = 10
+	// MaxTagKeyLength is the max length of a tag key
+	MaxTagKeyLength = 128
+	// MaxTagValueLength is the max length of a tag value
+	MaxTagValueLength = 256
+)
+
+func validateTags(tags map[string]string) error {
+	if len(tags) > MaxObjectTags {
+		return fmt.Errorf("too many tags: %d, the limit is %d", len(tags), MaxObjectTags)
+	}
+	for k, v := range tags {
+		if k == "" {
+			return fmt.Errorf("tag key can't be empty")
+		}
+		if len(k) > MaxTagKeyLength {
+			return fmt.Errorf("tag key %q is too long, the limit is %d", k, MaxTagKeyLength)
+		}
+		if len(v) > MaxTagValueLength {
+			return fmt.Errorf("value of tag %q is too long, the limit is %d", k, MaxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// sortedTagKeys makes the tagging requests deterministic
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encodeTagging encodes tags as the query string of the x-amz-tagging and x-ms-tags headers
+func encodeTagging(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
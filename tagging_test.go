@@ -0,0 +1,64 @@
+package awos
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectTagging(t *testing.T) {
+	tags := map[string]string{"env": "prod", "owner": "awos", "ttl": "7d"}
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			var (
+				mu      sync.Mutex
+				tagging []byte
+			)
+			client := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if _, ok := r.URL.Query()["tagging"]; !ok {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				switch r.Method {
+				case http.MethodPut:
+					tagging, _ = ioutil.ReadAll(r.Body)
+				case http.MethodGet:
+					_, _ = w.Write(tagging)
+				}
+			})
+
+			assert.NoError(t, client.PutObjectTagging(guid, tags))
+			// the s3 sdk doesn't keep the order of Key and Value
+			assert.Contains(t, string(tagging), "<Key>env</Key>")
+			assert.Contains(t, string(tagging), "<Value>prod</Value>")
+
+			res, err := client.GetObjectTagging(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, tags, res)
+		})
+	}
+}
+
+func TestObjectTagging_Limits(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxObjectTags; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	assert.Error(t, client.PutObjectTagging(guid, tooMany))
+	assert.Error(t, client.PutObjectTagging(guid, map[string]string{strings.Repeat("k", MaxTagKeyLength+1): "v"}))
+	assert.Error(t, client.PutObjectTagging(guid, map[string]string{"k": strings.Repeat("v", MaxTagValueLength+1)}))
+
+	assert.NoError(t, client.PutObjectTagging(guid, map[string]string{"env": "prod"}))
+	res, err := client.GetObjectTagging(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, res)
+}
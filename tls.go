@@ -0,0 +1,55 @@
+package awos
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gotomicro/ego/core/elog"
+)
+
+// tlsVersions are the versions of TLSMinVersion
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// buildTLSConfig returns the tls config of the transports, the one of WithTLSConfig with TLSMinVersion,
+// TLSCAFile and TLSInsecureSkipVerify applied, or nil for the default of net/http if none is set
+func (c *config) buildTLSConfig(logger *elog.Component) (*tls.Config, error) {
+	if c.tlsConfig == nil && c.TLSMinVersion == "" && c.TLSCAFile == "" && !c.TLSInsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{}
+	if c.tlsConfig != nil {
+		tlsConfig = c.tlsConfig.Clone()
+	}
+	if c.TLSMinVersion != "" {
+		version, ok := tlsVersions[c.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("awos: invalid TLSMinVersion %q, expected one of 1.0, 1.1, 1.2 and 1.3", c.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if c.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("awos: read TLSCAFile: %w", err)
+		}
+		// the private CAs are trusted in addition to the system ones, instead of the RootCAs of WithTLSConfig
+		if tlsConfig.RootCAs, err = x509.SystemCertPool(); err != nil || tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("awos: no certificates found in TLSCAFile %s", c.TLSCAFile)
+		}
+	}
+	if c.TLSInsecureSkipVerify {
+		logger.Warn("tls certificate verification is disabled by TLSInsecureSkipVerify", elog.FieldAddr(c.Endpoint))
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,103 @@
+package awos
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	dir, err := ioutil.TempDir("", "awos-tls")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	c := DefaultContainer()
+	WithTLSCAFile(caFile)(c)
+	WithTLSMinVersion("1.2")(c)
+	tlsConfig, err := c.config.buildTLSConfig(elog.DefaultLogger)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	c.config.tls = tlsConfig
+	for _, tp := range []*http.Transport{newBaseTransport(c.config).(*http.Transport), newOSSTransport(c.config)} {
+		assert.Same(t, tlsConfig, tp.TLSClientConfig)
+		// the pool has the system CAs and the one of the file
+		_, err := server.Certificate().Verify(x509.VerifyOptions{Roots: tp.TLSClientConfig.RootCAs})
+		assert.NoError(t, err)
+	}
+
+	for _, storageType := range []string{StorageTypeS3, StorageTypeOSS} {
+		t.Run(storageType, func(t *testing.T) {
+			// the certificate of the server isn't trusted by default
+			_, err := newTestEndpointComponent(storageType, server.URL).Get(guid)
+			assert.Error(t, err)
+
+			res, err := newTestEndpointComponent(storageType, server.URL, WithTLSCAFile(caFile)).Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+
+			res, err = newTestEndpointComponent(storageType, server.URL, WithTLSInsecureSkipVerify(true)).Get(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+		})
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	pool := x509.NewCertPool()
+	c := DefaultContainer()
+	WithTLSConfig(&tls.Config{RootCAs: pool, ServerName: "s3.internal"})(c)
+	WithTLSInsecureSkipVerify(true)(c)
+	tlsConfig, err := c.config.buildTLSConfig(elog.DefaultLogger)
+	assert.NoError(t, err)
+	assert.Same(t, pool, tlsConfig.RootCAs)
+	assert.Equal(t, "s3.internal", tlsConfig.ServerName)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	// the config of WithTLSConfig isn't modified
+	assert.False(t, c.config.tlsConfig.InsecureSkipVerify)
+
+	// no tls config is set by default
+	tlsConfig, err = DefaultConfig().buildTLSConfig(elog.DefaultLogger)
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	// TLSCAFile replaces the RootCAs of the tls config
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	c = DefaultContainer()
+	WithTLSConfig(&tls.Config{RootCAs: pool})(c)
+	WithTLSCAFile(caFile)(c)
+	tlsConfig, err = c.config.buildTLSConfig(elog.DefaultLogger)
+	assert.NoError(t, err)
+	assert.NotSame(t, pool, tlsConfig.RootCAs)
+	_, err = server.Certificate().Verify(x509.VerifyOptions{Roots: tlsConfig.RootCAs})
+	assert.NoError(t, err)
+	// the pool of WithTLSConfig isn't modified
+	_, err = server.Certificate().Verify(x509.VerifyOptions{Roots: pool})
+	assert.Error(t, err)
+
+	c = DefaultContainer()
+	WithTLSMinVersion("1.4")(c)
+	_, err = c.config.buildTLSConfig(elog.DefaultLogger)
+	assert.Error(t, err)
+	c = DefaultContainer()
+	WithTLSCAFile("missing.pem")(c)
+	_, err = c.config.buildTLSConfig(elog.DefaultLogger)
+	assert.Error(t, err)
+}
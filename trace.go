@@ -0,0 +1,512 @@
+package awos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// attributes of the operation spans
+const (
+	traceAttrBucket    = attribute.Key("awos.bucket")
+	traceAttrKey       = attribute.Key("awos.key")
+	traceAttrDestKey   = attribute.Key("awos.dest_key")
+	traceAttrOperation = attribute.Key("awos.operation")
+	traceAttrSize      = attribute.Key("awos.size")
+	traceAttrKeyCount  = attribute.Key("awos.key_count")
+)
+
+// tracedComponent starts a span named awos.<Operation> for each operation of c,
+// the spans of the http requests are children of it for s3, gcs, cos and azure.
+type tracedComponent struct {
+	c      Component
+	bucket string
+	ctx    context.Context
+	tracer trace.Tracer
+}
+
+func newTracedComponent(bucket string, c Component, tp trace.TracerProvider) Component {
+	return &tracedComponent{
+		c:      c,
+		bucket: bucket,
+		ctx:    context.Background(),
+		tracer: tp.Tracer(PackageName),
+	}
+}
+
+// start starts the span of operation on key, which is empty for batch operations,
+// the returned component sends requests with the context of the span
+func (t *tracedComponent) start(operation string, key string, attrs ...attribute.KeyValue) (Component, trace.Span) {
+	attrs = append(attrs, traceAttrBucket.String(t.bucket), traceAttrOperation.String(operation))
+	if key != "" {
+		attrs = append(attrs, traceAttrKey.String(key))
+	}
+	ctx, span := t.tracer.Start(t.ctx, "awos."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return t.c.WithContext(ctx), span
+}
+
+// endSpan records err on span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// seekerSize returns the number of bytes left in reader, the offset of reader is kept
+func seekerSize(reader io.Seeker) (int64, bool) {
+	cur, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	if _, err = reader.Seek(cur, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return size - cur, true
+}
+
+func (t *tracedComponent) WithContext(ctx context.Context) Component {
+	return &tracedComponent{
+		c:      t.c,
+		bucket: t.bucket,
+		ctx:    ctx,
+		tracer: t.tracer,
+	}
+}
+
+func (t *tracedComponent) Get(key string, options ...GetOptions) (string, error) {
+	c, span := t.start("Get", key)
+	res, err := c.Get(key, options...)
+	span.SetAttributes(traceAttrSize.Int(len(res)))
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) GetBytes(key string, options ...GetOptions) ([]byte, error) {
+	c, span := t.start("GetBytes", key)
+	res, err := c.GetBytes(key, options...)
+	span.SetAttributes(traceAttrSize.Int(len(res)))
+	endSpan(span, err)
+	return res, err
+}
+
+// GetAsReader only covers the request, not reading the body
+func (t *tracedComponent) GetAsReader(key string, options ...GetOptions) (io.ReadCloser, error) {
+	c, span := t.start("GetAsReader", key)
+	res, err := c.GetAsReader(key, options...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) GetMulti(keys []string, options ...GetMultiOptions) (map[string][]byte, map[string]error) {
+	c, span := t.start("GetMulti", "", traceAttrKeyCount.Int(len(keys)))
+	res, failed := c.GetMulti(keys, options...)
+	var size int
+	for _, data := range res {
+		size += len(data)
+	}
+	span.SetAttributes(traceAttrSize.Int(size))
+	if len(failed) > 0 {
+		span.SetStatus(codes.Error, "failed to get some keys")
+	}
+	span.End()
+	return res, failed
+}
+
+func (t *tracedComponent) RestoreObject(key string, options ...RestoreOptions) error {
+	c, span := t.start("RestoreObject", key)
+	err := c.RestoreObject(key, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) GetVersion(key string, versionID string, options ...GetOptions) (string, error) {
+	c, span := t.start("GetVersion", key)
+	res, err := c.GetVersion(key, versionID, options...)
+	span.SetAttributes(traceAttrSize.Int(len(res)))
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) GetWithMeta(key string, attributes []string, options ...GetOptions) (io.ReadCloser, map[string]string, error) {
+	c, span := t.start("GetWithMeta", key)
+	res, meta, err := c.GetWithMeta(key, attributes, options...)
+	endSpan(span, err)
+	return res, meta, err
+}
+
+func (t *tracedComponent) Put(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	c, span := t.start("Put", key)
+	if size, ok := seekerSize(reader); ok {
+		span.SetAttributes(traceAttrSize.Int64(size))
+	}
+	err := c.Put(key, reader, meta, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) MultipartUpload(key string, reader io.Reader, meta map[string]string, options ...PutOptions) error {
+	c, span := t.start("MultipartUpload", key)
+	err := c.MultipartUpload(key, reader, meta, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) Append(key string, reader io.Reader, position int64, options ...PutOptions) (int64, error) {
+	c, span := t.start("Append", key)
+	next, err := c.Append(key, reader, position, options...)
+	if err == nil {
+		span.SetAttributes(traceAttrSize.Int64(next))
+	}
+	endSpan(span, err)
+	return next, err
+}
+
+func (t *tracedComponent) Copy(srcKey, dstKey string, options ...CopyOptions) error {
+	c, span := t.start("Copy", srcKey, traceAttrDestKey.String(dstKey))
+	err := c.Copy(srcKey, dstKey, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) Move(srcKey, dstKey string, options ...CopyOptions) error {
+	c, span := t.start("Move", srcKey, traceAttrDestKey.String(dstKey))
+	err := c.Move(srcKey, dstKey, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) Del(key string) error {
+	c, span := t.start("Del", key)
+	err := c.Del(key)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) DelVersion(key string, versionID string, options ...DelOptions) error {
+	c, span := t.start("DelVersion", key)
+	err := c.DelVersion(key, versionID, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) DelMulti(keys []string) (map[string]error, error) {
+	c, span := t.start("DelMulti", "", traceAttrKeyCount.Int(len(keys)))
+	failed, err := c.DelMulti(keys)
+	endSpan(span, err)
+	return failed, err
+}
+
+func (t *tracedComponent) Head(key string, meta []string, options ...GetOptions) (map[string]string, error) {
+	c, span := t.start("Head", key)
+	res, err := c.Head(key, meta, options...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) HeadObject(key string, options ...GetOptions) (*ObjectMeta, error) {
+	c, span := t.start("HeadObject", key)
+	res, err := c.HeadObject(key, options...)
+	if err == nil {
+		span.SetAttributes(traceAttrSize.Int64(res.Size))
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) ListObject(key string, prefix string, marker string, maxKeys int, delimiter string) ([]string, error) {
+	c, span := t.start("ListObject", key)
+	res, err := c.ListObject(key, prefix, marker, maxKeys, delimiter)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) ListObjectsWithDelimiter(key string, prefix string, marker string, maxKeys int, delimiter string) (*ListResult, error) {
+	c, span := t.start("ListObjectsWithDelimiter", key)
+	res, err := c.ListObjectsWithDelimiter(key, prefix, marker, maxKeys, delimiter)
+	endSpan(span, err)
+	return res, err
+}
+
+// ListObjectsIter isn't covered by a span since the keys are listed lazily
+func (t *tracedComponent) ListObjectsIter(key string, prefix string, options ...ListOptions) *ObjectIterator {
+	return t.c.WithContext(t.ctx).ListObjectsIter(key, prefix, options...)
+}
+
+// WalkObjects covers the whole walk, including fn
+func (t *tracedComponent) WalkObjects(key string, prefix string, fn func(ObjectMeta) error, options ...ListOptions) error {
+	c, span := t.start("WalkObjects", key)
+	err := c.WalkObjects(key, prefix, fn, options...)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) ListObjectVersions(key string, prefix string, keyMarker string, versionIDMarker string, maxKeys int) ([]ObjectVersion, error) {
+	c, span := t.start("ListObjectVersions", key)
+	res, err := c.ListObjectVersions(key, prefix, keyMarker, versionIDMarker, maxKeys)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) SignURL(key string, expired int64, options ...SignOptions) (string, error) {
+	c, span := t.start("SignURL", key)
+	res, err := c.SignURL(key, expired, options...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) SignURLForPut(key string, expired int64, options ...SignOptions) (string, error) {
+	c, span := t.start("SignURLForPut", key)
+	res, err := c.SignURLForPut(key, expired, options...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) SignPostPolicy(key string, expired int64, options ...SignOptions) (*PostPolicy, error) {
+	c, span := t.start("SignPostPolicy", key)
+	res, err := c.SignPostPolicy(key, expired, options...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) GetAndDecompress(key string) (string, error) {
+	c, span := t.start("GetAndDecompress", key)
+	res, err := c.GetAndDecompress(key)
+	span.SetAttributes(traceAttrSize.Int(len(res)))
+	endSpan(span, err)
+	return res, err
+}
+
+// GetAndDecompressAsReader only covers the request, not reading the body
+func (t *tracedComponent) GetAndDecompressAsReader(key string) (io.ReadCloser, error) {
+	c, span := t.start("GetAndDecompressAsReader", key)
+	res, err := c.GetAndDecompressAsReader(key)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) CompressAndPut(key string, reader io.ReadSeeker, meta map[string]string, options ...PutOptions) error {
+	c, span := t.start("CompressAndPut", key)
+	if size, ok := seekerSize(reader); ok {
+		span.SetAttributes(traceAttrSize.Int64(size))
+	}
+	err := c.CompressAndPut(key, reader, meta, options...)
+	endSpan(span, err)
+	return err
+}
+
+// Range only covers the request, not reading the body
+func (t *tracedComponent) Range(key string, offset int64, length int64) (io.ReadCloser, error) {
+	c, span := t.start("Range", key)
+	res, err := c.Range(key, offset, length)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) Exists(key string) (bool, error) {
+	c, span := t.start("Exists", key)
+	res, err := c.Exists(key)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) GetObjectTagging(key string) (map[string]string, error) {
+	c, span := t.start("GetObjectTagging", key)
+	res, err := c.GetObjectTagging(key)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) PutObjectTagging(key string, tags map[string]string) error {
+	c, span := t.start("PutObjectTagging", key)
+	err := c.PutObjectTagging(key, tags)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) GetObjectACL(key string) (string, error) {
+	c, span := t.start("GetObjectACL", key)
+	res, err := c.GetObjectACL(key)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) PutObjectACL(key string, acl string) error {
+	c, span := t.start("PutObjectACL", key)
+	err := c.PutObjectACL(key, acl)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) GetObjectLegalHold(key string) (bool, error) {
+	c, span := t.start("GetObjectLegalHold", key)
+	res, err := c.GetObjectLegalHold(key)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) PutObjectLegalHold(key string, on bool) error {
+	c, span := t.start("PutObjectLegalHold", key)
+	err := c.PutObjectLegalHold(key, on)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) GetObjectRetention(key string) (string, time.Time, error) {
+	c, span := t.start("GetObjectRetention", key)
+	mode, retainUntil, err := c.GetObjectRetention(key)
+	endSpan(span, err)
+	return mode, retainUntil, err
+}
+
+func (t *tracedComponent) PutObjectRetention(key string, mode string, retainUntil time.Time) error {
+	c, span := t.start("PutObjectRetention", key)
+	err := c.PutObjectRetention(key, mode, retainUntil)
+	endSpan(span, err)
+	return err
+}
+
+// Close isn't traced, it sends no requests
+func (t *tracedComponent) Close() error {
+	return t.c.Close()
+}
+
+// resumableUploader traces each request of ResumeUpload, which is unsupported if the component isn't a resumableUploader
+func (t *tracedComponent) resumableUploader(operation string, key string) (resumableUploader, trace.Span, error) {
+	c, span := t.start(operation, key)
+	u, ok := c.(resumableUploader)
+	if !ok {
+		err := fmt.Errorf("ResumeUpload: %w", ErrUnsupported)
+		endSpan(span, err)
+		return nil, nil, err
+	}
+	return u, span, nil
+}
+
+func (t *tracedComponent) createResumableUpload(key string, meta map[string]string, putOptions *putOptions) (string, error) {
+	u, span, err := t.resumableUploader("CreateMultipartUpload", key)
+	if err != nil {
+		return "", err
+	}
+	res, err := u.createResumableUpload(key, meta, putOptions)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) uploadResumablePart(key string, uploadID string, partNumber int, data []byte, putOptions *putOptions) (string, error) {
+	u, span, err := t.resumableUploader("UploadPart", key)
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(traceAttrSize.Int(len(data)))
+	res, err := u.uploadResumablePart(key, uploadID, partNumber, data, putOptions)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) listResumableParts(key string, uploadID string) ([]UploadedPart, error) {
+	u, span, err := t.resumableUploader("ListParts", key)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.listResumableParts(key, uploadID)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) completeResumableUpload(key string, uploadID string, parts []UploadedPart, putOptions *putOptions) error {
+	u, span, err := t.resumableUploader("CompleteMultipartUpload", key)
+	if err != nil {
+		return err
+	}
+	err = u.completeResumableUpload(key, uploadID, parts, putOptions)
+	endSpan(span, err)
+	return err
+}
+
+// bucketManager traces BucketExists and CreateBucket, which are unsupported if the component isn't a bucketManager
+func (t *tracedComponent) bucketManager(operation string) (bucketManager, trace.Span, error) {
+	c, span := t.start(operation, "")
+	m, ok := c.(bucketManager)
+	if !ok {
+		err := fmt.Errorf("%s: %w", operation, ErrUnsupported)
+		endSpan(span, err)
+		return nil, nil, err
+	}
+	return m, span, nil
+}
+
+func (t *tracedComponent) bucketExists() (bool, error) {
+	m, span, err := t.bucketManager("BucketExists")
+	if err != nil {
+		return false, err
+	}
+	res, err := m.bucketExists()
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *tracedComponent) createBucket(options *createBucketOptions) error {
+	m, span, err := t.bucketManager("CreateBucket")
+	if err != nil {
+		return err
+	}
+	err = m.createBucket(options)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) ping() error {
+	c, span := t.start("Ping", "")
+	p, ok := c.(pinger)
+	if !ok {
+		err := fmt.Errorf("Ping: %w", ErrUnsupported)
+		endSpan(span, err)
+		return err
+	}
+	err := p.ping()
+	endSpan(span, err)
+	return err
+}
+
+// symlinker traces PutSymlink and GetSymlink, which are unsupported if the component isn't a symlinker
+func (t *tracedComponent) symlinker(operation string, key string) (symlinker, trace.Span, error) {
+	c, span := t.start(operation, key)
+	s, ok := c.(symlinker)
+	if !ok {
+		err := fmt.Errorf("%s: %w", operation, ErrUnsupported)
+		endSpan(span, err)
+		return nil, nil, err
+	}
+	return s, span, nil
+}
+
+func (t *tracedComponent) putSymlink(symlinkKey string, targetKey string) error {
+	s, span, err := t.symlinker("PutSymlink", symlinkKey)
+	if err != nil {
+		return err
+	}
+	err = s.putSymlink(symlinkKey, targetKey)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedComponent) getSymlink(symlinkKey string) (string, error) {
+	s, span, err := t.symlinker("GetSymlink", symlinkKey)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.getSymlink(symlinkKey)
+	endSpan(span, err)
+	return res, err
+}
@@ -0,0 +1,98 @@
+package awos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracedComponent(c Component) (Component, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return newTracedComponent("test-bucket", c, tp), recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	res := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
+
+func TestTracedComponent(t *testing.T) {
+	inner := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "missing") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(content))
+		}
+	})
+	client, recorder := newTestTracedComponent(inner)
+
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "parent")
+	res, err := client.WithContext(ctx).Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	_, err = client.Get("missing")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	var spans, httpSpans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if strings.HasPrefix(span.Name(), "awos.") {
+			spans = append(spans, span)
+		} else {
+			httpSpans = append(httpSpans, span)
+		}
+	}
+	assert.Len(t, spans, 3)
+	// the spans of the http requests are children of the operation spans
+	assert.Len(t, httpSpans, 3)
+	assert.Equal(t, spans[0].SpanContext().SpanID(), httpSpans[0].Parent().SpanID())
+
+	get := spans[0]
+	assert.Equal(t, "awos.Get", get.Name())
+	assert.Equal(t, trace.SpanKindClient, get.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), get.Parent().SpanID())
+	attrs := spanAttributes(get)
+	assert.Equal(t, "test-bucket", attrs[traceAttrBucket].AsString())
+	assert.Equal(t, guid, attrs[traceAttrKey].AsString())
+	assert.Equal(t, "Get", attrs[traceAttrOperation].AsString())
+	assert.Equal(t, int64(len(content)), attrs[traceAttrSize].AsInt64())
+	assert.Equal(t, codes.Unset, get.Status().Code)
+
+	put := spans[1]
+	assert.Equal(t, "awos.Put", put.Name())
+	assert.False(t, put.Parent().IsValid())
+	assert.Equal(t, int64(len(content)), spanAttributes(put)[traceAttrSize].AsInt64())
+
+	missing := spans[2]
+	assert.Equal(t, "awos.Get", missing.Name())
+	assert.Equal(t, codes.Error, missing.Status().Code)
+	assert.Len(t, missing.Events(), 1)
+	assert.Equal(t, "exception", missing.Events()[0].Name)
+}
+
+func TestBuild_EnableOperationTrace(t *testing.T) {
+	client := DefaultContainer().Build(WithStorageType(StorageTypeMemory), WithBucket("memory-bucket"), WithEnableOperationTrace(true))
+	assert.IsType(t, &tracedComponent{}, client)
+	assert.NoError(t, client.Put(guid, strings.NewReader(content), nil))
+	res, err := client.Get(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+
+	assert.IsType(t, &Memory{}, newTestMemory())
+}
@@ -0,0 +1,78 @@
+package awos
+
+import (
+	"context"
+)
+
+type TransferOptions func(options *transferOptions)
+
+type transferOptions struct {
+	getOptions []GetOptions
+	putOptions []PutOptions
+}
+
+func DefaultTransferOptions() *transferOptions {
+	return &transferOptions{}
+}
+
+// getWithRawContent gets the stored bytes of the objects encoded with gzip or zstd, without GetWithDecompression
+// and the gzip inflated by the http transport, so they match the size of the head and are copied as they are
+func getWithRawContent() GetOptions {
+	return func(options *getOptions) {
+		options.rawContent = true
+	}
+}
+
+// TransferWithGetOptions applies options to the head and the get of the source object, such as GetWithVersionID,
+// GetWithDecompression is ignored
+func TransferWithGetOptions(options ...GetOptions) TransferOptions {
+	return func(transferOpts *transferOptions) {
+		transferOpts.getOptions = append(transferOpts.getOptions, options...)
+	}
+}
+
+// TransferWithPutOptions applies options to the upload of the destination object, such as PutWithPartSize,
+// they override the content type and the metadata of the source
+func TransferWithPutOptions(options ...PutOptions) TransferOptions {
+	return func(transferOpts *transferOptions) {
+		transferOpts.putOptions = append(transferOpts.putOptions, options...)
+	}
+}
+
+// Transfer copies the object of srcKey in src to dstKey in dst by streaming it, so the clients can be of
+// different backends or accounts, e.g. to migrate from oss to s3. The content type, the content encoding
+// and the user metadata are preserved, the encoded objects, such as gzip, are copied as they are stored. It's uploaded with PutFromReader, the objects larger than the part size are uploaded by
+// MultipartUpload while they're read, so the memory is bounded by the parts in flight. The source is read
+// from the version of its head, if the bucket is versioned.
+func Transfer(ctx context.Context, src Component, srcKey string, dst Component, dstKey string, options ...TransferOptions) error {
+	transferOpts := DefaultTransferOptions()
+	for _, opt := range options {
+		opt(transferOpts)
+	}
+	src = src.WithContext(ctx)
+
+	// the listings don't have the user metadata of all the backends
+	meta, err := src.HeadObject(srcKey, transferOpts.getOptions...)
+	if err != nil {
+		return err
+	}
+	getOptions := append(transferOpts.getOptions[:len(transferOpts.getOptions):len(transferOpts.getOptions)], getWithRawContent())
+	if meta.VersionID != "" {
+		getOptions = append(getOptions, GetWithVersionID(meta.VersionID))
+	}
+	reader, err := src.GetAsReader(srcKey, getOptions...)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	putOptions := []PutOptions{PutWithContentLength(meta.Size)}
+	if meta.ContentType != "" {
+		putOptions = append(putOptions, PutWithContentType(meta.ContentType))
+	}
+	if meta.ContentEncoding != "" {
+		putOptions = append(putOptions, PutWithContentEncoding(meta.ContentEncoding))
+	}
+	putOptions = append(putOptions, transferOpts.putOptions...)
+	return PutFromReader(ctx, dst, dstKey, reader, meta.UserMeta, putOptions...)
+}
@@ -0,0 +1,104 @@
+package awos
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	src := newTestComponent(t, StorageTypeOSS, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+guid && r.URL.Path != "/test-bucket/"+guid {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("X-Oss-Meta-Owner", "alice")
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(largeContent))
+	})
+	dst := newTestMemory()
+
+	assert.NoError(t, Transfer(ctx, src, guid, dst, "migrated"))
+	meta, err := dst.HeadObject("migrated")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", meta.ContentType)
+	assert.Equal(t, map[string]string{"owner": "alice"}, meta.UserMeta)
+	res, err := dst.Get("migrated")
+	assert.NoError(t, err)
+	assert.Equal(t, largeContent, res)
+
+	// the put options override the source
+	assert.NoError(t, Transfer(ctx, src, guid, dst, "migrated", TransferWithPutOptions(PutWithContentType("text/plain"))))
+	meta, err = dst.HeadObject("migrated")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", meta.ContentType)
+
+	assert.ErrorIs(t, Transfer(ctx, src, "missing", dst, "missing"), ErrObjectNotFound)
+}
+
+func TestTransfer_Multipart(t *testing.T) {
+	data := make([]byte, 2*MinPartSize+100)
+	rand.Read(data)
+	src := newTestMemory()
+	assert.NoError(t, src.Put(guid, bytes.NewReader(data), map[string]string{"owner": "alice"}, PutWithContentType("video/mp4")))
+
+	var contentType, owner string
+	server := &multipartServer{parts: make(map[int][]byte)}
+	dst := newTestComponent(t, StorageTypeS3, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Query().Get("uploadId") == "" {
+			contentType, owner = r.Header.Get("Content-Type"), r.Header.Get("X-Amz-Meta-Owner")
+		}
+		server.handle(w, r)
+	})
+
+	err := Transfer(context.Background(), src, guid, dst, "key", TransferWithPutOptions(PutWithPartSize(MinPartSize)))
+	assert.NoError(t, err)
+	assert.Len(t, server.parts, 3)
+	assert.True(t, bytes.Equal(data, server.completed))
+	assert.Equal(t, "video/mp4", contentType)
+	assert.Equal(t, "alice", owner)
+}
+
+func TestTransfer_ContentEncoding(t *testing.T) {
+	for storageType, prefix := range map[string]string{StorageTypeS3: "X-Amz-", StorageTypeOSS: "X-Oss-"} {
+		t.Run(storageType, func(t *testing.T) {
+			server := newBucketServer(prefix)
+			var acceptEncoding string
+			src := newTestComponent(t, storageType, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					acceptEncoding = r.Header.Get("Accept-Encoding")
+				}
+				server.ServeHTTP(w, r)
+			})
+			assert.NoError(t, src.Put(guid, strings.NewReader(largeContent), nil, PutWithCompression(CompressionGzip)))
+			dst := newTestMemory()
+
+			// the gzip bytes are copied as they are, the size of the head is the one of them
+			assert.NoError(t, Transfer(context.Background(), src, guid, dst, "migrated"))
+			assert.Equal(t, "identity", acceptEncoding)
+			meta, err := dst.HeadObject("migrated")
+			assert.NoError(t, err)
+			assert.Equal(t, CompressionGzip, meta.ContentEncoding)
+			data, err := dst.GetBytes("migrated")
+			assert.NoError(t, err)
+			assert.Equal(t, server.object("/test-bucket/"+guid).data, data)
+			res, err := dst.Get("migrated", GetWithDecompression())
+			assert.NoError(t, err)
+			assert.Equal(t, largeContent, res)
+
+			// the decompression of the get options doesn't change the stored bytes
+			assert.NoError(t, Transfer(context.Background(), src, guid, dst, "migrated", TransferWithGetOptions(GetWithDecompression())))
+			data, err = dst.GetBytes("migrated")
+			assert.NoError(t, err)
+			assert.Equal(t, server.object("/test-bucket/"+guid).data, data)
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package awos
 
-import "io"
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/avast/retry-go"
+)
 
 // CombinedReadCloser combined a ReadCloser and a Readers to a new ReaderCloser
 // which will read from reader and close origin closer
@@ -17,3 +24,16 @@ func (combined CombinedReadCloser) Read(b []byte) (int, error) {
 func (combined CombinedReadCloser) Close() error {
 	return combined.ReadCloser.Close()
 }
+
+// putRetryOptions retries failed puts 3 times, except the ones which would fail again
+func putRetryOptions() []retry.Option {
+	return []retry.Option{
+		retry.Attempts(3),
+		retry.Delay(1 * time.Second),
+		retry.LastErrorOnly(true),
+		// the failed preconditions and denied accesses fail the same way again
+		retry.RetryIf(func(err error) bool {
+			return !errors.Is(err, ErrPreconditionFailed) && !errors.Is(err, ErrAccessDenied) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+		}),
+	}
+}
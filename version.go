@@ -0,0 +1,33 @@
+package awos
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ObjectVersion is a version of an object in a versioned bucket
+type ObjectVersion struct {
+	Key       string
+	VersionID string
+	IsLatest  bool
+	// IsDeleteMarker reports whether the version is a delete marker, which has no content
+	IsDeleteMarker bool
+	LastModified   time.Time
+	Size           int64
+	ETag           string
+}
+
+// sortObjectVersions sorts versions by key, and the newest first for the same key, like s3 and oss list them
+func sortObjectVersions(versions []ObjectVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Key != versions[j].Key {
+			return versions[i].Key < versions[j].Key
+		}
+		return versions[i].LastModified.After(versions[j].LastModified)
+	})
+}
+
+var errMemoryVersioning = fmt.Errorf("memory doesn't support versioning: %w", ErrUnsupported)
+
+var errFSVersioning = fmt.Errorf("fs doesn't support versioning: %w", ErrUnsupported)
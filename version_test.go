@@ -0,0 +1,83 @@
+package awos
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testListVersionsResult = `<ListVersionsResult>
+<Name>test-bucket</Name>
+<Version><Key>a</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest><LastModified>2026-01-01T00:00:00.000Z</LastModified><ETag>"e1"</ETag><Size>1</Size></Version>
+<Version><Key>a</Key><VersionId>v2</VersionId><IsLatest>true</IsLatest><LastModified>2026-01-02T00:00:00.000Z</LastModified><ETag>"e2"</ETag><Size>2</Size></Version>
+<DeleteMarker><Key>b</Key><VersionId>d1</VersionId><IsLatest>true</IsLatest><LastModified>2026-01-03T00:00:00.000Z</LastModified></DeleteMarker>
+</ListVersionsResult>`
+
+// versionHandler records the versionId query of object requests and serves a version list
+func versionHandler(prefix string, versionIDs *[]string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		query := r.URL.Query()
+		if _, ok := query["versions"]; ok {
+			*versionIDs = append(*versionIDs, "markers:"+query.Get("key-marker")+","+query.Get("version-id-marker"))
+			_, _ = w.Write([]byte(testListVersionsResult))
+			return
+		}
+		*versionIDs = append(*versionIDs, r.Method+":"+query.Get("versionId"))
+		w.Header().Set(prefix+"Version-Id", "v2")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(content))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func TestObjectVersions(t *testing.T) {
+	for storageType, prefix := range map[string]string{StorageTypeS3: "X-Amz-", StorageTypeOSS: "X-Oss-"} {
+		t.Run(storageType, func(t *testing.T) {
+			var versionIDs []string
+			client := newTestComponent(t, storageType, versionHandler(prefix, &versionIDs))
+
+			res, err := client.GetVersion(guid, "v1")
+			assert.NoError(t, err)
+			assert.Equal(t, content, res)
+			meta, err := client.Head(guid, []string{HeadVersionID}, GetWithVersionID("v1"))
+			assert.NoError(t, err)
+			assert.Equal(t, "v2", meta[HeadVersionID])
+			assert.NoError(t, client.DelVersion(guid, "v1"))
+			assert.Equal(t, []string{"GET:v1", "HEAD:v1", "DELETE:v1"}, versionIDs)
+
+			versions, err := client.ListObjectVersions(guid, "", "a", "v2", 10)
+			assert.NoError(t, err)
+			assert.Equal(t, "markers:a,v2", versionIDs[len(versionIDs)-1])
+			assert.Equal(t, []ObjectVersion{
+				{Key: "a", VersionID: "v2", IsLatest: true, LastModified: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Size: 2, ETag: `"e2"`},
+				{Key: "a", VersionID: "v1", LastModified: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Size: 1, ETag: `"e1"`},
+				{Key: "b", VersionID: "d1", IsLatest: true, IsDeleteMarker: true, LastModified: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+			}, versions)
+		})
+	}
+}
+
+func TestMemory_ObjectVersions(t *testing.T) {
+	client := newTestMemory()
+	assert.NoError(t, client.Put(guid, nil, nil))
+
+	_, err := client.GetVersion(guid, "v1")
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+	_, err = client.Get(guid, GetWithVersionID("v1"))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+	_, err = client.Head(guid, nil, GetWithVersionID("v1"))
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+	assert.True(t, errors.Is(client.DelVersion(guid, "v1"), ErrUnsupported))
+	_, err = client.ListObjectVersions(guid, "", "", "", 0)
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+}